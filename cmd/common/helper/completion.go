@@ -0,0 +1,209 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package helper
+
+import (
+	"apm/lib"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// CompletionLimit наибольшее число вариантов, выводимых при дополнении.
+const CompletionLimit = 50
+
+// completionPrefixEnv переменная, в которой сценарии дополнения передают дополняемое слово.
+const completionPrefixEnv = "APM_COMPLETE_PREFIX"
+
+// completionFlag флаг, с которым оболочка запрашивает варианты дополнения.
+const completionFlag = "--generate-shell-completion"
+
+// Completer возвращает варианты дополнения, начинающиеся с prefix. Дополнение не должно требовать
+// прав root и изменять базу, при ошибке возвращается пустой список.
+type Completer func(ctx context.Context, prefix string) []string
+
+// StaticCompleter возвращает Completer с фиксированным списком вариантов.
+func StaticCompleter(values []string) Completer {
+	return func(ctx context.Context, prefix string) []string {
+		return values
+	}
+}
+
+// SetupCompletion назначает дополнение команде cmd и всем её подкомандам. Значения флагов дополняются
+// из flags по основному имени флага, позиционные аргументы — из args по пути команды относительно cmd,
+// например "install" или "config repo remove". В остальных случаях дополняются флаги и подкоманды.
+func SetupCompletion(cmd *cli.Command, flags map[string]Completer, args map[string]Completer) {
+	setupCompletion(cmd, "", flags, args)
+}
+
+func setupCompletion(cmd *cli.Command, path string, flags map[string]Completer, args map[string]Completer) {
+	for _, sub := range cmd.Commands {
+		subPath := strings.TrimSpace(path + " " + sub.Name)
+		setupCompletion(sub, subPath, flags, args)
+	}
+
+	if cmd.ShellComplete == nil {
+		cmd.ShellComplete = shellComplete(flags, args[path])
+	}
+}
+
+// shellComplete возвращает функцию дополнения команды.
+func shellComplete(flags map[string]Completer, args Completer) cli.ShellCompleteFunc {
+	return func(ctx context.Context, cmd *cli.Command) {
+		prefix := os.Getenv(completionPrefixEnv)
+		last := lastCompletionArg()
+
+		if strings.HasPrefix(last, "-") {
+			if flag := findFlag(cmd, last); flag != "" && flags[flag] != nil {
+				printCompletions(cmd, flags[flag](ctx, prefix), prefix)
+				return
+			}
+		} else if args != nil {
+			printCompletions(cmd, args(ctx, prefix), prefix)
+			return
+		}
+
+		cli.DefaultCompleteWithFlags(ctx, cmd)
+	}
+}
+
+// lastCompletionArg возвращает аргумент, стоящий перед флагом запроса дополнения.
+func lastCompletionArg() string {
+	for i := len(os.Args) - 1; i > 0; i-- {
+		if os.Args[i] == completionFlag {
+			return os.Args[i-1]
+		}
+	}
+
+	return ""
+}
+
+// findFlag возвращает основное имя флага команды, записанного в аргументе как --name или -n.
+func findFlag(cmd *cli.Command, arg string) string {
+	for _, flag := range cmd.Flags {
+		for _, name := range flag.Names() {
+			if arg == "--"+name || (len(name) == 1 && arg == "-"+name) {
+				return flag.Names()[0]
+			}
+		}
+	}
+
+	return ""
+}
+
+// printCompletions выводит не более CompletionLimit вариантов, начинающихся с prefix.
+func printCompletions(cmd *cli.Command, values []string, prefix string) {
+	sort.Strings(values)
+
+	printed := 0
+	for _, value := range values {
+		if printed == CompletionLimit {
+			break
+		}
+		if strings.HasPrefix(value, prefix) {
+			_, _ = fmt.Fprintln(cmd.Root().Writer, value)
+			printed++
+		}
+	}
+}
+
+// CompletionCommand команда вывода сценария дополнения для bash, zsh и fish. В отличие от встроенных
+// сценариев urfave/cli сценарии bash и zsh передают дополняемое слово, чтобы варианты из базы
+// отбирались по префиксу.
+func CompletionCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "completion",
+		Usage:     lib.T_("Output shell completion script for bash, zsh or fish"),
+		ArgsUsage: "shell",
+		Hidden:    true,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			var script string
+			switch shell := cmd.Args().First(); shell {
+			case "bash":
+				script = fmt.Sprintf(bashCompletionScript, cmd.Root().Name)
+			case "zsh":
+				script = fmt.Sprintf(zshCompletionScript, cmd.Root().Name)
+			case "fish":
+				fish, err := cmd.Root().ToFishCompletion()
+				if err != nil {
+					return err
+				}
+				script = fish
+			default:
+				return fmt.Errorf(lib.T_("Unknown shell %s, available shells: bash, zsh, fish"), shell)
+			}
+
+			_, err := fmt.Fprint(cmd.Root().Writer, script)
+			return err
+		},
+	}
+}
+
+const bashCompletionScript = `#!/bin/bash
+
+__%[1]s_bash_autocomplete() {
+  local cur words cword requestComp opts
+  COMPREPLY=()
+  if declare -F _init_completion >/dev/null 2>&1; then
+    _init_completion -n "=:" || return
+  else
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    words=("${COMP_WORDS[@]}")
+    cword=$COMP_CWORD
+  fi
+  words=("${words[@]:0:$cword}")
+  if [[ "$cur" == "-"* ]]; then
+    requestComp="${words[*]} ${cur} ` + completionFlag + `"
+  else
+    requestComp="${words[*]} ` + completionFlag + `"
+  fi
+  opts=$(export ` + completionPrefixEnv + `="${cur}"; eval "${requestComp}" 2>/dev/null)
+  COMPREPLY=($(compgen -W "${opts}" -- "${cur}"))
+  return 0
+}
+
+complete -o bashdefault -o default -o nospace -F __%[1]s_bash_autocomplete %[1]s
+`
+
+const zshCompletionScript = `#compdef %[1]s
+compdef _%[1]s %[1]s
+
+_%[1]s() {
+  local -a opts
+  local current
+  current=${words[-1]}
+  if [[ "$current" == "-"* ]]; then
+    opts=("${(@f)$(${words[@]:0:#words[@]-1} ${current} ` + completionFlag + `)}")
+  else
+    opts=("${(@f)$(` + completionPrefixEnv + `=${current} ${words[@]:0:#words[@]-1} ` + completionFlag + `)}")
+  fi
+
+  if [[ "${opts[1]}" != "" ]]; then
+    _describe 'values' opts
+  else
+    _files
+  fi
+}
+
+if [ "$funcstack[1]" = "_%[1]s" ]; then
+  _%[1]s
+fi
+`
@@ -16,7 +16,24 @@
 
 package helper
 
-import "github.com/godbus/dbus/v5/introspect"
+import (
+	"apm/cmd/common/reply"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// ExportAPIVersion публикует свойство ApiVersion интерфейса iface: версию контракта ответов reply.APIVersion.
+func ExportAPIVersion(conn *dbus.Conn, iface string) error {
+	_, err := prop.Export(conn, "/com/application/APM", prop.Map{
+		iface: {
+			"ApiVersion": {Value: reply.APIVersion, Writable: false, Emit: prop.EmitFalse},
+		},
+	})
+
+	return err
+}
 
 const UserIntrospectXML = `
 <node>
@@ -24,9 +41,16 @@ const UserIntrospectXML = `
     <signal name="Notification">
       <arg type="s" name="message" direction="out"/>
     </signal>
+
+    <signal name="ContainerListUpdated">
+      <arg type="x" name="containersCount" direction="out"/>
+      <arg type="s" name="transaction" direction="out"/>
+    </signal>
   </interface>
 
   <interface name="com.application.distrobox">
+    <property name="ApiVersion" type="s" access="read"/>
+
     <method name="Update">
       <arg direction="in" type="s" name="container"/>
       <arg direction="in" type="s" name="transaction"/>
@@ -55,6 +79,7 @@ const UserIntrospectXML = `
     <method name="Search">
       <arg direction="in" type="s" name="container"/>
       <arg direction="in" type="s" name="packageName"/>
+      <arg direction="in" type="i" name="installed"/>
       <arg direction="in" type="s" name="transaction"/>
       <arg direction="out" type="s" name="result"/>
     </method>
@@ -65,6 +90,13 @@ const UserIntrospectXML = `
       <arg direction="out" type="s" name="result"/>
     </method>
 
+    <method name="CountDistroPackages">
+      <arg direction="in" type="s" name="container"/>
+      <arg direction="in" type="s" name="filtersJSON"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
     <method name="Install">
       <arg direction="in" type="s" name="container"/>
       <arg direction="in" type="s" name="packageName"/>
@@ -73,6 +105,13 @@ const UserIntrospectXML = `
       <arg direction="out" type="s" name="result"/>
     </method>
 
+    <method name="ReinstallPackage">
+      <arg direction="in" type="s" name="container"/>
+      <arg direction="in" type="s" name="packageName"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
     <method name="Remove">
       <arg direction="in" type="s" name="container"/>
       <arg direction="in" type="s" name="packageName"/>
@@ -81,6 +120,35 @@ const UserIntrospectXML = `
       <arg direction="out" type="s" name="result"/>
     </method>
 
+    <method name="ImportPackageList">
+      <arg direction="in" type="s" name="container"/>
+      <arg direction="in" type="s" name="filePath"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="DryRunInstall">
+      <arg direction="in" type="s" name="container"/>
+      <arg direction="in" type="s" name="packageName"/>
+      <arg direction="in" type="b" name="export"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="DryRunRemove">
+      <arg direction="in" type="s" name="container"/>
+      <arg direction="in" type="s" name="packageName"/>
+      <arg direction="in" type="b" name="onlyExport"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="ExportAll">
+      <arg direction="in" type="s" name="container"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
     <method name="ContainerList">
       <arg direction="in" type="s" name="transaction"/>
       <arg direction="out" type="s" name="result"/>
@@ -95,13 +163,89 @@ const UserIntrospectXML = `
       <arg direction="out" type="s" name="result"/>
     </method>
 
+    <method name="InitContainer">
+      <arg direction="in" type="s" name="containerName"/>
+      <arg direction="in" type="s" name="hookFile"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
     <method name="ContainerRemove">
       <arg direction="in" type="s" name="name"/>
       <arg direction="in" type="s" name="transaction"/>
       <arg direction="out" type="s" name="result"/>
     </method>
+    <method name="ContainerSetNetwork">
+      <arg direction="in" type="s" name="containerName"/>
+      <arg direction="in" type="s" name="networkMode"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    <method name="SetResourceLimits">
+      <arg direction="in" type="s" name="containerName"/>
+      <arg direction="in" type="i" name="memoryMB"/>
+      <arg direction="in" type="d" name="cpuPercent"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    <method name="GetResourceLimits">
+      <arg direction="in" type="s" name="containerName"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    <method name="ContainerHealthCheck">
+      <arg direction="in" type="s" name="containerName"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    <method name="ContainerAddVolume">
+      <arg direction="in" type="s" name="containerName"/>
+      <arg direction="in" type="s" name="hostPath"/>
+      <arg direction="in" type="s" name="containerPath"/>
+      <arg direction="in" type="b" name="readonly"/>
+      <arg direction="in" type="b" name="recreate"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    <method name="ContainerRemoveVolume">
+      <arg direction="in" type="s" name="containerName"/>
+      <arg direction="in" type="s" name="containerPath"/>
+      <arg direction="in" type="b" name="recreate"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    <method name="ContainerListVolumes">
+      <arg direction="in" type="s" name="containerName"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    <method name="RenameExport">
+      <arg direction="in" type="s" name="container"/>
+      <arg direction="in" type="s" name="packageName"/>
+      <arg direction="in" type="s" name="displayName"/>
+      <arg direction="in" type="s" name="icon"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    <method name="AddInitHook">
+      <arg direction="in" type="s" name="container"/>
+      <arg direction="in" type="s" name="hookCommand"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    <method name="RemoveInitHook">
+      <arg direction="in" type="s" name="container"/>
+      <arg direction="in" type="x" name="hookID"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    <method name="ListInitHooks">
+      <arg direction="in" type="s" name="container"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
   </interface>
-` + introspect.IntrospectDataString + `</node>`
+` + introspect.IntrospectDataString + prop.IntrospectDataString + `</node>`
 
 const SystemIntrospectXML = `
 <node>
@@ -109,10 +253,23 @@ const SystemIntrospectXML = `
     <signal name="Notification">
       <arg type="s" name="message" direction="out"/>
     </signal>
+
+    <signal name="UpdatesAvailable">
+      <arg type="b" name="imageUpdate" direction="out"/>
+      <arg type="x" name="packagesCount" direction="out"/>
+      <arg type="s" name="transaction" direction="out"/>
+    </signal>
+
+    <signal name="ConfigChanged">
+      <arg type="b" name="valid" direction="out"/>
+      <arg type="s" name="summary" direction="out"/>
+    </signal>
   </interface>
 
   <interface name="com.application.system">
 
+    <property name="ApiVersion" type="s" access="read"/>
+
     <method name="Install">
       <arg direction="in" type="as" name="packages"/>
       <arg direction="in" type="b" name="applyAtomic"/>
@@ -131,12 +288,48 @@ const SystemIntrospectXML = `
       <arg direction="in" type="s" name="transaction"/>
       <arg direction="out" type="s" name="result"/>
     </method>
+
+    <method name="CheckForUpdates">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
     
     <method name="List">
       <arg direction="in" type="s" name="paramsJSON"/>
       <arg direction="in" type="s" name="transaction"/>
       <arg direction="out" type="s" name="result"/>
     </method>
+
+    <method name="CountPackages">
+      <arg direction="in" type="s" name="paramsJSON"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ListUpgradablePackages">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="AptCacheStats">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="Doctor">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="QuickStats">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="Recover">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
     
     <method name="Info">
       <arg direction="in" type="s" name="packageName"/>
@@ -144,12 +337,23 @@ const SystemIntrospectXML = `
       <arg direction="out" type="s" name="result"/>
     </method>
     
+    <method name="AllVersions">
+      <arg direction="in" type="s" name="packageName"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
     <method name="CheckInstall">
       <arg direction="in" type="as" name="packages"/>
       <arg direction="in" type="s" name="transaction"/>
       <arg direction="out" type="s" name="result"/>
     </method>
 
+    <method name="UpgradeDryRun">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
     <method name="CheckRemove">
       <arg direction="in" type="as" name="packages"/>
       <arg direction="in" type="s" name="transaction"/>
@@ -168,11 +372,161 @@ const SystemIntrospectXML = `
       <arg direction="out" type="s" name="result"/>
     </method>
     
+    <method name="ImageApplySkipValidation">
+      <arg direction="in" type="b" name="skipValidation"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ImageApplyNoCached">
+      <arg direction="in" type="b" name="noCache"/>
+      <arg direction="in" type="b" name="pullAlways"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ImageApplyArch">
+      <arg direction="in" type="s" name="arch"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="ImageApplyPackagesOnly">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ImagePrune">
+      <arg direction="in" type="x" name="keepLast"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="ImageCacheSize">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="ImageCachePrune">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="ImageGC">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="ImageRollbackList">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="GenerateSBOM">
+      <arg direction="in" type="s" name="format"/>
+      <arg direction="in" type="s" name="output"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="GenerateManifest">
+      <arg direction="in" type="s" name="output"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ApplyManifest">
+      <arg direction="in" type="s" name="filePath"/>
+      <arg direction="in" type="b" name="applyAtomic"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ImageBuild">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ImageSwitch">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ImageSwitchTo">
+      <arg direction="in" type="s" name="reference"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
     <method name="ImageHistory">
       <arg direction="in" type="s" name="transaction"/>
       <arg direction="in" type="s" name="imageName"/>
       <arg direction="in" type="x" name="limit"/>
       <arg direction="in" type="x" name="offset"/>
+      <arg direction="in" type="x" name="since"/>
+      <arg direction="in" type="x" name="until"/>
+      <arg direction="in" type="s" name="status"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="SizeHistogram">
+      <arg direction="in" type="d" name="bucketSizeMB"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="PinVersion">
+      <arg direction="in" type="s" name="packageName"/>
+      <arg direction="in" type="s" name="version"/>
+      <arg direction="in" type="i" name="priority"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="UnpinVersion">
+      <arg direction="in" type="s" name="packageName"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ListPins">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ListMaintainers">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="PackagesByMaintainer">
+      <arg direction="in" type="s" name="maintainer"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ManuallyInstalledPackages">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="OperationsHistory">
+      <arg direction="in" type="s" name="op"/>
+      <arg direction="in" type="x" name="limit"/>
+      <arg direction="in" type="x" name="offset"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ImageHistoryShow">
+      <arg direction="in" type="x" name="id"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="GetBuildLog">
+      <arg direction="in" type="x" name="historyId"/>
+      <arg direction="in" type="s" name="transaction"/>
       <arg direction="out" type="s" name="result"/>
     </method>
     
@@ -181,9 +535,121 @@ const SystemIntrospectXML = `
       <arg direction="out" type="s" name="result"/>
     </method>
     
+    <method name="ImageUpdateForce">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
     <method name="ImageStatus">
       <arg direction="in" type="s" name="transaction"/>
       <arg direction="out" type="s" name="result"/>
     </method>
+    
+    <method name="ImageCheck">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ImagePlan">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+    
+    <method name="ImageCancelReboot">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="GetAvailableUpdates">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="AddAptSourceLayer">
+      <arg direction="in" type="s" name="sourceLine"/>
+      <arg direction="in" type="s" name="keyURL"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="RemoveAptSourceLayer">
+      <arg direction="in" type="x" name="id"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="ListAptSourceLayers">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="ImportDockerfile">
+      <arg direction="in" type="s" name="dockerfilePath"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="AddEnvLayer">
+      <arg direction="in" type="s" name="key"/>
+      <arg direction="in" type="s" name="value"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="AddLabel">
+      <arg direction="in" type="s" name="key"/>
+      <arg direction="in" type="s" name="value"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="RemoveLabel">
+      <arg direction="in" type="s" name="key"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="RemoveEnvLayer">
+      <arg direction="in" type="s" name="key"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="ListEnvLayers">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="PinPackageInConfig">
+      <arg direction="in" type="s" name="packageName"/>
+      <arg direction="in" type="s" name="version"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="UnpinPackageInConfig">
+      <arg direction="in" type="s" name="packageName"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="ListPinnedPackages">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="ImageCheckConflicts">
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
+
+    <method name="AddRepository">
+      <arg direction="in" type="s" name="repoURL"/>
+      <arg direction="in" type="s" name="component"/>
+      <arg direction="in" type="s" name="keyURL"/>
+      <arg direction="in" type="s" name="vendor"/>
+      <arg direction="in" type="s" name="transaction"/>
+      <arg direction="out" type="s" name="result"/>
+    </method>
   </interface>
-` + introspect.IntrospectDataString + `</node>`
+` + introspect.IntrospectDataString + prop.IntrospectDataString + `</node>`
@@ -20,8 +20,10 @@ import (
 	"apm/lib"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // GetVersionFromAptCache преобразует полную версию пакетов из apt ALT в коротких вид
@@ -73,3 +75,89 @@ func ParseBool(val interface{}) (bool, bool) {
 	}
 	return false, false
 }
+
+// ParseTimeBound разбирает границу временного интервала: относительную длительность назад от now
+// (30d, 2w, 12h, 45m), дату 2006-01-02 или время в формате RFC3339. Пустая строка даёт нулевое время.
+func ParseTimeBound(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+
+	// Дни и недели time.ParseDuration не поддерживает
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[value[len(value)-1]]; ok {
+		if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf(lib.T_("Invalid time %s. Use a duration such as 30d, 2w, 12h or a date such as 2006-01-02"), value)
+}
+
+// ClosestMatches возвращает до limit строк из candidates, наиболее похожих на value по расстоянию Левенштейна.
+func ClosestMatches(value string, candidates []string, limit int) []string {
+	type match struct {
+		value    string
+		distance int
+	}
+
+	// Слишком непохожие варианты не предлагаем
+	maxDistance := len(value)/2 + 1
+
+	var matches []match
+	for _, candidate := range candidates {
+		distance := levenshtein(value, candidate)
+		if distance <= maxDistance {
+			matches = append(matches, match{value: candidate, distance: distance})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance == matches[j].distance {
+			return matches[i].value < matches[j].value
+		}
+		return matches[i].distance < matches[j].distance
+	})
+
+	var result []string
+	for i := 0; i < len(matches) && i < limit; i++ {
+		result = append(result, matches[i].value)
+	}
+
+	return result
+}
+
+// levenshtein вычисляет расстояние Левенштейна между двумя строками.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -17,6 +17,7 @@
 package icon
 
 import (
+	"apm/cmd/common/reply"
 	"apm/cmd/distrobox/service"
 	"apm/lib"
 	"bytes"
@@ -65,6 +66,9 @@ func (s *Service) GetIcon(pkgName, container string) ([]byte, error) {
 
 // ReloadIcons загружает и сохраняет иконки из SWCatalog в базу данных.
 func (s *Service) ReloadIcons(ctx context.Context) error {
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("icon.ReloadIcons"), reply.WithProgress(0))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("icon.ReloadIcons"), reply.WithProgress(100))
+
 	containerList, err := s.serviceDistroAPI.GetContainerList(ctx, true)
 	if err != nil {
 		return err
@@ -86,7 +90,13 @@ func (s *Service) ReloadIcons(ctx context.Context) error {
 	}
 
 	// Обработка иконок для каждого контейнера
-	for _, distroContainer := range containerList {
+	for i, distroContainer := range containerList {
+		reply.CreateEventNotification(ctx, reply.StateBefore,
+			reply.WithEventName("icon.ReloadIcons"),
+			reply.WithProgress(float64((i+1)*100/(len(containerList)+1))),
+			reply.WithMessage(fmt.Sprintf(lib.T_("Loading icons: %s"), distroContainer.ContainerName)),
+		)
+
 		if distroContainer.OS == "Arch" {
 			distroPackages, err := s.getPackages(ctx, distroContainer.ContainerName)
 			if err != nil {
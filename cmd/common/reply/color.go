@@ -0,0 +1,107 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/lib"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Режимы цветного вывода, задаваемые флагом --color.
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// Стили значений текстового вывода по смыслу поля.
+var (
+	successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	failureStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+)
+
+// SetColorMode включает или отключает цвета текстового вывода. В режиме auto цвета выводятся
+// только в терминал и при пустой переменной NO_COLOR. Форматы json, yaml, csv и dbus цвета не используют.
+func SetColorMode(mode string) error {
+	switch mode {
+	case ColorAuto, "":
+		if !IsTTY() || os.Getenv("NO_COLOR") != "" {
+			lipgloss.SetColorProfile(termenv.Ascii)
+		}
+	case ColorAlways:
+		// Вне терминала lipgloss сам отключает цвета, поэтому профиль задаётся явно
+		if !IsTTY() {
+			lipgloss.SetColorProfile(termenv.ANSI256)
+		}
+	case ColorNever:
+		lipgloss.SetColorProfile(termenv.Ascii)
+	default:
+		return Errorf(ErrorCodeInvalidArgument, lib.T_("Unknown color mode %s. Supported modes: %s, %s, %s"), mode, ColorAuto, ColorAlways, ColorNever)
+	}
+
+	return nil
+}
+
+// fieldStyle возвращает стиль значения поля: установленные пакеты выделяются зелёным,
+// удаляемые и ошибки — красным, предупреждения и необновлённые пакеты — жёлтым.
+func fieldStyle(key string) (lipgloss.Style, bool) {
+	switch key {
+	case "installed", "newInstalledPackages", "newInstalledCount", "extraInstalled", "upgradedPackages", "upgradedCount",
+		"packagesToInstall", "exported":
+		return successStyle, true
+	case "removedPackages", "removedCount", "packagesToRemove", "failed", "error":
+		return failureStyle, true
+	case "warning", "warnings", "notUpgradedCount", "notUpgradedPackages":
+		return warningStyle, true
+	default:
+		return lipgloss.Style{}, false
+	}
+}
+
+// colorizeField окрашивает значение поля по его смыслу. Нулевые счётчики и ложные флаги не выделяются.
+func colorizeField(key string, value interface{}, text string) string {
+	style, ok := fieldStyle(key)
+	if !ok {
+		return text
+	}
+
+	switch v := value.(type) {
+	case bool:
+		if !v {
+			return text
+		}
+	case int:
+		if v == 0 {
+			return text
+		}
+	case float64:
+		if v == 0 {
+			return text
+		}
+	}
+
+	return style.Render(text)
+}
+
+// colorizeMessage окрашивает сообщение об ошибке.
+func colorizeMessage(message string) string {
+	return failureStyle.Render(message)
+}
@@ -0,0 +1,109 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/tree"
+)
+
+// compactValueLimit длина, до которой обрезаются строковые значения в компактных блоках списка.
+const compactValueLimit = 80
+
+// listItemTitles поля заголовка компактного блока: основное поле и поля, дописываемые после него.
+// Используется первое описание, основное поле которого есть в элементе списка.
+var listItemTitles = []struct {
+	main    string
+	details []string
+}{
+	{"name", []string{"version", "os"}},
+	{"image", []string{"date"}},
+}
+
+// listItemMarkers логические поля, которые при значении true отмечаются в заголовке блока, и стиль отметки.
+var listItemMarkers = []struct {
+	key   string
+	style *lipgloss.Style
+}{
+	{"installed", &successStyle},
+	{"running", &successStyle},
+	{"unhealthy", &failureStyle},
+}
+
+// listItemTree строит узел элемента списка. Пакеты, контейнеры и записи истории выводятся компактным
+// блоком: в первой строке название, версия и отметки, ниже описание и остальные поля с обрезкой длинных
+// значений. Прочие элементы выводятся полным деревом.
+func listItemTree(index int, item map[string]interface{}, command string, path string) *tree.Tree {
+	prefix := fmt.Sprintf("%d)", index)
+
+	for _, title := range listItemTitles {
+		main, ok := item[title.main].(string)
+		if !ok || main == "" {
+			continue
+		}
+
+		return compactItemTree(prefix, title.main, title.details, item, command, path)
+	}
+
+	return buildTreeFromMap(prefix, item, command, path)
+}
+
+// compactItemTree строит компактный блок элемента списка с заголовком из поля mainKey и полей detailKeys.
+func compactItemTree(prefix string, mainKey string, detailKeys []string, item map[string]interface{}, command string, path string) *tree.Tree {
+	used := map[string]bool{mainKey: true, "description": true}
+
+	parts := []string{prefix, formatField("name", item[mainKey])}
+	for _, key := range detailKeys {
+		if value, ok := item[key].(string); ok && value != "" {
+			parts = append(parts, value)
+			used[key] = true
+		}
+	}
+	for _, marker := range listItemMarkers {
+		if marked, ok := item[marker.key].(bool); ok {
+			used[marker.key] = true
+			if marked {
+				parts = append(parts, marker.style.Render(fmt.Sprintf("[%s]", TranslateField(command, joinFieldPath(path, marker.key)))))
+			}
+		}
+	}
+
+	t := tree.New().Root(strings.Join(parts, " "))
+	if description, ok := item["description"].(string); ok && description != "" {
+		firstLine := strings.TrimSpace(strings.SplitN(description, "\n", 2)[0])
+		t.Child(truncateTableCell(firstLine, compactValueLimit))
+	}
+
+	rest := make(map[string]interface{}, len(item))
+	for key, value := range item {
+		if used[key] {
+			continue
+		}
+		if text, ok := value.(string); ok {
+			value = truncateTableCell(strings.ReplaceAll(text, "\n", " "), compactValueLimit)
+		}
+		rest[key] = value
+	}
+	if len(rest) > 0 {
+		t.Child(buildTreeFromMap("", rest, command, path).Children())
+	}
+
+	return t
+}
@@ -0,0 +1,68 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/lib"
+	"os"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ConfirmationMode способ подтверждения изменений перед их выполнением.
+type ConfirmationMode int
+
+const (
+	// ConfirmationDialog подтверждение запрашивается у пользователя в диалоге.
+	ConfirmationDialog ConfirmationMode = iota
+	// ConfirmationGranted изменения подтверждены заранее флагом --yes или вызывающей стороной D-Bus.
+	ConfirmationGranted
+	// ConfirmationDeferred диалог не показывается, вместо выполнения возвращаются планируемые изменения
+	// с отметкой confirmationRequired, чтобы вызывающая программа повторила вызов с --yes.
+	ConfirmationDeferred
+)
+
+// assumeYes подтверждает изменения без диалога, включается флагом --yes.
+var assumeYes = false
+
+// SetAssumeYes включает или отключает подтверждение изменений без диалога.
+func SetAssumeYes(enabled bool) {
+	assumeYes = enabled
+}
+
+// GetConfirmationMode определяет, как подтвердить изменения. Диалог показывается только в текстовых форматах,
+// когда и ввод, и вывод подключены к терминалу. Без терминала ответить на диалог некому, и вместо зависания
+// в ожидании ввода возвращается ошибка с кодом ErrorCodeConfirmationRequired.
+func GetConfirmationMode() (ConfirmationMode, error) {
+	if assumeYes {
+		return ConfirmationGranted, nil
+	}
+
+	switch lib.Env.Format {
+	case "dbus":
+		return ConfirmationGranted, nil
+	case "json", "jsonstream":
+		return ConfirmationDeferred, nil
+	}
+
+	if !IsTTY() || !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return ConfirmationDialog, Errorf(ErrorCodeConfirmationRequired,
+			lib.T_("Confirmation required, but there is no terminal to ask it in. Rerun the command with --yes"))
+	}
+
+	return ConfirmationDialog, nil
+}
@@ -0,0 +1,89 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeCSV выводит первый найденный в ответе список записей в формате CSV: строку заголовков
+// с именами полей JSON и по строке на запись. Остальные поля ответа, включая message, не выводятся.
+// Возвращает false, если в ответе нет списка.
+func writeCSV(ctx context.Context, w io.Writer, data map[string]interface{}) (bool, error) {
+	listKey, rows := findTableRows(data)
+	if listKey == "" {
+		return false, nil
+	}
+
+	fields, _ := ctx.Value("columns").([]string)
+	columns := tableColumns(rows, fields)
+	if len(columns) == 0 {
+		return false, nil
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return true, err
+	}
+
+	for _, row := range rows {
+		record := make([]string, 0, len(columns))
+		for _, column := range columns {
+			record = append(record, formatCSVCell(row[column]))
+		}
+		if err := writer.Write(record); err != nil {
+			return true, err
+		}
+	}
+
+	writer.Flush()
+	return true, writer.Error()
+}
+
+// formatCSVCell приводит значение к строке CSV. Списки объединяются через «;».
+func formatCSVCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, elem := range v {
+			parts = append(parts, formatCSVCell(elem))
+		}
+		return strings.Join(parts, ";")
+	case map[string]interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,139 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Коды ошибок ответа. В отличие от переведённого сообщения код не зависит от языка,
+// по нему клиенты различают ошибки.
+const (
+	ErrorCodeInternal             = "internal-error"
+	ErrorCodeInvalidArgument      = "invalid-argument"
+	ErrorCodeNotRoot              = "not-root"
+	ErrorCodeRootForbidden        = "root-forbidden"
+	ErrorCodeNotAtomic            = "not-atomic"
+	ErrorCodePackageNotFound      = "package-not-found"
+	ErrorCodeDBEmpty              = "db-empty"
+	ErrorCodeAptLock              = "apt-lock"
+	ErrorCodeAptFailed            = "apt-failed"
+	ErrorCodeDependencyConflict   = "dependency-conflict"
+	ErrorCodeDownloadFailed       = "download-failed"
+	ErrorCodeContainerMissing     = "container-missing"
+	ErrorCodeImageBuildFailed     = "image-build-failed"
+	ErrorCodeNotFound             = "not-found"
+	ErrorCodePermissionDenied     = "permission-denied"
+	ErrorCodeNetwork              = "network-error"
+	ErrorCodeDatabase             = "database-error"
+	ErrorCodeConfirmationRequired = "confirmation-required"
+	ErrorCodeNotSupported         = "not-supported"
+	ErrorCodeRecoveryRequired     = "recovery-required"
+)
+
+// CodedError ошибка с кодом из реестра кодов ответа.
+type CodedError struct {
+	Code string
+	Err  error
+}
+
+func (e *CodedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode возвращает код ошибки.
+func (e *CodedError) ErrorCode() string {
+	return e.Code
+}
+
+// Errorf формирует ошибку с кодом, сообщение форматируется как в fmt.Errorf.
+func Errorf(code string, format string, args ...interface{}) error {
+	return &CodedError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// WithErrorCode присваивает ошибке код, для nil возвращается nil.
+func WithErrorCode(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &CodedError{Code: code, Err: err}
+}
+
+// ErrorCode возвращает код ошибки: ближайший в цепочке обёрток код, заданный через CodedError
+// или методом ErrorCode() у собственных типов ошибок. Ошибки без кода определяются по системным
+// типам ошибок: доступ, сеть, база данных, остальные считаются внутренними.
+func ErrorCode(err error) string {
+	var coded interface{ ErrorCode() string }
+	if errors.As(err, &coded) {
+		return coded.ErrorCode()
+	}
+
+	var netErr net.Error
+	var sqliteErr sqlite3.Error
+	switch {
+	case errors.Is(err, os.ErrPermission):
+		return ErrorCodePermissionDenied
+	case errors.Is(err, os.ErrNotExist):
+		return ErrorCodeNotFound
+	case errors.As(err, &netErr):
+		return ErrorCodeNetwork
+	case errors.As(err, &sqliteErr), errors.Is(err, sql.ErrConnDone), errors.Is(err, sql.ErrTxDone):
+		return ErrorCodeDatabase
+	default:
+		return ErrorCodeInternal
+	}
+}
+
+// ErrorDetails возвращает подробности ошибки для клиентов, если тип ошибки их предоставляет
+// методом ErrorDetails(), иначе nil.
+func ErrorDetails(err error) map[string]interface{} {
+	var detailed interface{ ErrorDetails() map[string]interface{} }
+	if errors.As(err, &detailed) {
+		return detailed.ErrorDetails()
+	}
+
+	return nil
+}
+
+// DBusError формирует ошибку D-Bus. Первым элементом тела идёт сообщение, как у dbus.MakeFailedError,
+// вторым — код ошибки, третьим, если они есть, — подробности ошибки в JSON.
+func DBusError(err error) *dbus.Error {
+	body := []interface{}{err.Error(), ErrorCode(err)}
+	if details := ErrorDetails(err); len(details) > 0 {
+		if data, jerr := json.Marshal(details); jerr == nil {
+			body = append(body, string(data))
+		}
+	}
+
+	return &dbus.Error{
+		Name: "org.freedesktop.DBus.Error.Failed",
+		Body: body,
+	}
+}
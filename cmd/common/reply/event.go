@@ -23,19 +23,26 @@ import (
 	"errors"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 )
 
-// EventData содержит данные события.
-type EventData struct {
-	Name            string  `json:"name"`
-	View            string  `json:"message"`
-	State           string  `json:"state"`
+// Event событие о ходе выполнения операции. Событие выводится в индикатор выполнения, строкой потока
+// jsonstream и отправляется сигналом Notification в D-Bus.
+type Event struct {
+	// Name имя события из реестра событий, у событий с прогрессом по пакету или слою - с суффиксом
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// Phase стадия операции: download, install, build и т.д.
+	Phase           string  `json:"phase,omitempty"`
 	Type            string  `json:"type"`
 	ProgressPercent float64 `json:"progress"`
 	ProgressDone    string  `json:"progressDone"`
-	Transaction     string  `json:"transaction,omitempty"`
+	// Message описание события для человека
+	Message     string    `json:"message"`
+	Transaction string    `json:"transaction,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
 }
 
 var (
@@ -46,65 +53,63 @@ var (
 	StateAfter  = "AFTER"
 )
 
-// NotificationOption — функция-опция для настройки EventData.
-type NotificationOption func(*EventData)
+// EventOption — функция-опция для настройки Event.
+type EventOption func(*Event)
 
 // WithEventName задаёт имя события.
-func WithEventName(name string) NotificationOption {
-	return func(ed *EventData) {
-		ed.Name = name
+func WithEventName(name string) EventOption {
+	return func(e *Event) {
+		e.Name = name
 	}
 }
 
-// WithEventView задаёт текст отображения события
-func WithEventView(name string) NotificationOption {
-	return func(ed *EventData) {
-		ed.View = name
+// WithMessage задаёт описание события вместо описания из реестра событий.
+func WithMessage(message string) EventOption {
+	return func(e *Event) {
+		e.Message = message
 	}
 }
 
-// WithProgress указывает, что событие является прогрессом.
-func WithProgress(isProgress bool) NotificationOption {
-	return func(ed *EventData) {
-		if isProgress {
-			ed.Type = EventTypeProgress
-		} else {
-			ed.Type = EventTypeNotification
-		}
+// WithPhase задаёт стадию операции вместо стадии из реестра событий.
+func WithPhase(phase string) EventOption {
+	return func(e *Event) {
+		e.Phase = phase
 	}
 }
 
-// WithProgressPercent задаёт процент выполнения.
-func WithProgressPercent(percent float64) NotificationOption {
-	return func(ed *EventData) {
-		ed.ProgressPercent = percent
+// WithProgress указывает, что событие является прогрессом, и задаёт процент выполнения.
+func WithProgress(percent float64) EventOption {
+	return func(e *Event) {
+		e.Type = EventTypeProgress
+		e.ProgressPercent = percent
 	}
 }
 
 // WithProgressDoneText задаёт текст в конце прогресса.
-func WithProgressDoneText(text string) NotificationOption {
-	return func(ed *EventData) {
-		ed.ProgressDone = text
+func WithProgressDoneText(text string) EventOption {
+	return func(e *Event) {
+		e.ProgressDone = text
 	}
 }
 
-// CreateEventNotification создаёт EventData, используя заданное состояние и опции.
-func CreateEventNotification(ctx context.Context, state string, opts ...NotificationOption) {
+// CreateEventNotification создаёт Event, используя заданное состояние и опции, и отправляет его.
+func CreateEventNotification(ctx context.Context, state string, opts ...EventOption) {
 	// Устанавливаем значения по умолчанию.
-	ed := EventData{
+	event := Event{
 		Name:            "",
 		State:           state,
 		Type:            EventTypeNotification,
 		ProgressPercent: 0,
+		Timestamp:       time.Now(),
 	}
 
 	// Применяем переданные опции.
 	for _, opt := range opts {
-		opt(&ed)
+		opt(&event)
 	}
 
 	// Если имя события не задано, определяем его через runtime
-	if ed.Name == "" {
+	if event.Name == "" {
 		pc, _, _, ok := runtime.Caller(1)
 		if !ok {
 			errText := lib.T_("Failed to retrieve call information")
@@ -119,35 +124,46 @@ func CreateEventNotification(ctx context.Context, state string, opts ...Notifica
 		}
 		fullName := fn.Name()
 		parts := strings.Split(fullName, "/")
-		ed.Name = parts[len(parts)-1]
+		event.Name = parts[len(parts)-1]
 	}
 
-	if ed.View == "" {
-		ed.View = getTaskText(ed.Name)
+	info, known := lookupEvent(event.Name)
+	if event.Message == "" {
+		event.Message = event.Name
+		if known {
+			event.Message = lib.T_(info.message)
+		}
+	}
+	if event.Phase == "" {
+		event.Phase = info.phase
 	}
 
-	SendFuncNameDBUS(ctx, ed)
+	SendFuncNameDBUS(ctx, event)
 }
 
 // SendFuncNameDBUS отправляет уведомление через DBUS.
-func SendFuncNameDBUS(ctx context.Context, eventData EventData) {
+func SendFuncNameDBUS(ctx context.Context, event Event) {
 	txVal := ctx.Value("transaction")
 	txStr, ok := txVal.(string)
 	if ok {
-		eventData.Transaction = txStr
+		event.Transaction = txStr
+	}
+
+	if lib.Env.Format == "jsonstream" {
+		writeStreamEvent(event)
 	}
 
-	b, err := json.MarshalIndent(eventData, "", "  ")
+	b, err := json.MarshalIndent(event, "", "  ")
 	if err != nil {
-		lib.Log.Debug(err.Error())
+		Logger(ctx).Debug(err.Error())
 	}
 
 	eventType := "PROGRESS"
-	if eventData.Type != "PROGRESS" {
+	if event.Type != EventTypeProgress {
 		eventType = "TASK"
 	}
 
-	UpdateTask(eventType, eventData.Name, eventData.View, eventData.State, eventData.ProgressPercent, eventData.ProgressDone)
+	UpdateTask(eventType, event.Name, event.Message, event.State, event.ProgressPercent, event.ProgressDone)
 	SendNotificationResponse(string(b))
 }
 
@@ -170,63 +186,3 @@ func SendNotificationResponse(message string) {
 		lib.Log.Error(lib.T_("Error sending notification: %v"), err)
 	}
 }
-
-func getTaskText(task string) string {
-	switch task {
-	case "distro.SavePackagesToDB":
-		return lib.T_("Saving packages to the database")
-	case "distro.GetContainerList":
-		return lib.T_("Requesting list of containers")
-	case "distro.ExportingApp":
-		return lib.T_("Exporting package")
-	case "distro.GetContainerOsInfo":
-		return lib.T_("Requesting container information")
-	case "distro.CreateContainer":
-		return lib.T_("Creating container")
-	case "distro.RemoveContainer":
-		return lib.T_("Deleting container")
-	case "distro.InstallPackage":
-		return lib.T_("Installing package")
-	case "distro.RemovePackage":
-		return lib.T_("Removing package")
-	case "distro.GetPackages":
-		return lib.T_("Retrieving list of packages")
-	case "distro.GetPackageOwner":
-		return lib.T_("Determining file owner")
-	case "distro.GetPathByPackageName":
-		return lib.T_("Searching package paths")
-	case "distro.GetInfoPackage":
-		return lib.T_("Retrieving package information")
-	case "distro.UpdatePackages":
-		return lib.T_("Updating packages")
-	case "distro.GetPackagesQuery":
-		return lib.T_("Filtering packages")
-	case "system.Working":
-		return lib.T_("Working with packages")
-	case "system.Check":
-		return lib.T_("Analyzing packages")
-	case "system.Update":
-		return lib.T_("General update process")
-	case "system.AptUpdate":
-		return lib.T_("Loading package list from ALT repository")
-	case "system.SavePackagesToDB":
-		return lib.T_("Saving packages to the database")
-	case "system.SaveImageToDB":
-		return lib.T_("Saving image history to the database")
-	case "system.BuildImage":
-		return lib.T_("Building local image")
-	case "system.SwitchImage":
-		return lib.T_("Switching to local image")
-	case "system.CheckAndUpdateBaseImage":
-		return lib.T_("Checking for updates")
-	case "system.bootcUpgrade":
-		return lib.T_("Downloading base image update")
-	case "system.pruneOldImages":
-		return lib.T_("Cleaning up old images")
-	case "system.updateAllPackagesDB":
-		return lib.T_("Synchronizing database")
-	default:
-		// If the task name is unknown, we return it unchanged.
-		return task
-	}
-}
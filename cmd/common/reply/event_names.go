@@ -0,0 +1,118 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/lib"
+	"sort"
+	"strings"
+)
+
+// Стадии операций, которые передаются в поле phase события.
+const (
+	EventPhaseQuery     = "query"
+	EventPhaseSync      = "sync"
+	EventPhaseDownload  = "download"
+	EventPhaseInstall   = "install"
+	EventPhaseRemove    = "remove"
+	EventPhaseCheck     = "check"
+	EventPhaseBuild     = "build"
+	EventPhaseSwitch    = "switch"
+	EventPhaseContainer = "container"
+	EventPhaseExport    = "export"
+	EventPhaseIcons     = "icons"
+)
+
+// eventInfo стадия и описание события по умолчанию.
+type eventInfo struct {
+	phase   string
+	message string
+}
+
+// eventRegistry реестр всех событий, которые отправляет apm. Имена, оканчивающиеся на "-", - префиксы
+// событий с прогрессом по отдельному пакету или слою образа, к ним добавляется имя пакета или слоя.
+var eventRegistry = map[string]eventInfo{
+	"distro.SavePackagesToDB":         {EventPhaseSync, lib.N_("Saving packages to the database")},
+	"distro.GetContainerList":         {EventPhaseQuery, lib.N_("Requesting list of containers")},
+	"distro.ExportingApp":             {EventPhaseExport, lib.N_("Exporting package")},
+	"distro.ExportAll":                {EventPhaseExport, lib.N_("Exporting packages")},
+	"distro.GetContainerOsInfo":       {EventPhaseQuery, lib.N_("Requesting container information")},
+	"distro.CreateContainer":          {EventPhaseContainer, lib.N_("Creating container")},
+	"distro.RemoveContainer":          {EventPhaseContainer, lib.N_("Deleting container")},
+	"distro.RecreateContainer":        {EventPhaseContainer, lib.N_("Recreating container")},
+	"distro.SetContainerNetwork":      {EventPhaseContainer, lib.N_("Changing container network")},
+	"distro.UpdateContainerResources": {EventPhaseContainer, lib.N_("Changing container resource limits")},
+	"distro.CopyToContainer":          {EventPhaseContainer, lib.N_("Copying files into the container")},
+	"distro.CopyFromContainer":        {EventPhaseContainer, lib.N_("Copying files from the container")},
+	"distro.RunHook":                  {EventPhaseContainer, lib.N_("Running container hook")},
+	"distro.InstallPackage":           {EventPhaseInstall, lib.N_("Installing package")},
+	"distro.InstallPackages":          {EventPhaseInstall, lib.N_("Installing packages")},
+	"distro.ReinstallPackage":         {EventPhaseInstall, lib.N_("Reinstalling package")},
+	"distro.RemovePackage":            {EventPhaseRemove, lib.N_("Removing package")},
+	"distro.GetPackages":              {EventPhaseQuery, lib.N_("Retrieving list of packages")},
+	"distro.GetPackageOwner":          {EventPhaseQuery, lib.N_("Determining file owner")},
+	"distro.GetPathByPackageName":     {EventPhaseQuery, lib.N_("Searching package paths")},
+	"distro.GetInfoPackage":           {EventPhaseQuery, lib.N_("Retrieving package information")},
+	"distro.UpdatePackages":           {EventPhaseSync, lib.N_("Updating packages")},
+	"distro.GetPackagesQuery":         {EventPhaseQuery, lib.N_("Filtering packages")},
+	"icon.ReloadIcons":                {EventPhaseIcons, lib.N_("Loading package icons")},
+	"system.Working":                  {EventPhaseInstall, lib.N_("Working with packages")},
+	"system.Check":                    {EventPhaseCheck, lib.N_("Analyzing packages")},
+	"system.Update":                   {EventPhaseSync, lib.N_("General update process")},
+	"system.AptUpdate":                {EventPhaseDownload, lib.N_("Loading package list from ALT repository")},
+	"system.SavePackagesToDB":         {EventPhaseSync, lib.N_("Saving packages to the database")},
+	"system.SaveImageToDB":            {EventPhaseSync, lib.N_("Saving image history to the database")},
+	"system.BuildImage":               {EventPhaseBuild, lib.N_("Building local image")},
+	"system.SwitchImage":              {EventPhaseSwitch, lib.N_("Switching to local image")},
+	"system.CheckAndUpdateBaseImage":  {EventPhaseCheck, lib.N_("Checking for updates")},
+	"system.bootcUpgrade":             {EventPhaseDownload, lib.N_("Downloading base image update")},
+	"system.pruneOldImages":           {EventPhaseBuild, lib.N_("Cleaning up old images")},
+	"system.updateAllPackagesDB":      {EventPhaseSync, lib.N_("Synchronizing database")},
+	"system.CheckAvailableUpdates":    {EventPhaseCheck, lib.N_("Checking for available updates")},
+	"system.installProgress":          {EventPhaseInstall, lib.N_("Installing packages")},
+	"system.downloadProgress-":        {EventPhaseDownload, lib.N_("Downloading package")},
+	"system.packageProgress-":         {EventPhaseInstall, lib.N_("Installing package")},
+	"service.pullImage-":              {EventPhaseDownload, lib.N_("Downloading image layer")},
+}
+
+// lookupEvent возвращает описание события по имени. Для событий с суффиксом пакета или слоя
+// описание берётся по префиксу имени.
+func lookupEvent(name string) (eventInfo, bool) {
+	if info, ok := eventRegistry[name]; ok {
+		return info, true
+	}
+
+	for prefix, info := range eventRegistry {
+		if strings.HasSuffix(prefix, "-") && strings.HasPrefix(name, prefix) {
+			return info, true
+		}
+	}
+
+	return eventInfo{}, false
+}
+
+// EventNames возвращает отсортированные имена всех событий из реестра. Префиксы событий с прогрессом
+// по пакету или слою оканчиваются на "-".
+func EventNames() []string {
+	names := make([]string, 0, len(eventRegistry))
+	for name := range eventRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
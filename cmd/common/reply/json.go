@@ -0,0 +1,97 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// prettyJSON включает вывод json с отступами, по умолчанию json выводится одной строкой.
+var prettyJSON bool
+
+// SetPrettyJSON включает или отключает вывод json с отступами (флаг --pretty).
+func SetPrettyJSON(enabled bool) {
+	prettyJSON = enabled
+}
+
+// orderedData данные ответа, ключи которых сериализуются в стабильном порядке: message первым,
+// остальные по алфавиту. Так вывод одной и той же команды можно сравнивать между запусками.
+type orderedData map[string]interface{}
+
+// MarshalJSON сериализует данные, выводя message перед остальными ключами.
+func (d orderedData) MarshalJSON() ([]byte, error) {
+	keys := make([]string, 0, len(d))
+	for key := range d {
+		if key != "message" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := d["message"]; ok {
+		keys = append([]string{"message"}, keys...)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(d[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// withOrderedData возвращает ответ, данные которого сериализуются в стабильном порядке ключей.
+func withOrderedData(resp APIResponse) APIResponse {
+	if dataMap, ok := resp.Data.(map[string]interface{}); ok {
+		resp.Data = orderedData(dataMap)
+	}
+
+	return resp
+}
+
+// marshalJSON сериализует ответ одной строкой или, с флагом --pretty, с отступами.
+func marshalJSON(resp APIResponse) ([]byte, error) {
+	b, err := json.Marshal(withOrderedData(resp))
+	if err != nil || !prettyJSON {
+		return b, err
+	}
+
+	var buf bytes.Buffer
+	if err = json.Indent(&buf, b, "", "  "); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
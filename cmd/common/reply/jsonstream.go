@@ -0,0 +1,95 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/lib"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Типы строк потока jsonstream.
+const (
+	streamTypeEvent  = "event"
+	streamTypeResult = "result"
+)
+
+// streamEvent строка потока с событием.
+type streamEvent struct {
+	Type         string    `json:"type"`
+	Name         string    `json:"name"`
+	State        string    `json:"state"`
+	Phase        string    `json:"phase,omitempty"`
+	EventType    string    `json:"eventType"`
+	Message      string    `json:"message"`
+	Progress     float64   `json:"progress"`
+	ProgressDone string    `json:"progressDone,omitempty"`
+	Transaction  string    `json:"transaction,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// streamResult итоговая строка потока с ответом команды.
+type streamResult struct {
+	Type string `json:"type"`
+	APIResponse
+}
+
+// streamMutex не даёт строкам событий из разных горутин перемешаться.
+var streamMutex sync.Mutex
+
+// writeStreamEvent выводит событие строкой потока jsonstream.
+func writeStreamEvent(event Event) {
+	err := writeStreamLine(streamEvent{
+		Type:         streamTypeEvent,
+		Name:         event.Name,
+		State:        event.State,
+		Phase:        event.Phase,
+		EventType:    event.Type,
+		Message:      event.Message,
+		Progress:     event.ProgressPercent,
+		ProgressDone: event.ProgressDone,
+		Transaction:  event.Transaction,
+		Timestamp:    event.Timestamp,
+	})
+	if err != nil {
+		lib.Log.Debug(err.Error())
+	}
+}
+
+// writeStreamResult выводит итоговый ответ последней строкой потока jsonstream.
+func writeStreamResult(resp APIResponse) error {
+	return writeStreamLine(streamResult{Type: streamTypeResult, APIResponse: withOrderedData(resp)})
+}
+
+// writeStreamLine выводит значение одной строкой JSON. Строка записывается в stdout без буфера
+// одним вызовом записи, поэтому читатель получает её сразу, а при завершении процесса
+// в потоке не остаётся недописанных строк.
+func writeStreamLine(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	streamMutex.Lock()
+	defer streamMutex.Unlock()
+
+	_, err = os.Stdout.Write(b)
+	return err
+}
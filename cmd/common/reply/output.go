@@ -0,0 +1,86 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/lib"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// outputPath путь файла из флага --output, в который записывается итоговый ответ.
+var outputPath string
+
+// SetOutputFile задаёт файл, в который CliResponse записывает итоговый ответ. Пустой путь отключает запись.
+func SetOutputFile(path string) {
+	outputPath = path
+}
+
+// writeOutputFile записывает ответ в файл outputPath в формате yaml или, для остальных форматов, json.
+// Ответ записывается во временный файл рядом с целевым и переименовывается, поэтому читатель никогда
+// не увидит файл записанным частично.
+func writeOutputFile(resp APIResponse, format string) error {
+	var content []byte
+	var err error
+
+	if format == "yaml" {
+		content, err = marshalYAML(resp)
+	} else {
+		// Как и в выводе json, успешный ответ записывается без message
+		if dataMap, ok := resp.Data.(map[string]interface{}); ok && !resp.Error {
+			data := make(map[string]interface{}, len(dataMap))
+			for key, value := range dataMap {
+				if key != "message" {
+					data[key] = value
+				}
+			}
+			resp.Data = data
+		}
+		content, err = marshalJSON(resp)
+		content = append(content, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to write the response to %s: %v"), outputPath, err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to write the response to %s: %v"), outputPath, err)
+	}
+	tmpPath := tmpFile.Name()
+
+	_, err = tmpFile.Write(content)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if errClose := tmpFile.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, outputPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf(lib.T_("Failed to write the response to %s: %v"), outputPath, err)
+	}
+
+	return nil
+}
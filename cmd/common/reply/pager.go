@@ -0,0 +1,118 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/lib"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// defaultPager команда постраничного просмотра, если переменная PAGER не задана.
+const defaultPager = "less -FRX"
+
+var (
+	// pagerEnabled разрешает постраничный вывод, отключается флагом --no-pager и параметром disablePager.
+	pagerEnabled = true
+	// pagerActive выставлен, пока открыт постраничный просмотр.
+	pagerActive atomic.Bool
+)
+
+// SetPagerEnabled включает или отключает постраничный вывод длинных ответов.
+func SetPagerEnabled(enabled bool) {
+	pagerEnabled = enabled
+}
+
+// PagerActive сообщает, что сейчас открыт постраничный просмотр. Прерывание Ctrl-C в это время
+// обрабатывает сам просмотрщик, и оно не должно завершать apm.
+func PagerActive() bool {
+	return pagerActive.Load()
+}
+
+// printPaged выводит текстовый ответ. Если вывод не помещается в терминал по высоте,
+// он передаётся в $PAGER, при ошибке запуска просмотрщика текст печатается как есть.
+func printPaged(text string) {
+	if !shouldPage(text) {
+		fmt.Print(text)
+		return
+	}
+
+	pager := strings.TrimSpace(os.Getenv("PAGER"))
+	if pager == "" {
+		pager = defaultPager
+	}
+
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	pagerActive.Store(true)
+	defer pagerActive.Store(false)
+
+	finish := lib.LogCommand(cmd)
+	if err := cmd.Start(); err != nil {
+		finish(err)
+		fmt.Print(text)
+		return
+	}
+
+	// Код выхода просмотрщика, в том числе после Ctrl-C, не является ошибкой команды
+	finish(cmd.Wait())
+}
+
+// shouldPage проверяет, нужен ли постраничный вывод: только для текстовых форматов в терминале,
+// вне работы индикатора выполнения и если вывод длиннее высоты терминала.
+func shouldPage(text string) bool {
+	if !pagerEnabled || !IsTTY() {
+		return false
+	}
+
+	if lib.Env.Format != "text" && lib.Env.Format != "table" {
+		return false
+	}
+
+	if spinnerRunning() {
+		return false
+	}
+
+	width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height <= 0 {
+		return false
+	}
+
+	lines := 0
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		lineWidth := lipgloss.Width(line)
+		if width > 0 && lineWidth > width {
+			lines += (lineWidth + width - 1) / width
+		} else {
+			lines++
+		}
+		if lines >= height {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,198 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/lib"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// PickerItem элемент списка выбора.
+type PickerItem struct {
+	Title       string
+	Description string
+}
+
+var (
+	pickerTitleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#a2734c"))
+	pickerMarkedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#2bb389"))
+	pickerShortcutStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Faint(true)
+)
+
+// CheckInteractive проверяет, что выбор можно показать пользователю: нужен текстовый формат вывода,
+// а ввод и вывод должны быть подключены к терминалу.
+func CheckInteractive() error {
+	if lib.Env.Format != "text" && lib.Env.Format != "table" {
+		return Errorf(ErrorCodeNotSupported, lib.T_("Interactive selection is only available in the text and table formats"))
+	}
+
+	if !IsTTY() || !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return Errorf(ErrorCodeNotSupported, lib.T_("Interactive selection requires a terminal"))
+	}
+
+	return nil
+}
+
+// PickItems показывает прокручиваемый список, в котором можно отметить несколько элементов,
+// и возвращает индексы отмеченных элементов по порядку. Отмена выбора возвращает ошибку.
+func PickItems(title string, items []PickerItem) ([]int, error) {
+	if err := CheckInteractive(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf(lib.T_("Nothing found"))
+	}
+
+	StopSpinner()
+	p := tea.NewProgram(pickerModel{title: title, items: items, marked: make(map[int]bool), height: 20},
+		tea.WithOutput(os.Stdout),
+		tea.WithAltScreen(),
+		tea.WithoutSignalHandler())
+	finalModel, err := p.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := finalModel.(pickerModel)
+	if !ok || m.canceled {
+		return nil, fmt.Errorf(lib.T_("Operation cancelled"))
+	}
+
+	var selected []int
+	for i := range m.items {
+		if m.marked[i] {
+			selected = append(selected, i)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf(lib.T_("Nothing selected"))
+	}
+
+	return selected, nil
+}
+
+// pickerModel состояние списка выбора. offset первая видимая строка, height число видимых строк.
+type pickerModel struct {
+	title    string
+	items    []PickerItem
+	marked   map[int]bool
+	cursor   int
+	offset   int
+	height   int
+	width    int
+	canceled bool
+}
+
+func (m pickerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// Заголовок и подсказка по клавишам занимают четыре строки
+		m.width = msg.Width
+		m.height = max(msg.Height-4, 1)
+		m.scroll()
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc", "q":
+			m.canceled = true
+			return m, tea.Quit
+		case "enter":
+			// Без отметок выбирается пакет под курсором
+			if len(m.marked) == 0 {
+				m.marked[m.cursor] = true
+			}
+			return m, tea.Quit
+		case " ", "x":
+			if m.marked[m.cursor] {
+				delete(m.marked, m.cursor)
+			} else {
+				m.marked[m.cursor] = true
+			}
+		case "up", "k":
+			m.cursor--
+		case "down", "j":
+			m.cursor++
+		case "pgup":
+			m.cursor -= m.height
+		case "pgdown":
+			m.cursor += m.height
+		case "home":
+			m.cursor = 0
+		case "end":
+			m.cursor = len(m.items) - 1
+		}
+		m.cursor = min(max(m.cursor, 0), len(m.items)-1)
+		m.scroll()
+	}
+
+	return m, nil
+}
+
+// scroll сдвигает видимую часть списка так, чтобы курсор оставался на экране.
+func (m *pickerModel) scroll() {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.height {
+		m.offset = m.cursor - m.height + 1
+	}
+}
+
+func (m pickerModel) View() string {
+	var sb strings.Builder
+	sb.WriteString(pickerTitleStyle.Render(fmt.Sprintf("%s (%d/%d)", m.title, len(m.marked), len(m.items))))
+	sb.WriteString("\n\n")
+
+	end := min(m.offset+m.height, len(m.items))
+	for i := m.offset; i < end; i++ {
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "» "
+		}
+		mark := "[ ]"
+		if m.marked[i] {
+			mark = "[x]"
+		}
+
+		line := fmt.Sprintf("%s%s %s", cursor, mark, m.items[i].Title)
+		if description := strings.SplitN(m.items[i].Description, "\n", 2)[0]; description != "" {
+			line += " - " + description
+		}
+		if m.width > 0 && lipgloss.Width(line) > m.width {
+			line = truncateTableCell(line, m.width)
+		}
+		if m.marked[i] {
+			line = pickerMarkedStyle.Render(line)
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	sb.WriteString("\n" + pickerShortcutStyle.Render(lib.T_("Navigation: ↑/↓, j/k - move, PgUp/PgDn - scroll, Space - mark, Enter - confirm, Esc/q - cancel")))
+
+	return sb.String()
+}
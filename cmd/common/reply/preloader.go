@@ -35,6 +35,8 @@ var (
 	tasksDoneChan chan struct{}
 	mu            sync.Mutex
 	lastLines     int
+	// spinnerEnabled разрешает индикатор выполнения, отключается флагом --no-spinner.
+	spinnerEnabled = true
 )
 
 // TaskUpdateMsg TASK" или "PROGRESS"
@@ -64,9 +66,28 @@ type model struct {
 	tasks        []task
 }
 
+// SetSpinnerEnabled включает или отключает индикатор выполнения, отключается флагом --no-spinner.
+func SetSpinnerEnabled(enabled bool) {
+	spinnerEnabled = enabled
+}
+
+// spinnerAllowed проверяет, можно ли выводить индикатор выполнения. Управляющие последовательности
+// индикатора портят вывод, перенаправленный в файл или канал, и машиночитаемые форматы json, jsonstream и dbus.
+func spinnerAllowed() bool {
+	if !spinnerEnabled || IsQuiet() {
+		return false
+	}
+
+	if lib.Env.Format != "text" && lib.Env.Format != "table" {
+		return false
+	}
+
+	return IsTTY()
+}
+
 // CreateSpinner Создание и запуск Bubble Tea
 func CreateSpinner() {
-	if lib.Env.Format != "text" && IsTTY() {
+	if !spinnerAllowed() {
 		return
 	}
 
@@ -95,8 +116,9 @@ func CreateSpinner() {
 }
 
 // StopSpinner Остановка и очистка вывода
+// Повторная остановка и остановка незапущенного индикатора ничего не делают.
 func StopSpinner() {
-	if lib.Env.Format != "text" && IsTTY() {
+	if !spinnerRunning() {
 		return
 	}
 
@@ -124,6 +146,14 @@ func StopSpinner() {
 	}
 }
 
+// spinnerRunning сообщает, что индикатор выполнения ещё выводится в терминал.
+func spinnerRunning() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return p != nil
+}
+
 // UpdateTask  Функция для внешнего вызова: отправить задачу/прогресс в модель ===
 // Пример:
 //
@@ -140,10 +170,6 @@ func StopSpinner() {
 //	UpdateTask("TASK", "install", "Установка пакетов", "BEFORE", "")
 //	UpdateTask("TASK", "install", "Установка пакетов", "AFTER", "")
 func UpdateTask(eventType string, taskName string, viewName string, state string, progressValue float64, progressDone string) {
-	if lib.Env.Format != "text" && IsTTY() {
-		return
-	}
-
 	mu.Lock()
 	defer mu.Unlock()
 
@@ -0,0 +1,86 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/lib"
+	"fmt"
+	"os"
+)
+
+// Уровни подробности вывода, задаваемые числом флагов -q/--quiet.
+const (
+	// VerbosityNormal обычный вывод
+	VerbosityNormal = 0
+	// VerbosityQuiet выводится только основной результат команды
+	VerbosityQuiet = 1
+	// VerbositySilent выводятся только ошибки в stderr
+	VerbositySilent = 2
+)
+
+// verbosity текущий уровень подробности вывода.
+var verbosity = VerbosityNormal
+
+// SetVerbosity задаёт уровень подробности по числу флагов -q.
+func SetVerbosity(quietCount int) {
+	switch {
+	case quietCount <= 0:
+		verbosity = VerbosityNormal
+	case quietCount == 1:
+		verbosity = VerbosityQuiet
+	default:
+		verbosity = VerbositySilent
+	}
+}
+
+// IsQuiet сообщает, что индикаторы, уведомления и информационные сообщения отключены.
+// Тихий режим действует только на вывод для человека, форматы json, yaml и csv выводятся полностью.
+func IsQuiet() bool {
+	return isQuietFormat(lib.Env.Format)
+}
+
+// isQuietFormat сообщает, действует ли тихий режим для вывода в формате format.
+func isQuietFormat(format string) bool {
+	if verbosity == VerbosityNormal {
+		return false
+	}
+
+	return format == "text" || format == "table"
+}
+
+// printQuiet выводит ответ в тихом режиме. Ошибка печатается в stderr, при успехе с -q выводятся
+// только названия записей из списка в ответе, по одному в строке, а с -qq не выводится ничего.
+func printQuiet(resp APIResponse) {
+	data, _ := resp.Data.(map[string]interface{})
+	if resp.Error {
+		if msg, ok := data["message"]; ok {
+			fmt.Fprintln(os.Stderr, msg)
+		}
+		return
+	}
+
+	if verbosity >= VerbositySilent {
+		return
+	}
+
+	_, rows := findTableRows(data)
+	for _, row := range rows {
+		if name, ok := row["name"].(string); ok && name != "" {
+			fmt.Println(name)
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"os"
 	"reflect"
 	"sort"
+	"strings"
 	"unicode"
 
 	"github.com/charmbracelet/lipgloss"
@@ -33,9 +34,14 @@ import (
 
 // APIResponse описывает итоговую структуру ответа.
 type APIResponse struct {
-	Data        interface{} `json:"data"`
-	Error       bool        `json:"error"`
-	Transaction string      `json:"transaction,omitempty"`
+	// APIVersion версия контракта ответа, см. APIVersion
+	APIVersion string      `json:"apiVersion"`
+	Data       interface{} `json:"data"`
+	Error      bool        `json:"error"`
+	Code       string      `json:"code,omitempty"`
+	// Details подробности ошибки для клиентов, например список конфликтующих пакетов
+	Details     map[string]interface{} `json:"details,omitempty"`
+	Transaction string                 `json:"transaction,omitempty"`
 }
 
 // Глобальные стили для дерева.
@@ -75,7 +81,8 @@ func formatField(key string, value interface{}) string {
 }
 
 // buildTreeFromMap рекурсивно строит дерево (tree.Tree) из map[string]interface{}.
-func buildTreeFromMap(prefix string, data map[string]interface{}) *tree.Tree {
+// path — путь к data в ответе, по нему и имени команды command выбираются подписи вложенных полей.
+func buildTreeFromMap(prefix string, data map[string]interface{}, command string, path string) *tree.Tree {
 	// Создаем корень дерева
 	t := tree.New().Root(prefix)
 
@@ -87,7 +94,7 @@ func buildTreeFromMap(prefix string, data map[string]interface{}) *tree.Tree {
 		case int, float64, bool:
 			t.Child(fmt.Sprintf("%v", vv))
 		case map[string]interface{}:
-			subTree := buildTreeFromMap("message", vv)
+			subTree := buildTreeFromMap("message", vv, command, "message")
 			t.Child(subTree)
 		case []interface{}:
 			listNode := tree.New().Root("message")
@@ -96,14 +103,14 @@ func buildTreeFromMap(prefix string, data map[string]interface{}) *tree.Tree {
 			}
 			t.Child(listNode)
 		default:
-			rv := reflect.ValueOf(msgVal)
+			rv := reflect.Indirect(reflect.ValueOf(msgVal))
 			switch rv.Kind() {
 			case reflect.Struct:
 				b, err := json.Marshal(vv)
 				if err == nil {
 					var mm map[string]interface{}
 					if err2 := json.Unmarshal(b, &mm); err2 == nil {
-						subTree := buildTreeFromMap("message", mm)
+						subTree := buildTreeFromMap("message", mm, command, "message")
 						t.Child(subTree)
 					} else {
 						t.Child(fmt.Sprintf("message: %s", fmt.Sprintf(lib.T_("%T (unknown type)"), vv)))
@@ -117,7 +124,7 @@ func buildTreeFromMap(prefix string, data map[string]interface{}) *tree.Tree {
 						listNode := tree.New().Root("message")
 						for i, elem := range arr {
 							if mm, ok := elem.(map[string]interface{}); ok {
-								subTree := buildTreeFromMap(fmt.Sprintf("%d)", i+1), mm)
+								subTree := buildTreeFromMap(fmt.Sprintf("%d)", i+1), mm, command, "message")
 								listNode.Child(subTree)
 							} else {
 								listNode.Child(fmt.Sprintf("%d) %v", i+1, elem))
@@ -147,21 +154,23 @@ func buildTreeFromMap(prefix string, data map[string]interface{}) *tree.Tree {
 	// 3) Обрабатываем остальные ключи
 	for _, k := range keys {
 		v := data[k]
+		fieldPath := joinFieldPath(path, k)
+		label := TranslateField(command, fieldPath)
 		switch vv := v.(type) {
 
 		//----------------------------------------------------------------------
 		// СЛУЧАЙ: значение == nil
 		case nil:
-			t.Child(fmt.Sprintf(lib.T_("%s: no"), TranslateKey(k)))
+			t.Child(fmt.Sprintf(lib.T_("%s: no"), label))
 			//t.Child(fmt.Sprintf("%s: []", translateKey(k)))
 
 		//----------------------------------------------------------------------
 		// СЛУЧАЙ: строка
 		case string:
 			if vv == "" {
-				t.Child(fmt.Sprintf(lib.T_("%s: no"), TranslateKey(k)))
+				t.Child(fmt.Sprintf(lib.T_("%s: no"), label))
 			} else {
-				t.Child(fmt.Sprintf("%s: %s", TranslateKey(k), formatField(k, vv)))
+				t.Child(fmt.Sprintf("%s: %s", label, colorizeField(k, vv, formatField(k, vv))))
 			}
 
 		//----------------------------------------------------------------------
@@ -173,33 +182,32 @@ func buildTreeFromMap(prefix string, data map[string]interface{}) *tree.Tree {
 			} else {
 				boolStr = lib.T_("No")
 			}
-			t.Child(fmt.Sprintf("%s: %s", TranslateKey(k), boolStr))
+			t.Child(fmt.Sprintf("%s: %s", label, colorizeField(k, vv, boolStr)))
 
 		//----------------------------------------------------------------------
 		// СЛУЧАЙ: числа (int, float64)
 		case int, float64:
-			t.Child(fmt.Sprintf("%s: %v", TranslateKey(k), vv))
+			t.Child(fmt.Sprintf("%s: %s", label, colorizeField(k, vv, fmt.Sprintf("%v", vv))))
 
 		//----------------------------------------------------------------------
 		// СЛУЧАЙ: вложенная map
 		case map[string]interface{}:
-			subTree := buildTreeFromMap(TranslateKey(k), vv)
+			subTree := buildTreeFromMap(label, vv, command, fieldPath)
 			t.Child(subTree)
 
 		//----------------------------------------------------------------------
 		// СЛУЧАЙ: срез (slice) из interface{}
 		case []interface{}:
 			if len(vv) == 0 {
-				t.Child(fmt.Sprintf("%s: []", TranslateKey(k))) // пустой срез
+				t.Child(fmt.Sprintf("%s: []", label)) // пустой срез
 				continue
 			}
-			listNode := tree.New().Root(TranslateKey(k))
+			listNode := tree.New().Root(label)
 			for i, elem := range vv {
 				if mm, ok := elem.(map[string]interface{}); ok {
-					subTree := buildTreeFromMap(fmt.Sprintf("%d)", i+1), mm)
-					listNode.Child(subTree)
+					listNode.Child(listItemTree(i+1, mm, command, fieldPath))
 				} else {
-					listNode.Child(fmt.Sprintf("%d) %v", i+1, elem))
+					listNode.Child(colorizeField(k, elem, fmt.Sprintf("%d) %v", i+1, elem)))
 				}
 			}
 			t.Child(listNode)
@@ -207,7 +215,7 @@ func buildTreeFromMap(prefix string, data map[string]interface{}) *tree.Tree {
 		//----------------------------------------------------------------------
 		// ДРУГИЕ СЛУЧАИ: структуры, срезы непонятных типов и т.д.
 		default:
-			rv := reflect.ValueOf(v)
+			rv := reflect.Indirect(reflect.ValueOf(v))
 			switch rv.Kind() {
 
 			//------------------------------------------------------------------
@@ -217,12 +225,12 @@ func buildTreeFromMap(prefix string, data map[string]interface{}) *tree.Tree {
 				if err == nil {
 					var mm map[string]interface{}
 					if err2 := json.Unmarshal(b, &mm); err2 == nil {
-						subTree := buildTreeFromMap(TranslateKey(k), mm)
+						subTree := buildTreeFromMap(label, mm, command, fieldPath)
 						t.Child(subTree)
 						continue
 					}
 				}
-				t.Child(fmt.Sprintf("%s: %s", TranslateKey(k), fmt.Sprintf(lib.T_("%T (unknown type)"), vv)))
+				t.Child(fmt.Sprintf("%s: %s", label, fmt.Sprintf(lib.T_("%T (unknown type)"), vv)))
 
 			//------------------------------------------------------------------
 			// СЛУЧАЙ: срез (slice) непонятного типа
@@ -231,24 +239,23 @@ func buildTreeFromMap(prefix string, data map[string]interface{}) *tree.Tree {
 				if err == nil {
 					var arr []interface{}
 					if err2 := json.Unmarshal(b, &arr); err2 == nil {
-						listNode := tree.New().Root(TranslateKey(k))
+						listNode := tree.New().Root(label)
 						for i, elem := range arr {
 							if mm, ok := elem.(map[string]interface{}); ok {
-								subTree := buildTreeFromMap(fmt.Sprintf("%d)", i+1), mm)
-								listNode.Child(subTree)
+								listNode.Child(listItemTree(i+1, mm, command, fieldPath))
 							} else {
-								listNode.Child(fmt.Sprintf("%d) %v", i+1, elem))
+								listNode.Child(colorizeField(k, elem, fmt.Sprintf("%d) %v", i+1, elem)))
 							}
 						}
 						t.Child(listNode)
 						continue
 					}
 				}
-				t.Child(fmt.Sprintf("%s: %s", TranslateKey(k), fmt.Sprintf(lib.T_("%T (slice of unknown type)"), vv)))
+				t.Child(fmt.Sprintf("%s: %s", label, fmt.Sprintf(lib.T_("%T (slice of unknown type)"), vv)))
 
 			//------------------------------------------------------------------
 			default:
-				t.Child(fmt.Sprintf("%s: %s", TranslateKey(k), fmt.Sprintf(lib.T_("%T (unknown type)"), vv)))
+				t.Child(fmt.Sprintf("%s: %s", label, fmt.Sprintf(lib.T_("%T (unknown type)"), vv)))
 			}
 		}
 	}
@@ -256,7 +263,22 @@ func buildTreeFromMap(prefix string, data map[string]interface{}) *tree.Tree {
 	return t
 }
 
-// CliResponse рендерит ответ в зависимости от формата (dbus/json/text).
+// SupportedFormats форматы вывода, доступные в флаге --format.
+var SupportedFormats = []string{"text", "json", "jsonstream", "table", "yaml", "csv"}
+
+// ValidateFormat проверяет, что формат вывода поддерживается.
+func ValidateFormat(format string) error {
+	for _, supported := range SupportedFormats {
+		if format == supported {
+			return nil
+		}
+	}
+
+	return Errorf(ErrorCodeInvalidArgument, lib.T_("Unknown output format %s. Supported formats: %s"), format, strings.Join(SupportedFormats, ", "))
+}
+
+// CliResponse рендерит ответ в зависимости от формата (dbus/json/yaml/csv/table/text).
+// С флагом --output ответ дополнительно записывается в файл, а в терминал выводится текстом.
 func CliResponse(ctx context.Context, resp APIResponse) error {
 	StopSpinner()
 	format := lib.Env.Format
@@ -265,6 +287,43 @@ func CliResponse(ctx context.Context, resp APIResponse) error {
 	if ok {
 		resp.Transaction = txStr
 	}
+	resp.APIVersion = APIVersion
+	// Типизированный ответ выводится всеми форматами так же, как карта
+	ResponseData(&resp)
+
+	// Ошибка без кода из реестра считается внутренней
+	if resp.Error && resp.Code == "" {
+		resp.Code = ErrorCodeInternal
+	}
+
+	if outputPath == "" {
+		return renderResponse(ctx, resp, format)
+	}
+
+	// Вызывающий прочитает файл, поэтому ошибка записи возвращается даже после вывода успешного ответа
+	writeErr := writeOutputFile(resp, format)
+	if writeErr != nil {
+		outputPath = ""
+	}
+	if format != "text" && format != "table" {
+		format = "text"
+	}
+
+	if err := renderResponse(ctx, resp, format); err != nil {
+		return err
+	}
+
+	return writeErr
+}
+
+// renderResponse выводит ответ в терминал в формате format.
+func renderResponse(ctx context.Context, resp APIResponse, format string) error {
+	command, _ := ctx.Value("command").(string)
+
+	if isQuietFormat(format) {
+		printQuiet(resp)
+		return nil
+	}
 
 	switch format {
 	// ---------------------------------- JSON ----------------------------------
@@ -275,12 +334,61 @@ func CliResponse(ctx context.Context, resp APIResponse) error {
 				delete(dataMap, "message")
 			}
 		}
-		b, err := json.MarshalIndent(resp, "", "  ")
+		b, err := marshalJSON(resp)
 		if err != nil {
 			return err
 		}
 		fmt.Println(string(b))
 
+	// ------------------------------- JSON stream -------------------------------
+	case "jsonstream":
+		if !resp.Error {
+			if dataMap, ok := resp.Data.(map[string]interface{}); ok {
+				delete(dataMap, "message")
+			}
+		}
+		return writeStreamResult(resp)
+
+	// ---------------------------------- YAML ----------------------------------
+	case "yaml":
+		b, err := marshalYAML(resp)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(b))
+
+	// ---------------------------------- CSV -----------------------------------
+	case "csv":
+		// В stdout попадают только строки CSV, сообщения и ошибки уходят в stderr
+		dataMap, _ := resp.Data.(map[string]interface{})
+		if resp.Error {
+			if msg, ok := dataMap["message"]; ok {
+				fmt.Fprintln(os.Stderr, msg)
+			}
+			return nil
+		}
+
+		written, err := writeCSV(ctx, os.Stdout, dataMap)
+		if err != nil {
+			return err
+		}
+		if !written {
+			fmt.Fprintln(os.Stderr, lib.T_("The csv format is not supported for this command"))
+		}
+
+	// ---------------------------------- TABLE ---------------------------------
+	case "table":
+		if dataMap, ok := resp.Data.(map[string]interface{}); ok && !resp.Error {
+			var out strings.Builder
+			if printTable(ctx, &out, dataMap) {
+				printPaged(out.String())
+				return nil
+			}
+		}
+
+		// Если табличное представление невозможно, выводим как текст
+		return renderResponse(ctx, resp, "text")
+
 	// ---------------------------------- TEXT (по умолчанию) ------------------
 	default:
 		switch data := resp.Data.(type) {
@@ -293,17 +401,29 @@ func CliResponse(ctx context.Context, resp APIResponse) error {
 						runes := []rune(msgStr)
 						if unicode.IsLower(runes[0]) {
 							runes[0] = unicode.ToUpper(runes[0])
-							data["message"] = string(runes)
+							msgStr = string(runes)
 						}
+						data["message"] = colorizeMessage(msgStr)
+					}
+				}
+
+				// Подробности ошибки выводятся в дереве вместе с сообщением
+				for key, value := range resp.Details {
+					if _, exists := data[key]; !exists {
+						data[key] = value
 					}
 				}
 			}
 
+			// Длительность этапов занимает в тексте одну строку и только в подробном режиме
+			timings, _ := data["timings"].(map[string]int64)
+			delete(data, "timings")
+
 			var t *tree.Tree
 			if resp.Error {
-				t = buildTreeFromMap("⚛", data)
+				t = buildTreeFromMap("⚛", data, command, "")
 			} else {
-				t = buildTreeFromMap("⚛", data)
+				t = buildTreeFromMap("⚛", data, command, "")
 			}
 
 			var rootColor lipgloss.Style
@@ -322,7 +442,11 @@ func CliResponse(ctx context.Context, resp APIResponse) error {
 				RootStyle(rootColor).
 				ItemStyle(itemStyle)
 
-			fmt.Println(t.String())
+			output := t.String() + "\n"
+			if lib.VerboseOutput && len(timings) > 0 {
+				output += TimingSummary(command, timings) + "\n"
+			}
+			printPaged(output)
 
 		default:
 			var message string
@@ -0,0 +1,291 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/lib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// tableMinColumnWidth ширина, меньше которой колонки не сжимаются под ширину терминала.
+const tableMinColumnWidth = 6
+
+var (
+	// Стиль заголовков таблицы.
+	tableHeaderStyle = lipgloss.NewStyle().
+				Bold(true).
+				Foreground(lipgloss.Color("#2aa1b3")).
+				Padding(0, 1)
+
+	// Стиль ячеек таблицы.
+	tableCellStyle = lipgloss.NewStyle().
+			Foreground(adaptiveItemColor).
+			Padding(0, 1)
+)
+
+// printTable выводит в w первый найденный в ответе список записей в виде таблицы.
+// Набор и порядок колонок берётся из значения "columns" контекста, иначе выводятся все поля.
+// В терминале длинные значения обрезаются, чтобы таблица помещалась по ширине.
+// Возвращает false, если в ответе нет данных, пригодных для таблицы.
+func printTable(ctx context.Context, w io.Writer, data map[string]interface{}) bool {
+	listKey, rows := findTableRows(data)
+	if listKey == "" {
+		return false
+	}
+
+	fields, _ := ctx.Value("columns").([]string)
+	columns := tableColumns(rows, fields)
+	if len(columns) == 0 {
+		return false
+	}
+
+	command, _ := ctx.Value("command").(string)
+	headers := make([]string, 0, len(columns))
+	for _, column := range columns {
+		headers = append(headers, TranslateField(command, joinFieldPath(listKey, column)))
+	}
+
+	cells := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		line := make([]string, 0, len(columns))
+		for _, column := range columns {
+			line = append(line, strings.ReplaceAll(formatTableCell(row[column]), "\n", " "))
+		}
+		cells = append(cells, line)
+	}
+
+	widths := fitTableColumns(headers, cells, terminalWidth())
+	for i := range headers {
+		headers[i] = truncateTableCell(headers[i], widths[i])
+	}
+	for _, line := range cells {
+		for i := range line {
+			line[i] = truncateTableCell(line[i], widths[i])
+		}
+	}
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(enumeratorStyle).
+		Headers(headers...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return tableHeaderStyle
+			}
+			return tableCellStyle
+		})
+
+	for _, line := range cells {
+		t.Row(line...)
+	}
+
+	if msg, ok := data["message"].(string); ok && msg != "" {
+		fmt.Fprintln(w, msg)
+	}
+	fmt.Fprintln(w, t.String())
+
+	// Остальные скалярные поля ответа выводим под таблицей
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		if k != "message" && k != listKey {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := data[k].(type) {
+		case string, int, int64, float64, bool:
+			fmt.Fprintf(w, "%s: %s\n", TranslateField(command, k), formatTableCell(v))
+		}
+	}
+
+	return true
+}
+
+// findTableRows ищет в ответе первый по алфавиту ключ, значение которого является списком объектов.
+func findTableRows(data map[string]interface{}) (string, []map[string]interface{}) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if k == "message" {
+			continue
+		}
+
+		b, err := json.Marshal(data[k])
+		if err != nil {
+			continue
+		}
+
+		var rows []map[string]interface{}
+		if err = json.Unmarshal(b, &rows); err != nil || len(rows) == 0 {
+			continue
+		}
+
+		return k, rows
+	}
+
+	return "", nil
+}
+
+// tableColumns определяет колонки таблицы. Поля из fields сопоставляются с ключами без учёта регистра.
+func tableColumns(rows []map[string]interface{}, fields []string) []string {
+	var available []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		keys := make([]string, 0, len(row))
+		for k := range row {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !seen[k] {
+				seen[k] = true
+				available = append(available, k)
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return available
+	}
+
+	var columns []string
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		for _, k := range available {
+			if strings.EqualFold(k, field) {
+				columns = append(columns, k)
+				break
+			}
+		}
+	}
+
+	return columns
+}
+
+// formatTableCell приводит значение ячейки к строке.
+func formatTableCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		if v {
+			return lib.T_("Yes")
+		}
+		return lib.T_("No")
+	case float64:
+		return fmt.Sprintf("%v", v)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, elem := range v {
+			parts = append(parts, formatTableCell(elem))
+		}
+		return strings.Join(parts, ", ")
+	case map[string]interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// terminalWidth возвращает ширину терминала или 0, если вывод идёт не в терминал.
+func terminalWidth() int {
+	if !IsTTY() {
+		return 0
+	}
+
+	width, _, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+
+	return width
+}
+
+// fitTableColumns подбирает ширину колонок так, чтобы таблица помещалась в width символов,
+// сжимая самые широкие колонки. Нулевая ширина снимает ограничение.
+func fitTableColumns(headers []string, cells [][]string, width int) []int {
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = lipgloss.Width(header)
+	}
+	for _, line := range cells {
+		for i, cell := range line {
+			if w := lipgloss.Width(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	if width <= 0 {
+		return widths
+	}
+
+	// Каждая колонка дополнительно занимает рамку и отступы по краям
+	available := width - len(widths)*3 - 1
+	for {
+		total := 0
+		widest := -1
+		for i, w := range widths {
+			total += w
+			if w > tableMinColumnWidth && (widest < 0 || w > widths[widest]) {
+				widest = i
+			}
+		}
+
+		if total <= available || widest < 0 {
+			return widths
+		}
+		widths[widest]--
+	}
+}
+
+// truncateTableCell обрезает значение до width символов, отмечая обрезку многоточием.
+func truncateTableCell(value string, width int) string {
+	if lipgloss.Width(value) <= width {
+		return value
+	}
+
+	runes := []rune(value)
+	if len(runes) > width {
+		runes = runes[:width]
+	}
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[:len(runes)-1]
+	}
+
+	return string(runes) + "…"
+}
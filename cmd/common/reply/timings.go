@@ -0,0 +1,138 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/lib"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Этапы операций, длительность которых выводится в поле timings ответа.
+const (
+	// TimingAptCheck расчёт изменений через apt-get -s
+	TimingAptCheck = "aptCheck"
+	// TimingDialog ожидание подтверждения пользователя
+	TimingDialog = "dialog"
+	// TimingApt выполнение транзакции apt
+	TimingApt = "apt"
+	// TimingPackagesDB синхронизация базы пакетов
+	TimingPackagesDB = "packagesDB"
+	// TimingDockerfile генерация Dockerfile образа
+	TimingDockerfile = "dockerfile"
+	// TimingImageBuild сборка и переключение образа
+	TimingImageBuild = "imageBuild"
+	// TimingContainerCheck проверка контейнера distrobox
+	TimingContainerCheck = "containerCheck"
+	// TimingPackageManager работа пакетного менеджера внутри контейнера
+	TimingPackageManager = "packageManager"
+	// TimingExport экспорт приложения из контейнера
+	TimingExport = "export"
+)
+
+// timingOrder порядок этапов в сводке, этапы не из списка выводятся после них по алфавиту.
+var timingOrder = []string{
+	TimingContainerCheck, TimingAptCheck, TimingDialog, TimingApt, TimingPackageManager, TimingPackagesDB,
+	TimingExport, TimingDockerfile, TimingImageBuild,
+}
+
+// TimingCollector собирает длительность этапов операции. Замер стоит двух вызовов time.Now, поэтому
+// сбор включён всегда. Методы nil-коллектора ничего не делают, это позволяет замерять этапы во вложенных
+// вызовах без проверки, что вызывающий создал коллектор.
+type TimingCollector struct {
+	mu     sync.Mutex
+	stages map[string]time.Duration
+}
+
+// WithTimings создаёт коллектор и сохраняет его в контексте для вложенных вызовов.
+func WithTimings(ctx context.Context) (context.Context, *TimingCollector) {
+	collector := &TimingCollector{stages: make(map[string]time.Duration)}
+	return context.WithValue(ctx, "timings", collector), collector
+}
+
+// TimingsFromContext возвращает коллектор из контекста или nil.
+func TimingsFromContext(ctx context.Context) *TimingCollector {
+	collector, _ := ctx.Value("timings").(*TimingCollector)
+	return collector
+}
+
+// Start начинает замер этапа stage и возвращает функцию, завершающую замер. Повторные замеры этапа суммируются.
+func (c *TimingCollector) Start(stage string) func() {
+	if c == nil {
+		return func() {}
+	}
+
+	started := time.Now()
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.stages[stage] += time.Since(started)
+	}
+}
+
+// Milliseconds возвращает длительность этапов в миллисекундах для поля timings ответа.
+func (c *TimingCollector) Milliseconds() map[string]int64 {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.stages) == 0 {
+		return nil
+	}
+
+	timings := make(map[string]int64, len(c.stages))
+	for stage, duration := range c.stages {
+		timings[stage] = duration.Milliseconds()
+	}
+
+	return timings
+}
+
+// TimingSummary возвращает одну строку со сводкой длительности этапов для подробного текстового вывода.
+func TimingSummary(command string, timings map[string]int64) string {
+	stages := make([]string, 0, len(timings))
+	for _, stage := range timingOrder {
+		if _, ok := timings[stage]; ok {
+			stages = append(stages, stage)
+		}
+	}
+
+	var other []string
+	for stage := range timings {
+		if !slices.Contains(timingOrder, stage) {
+			other = append(other, stage)
+		}
+	}
+	sort.Strings(other)
+	stages = append(stages, other...)
+
+	parts := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		parts = append(parts, fmt.Sprintf(lib.T_("%s %d ms"), TranslateField(command, "timings."+stage), timings[stage]))
+	}
+
+	return TranslateField(command, "timings") + ": " + strings.Join(parts, ", ")
+}
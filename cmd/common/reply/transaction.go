@@ -0,0 +1,80 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/lib"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// NewTransactionID создаёт случайный идентификатор транзакции в формате UUID версии 4.
+func NewTransactionID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		lib.Log.Error(err.Error())
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// TransactionFromContext возвращает идентификатор транзакции вызова или пустую строку.
+func TransactionFromContext(ctx context.Context) string {
+	transaction, _ := ctx.Value("transaction").(string)
+	return transaction
+}
+
+// Logger возвращает запись журнала с идентификатором транзакции вызова. В журнале systemd он передаётся
+// полем TRANSACTION.
+func Logger(ctx context.Context) *logrus.Entry {
+	if transaction := TransactionFromContext(ctx); transaction != "" {
+		return lib.Log.WithField("transaction", transaction)
+	}
+
+	return logrus.NewEntry(lib.Log)
+}
+
+// DBusContext создаёт контекст вызова метода D-Bus. Если клиент не передал transaction, создаётся новый
+// идентификатор: он попадает в уведомления о ходе выполнения и в ответ, чтобы их можно было сопоставить.
+func DBusContext(transaction string) context.Context {
+	if transaction == "" {
+		transaction = NewTransactionID()
+	}
+
+	return context.WithValue(context.Background(), "transaction", transaction)
+}
+
+// DBusResponse сериализует ответ метода D-Bus, добавляя в него идентификатор транзакции вызова.
+func DBusResponse(ctx context.Context, resp *APIResponse) (string, *dbus.Error) {
+	resp.Transaction = TransactionFromContext(ctx)
+	resp.APIVersion = APIVersion
+	ResponseData(resp)
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return "", DBusError(err)
+	}
+
+	return string(data), nil
+}
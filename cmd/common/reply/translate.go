@@ -2,128 +2,406 @@ package reply
 
 import (
 	"apm/lib"
+	"strings"
+	"unicode"
 )
 
-// TranslateKey принимает ключ и возвращает английский текст.
+// fieldLabels подписи полей ответа. Ключом служит название поля или путь к вложенному полю через точку,
+// например "config.image". Строки помечены для xgettext и переводятся при выводе.
+var fieldLabels = map[string]string{
+	"package":               lib.N_("Package"),
+	"count":                 lib.N_("Count"),
+	"isConsole":             lib.N_("Console Application"),
+	"packageInfo":           lib.N_("Package Information"),
+	"install":               lib.N_("Install"),
+	"store":                 lib.N_("Storage Type"),
+	"timestamp":             lib.N_("Date"),
+	"imageDigest":           lib.N_("Image Digest"),
+	"os":                    lib.N_("Distribution"),
+	"container":             lib.N_("Container"),
+	"name":                  lib.N_("Name"),
+	"extraInstalled":        lib.N_("Extra Installed"),
+	"upgradedCount":         lib.N_("Upgraded Count"),
+	"bootedImage":           lib.N_("Booted Image"),
+	"removedPackages":       lib.N_("Removed Packages"),
+	"providers":             lib.N_("Providers"),
+	"provides":              lib.N_("Provides"),
+	"version":               lib.N_("Version"),
+	"versions":              lib.N_("Versions"),
+	"priority":              lib.N_("Priority"),
+	"archive":               lib.N_("Archive"),
+	"exported":              lib.N_("Exported"),
+	"skipped":               lib.N_("Skipped"),
+	"failed":                lib.N_("Failed"),
+	"history":               lib.N_("History"),
+	"conflicts":             lib.N_("Conflicts"),
+	"conflictsWith":         lib.N_("Conflicts with"),
+	"plan":                  lib.N_("Build plan"),
+	"dockerfile":            lib.N_("Dockerfile"),
+	"baseImage":             lib.N_("Base image"),
+	"baseImageUpdate":       lib.N_("Base image update"),
+	"packagesToInstall":     lib.N_("Packages to install"),
+	"alreadyInstalled":      lib.N_("Already installed"),
+	"packagesToRemove":      lib.N_("Packages to remove"),
+	"customLayers":          lib.N_("Custom layers"),
+	"estimatedMinutes":      lib.N_("Estimated build time, min"),
+	"baseImageOverride":     lib.N_("Base image for this build"),
+	"configImage":           lib.N_("Configuration image"),
+	"matchedOn":             lib.N_("Matched on"),
+	"generations":           lib.N_("Generations"),
+	"generation":            lib.N_("Generation"),
+	"deployment":            lib.N_("Deployment"),
+	"historyId":             lib.N_("History entry"),
+	"id":                    lib.N_("ID"),
+	"trigger":               lib.N_("Trigger"),
+	"summary":               lib.N_("Summary"),
+	"isBooted":              lib.N_("Booted"),
+	"isAvailableLocally":    lib.N_("Available locally"),
+	"pendingImage":          lib.N_("Pending image"),
+	"deployedImage":         lib.N_("Deployed image"),
+	"stagedLabels":          lib.N_("Staged image labels"),
+	"origin":                lib.N_("Origin"),
+	"baseSignature":         lib.N_("Base image signature"),
+	"signedBy":              lib.N_("Signed by"),
+	"scope":                 lib.N_("Policy scope"),
+	"warning":               lib.N_("Warning"),
+	"prunedImages":          lib.N_("Removed images"),
+	"removedHistory":        lib.N_("Removed history records"),
+	"retention":             lib.N_("Retention"),
+	"keepImages":            lib.N_("Keep images"),
+	"keepHistoryDays":       lib.N_("Keep history (days)"),
+	"imageRemoved":          lib.N_("Image removed"),
+	"rollback":              lib.N_("Rollback"),
+	"otherDeployments":      lib.N_("Other deployments"),
+	"freedBytes":            lib.N_("Freed (bytes)"),
+	"freedSpaceMB":          lib.N_("Freed space (MB)"),
+	"freedSpace":            lib.N_("Freed space"),
+	"buildCacheSize":        lib.N_("Build cache size"),
+	"buildCacheSizeMB":      lib.N_("Build cache size (MB)"),
+	"configHash":            lib.N_("Configuration hash"),
+	"imageId":               lib.N_("Image ID"),
+	"scheduledReboot":       lib.N_("Scheduled reboot"),
+	"cancelled":             lib.N_("Cancelled"),
+	"at":                    lib.N_("Time"),
+	"type":                  lib.N_("Type"),
+	"updateAvailable":       lib.N_("Update available"),
+	"localDigest":           lib.N_("Local digest"),
+	"remoteDigest":          lib.N_("Registry digest"),
+	"remoteCreated":         lib.N_("Registry image created"),
+	"error":                 lib.N_("Error"),
+	"manual":                lib.N_("Manually installed"),
+	"auto":                  lib.N_("Automatically installed"),
+	"reason":                lib.N_("Install reason"),
+	"labels":                lib.N_("Image labels"),
+	"built":                 lib.N_("Build date"),
+	"transaction":           lib.N_("Transaction"),
+	"network":               lib.N_("Network"),
+	"previousNetwork":       lib.N_("Previous network"),
+	"hooks":                 lib.N_("Hooks"),
+	"hook":                  lib.N_("Hook"),
+	"hooksRun":              lib.N_("Hooks run"),
+	"hookResults":           lib.N_("Hook results"),
+	"buildHooks":            lib.N_("Build hooks"),
+	"exitCode":              lib.N_("Exit code"),
+	"output":                lib.N_("Output"),
+	"components":            lib.N_("Components"),
+	"sbom":                  lib.N_("SBOM"),
+	"path":                  lib.N_("Path"),
+	"command":               lib.N_("Command"),
+	"histogram":             lib.N_("Histogram"),
+	"range":                 lib.N_("Range"),
+	"rangeLow":              lib.N_("Range from (MB)"),
+	"rangeHigh":             lib.N_("Range to (MB)"),
+	"totalSizeMB":           lib.N_("Total size (MB)"),
+	"operations":            lib.N_("Operations"),
+	"operation":             lib.N_("Operation"),
+	"pendingRecovery":       lib.N_("Interrupted operation"),
+	"apply":                 lib.N_("Apply to image"),
+	"steps":                 lib.N_("Completed steps"),
+	"startedAt":             lib.N_("Started at"),
+	"total":                 lib.N_("Total"),
+	"upgradable":            lib.N_("Upgradable"),
+	"lastSyncTime":          lib.N_("Last sync"),
+	"atomic":                lib.N_("System type"),
+	"isAtomic":              lib.N_("Atomic system"),
+	"evidence":              lib.N_("Evidence"),
+	"searchHistory":         lib.N_("Search history"),
+	"query":                 lib.N_("Query"),
+	"resultCount":           lib.N_("Results"),
+	"success":               lib.N_("Success"),
+	"depends":               lib.N_("Dependencies"),
+	"installedSize":         lib.N_("Installed Size"),
+	"removedCount":          lib.N_("Removed Count"),
+	"upgradedPackages":      lib.N_("Upgraded Packages"),
+	"simulated":             lib.N_("Simulated"),
+	"diskSpaceDeltaMB":      lib.N_("Disk space change, MB"),
+	"packageName":           lib.N_("Package Name"),
+	"image":                 lib.N_("Image"),
+	"commands":              lib.N_("Commands"),
+	"maintainer":            lib.N_("Maintainer"),
+	"maintainers":           lib.N_("Maintainers"),
+	"pin":                   lib.N_("Version pin"),
+	"pins":                  lib.N_("Version pins"),
+	"versionInstalled":      lib.N_("Installed Version"),
+	"remove":                lib.N_("Remove"),
+	"containers":            lib.N_("Containers"),
+	"override":              lib.N_("Override"),
+	"icon":                  lib.N_("Icon"),
+	"paths":                 lib.N_("Paths"),
+	"description":           lib.N_("Description"),
+	"date":                  lib.N_("Date"),
+	"newInstalledCount":     lib.N_("Newly Installed Count"),
+	"active":                lib.N_("Active"),
+	"info":                  lib.N_("Information"),
+	"totalCount":            lib.N_("Total Count"),
+	"installed":             lib.N_("Installed"),
+	"manager":               lib.N_("Package Manager"),
+	"lastChangelog":         lib.N_("Last Changelog"),
+	"section":               lib.N_("Section"),
+	"spec":                  lib.N_("Specification"),
+	"booted":                lib.N_("Booted"),
+	"staged":                lib.N_("Staged"),
+	"size":                  lib.N_("Size"),
+	"sizeMB":                lib.N_("Size (MB)"),
+	"newInstalledPackages":  lib.N_("Newly Installed Packages"),
+	"notUpgradedCount":      lib.N_("Not Upgraded Count"),
+	"containerName":         lib.N_("Container Name"),
+	"config":                lib.N_("Configuration"),
+	"exporting":             lib.N_("Exporting"),
+	"status":                lib.N_("Status"),
+	"imageDate":             lib.N_("Image Date"),
+	"packages":              lib.N_("Packages"),
+	"filename":              lib.N_("Filename"),
+	"containerInfo":         lib.N_("Container Information"),
+	"imageName":             lib.N_("Image Name"),
+	"transport":             lib.N_("Transport"),
+	"pinned":                lib.N_("Pinned"),
+	"list":                  lib.N_("List"),
+	"packageCount":          lib.N_("Package Count"),
+	"autoStart":             lib.N_("Autostart"),
+	"running":               lib.N_("Running"),
+	"source":                lib.N_("Source"),
+	"sources":               lib.N_("Sources"),
+	"keyUrl":                lib.N_("Key URL"),
+	"aptSources":            lib.N_("Apt Sources"),
+	"label":                 lib.N_("Label"),
+	"env":                   lib.N_("Environment variable"),
+	"envVars":               lib.N_("Environment variables"),
+	"heldPackage":           lib.N_("Pinned package"),
+	"heldPackages":          lib.N_("Pinned packages"),
+	"key":                   lib.N_("Key"),
+	"value":                 lib.N_("Value"),
+	"repository":            lib.N_("Repository"),
+	"component":             lib.N_("Component"),
+	"packageDiff":           lib.N_("Package Changes"),
+	"reverted":              lib.N_("Reverted"),
+	"removed":               lib.N_("Removed"),
+	"resources":             lib.N_("Resource limits"),
+	"appliedResources":      lib.N_("Applied resource limits"),
+	"memoryMB":              lib.N_("Memory, MB"),
+	"cpuPercent":            lib.N_("CPU, %"),
+	"details":               lib.N_("Details"),
+	"params":                lib.N_("Parameters"),
+	"healthy":               lib.N_("Healthy"),
+	"unhealthy":             lib.N_("Unhealthy"),
+	"checks":                lib.N_("Checks"),
+	"manifest":              lib.N_("Manifest"),
+	"changes":               lib.N_("Changes"),
+	"hold":                  lib.N_("Hold"),
+	"held":                  lib.N_("Held packages"),
+	"versionChanged":        lib.N_("Version changed"),
+	"confirmationRequired":  lib.N_("Confirmation required"),
+	"installedCount":        lib.N_("Installed packages"),
+	"exportedCount":         lib.N_("Exported packages"),
+	"simulation":            lib.N_("Planned changes"),
+	"upgrade":               lib.N_("Upgrade"),
+	"downloadSize":          lib.N_("Download size"),
+	"export":                lib.N_("Export"),
+	"stats":                 lib.N_("Statistics"),
+	"dbPackages":            lib.N_("Packages in the database"),
+	"drift":                 lib.N_("Difference"),
+	"totalPackageNames":     lib.N_("Total package names"),
+	"normalPackages":        lib.N_("Normal packages"),
+	"pureVirtualPackages":   lib.N_("Pure virtual packages"),
+	"singleVirtualPackages": lib.N_("Single virtual packages"),
+	"mixedVirtualPackages":  lib.N_("Mixed virtual packages"),
+	"missingPackages":       lib.N_("Missing packages"),
+	"totalDistinctVersions": lib.N_("Total distinct versions"),
+	"totalDependencies":     lib.N_("Total dependencies"),
+	"totalProvides":         lib.N_("Total provides"),
+	"buildLog":              lib.N_("Build log"),
+	"buildLogPath":          lib.N_("Build log file"),
+	"log":                   lib.N_("Log"),
+	"logPath":               lib.N_("Log file"),
+	"notFound":              lib.N_("Not found"),
+	"listsStale":            lib.N_("Package lists are out of date"),
+	"lastUpdated":           lib.N_("Last updated"),
+	"delegated":             lib.N_("Updated by the system service"),
+	"upgradeAvailableCount": lib.N_("Upgrades available"),
+	"selected":              lib.N_("Selected"),
+	"volume":                lib.N_("Volume"),
+	"volumes":               lib.N_("Volumes"),
+	"hostPath":              lib.N_("Host path"),
+	"containerPath":         lib.N_("Container path"),
+	"readOnly":              lib.N_("Read-only"),
+	"arch":                  lib.N_("Architecture"),
+	"targetArch":            lib.N_("Target Architecture"),
+	"baseImageDigest":       lib.N_("Base image digest"),
+	"packagesOnly":          lib.N_("Packages only"),
+	"copy":                  lib.N_("Copy"),
+	"updated":               lib.N_("Updated"),
+	"forced":                lib.N_("Forced rebuild"),
+	"noChanges":             lib.N_("No changes"),
+	"destination":           lib.N_("Destination"),
+	"checksum":              lib.N_("Checksum (SHA-256)"),
+	"config.image":          lib.N_("Base image"),
+
+	// Этапы операции в поле timings
+	"timings":                lib.N_("Timings"),
+	"timings.aptCheck":       lib.N_("apt check"),
+	"timings.dialog":         lib.N_("confirmation"),
+	"timings.apt":            lib.N_("apt"),
+	"timings.packagesDB":     lib.N_("database sync"),
+	"timings.dockerfile":     lib.N_("Dockerfile generation"),
+	"timings.imageBuild":     lib.N_("image build"),
+	"timings.containerCheck": lib.N_("container check"),
+	"timings.packageManager": lib.N_("package manager"),
+	"timings.export":         lib.N_("export"),
+
+	// Базы данных в выводе apm db path
+	"storage":                 lib.N_("Databases"),
+	"storage.scope":           lib.N_("Scope"),
+	"storage.pathDBSQL":       lib.N_("SQL database"),
+	"storage.pathDBKV":        lib.N_("Key-value database"),
+	"storage.systemPathDBSQL": lib.N_("System SQL database"),
+	"storage.systemPathDBKV":  lib.N_("System key-value database"),
+	"storage.reason":          lib.N_("Reason"),
+	"storage.migratedFrom":    lib.N_("Moved from"),
+}
+
+// commandFieldLabels подписи полей, переопределённые для отдельных команд, по полному имени команды.
+var commandFieldLabels = map[string]map[string]string{}
+
+// RegisterFieldLabels добавляет подписи полей. С пустым command подписи становятся общими, иначе действуют
+// только в выводе команды с указанным полным именем, например "apm system image status".
+func RegisterFieldLabels(command string, labels map[string]string) {
+	target := fieldLabels
+	if command != "" {
+		if commandFieldLabels[command] == nil {
+			commandFieldLabels[command] = make(map[string]string)
+		}
+		target = commandFieldLabels[command]
+	}
+
+	for key, label := range labels {
+		target[key] = label
+	}
+}
+
+// TranslateKey возвращает переведённую подпись поля key.
 func TranslateKey(key string) string {
-	switch key {
-	case "package":
-		return lib.T_("Package")
-	case "count":
-		return lib.T_("Count")
-	case "isConsole":
-		return lib.T_("Console Application")
-	case "packageInfo":
-		return lib.T_("Package Information")
-	case "install":
-		return lib.T_("Install")
-	case "store":
-		return lib.T_("Storage Type")
-	case "timestamp":
-		return lib.T_("Date")
-	case "imageDigest":
-		return lib.T_("Image Digest")
-	case "os":
-		return lib.T_("Distribution")
-	case "container":
-		return lib.T_("Container")
-	case "name":
-		return lib.T_("Name")
-	case "extraInstalled":
-		return lib.T_("Extra Installed")
-	case "upgradedCount":
-		return lib.T_("Upgraded Count")
-	case "bootedImage":
-		return lib.T_("Booted Image")
-	case "removedPackages":
-		return lib.T_("Removed Packages")
-	case "providers":
-		return lib.T_("Providers")
-	case "version":
-		return lib.T_("Version")
-	case "history":
-		return lib.T_("History")
-	case "depends":
-		return lib.T_("Dependencies")
-	case "installedSize":
-		return lib.T_("Installed Size")
-	case "removedCount":
-		return lib.T_("Removed Count")
-	case "upgradedPackages":
-		return lib.T_("Upgraded Packages")
-	case "packageName":
-		return lib.T_("Package Name")
-	case "image":
-		return lib.T_("Image")
-	case "commands":
-		return lib.T_("Commands")
-	case "maintainer":
-		return lib.T_("Maintainer")
-	case "versionInstalled":
-		return lib.T_("Installed Version")
-	case "remove":
-		return lib.T_("Remove")
-	case "containers":
-		return lib.T_("Containers")
-	case "paths":
-		return lib.T_("Paths")
-	case "description":
-		return lib.T_("Description")
-	case "date":
-		return lib.T_("Date")
-	case "newInstalledCount":
-		return lib.T_("Newly Installed Count")
-	case "active":
-		return lib.T_("Active")
-	case "info":
-		return lib.T_("Information")
-	case "totalCount":
-		return lib.T_("Total Count")
-	case "installed":
-		return lib.T_("Installed")
-	case "manager":
-		return lib.T_("Package Manager")
-	case "lastChangelog":
-		return lib.T_("Last Changelog")
-	case "section":
-		return lib.T_("Section")
-	case "spec":
-		return lib.T_("Specification")
-	case "booted":
-		return lib.T_("Booted")
-	case "staged":
-		return lib.T_("Staged")
-	case "size":
-		return lib.T_("Size")
-	case "newInstalledPackages":
-		return lib.T_("Newly Installed Packages")
-	case "notUpgradedCount":
-		return lib.T_("Not Upgraded Count")
-	case "containerName":
-		return lib.T_("Container Name")
-	case "config":
-		return lib.T_("Configuration")
-	case "exporting":
-		return lib.T_("Exporting")
-	case "status":
-		return lib.T_("Status")
-	case "imageDate":
-		return lib.T_("Image Date")
-	case "packages":
-		return lib.T_("Packages")
-	case "filename":
-		return lib.T_("Filename")
-	case "containerInfo":
-		return lib.T_("Container Information")
-	case "imageName":
-		return lib.T_("Image Name")
-	case "transport":
-		return lib.T_("Transport")
-	case "pinned":
-		return lib.T_("Pinned")
-	case "list":
-		return lib.T_("List")
-	default:
-		return lib.T_(key)
+	return TranslateField("", key)
+}
+
+// TranslateField возвращает переведённую подпись поля по пути path в выводе команды command.
+// Подпись ищется среди подписей команды, затем среди общих по полному пути и по последнему элементу пути.
+// Если подписи нет, ключ camelCase разбивается на слова: "installedSize" → "Installed size".
+func TranslateField(command string, path string) string {
+	if label, ok := lookupFieldLabel(command, path); ok {
+		return lib.T_(label)
+	}
+
+	return lib.T_(prettifyKey(lastPathElement(path)))
+}
+
+// HasFieldLabel сообщает, что для поля по пути path задана общая подпись.
+func HasFieldLabel(path string) bool {
+	_, ok := lookupFieldLabel("", path)
+	return ok
+}
+
+// lookupFieldLabel ищет подпись поля, от самой точной к самой общей.
+func lookupFieldLabel(command string, path string) (string, bool) {
+	key := lastPathElement(path)
+
+	if labels, ok := commandFieldLabels[command]; ok {
+		if label, ok := labels[path]; ok {
+			return label, true
+		}
+		if label, ok := labels[key]; ok {
+			return label, true
+		}
+	}
+
+	if label, ok := fieldLabels[path]; ok {
+		return label, true
 	}
+	label, ok := fieldLabels[key]
+
+	return label, ok
+}
+
+// joinFieldPath добавляет к пути поля очередной ключ.
+func joinFieldPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// lastPathElement возвращает последний элемент пути поля.
+func lastPathElement(path string) string {
+	if i := strings.LastIndex(path, "."); i != -1 {
+		return path[i+1:]
+	}
+
+	return path
+}
+
+// prettifyKey превращает ключ camelCase или snake_case в подпись из слов с заглавной первой буквой.
+// Аббревиатуры из заглавных букв сохраняются: "imageID" → "Image ID".
+func prettifyKey(key string) string {
+	runes := []rune(key)
+	var words []string
+	var word []rune
+
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == ' ':
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	for i, w := range words {
+		if strings.ToUpper(w) == w {
+			continue
+		}
+		words[i] = strings.ToLower(w)
+	}
+	if len(words) == 0 {
+		return key
+	}
+
+	first := []rune(words[0])
+	first[0] = unicode.ToUpper(first[0])
+	words[0] = string(first)
+
+	return strings.Join(words, " ")
 }
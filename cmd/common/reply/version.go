@@ -0,0 +1,123 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"reflect"
+	"strings"
+)
+
+// APIVersion версия контракта ответов: полей APIResponse и типизированных ответов основных команд.
+// Добавление поля совместимо и версию не меняет. Переименование или удаление поля требует повысить
+// версию вручную и сохранить эталоны новой версии в tests/reply/testdata/api.
+const APIVersion = "1"
+
+// StructData преобразует типизированный ответ команды в поля Data. Имена полей берутся из тегов json,
+// значения сохраняют свои типы, поэтому текстовый и табличный вывод работают как с обычной картой.
+// Поля с omitempty и нулевым значением пропускаются, встроенные структуры раскрываются на том же уровне.
+func StructData(v interface{}) map[string]interface{} {
+	data := map[string]interface{}{}
+
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return data
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return data
+	}
+
+	collectStructData(value, data)
+	return data
+}
+
+// ResponseData возвращает поля Data ответа картой. Типизированный ответ преобразуется через StructData
+// и сохраняется в resp.Data, поэтому дополнить его можно так же, как обычную карту. ok равен false,
+// если Data не карта и не структура.
+func ResponseData(resp *APIResponse) (data map[string]interface{}, ok bool) {
+	if data, ok = resp.Data.(map[string]interface{}); ok {
+		return data, true
+	}
+
+	value := reflect.ValueOf(resp.Data)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	data = StructData(resp.Data)
+	resp.Data = data
+	return data, true
+}
+
+// collectStructData записывает поля структуры value в data.
+func collectStructData(value reflect.Value, data map[string]interface{}) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if field.Anonymous && tag[0] == "" && fieldValue.Kind() == reflect.Struct {
+			collectStructData(fieldValue, data)
+			continue
+		}
+
+		name := tag[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		omitEmpty := len(tag) > 1 && strings.Contains(","+strings.Join(tag[1:], ",")+",", ",omitempty,")
+		if omitEmpty && isEmptyValue(fieldValue) {
+			continue
+		}
+
+		data[name] = fieldValue.Interface()
+	}
+}
+
+// isEmptyValue повторяет правило omitempty пакета encoding/json.
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+
+	return false
+}
@@ -0,0 +1,96 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
+
+// marshalYAML сериализует ответ в YAML. Ответ сначала проходит через JSON, чтобы
+// учитывались json-теги структур, а вложенные данные выводились как YAML, а не как строки JSON.
+func marshalYAML(resp APIResponse) ([]byte, error) {
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err = yaml.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	resetYAMLStyle(&document)
+
+	// JSON сортирует ключи, поэтому message возвращается на первое место
+	if len(document.Content) > 0 {
+		if data := yamlMappingValue(document.Content[0], "data"); data != nil {
+			moveYAMLKeyFirst(data, "message")
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+	if err = encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// resetYAMLStyle сбрасывает унаследованный от JSON стиль узлов (кавычки и flow-записи),
+// чтобы кодировщик выбрал блочный YAML.
+func resetYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYAMLStyle(child)
+	}
+}
+
+// yamlMappingValue возвращает значение ключа key, если оно является отображением.
+func yamlMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key && mapping.Content[i+1].Kind == yaml.MappingNode {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// moveYAMLKeyFirst переносит пару с ключом key в начало отображения.
+func moveYAMLKeyFirst(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+
+		pair := []*yaml.Node{mapping.Content[i], mapping.Content[i+1]}
+		rest := append(mapping.Content[:i:i], mapping.Content[i+2:]...)
+		mapping.Content = append(pair, rest...)
+		return
+	}
+}
@@ -6,8 +6,17 @@ import (
 	"apm/lib"
 	"context"
 	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"syscall"
+	"time"
+
+	"github.com/godbus/dbus/v5"
 )
 
 type Actions struct {
@@ -43,17 +52,22 @@ func NewActions() *Actions {
 
 // Update обновляет и синхронизирует список пакетов в контейнере.
 func (a *Actions) Update(ctx context.Context, container string) (*reply.APIResponse, error) {
+	ctx, timings := reply.WithTimings(ctx)
 	err := a.checkRoot()
 	if err != nil {
 		return nil, err
 	}
 
+	stopTiming := timings.Start(reply.TimingContainerCheck)
 	osInfo, err := a.validateContainer(ctx, container)
+	stopTiming()
 	if err != nil {
 		return nil, err
 	}
 
+	stopTiming = timings.Start(reply.TimingPackageManager)
 	packages, err := a.servicePackage.UpdatePackages(ctx, osInfo)
+	stopTiming()
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +76,7 @@ func (a *Actions) Update(ctx context.Context, container string) (*reply.APIRespo
 			"message":   lib.T_("Package list successfully updated"),
 			"container": osInfo,
 			"count":     len(packages),
+			"timings":   timings.Milliseconds(),
 		},
 		Error: false,
 	}
@@ -89,9 +104,9 @@ func (a *Actions) Info(ctx context.Context, container string, packageName string
 		return nil, err
 	}
 	resp := reply.APIResponse{
-		Data: map[string]interface{}{
-			"message":     lib.T_("Package found"),
-			"packageInfo": packageInfo,
+		Data: PackageInfoResponse{
+			Message:     lib.T_("Package found"),
+			PackageInfo: packageInfo,
 		},
 		Error: false,
 	}
@@ -99,49 +114,106 @@ func (a *Actions) Info(ctx context.Context, container string, packageName string
 }
 
 // Search выполняет поиск пакета по названию.
-func (a *Actions) Search(ctx context.Context, container string, packageName string) (*reply.APIResponse, error) {
-	err := a.checkRoot()
+// installed: nil - любые пакеты, true - только установленные, false - только неустановленные.
+func (a *Actions) Search(ctx context.Context, container string, packageName string, installed *bool) (*reply.APIResponse, error) {
+	queryResult, err := a.searchPackages(ctx, container, packageName, installed)
+	if err != nil {
+		return nil, err
+	}
+	msg := fmt.Sprintf(
+		lib.TN_("%d record found", "%d records found", len(queryResult.Packages)),
+		len(queryResult.Packages),
+	)
+	resp := reply.APIResponse{
+		Data: PackageSearchResponse{
+			Message:  msg,
+			Packages: queryResult.Packages,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// SearchInteractive ищет пакеты как Search, показывает найденные в списке выбора и устанавливает
+// отмеченные пакеты в их контейнеры через Install.
+func (a *Actions) SearchInteractive(ctx context.Context, container string, packageName string, installed *bool, export bool) (*reply.APIResponse, error) {
+	if err := reply.CheckInteractive(); err != nil {
+		return nil, err
+	}
+
+	queryResult, err := a.searchPackages(ctx, container, packageName, installed)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]reply.PickerItem, 0, len(queryResult.Packages))
+	for _, pkg := range queryResult.Packages {
+		items = append(items, reply.PickerItem{Title: fmt.Sprintf("%s (%s)", pkg.Name, pkg.Container), Description: pkg.Description})
+	}
+
+	indexes, err := reply.PickItems(lib.T_("Select packages to install"), items)
 	if err != nil {
 		return nil, err
 	}
 
+	selected := make([]string, 0, len(indexes))
+	packages := make([]service.PackageInfo, 0, len(indexes))
+	reply.CreateSpinner()
+	for _, index := range indexes {
+		pkg := queryResult.Packages[index]
+		selected = append(selected, pkg.Name)
+
+		installResp, err := a.Install(ctx, pkg.Container, pkg.Name, export, false)
+		if err != nil {
+			return nil, err
+		}
+		if data, ok := installResp.Data.(PackageInfoResponse); ok {
+			pkg = data.PackageInfo.Package
+		}
+		packages = append(packages, pkg)
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":  fmt.Sprintf(lib.TN_("%d package installed", "%d packages installed", len(packages)), len(packages)),
+			"selected": selected,
+			"packages": packages,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// searchPackages ищет пакеты по названию в контейнере или, если контейнер не указан, во всех контейнерах.
+func (a *Actions) searchPackages(ctx context.Context, container string, packageName string, installed *bool) (service.PackageQueryResult, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return service.PackageQueryResult{}, err
+	}
+
 	var osInfo service.ContainerInfo
 
 	if len(container) > 0 {
 		osInfo, err = a.validateContainer(ctx, container)
 		if err != nil {
-			return nil, err
+			return service.PackageQueryResult{}, err
 		}
 	} else {
 		err = a.validateDatabase(ctx)
 		if err != nil {
-			return nil, err
+			return service.PackageQueryResult{}, err
 		}
 	}
 
 	packageName = strings.TrimSpace(packageName)
 	if packageName == "" {
 		errMsg := fmt.Sprintf(lib.T_("You must specify the package name, for example `%s package`"), "search")
-		return nil, fmt.Errorf(errMsg)
-	}
-
-	queryResult, err := a.servicePackage.GetPackageByName(ctx, osInfo, packageName)
-	if err != nil {
-		return nil, err
-	}
-	msg := fmt.Sprintf(
-		lib.TN_("%d record found", "%d records found", len(queryResult.Packages)),
-		len(queryResult.Packages),
-	)
-	resp := reply.APIResponse{
-		Data: map[string]interface{}{
-			"message":  msg,
-			"packages": queryResult.Packages,
-		},
-		Error: false,
+		return service.PackageQueryResult{}, fmt.Errorf(errMsg)
 	}
 
-	return &resp, nil
+	return a.servicePackage.GetPackageByName(ctx, osInfo, packageName, installed)
 }
 
 // ListParams задаёт параметры для запроса списка пакетов.
@@ -184,23 +256,7 @@ func (a *Actions) List(ctx context.Context, params ListParams) (*reply.APIRespon
 		Filters:     make(map[string]interface{}),
 	}
 
-	// Формируем фильтры (map[string]interface{})
-	filters := make(map[string]interface{})
-	for _, filter := range params.Filters {
-		filter = strings.TrimSpace(filter)
-		if filter == "" {
-			continue
-		}
-		parts := strings.SplitN(filter, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		if key != "" && value != "" {
-			filters[key] = value
-		}
-	}
+	filters := parseListFilters(params.Filters)
 
 	builder.Filters = filters
 
@@ -210,11 +266,36 @@ func (a *Actions) List(ctx context.Context, params ListParams) (*reply.APIRespon
 	}
 	msg := fmt.Sprintf(
 		lib.TN_("%d record found", "%d records found", len(queryResult.Packages)), len(queryResult.Packages))
+	resp := reply.APIResponse{
+		Data: PackageListResponse{
+			Message:    msg,
+			Packages:   queryResult.Packages,
+			TotalCount: queryResult.TotalCount,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// Count возвращает только число пакетов контейнера, подходящих под фильтры, без выборки самих пакетов.
+// Запрос выполняется только к базе данных, без обращения к distrobox, поэтому подходит для частых вызовов.
+func (a *Actions) Count(ctx context.Context, params ListParams) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	container := strings.TrimSpace(params.Container)
+	totalCount, err := a.serviceDistroDatabase.CountTotalPackages(container, parseListFilters(params.Filters))
+	if err != nil {
+		return nil, err
+	}
+
 	resp := reply.APIResponse{
 		Data: map[string]interface{}{
-			"message":    msg,
-			"packages":   queryResult.Packages,
-			"totalCount": queryResult.TotalCount,
+			"message":    fmt.Sprintf(lib.TN_("%d record found", "%d records found", totalCount), totalCount),
+			"totalCount": totalCount,
 		},
 		Error: false,
 	}
@@ -222,14 +303,39 @@ func (a *Actions) List(ctx context.Context, params ListParams) (*reply.APIRespon
 	return &resp, nil
 }
 
+// parseListFilters преобразует фильтры вида key=value в условия запроса. Пустые и некорректные фильтры пропускаются.
+func parseListFilters(params []string) map[string]interface{} {
+	filters := make(map[string]interface{})
+	for _, filter := range params {
+		filter = strings.TrimSpace(filter)
+		if filter == "" {
+			continue
+		}
+		parts := strings.SplitN(filter, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if key != "" && value != "" {
+			filters[key] = value
+		}
+	}
+
+	return filters
+}
+
 // Install устанавливает указанный пакет и опционально экспортирует его.
-func (a *Actions) Install(ctx context.Context, container string, packageName string, export bool) (*reply.APIResponse, error) {
+func (a *Actions) Install(ctx context.Context, container string, packageName string, export bool, reinstall bool) (*reply.APIResponse, error) {
+	ctx, timings := reply.WithTimings(ctx)
 	err := a.checkRoot()
 	if err != nil {
 		return nil, err
 	}
 
+	stopTiming := timings.Start(reply.TimingContainerCheck)
 	osInfo, err := a.validateContainer(ctx, container)
+	stopTiming()
 	if err != nil {
 		return nil, err
 	}
@@ -243,28 +349,115 @@ func (a *Actions) Install(ctx context.Context, container string, packageName str
 	if err != nil {
 		return nil, err
 	}
-	if !packageInfo.Package.Installed {
+	message := fmt.Sprintf(lib.T_("Package %s installed"), packageName)
+	if reinstall && packageInfo.Package.Installed {
+		stopTiming = timings.Start(reply.TimingPackageManager)
+		err = a.servicePackage.ReinstallPackage(ctx, osInfo, packageName)
+		stopTiming()
+		if err != nil {
+			return nil, err
+		}
+		message = fmt.Sprintf(lib.T_("Package %s reinstalled"), packageName)
+	} else if !packageInfo.Package.Installed {
+		stopTiming = timings.Start(reply.TimingPackageManager)
 		err = a.servicePackage.InstallPackage(ctx, osInfo, packageName)
+		stopTiming()
 		if err != nil {
 			return nil, err
 		}
 		packageInfo.Package.Installed = true
+		stopTiming = timings.Start(reply.TimingPackagesDB)
 		a.serviceDistroDatabase.UpdatePackageField(ctx, osInfo.ContainerName, packageName, "installed", true)
 		packageInfo, _ = a.servicePackage.GetInfoPackage(ctx, osInfo, packageName)
+		stopTiming()
 	}
 	if export && !packageInfo.Package.Exporting {
+		stopTiming = timings.Start(reply.TimingExport)
 		errExport := a.serviceDistroAPI.ExportingApp(ctx, osInfo, packageName, packageInfo.IsConsole, packageInfo.Paths, false)
+		stopTiming()
 		if errExport != nil {
 			return nil, errExport
 		}
 		packageInfo.Package.Exporting = true
 		a.serviceDistroDatabase.UpdatePackageField(ctx, osInfo.ContainerName, packageName, "exporting", true)
+		a.reapplyExportOverride(ctx, osInfo, packageName, packageInfo)
+	}
+
+	resp := reply.APIResponse{
+		Data: PackageInfoResponse{
+			Message:     message,
+			PackageInfo: packageInfo,
+			Timings:     timings.Milliseconds(),
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImportPackageList устанавливает в контейнер пакеты из текстового файла filePath одним запуском
+// пакетного менеджера. Если каких-то пакетов нет в базе, список пакетов контейнера сначала обновляется.
+func (a *Actions) ImportPackageList(ctx context.Context, container string, filePath string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	osInfo, err := a.validateContainer(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	specs, err := service.ParsePackageList(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := a.findImportPackages(osInfo.ContainerName, specs)
+	if err != nil {
+		return nil, err
+	}
+	if len(known) < len(specs) {
+		if _, err = a.servicePackage.UpdatePackages(ctx, osInfo); err != nil {
+			return nil, err
+		}
+		if known, err = a.findImportPackages(osInfo.ContainerName, specs); err != nil {
+			return nil, err
+		}
+	}
+
+	installed := []string{}
+	skipped := []string{}
+	notFound := []string{}
+	var toInstall []service.PackageSpec
+	for _, spec := range specs {
+		pkg, ok := known[spec.Name]
+		switch {
+		case !ok:
+			notFound = append(notFound, spec.Name)
+		case pkg.Installed && (spec.Version == "" || spec.Version == pkg.Version):
+			skipped = append(skipped, spec.Name)
+		default:
+			toInstall = append(toInstall, spec)
+		}
+	}
+
+	if len(toInstall) > 0 {
+		if err = a.servicePackage.InstallPackages(ctx, osInfo, toInstall); err != nil {
+			return nil, err
+		}
+		for _, spec := range toInstall {
+			installed = append(installed, spec.Name)
+			a.serviceDistroDatabase.UpdatePackageField(ctx, osInfo.ContainerName, spec.Name, "installed", true)
+		}
 	}
 
 	resp := reply.APIResponse{
 		Data: map[string]interface{}{
-			"message":     fmt.Sprintf(lib.T_("Package %s installed"), packageName),
-			"packageInfo": packageInfo,
+			"message":   fmt.Sprintf(lib.TN_("%d package installed", "%d packages installed", len(installed)), len(installed)),
+			"installed": installed,
+			"skipped":   skipped,
+			"notFound":  notFound,
 		},
 		Error: false,
 	}
@@ -272,6 +465,25 @@ func (a *Actions) Install(ctx context.Context, container string, packageName str
 	return &resp, nil
 }
 
+// findImportPackages возвращает найденные в базе контейнера пакеты из списка specs по точному имени.
+func (a *Actions) findImportPackages(container string, specs []service.PackageSpec) (map[string]service.PackageInfo, error) {
+	known := make(map[string]service.PackageInfo, len(specs))
+	for _, spec := range specs {
+		packages, err := a.serviceDistroDatabase.FindPackagesByName(container, spec.Name, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range packages {
+			if pkg.Name == spec.Name {
+				known[pkg.Name] = pkg
+				break
+			}
+		}
+	}
+
+	return known, nil
+}
+
 // Remove удаляет указанный пакет. Если onlyExport равен true, удаляется только экспорт.
 func (a *Actions) Remove(ctx context.Context, container string, packageName string, onlyExport bool) (*reply.APIResponse, error) {
 	err := a.checkRoot()
@@ -314,9 +526,9 @@ func (a *Actions) Remove(ctx context.Context, container string, packageName stri
 	}
 
 	resp := reply.APIResponse{
-		Data: map[string]interface{}{
-			"message":     fmt.Sprintf(lib.T_("Package %s removed"), packageName),
-			"packageInfo": packageInfo,
+		Data: PackageInfoResponse{
+			Message:     fmt.Sprintf(lib.T_("Package %s removed"), packageName),
+			PackageInfo: packageInfo,
 		},
 		Error: false,
 	}
@@ -324,21 +536,43 @@ func (a *Actions) Remove(ctx context.Context, container string, packageName stri
 	return &resp, nil
 }
 
-// ContainerList возвращает список контейнеров.
-func (a *Actions) ContainerList(ctx context.Context) (*reply.APIResponse, error) {
+// DryRunInstall показывает изменения, которые выполнит Install, не меняя контейнер и базу:
+// пакеты на установку и обновление, объём загрузки и будет ли пакет экспортирован.
+func (a *Actions) DryRunInstall(ctx context.Context, container string, packageName string, export bool) (*reply.APIResponse, error) {
 	err := a.checkRoot()
 	if err != nil {
 		return nil, err
 	}
 
-	containers, err := a.serviceDistroAPI.GetContainerList(ctx, true)
+	osInfo, err := a.validateContainer(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+	packageName = strings.TrimSpace(packageName)
+	if packageName == "" {
+		errMsg := fmt.Sprintf(lib.T_("You must specify the package name, for example `%s package`"), "install")
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	packageInfo, err := a.servicePackage.GetInfoPackage(ctx, osInfo, packageName)
 	if err != nil {
 		return nil, err
 	}
 
+	simulation := service.SimulationResult{Install: []string{}, Upgrade: []string{}, Remove: []string{}}
+	if !packageInfo.Package.Installed {
+		simulation, err = a.servicePackage.SimulateInstall(ctx, osInfo, packageName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	resp := reply.APIResponse{
 		Data: map[string]interface{}{
-			"containers": containers,
+			"message":     fmt.Sprintf(lib.T_("Dry run: installation of package %s was not performed"), packageName),
+			"simulation":  simulation,
+			"export":      export && !packageInfo.Package.Exporting,
+			"packageInfo": packageInfo,
 		},
 		Error: false,
 	}
@@ -346,34 +580,44 @@ func (a *Actions) ContainerList(ctx context.Context) (*reply.APIResponse, error)
 	return &resp, nil
 }
 
-// ContainerAdd создаёт новый контейнер.
-func (a *Actions) ContainerAdd(ctx context.Context, image string, name string, additionalPackages, initHooks string) (*reply.APIResponse, error) {
+// DryRunRemove показывает изменения, которые выполнит Remove, не меняя контейнер и базу:
+// удаляемые пакеты и будет ли снят экспорт.
+func (a *Actions) DryRunRemove(ctx context.Context, container string, packageName string, onlyExport bool) (*reply.APIResponse, error) {
 	err := a.checkRoot()
 	if err != nil {
 		return nil, err
 	}
 
-	image = strings.TrimSpace(image)
-	name = strings.TrimSpace(name)
-	if image == "" {
-		errMsg := lib.T_("You must specify the image link (--image)")
-		return nil, fmt.Errorf(errMsg)
+	osInfo, err := a.validateContainer(ctx, container)
+	if err != nil {
+		return nil, err
 	}
 
-	if name == "" {
-		errMsg := lib.T_("You must specify the container name (--name)")
+	packageName = strings.TrimSpace(packageName)
+	if packageName == "" {
+		errMsg := fmt.Sprintf(lib.T_("You must specify the package name, for example `%s package`"), "remove")
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	result, err := a.serviceDistroAPI.CreateContainer(ctx, image, name, additionalPackages, initHooks)
+	packageInfo, err := a.servicePackage.GetInfoPackage(ctx, osInfo, packageName)
 	if err != nil {
 		return nil, err
 	}
 
+	simulation := service.SimulationResult{Install: []string{}, Upgrade: []string{}, Remove: []string{}}
+	if !onlyExport && packageInfo.Package.Installed {
+		simulation, err = a.servicePackage.SimulateRemove(ctx, osInfo, packageName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	resp := reply.APIResponse{
 		Data: map[string]interface{}{
-			"message":       fmt.Sprintf(lib.T_("Container %s successfully created"), name),
-			"containerInfo": result,
+			"message":     fmt.Sprintf(lib.T_("Dry run: removal of package %s was not performed"), packageName),
+			"simulation":  simulation,
+			"export":      packageInfo.Package.Exporting,
+			"packageInfo": packageInfo,
 		},
 		Error: false,
 	}
@@ -381,38 +625,1356 @@ func (a *Actions) ContainerAdd(ctx context.Context, image string, name string, a
 	return &resp, nil
 }
 
-// ContainerRemove удаляет контейнер по имени.
-func (a *Actions) ContainerRemove(ctx context.Context, name string) (*reply.APIResponse, error) {
+// RenameExport меняет название и, при необходимости, значок экспортированного приложения.
+// Переопределение сохраняется в базе и применяется повторно при следующем экспорте.
+func (a *Actions) RenameExport(ctx context.Context, container string, packageName string, displayName string, icon string) (*reply.APIResponse, error) {
 	err := a.checkRoot()
 	if err != nil {
 		return nil, err
 	}
 
-	name = strings.TrimSpace(name)
-	if name == "" {
-		errMsg := lib.T_("You must specify the container name (--name)")
+	osInfo, err := a.validateContainer(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	packageName = strings.TrimSpace(packageName)
+	if packageName == "" {
+		errMsg := fmt.Sprintf(lib.T_("You must specify the package name, for example `%s package`"), "rename-export")
 		return nil, fmt.Errorf(errMsg)
 	}
 
-	result, err := a.serviceDistroAPI.RemoveContainer(ctx, name)
+	displayName = strings.TrimSpace(displayName)
+	if displayName == "" || strings.ContainsAny(displayName, "\r\n") {
+		return nil, fmt.Errorf(lib.T_("You must specify a single-line application name"))
+	}
+
+	icon = strings.TrimSpace(icon)
+	if icon != "" {
+		if _, err = os.Stat(icon); err != nil {
+			return nil, fmt.Errorf(lib.T_("Icon file %s not found"), icon)
+		}
+	}
+
+	packageInfo, err := a.servicePackage.GetInfoPackage(ctx, osInfo, packageName)
 	if err != nil {
 		return nil, err
 	}
 
+	if !packageInfo.Package.Exporting || packageInfo.IsConsole {
+		return nil, fmt.Errorf(lib.T_("Package %s is not exported as a desktop application"), packageName)
+	}
+
+	files, err := service.ExportedDesktopFiles(osInfo, packageInfo.Paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf(lib.T_("Exported desktop files of package %s not found"), packageName)
+	}
+
+	override := service.ExportOverride{
+		Container: osInfo.ContainerName,
+		Package:   packageName,
+		Name:      displayName,
+		Icon:      icon,
+	}
+
+	if err = service.ApplyExportOverride(files, override); err != nil {
+		return nil, err
+	}
+
+	if err = a.serviceDistroDatabase.SaveExportOverride(ctx, override); err != nil {
+		return nil, err
+	}
+
 	resp := reply.APIResponse{
 		Data: map[string]interface{}{
-			"message":       fmt.Sprintf(lib.T_("Container %s successfully deleted"), name),
-			"containerInfo": result,
+			"message":  fmt.Sprintf(lib.T_("Exported application %s renamed to %s"), packageName, displayName),
+			"override": override,
+			"paths":    files,
 		},
 		Error: false,
 	}
 
-	err = a.serviceDistroDatabase.DeletePackagesFromContainer(ctx, name)
-	if err != nil {
-		return nil, fmt.Errorf(lib.T_("Error deleting container: %v"), err)
+	return &resp, nil
+}
+
+// reapplyExportOverride применяет сохранённое переопределение названия к только что экспортированному приложению.
+func (a *Actions) reapplyExportOverride(ctx context.Context, osInfo service.ContainerInfo, packageName string, packageInfo service.InfoPackageAnswer) {
+	if packageInfo.IsConsole {
+		return
 	}
 
-	return &resp, nil
+	override, err := a.serviceDistroDatabase.GetExportOverride(ctx, osInfo.ContainerName, packageName)
+	if err != nil || override == nil {
+		return
+	}
+
+	files, err := service.ExportedDesktopFiles(osInfo, packageInfo.Paths)
+	if err == nil {
+		err = service.ApplyExportOverride(files, *override)
+	}
+	if err != nil {
+		reply.Logger(ctx).WithField("container", osInfo.ContainerName).WithField("package", packageName).
+			Warningf(lib.T_("Failed to apply the saved name of exported application %s: %v"), packageName, err)
+	}
+}
+
+// ExportAll экспортирует на хост все установленные в контейнере графические приложения, то есть пакеты
+// с desktop-файлами. Ошибка экспорта одного пакета не прерывает экспорт остальных.
+func (a *Actions) ExportAll(ctx context.Context, container string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	osInfo, err := a.validateContainer(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	packages, err := a.serviceDistroDatabase.QueryPackages(osInfo.ContainerName, map[string]interface{}{"installed": true}, "name", "ASC", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := []string{}
+	skipped := []string{}
+	failed := map[string]string{}
+	for i, pkg := range packages {
+		reply.CreateEventNotification(ctx, reply.StateBefore,
+			reply.WithEventName("distro.ExportAll"),
+			reply.WithProgress(float64(i*100/len(packages))),
+			reply.WithMessage(fmt.Sprintf(lib.T_("Exporting: %s"), pkg.Name)),
+		)
+
+		if pkg.Exporting {
+			skipped = append(skipped, pkg.Name)
+			continue
+		}
+
+		packageInfo, errInfo := a.servicePackage.GetInfoPackage(ctx, osInfo, pkg.Name)
+		if errInfo != nil {
+			failed[pkg.Name] = errInfo.Error()
+			continue
+		}
+		if packageInfo.IsConsole || len(packageInfo.Paths) == 0 {
+			continue
+		}
+
+		if errExport := a.serviceDistroAPI.ExportingApp(ctx, osInfo, pkg.Name, false, packageInfo.Paths, false); errExport != nil {
+			failed[pkg.Name] = errExport.Error()
+			continue
+		}
+		a.serviceDistroDatabase.UpdatePackageField(ctx, osInfo.ContainerName, pkg.Name, "exporting", true)
+		packageInfo.Package.Exporting = true
+		a.reapplyExportOverride(ctx, osInfo, pkg.Name, packageInfo)
+		exported = append(exported, pkg.Name)
+	}
+
+	reply.CreateEventNotification(ctx, reply.StateAfter,
+		reply.WithEventName("distro.ExportAll"),
+		reply.WithProgress(100),
+		reply.WithProgressDoneText(lib.T_("Export completed")),
+	)
+
+	msg := fmt.Sprintf(lib.T_("Exported: %d, already exported: %d, failed: %d"), len(exported), len(skipped), len(failed))
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":  msg,
+			"exported": exported,
+			"skipped":  skipped,
+			"failed":   failed,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ContainerList возвращает список контейнеров. Список кэшируется на время containerListCacheTTL,
+// чтобы не вызывать distrobox и podman при каждом запросе.
+func (a *Actions) ContainerList(ctx context.Context, sortBySize bool) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	// Недоступный кэш не мешает получить список напрямую
+	cached, fresh, err := a.serviceDistroDatabase.GetCachedContainers(ctx, service.ContainerListCacheTTL())
+	if err != nil {
+		lib.Log.Debug(err.Error())
+	}
+
+	// Размеры считает podman system df, поэтому они запрашиваются только для сортировки или колонки sizeMB.
+	// Кэш, заполненный без размеров, обновляется при первом таком запросе.
+	withSizes := sortBySize || columnRequested(ctx, "sizeMB")
+	if !fresh || (withSizes && !hasContainerSizes(cached)) {
+		containers, err := a.fetchContainers(ctx, withSizes)
+		if err != nil {
+			return nil, err
+		}
+
+		// Пустой кэш не с чем сравнивать: он ещё не заполнялся или был сброшен самим apm
+		if len(cached) > 0 && !sameContainers(containers, cached) {
+			sendContainerListUpdated(ctx, len(containers))
+		}
+		if err = a.serviceDistroDatabase.SaveCachedContainers(ctx, containers); err != nil {
+			lib.Log.Debug(err.Error())
+		}
+		cached = containers
+	}
+
+	// Без результатов проверок состояния контейнеры просто не отмечаются
+	unhealthy, err := a.serviceDistroDatabase.GetUnhealthyContainers(ctx)
+	if err != nil {
+		lib.Log.Debug(err.Error())
+	}
+
+	// Таблица пакетов может ещё не существовать, в этом случае счётчики остаются нулевыми
+	packageCounts, err := a.serviceDistroDatabase.CountPackagesByContainer(ctx)
+	if err != nil {
+		lib.Log.Debug(err.Error())
+	}
+
+	list := make([]ContainerListItem, 0, len(cached))
+	for _, container := range cached {
+		item := ContainerListItem{
+			ContainerInfo: service.ContainerInfo{
+				OS:            container.OS,
+				ContainerName: container.Name,
+				Active:        container.OS != "",
+			},
+			Status:    container.Status,
+			Image:     container.Image,
+			Running:   container.Running,
+			AutoStart: container.AutoStart,
+			Network:   container.Network,
+			Unhealthy: unhealthy[container.Name],
+			SizeMB:    bytesToMB(container.SizeBytes),
+		}
+
+		count := packageCounts[container.Name]
+		item.PackageCount = count.Total
+		item.InstalledCount = count.Installed
+		item.ExportedCount = count.Exported
+		item.Manager = count.Manager
+
+		list = append(list, item)
+	}
+
+	if sortBySize {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].SizeMB > list[j].SizeMB
+		})
+	}
+
+	resp := reply.APIResponse{
+		Data: ContainerListResponse{
+			Containers: list,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// fetchContainers получает список контейнеров и их состояние от distrobox и podman, упорядоченный по имени.
+func (a *Actions) fetchContainers(ctx context.Context, withSizes bool) ([]service.CachedContainer, error) {
+	containers, err := a.serviceDistroAPI.GetContainerList(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Без состояний список всё равно выводится, контейнеры получают пустой статус
+	states, err := a.serviceDistroAPI.GetContainerStates(ctx)
+	if err != nil {
+		lib.Log.Warning(err.Error())
+	}
+
+	// Без размеров список всё равно выводится, контейнеры получают нулевой размер
+	var sizes map[string]int64
+	if withSizes {
+		sizes, err = a.GetContainerSizes(ctx)
+		if err != nil {
+			lib.Log.Debug(err.Error())
+		}
+	}
+
+	result := make([]service.CachedContainer, 0, len(containers))
+	for _, container := range containers {
+		state := states[container.ContainerName]
+		result = append(result, service.CachedContainer{
+			Name:      container.ContainerName,
+			OS:        container.OS,
+			Image:     state.Image,
+			Status:    state.Status,
+			Running:   state.Running,
+			AutoStart: state.AutoStart,
+			Network:   state.Network,
+			SizeBytes: sizes[container.ContainerName],
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// sameContainers сравнивает списки контейнеров без учёта размеров: размер меняется при каждой записи на диск
+// и не считается изменением списка.
+func sameContainers(a, b []service.CachedContainer) bool {
+	return slices.EqualFunc(a, b, func(x, y service.CachedContainer) bool {
+		x.SizeBytes, y.SizeBytes = 0, 0
+		return x == y
+	})
+}
+
+// hasContainerSizes сообщает, были ли размеры получены при заполнении кэша.
+func hasContainerSizes(containers []service.CachedContainer) bool {
+	return slices.ContainsFunc(containers, func(container service.CachedContainer) bool {
+		return container.SizeBytes > 0
+	})
+}
+
+// columnRequested сообщает, запрошена ли колонка через --columns.
+func columnRequested(ctx context.Context, column string) bool {
+	columns, _ := ctx.Value("columns").([]string)
+	return slices.Contains(columns, column)
+}
+
+// GetContainerSizes возвращает размер каждого контейнера на диске в байтах.
+func (a *Actions) GetContainerSizes(ctx context.Context) (map[string]int64, error) {
+	return a.serviceDistroAPI.GetContainerSizes(ctx)
+}
+
+// bytesToMB переводит байты в мегабайты с округлением до двух знаков.
+func bytesToMB(size int64) float64 {
+	return math.Round(float64(size)/(1024*1024)*100) / 100
+}
+
+// sendContainerListUpdated отправляет сигнал ContainerListUpdated, когда актуальный список контейнеров отличается от кэша.
+// Сигнал содержит транзакцию вызова, обнаружившего изменение.
+func sendContainerListUpdated(ctx context.Context, count int) {
+	if lib.DBUSConn == nil {
+		return
+	}
+
+	objPath := dbus.ObjectPath("/com/application/APM")
+	signalName := "com.application.APM.ContainerListUpdated"
+
+	err := lib.DBUSConn.Emit(objPath, signalName, int64(count), reply.TransactionFromContext(ctx))
+	if err != nil {
+		lib.Log.Errorf(lib.T_("Error sending notification: %v"), err)
+	}
+}
+
+// ContainerListItem расширенная информация о контейнере для списка контейнеров.
+type ContainerListItem struct {
+	service.ContainerInfo
+	Status         string  `json:"status"`
+	Image          string  `json:"image"`
+	Running        bool    `json:"running"`
+	PackageCount   int     `json:"packageCount"`
+	InstalledCount int     `json:"installedCount"`
+	ExportedCount  int     `json:"exportedCount"`
+	Manager        string  `json:"manager"`
+	AutoStart      bool    `json:"autoStart"`
+	Network        string  `json:"network"`
+	Unhealthy      bool    `json:"unhealthy,omitempty"`
+	SizeMB         float64 `json:"sizeMB,omitempty"`
+}
+
+// ContainerAdd создаёт новый контейнер. Команды из файла хуков hookFile выполняются в контейнере после создания:
+// ошибка хука только записывается в журнал, а со strictHooks контейнер удаляется и возвращается ошибка.
+func (a *Actions) ContainerAdd(ctx context.Context, image string, name string, additionalPackages, initHooks string,
+	hookFile string, strictHooks bool) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	image = strings.TrimSpace(image)
+	name = strings.TrimSpace(name)
+	if image == "" {
+		errMsg := lib.T_("You must specify the image link (--image)")
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	if name == "" {
+		errMsg := lib.T_("You must specify the container name (--name)")
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	// Файл хуков читается до создания контейнера, чтобы ошибка в нём не оставляла контейнер без инициализации
+	var postCreateHooks []string
+	if hookFile != "" {
+		postCreateHooks, err = service.ReadHookFile(hookFile)
+		if err != nil {
+			return nil, reply.WithErrorCode(reply.ErrorCodeInvalidArgument, err)
+		}
+	}
+
+	// Хуки, сохранённые ранее для контейнера с таким именем, выполняются вместе с переданными
+	savedHooks, err := a.serviceDistroDatabase.GetInitHooks(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	initHooks = strings.TrimSpace(initHooks)
+	allHooks := service.JoinInitHooks(savedHooks)
+	if initHooks != "" {
+		if allHooks != "" {
+			allHooks += " && "
+		}
+		allHooks += initHooks
+	}
+
+	// Тома, сохранённые ранее для контейнера с таким именем, подключаются к новому контейнеру
+	volumes, err := a.serviceDistroDatabase.GetContainerVolumes(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := a.serviceDistroAPI.CreateContainer(ctx, image, name, additionalPackages, allHooks, volumes)
+	a.invalidateContainerCache(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if initHooks != "" {
+		if _, err = a.serviceDistroDatabase.AddInitHook(ctx, name, initHooks); err != nil {
+			return nil, err
+		}
+	}
+
+	// Ограничения ресурсов, сохранённые ранее для контейнера с таким именем, применяются к новому контейнеру
+	resources, err := a.serviceDistroDatabase.GetContainerResources(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if resources != nil {
+		if err = a.applyResourceLimits(ctx, name, *resources); err != nil {
+			return nil, err
+		}
+	}
+
+	data := map[string]interface{}{
+		"message":       fmt.Sprintf(lib.T_("Container %s successfully created"), name),
+		"containerInfo": result,
+	}
+
+	if len(postCreateHooks) > 0 {
+		hookResults, failed := a.runPostCreateHooks(ctx, name, postCreateHooks)
+		if failed > 0 && strictHooks {
+			if _, err = a.serviceDistroAPI.RemoveContainer(ctx, name); err != nil {
+				reply.Logger(ctx).WithField("container", name).Error(err.Error())
+			}
+			a.invalidateContainerCache(ctx)
+
+			return nil, fmt.Errorf(lib.TN_("%d hook failed in container %s, the container was removed",
+				"%d hooks failed in container %s, the container was removed", failed), failed, name)
+		}
+
+		data["hooksRun"] = len(postCreateHooks)
+		data["hookResults"] = hookResults
+		if failed > 0 {
+			data["message"] = fmt.Sprintf(lib.TN_("Container %s created, %d hook failed",
+				"Container %s created, %d hooks failed", failed), name, failed)
+		}
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// InitContainer выполняет в существующем контейнере команды из файла хуков hookFile. Ошибка хука не прерывает
+// выполнение остальных, результат каждого хука возвращается в ответе и сохраняется в базе.
+func (a *Actions) InitContainer(ctx context.Context, containerName string, hookFile string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(hookFile) == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the hook file (--hook-file)"))
+	}
+
+	osInfo, err := a.validateContainer(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := service.ReadHookFile(hookFile)
+	if err != nil {
+		return nil, reply.WithErrorCode(reply.ErrorCodeInvalidArgument, err)
+	}
+
+	hookResults, failed := a.runPostCreateHooks(ctx, osInfo.ContainerName, hooks)
+
+	message := fmt.Sprintf(lib.TN_("%d hook run in container %s", "%d hooks run in container %s", len(hooks)),
+		len(hooks), osInfo.ContainerName)
+	if failed > 0 {
+		message = fmt.Sprintf(lib.TN_("%d hook failed in container %s", "%d hooks failed in container %s", failed),
+			failed, osInfo.ContainerName)
+	}
+
+	resp := reply.APIResponse{
+		Data: InitContainerResponse{
+			Message:     message,
+			HooksRun:    len(hooks),
+			HookResults: hookResults,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// runPostCreateHooks выполняет хуки в контейнере по порядку и сохраняет их результаты. Результаты возвращаются
+// по команде хука, у повторяющейся команды к ключу добавляется её номер в файле. Второе значение - число ошибок.
+func (a *Actions) runPostCreateHooks(ctx context.Context, containerName string, hooks []string) (map[string]service.HookResult, int) {
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("distro.RunHook"))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("distro.RunHook"))
+
+	results := make(map[string]service.HookResult, len(hooks))
+	failed := 0
+	for i, hook := range hooks {
+		output, err := a.serviceDistroAPI.ContainerExec(ctx, containerName, hook)
+		result := service.HookResult{Command: hook, Success: err == nil, Output: output}
+		if err != nil {
+			failed++
+			result.Error = err.Error()
+			reply.Logger(ctx).WithField("container", containerName).
+				Warningf(lib.T_("Hook %q failed in container %s: %v"), hook, containerName, err)
+		}
+
+		if saveErr := a.serviceDistroDatabase.SaveHookResult(ctx, containerName, result); saveErr != nil {
+			reply.Logger(ctx).WithField("container", containerName).Error(saveErr.Error())
+		}
+
+		key := hook
+		if _, exists := results[key]; exists {
+			key = fmt.Sprintf("%s #%d", hook, i+1)
+		}
+		results[key] = result
+	}
+
+	return results, failed
+}
+
+// ContainerSetNetwork меняет сетевой режим контейнера: host, none или именованная сеть podman.
+func (a *Actions) ContainerSetNetwork(ctx context.Context, containerName string, networkMode string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	containerName = strings.TrimSpace(containerName)
+	networkMode = strings.TrimSpace(networkMode)
+	if containerName == "" {
+		return nil, fmt.Errorf(lib.T_("You must specify the container name"))
+	}
+	if networkMode == "" {
+		networkMode = service.NetworkHost
+	}
+
+	err = a.serviceDistroAPI.ValidateNetworkMode(ctx, networkMode)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := a.serviceDistroAPI.GetContainerStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state, ok := states[containerName]
+	if !ok {
+		return nil, reply.Errorf(reply.ErrorCodeContainerMissing, lib.T_("Container %s not found"), containerName)
+	}
+
+	if state.Network == networkMode {
+		return nil, fmt.Errorf(lib.T_("Container %s already uses network %s"), containerName, networkMode)
+	}
+
+	hooks, err := a.serviceDistroDatabase.GetInitHooks(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes, err := a.serviceDistroDatabase.GetContainerVolumes(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.serviceDistroAPI.SetContainerNetwork(ctx, state, networkMode, service.JoinInitHooks(hooks), volumes)
+	a.invalidateContainerCache(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.serviceDistroDatabase.SaveContainerNetwork(ctx, containerName, networkMode)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":         fmt.Sprintf(lib.T_("Container %s network changed to %s"), containerName, networkMode),
+			"network":         networkMode,
+			"previousNetwork": state.Network,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// SetResourceLimits задаёт ограничения памяти и CPU контейнера, применяет их через podman update
+// и сохраняет, чтобы применить снова при пересоздании контейнера с тем же именем.
+func (a *Actions) SetResourceLimits(ctx context.Context, containerName string, memoryMB int, cpuPercent float64) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	containerName = strings.TrimSpace(containerName)
+	if containerName == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the container name"))
+	}
+	if memoryMB < 0 || cpuPercent < 0 {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Resource limits cannot be negative"))
+	}
+	if memoryMB == 0 && cpuPercent == 0 {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the memory limit (--memory) or the CPU limit (--cpu)"))
+	}
+	if maxPercent := float64(runtime.NumCPU() * 100); cpuPercent > maxPercent {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("The CPU limit cannot exceed %.0f%%"), maxPercent)
+	}
+
+	// Не заданные в запросе ограничения сохраняют прежние значения
+	resources := service.ContainerResources{MemoryMB: memoryMB, CPUPercent: cpuPercent}
+	saved, err := a.serviceDistroDatabase.GetContainerResources(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+	if saved != nil {
+		if resources.MemoryMB == 0 {
+			resources.MemoryMB = saved.MemoryMB
+		}
+		if resources.CPUPercent == 0 {
+			resources.CPUPercent = saved.CPUPercent
+		}
+	}
+
+	err = a.applyResourceLimits(ctx, containerName, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.serviceDistroDatabase.SaveContainerResources(ctx, containerName, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":   fmt.Sprintf(lib.T_("Resource limits of container %s updated"), containerName),
+			"resources": resources,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// GetResourceLimits возвращает сохранённые ограничения ресурсов контейнера и ограничения, действующие в podman.
+func (a *Actions) GetResourceLimits(ctx context.Context, containerName string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	containerName = strings.TrimSpace(containerName)
+	if containerName == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the container name"))
+	}
+
+	containerID, err := a.serviceDistroAPI.GetContainerID(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := a.serviceDistroAPI.InspectContainerResources(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := a.serviceDistroDatabase.GetContainerResources(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+	if saved == nil {
+		saved = &service.ContainerResources{}
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":          fmt.Sprintf(lib.T_("Resource limits of container %s"), containerName),
+			"resources":        saved,
+			"appliedResources": applied,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// applyResourceLimits находит контейнер в podman и применяет к нему ограничения ресурсов.
+func (a *Actions) applyResourceLimits(ctx context.Context, containerName string, resources service.ContainerResources) error {
+	containerID, err := a.serviceDistroAPI.GetContainerID(ctx, containerName)
+	if err != nil {
+		return err
+	}
+
+	return a.serviceDistroAPI.UpdateContainerResources(ctx, containerID, resources)
+}
+
+// packageCountTolerance допустимое расхождение количества пакетов в базе и в контейнере, в процентах.
+const packageCountTolerance = 5.0
+
+// ContainerHealthCheck проверяет работоспособность контейнера: наличие в podman, возможность войти в него,
+// наличие пакетного менеджера, записи в базе и соответствие количества установленных пакетов.
+// Итог проверки сохраняется, чтобы отметить неисправный контейнер в списке контейнеров.
+func (a *Actions) ContainerHealthCheck(ctx context.Context, name string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the container name"))
+	}
+
+	// Первая проверка: без контейнера в podman остальные проверки не имеют смысла
+	if _, err = a.serviceDistroAPI.GetContainerID(ctx, name); err != nil {
+		return nil, err
+	}
+
+	results := []service.HealthCheckResult{
+		{Name: "exists", Status: service.HealthStatusOK, Message: lib.T_("The container exists in podman")},
+	}
+
+	var osInfo service.ContainerInfo
+	if err = a.serviceDistroAPI.CheckContainerEnter(ctx, name); err != nil {
+		results = append(results, service.HealthCheckResult{Name: "enter", Status: service.HealthStatusFailed, Message: err.Error()})
+	} else if osInfo, err = a.serviceDistroAPI.GetContainerOsInfo(ctx, name); err != nil {
+		results = append(results, service.HealthCheckResult{Name: "enter", Status: service.HealthStatusFailed, Message: err.Error()})
+	} else {
+		results = append(results, service.HealthCheckResult{Name: "enter", Status: service.HealthStatusOK, Message: lib.T_("The container can be entered")})
+	}
+
+	entered := osInfo.ContainerName != ""
+	if !entered {
+		results = append(results, service.HealthCheckResult{Name: "packageManager", Status: service.HealthStatusSkipped, Message: lib.T_("The container cannot be entered")})
+	} else if binary, errManager := a.servicePackage.CheckPackageManager(ctx, osInfo); errManager != nil {
+		results = append(results, service.HealthCheckResult{Name: "packageManager", Status: service.HealthStatusFailed, Message: errManager.Error()})
+	} else {
+		results = append(results, service.HealthCheckResult{Name: "packageManager", Status: service.HealthStatusOK,
+			Message: fmt.Sprintf(lib.T_("Package manager %s found"), binary)})
+	}
+
+	databaseExists := false
+	if err = a.serviceDistroDatabase.ContainerDatabaseExist(ctx, name); err != nil {
+		results = append(results, service.HealthCheckResult{Name: "database", Status: service.HealthStatusFailed, Message: err.Error()})
+	} else {
+		databaseExists = true
+		results = append(results, service.HealthCheckResult{Name: "database", Status: service.HealthStatusOK, Message: lib.T_("Package records exist in the database")})
+	}
+
+	results = append(results, a.checkPackageCount(ctx, osInfo, entered && databaseExists))
+
+	healthy := true
+	for _, result := range results {
+		if result.Status == service.HealthStatusFailed {
+			healthy = false
+		}
+	}
+
+	if err = a.serviceDistroDatabase.SaveContainerHealth(ctx, name, healthy); err != nil {
+		lib.Log.Warning(err.Error())
+	}
+
+	message := fmt.Sprintf(lib.T_("Container %s is healthy"), name)
+	if !healthy {
+		message = fmt.Sprintf(lib.T_("Container %s is unhealthy"), name)
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": message,
+			"healthy": healthy,
+			"checks":  results,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// checkPackageCount сравнивает количество установленных пакетов в базе с данными пакетного менеджера контейнера.
+func (a *Actions) checkPackageCount(ctx context.Context, osInfo service.ContainerInfo, possible bool) service.HealthCheckResult {
+	result := service.HealthCheckResult{Name: "packageCount"}
+	if !possible {
+		result.Status = service.HealthStatusSkipped
+		result.Message = lib.T_("The container cannot be entered or has no package records")
+		return result
+	}
+
+	installed, err := a.servicePackage.CountInstalledPackages(ctx, osInfo)
+	if err != nil {
+		result.Status = service.HealthStatusFailed
+		result.Message = err.Error()
+		return result
+	}
+
+	recorded, err := a.serviceDistroDatabase.CountTotalPackages(osInfo.ContainerName, map[string]interface{}{"installed": true})
+	if err != nil {
+		result.Status = service.HealthStatusFailed
+		result.Message = err.Error()
+		return result
+	}
+
+	result.Status = service.HealthStatusOK
+	result.Message = fmt.Sprintf(lib.T_("The database has %d installed packages, the container has %d"), recorded, installed)
+	if math.Abs(float64(recorded-installed)) > float64(installed)*packageCountTolerance/100 {
+		result.Status = service.HealthStatusFailed
+		result.Message += ". " + lib.T_("Update the package database with apm distrobox update")
+	}
+
+	return result
+}
+
+// AddInitHook добавляет хук инициализации контейнера и сразу выполняет его в существующем контейнере.
+func (a *Actions) AddInitHook(ctx context.Context, container string, hookCommand string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	hookCommand = strings.TrimSpace(hookCommand)
+	if hookCommand == "" {
+		return nil, fmt.Errorf(lib.T_("You must specify the hook command"))
+	}
+
+	osInfo, err := a.validateContainer(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.serviceDistroAPI.RunHook(ctx, osInfo.ContainerName, hookCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	hook, err := a.serviceDistroDatabase.AddInitHook(ctx, osInfo.ContainerName, hookCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.T_("Hook added to container %s"), osInfo.ContainerName),
+			"hook":    hook,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// RemoveInitHook удаляет хук инициализации контейнера по идентификатору.
+func (a *Actions) RemoveInitHook(ctx context.Context, container string, hookID int64) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	container = strings.TrimSpace(container)
+	if container == "" {
+		return nil, fmt.Errorf(lib.T_("You must specify the container name"))
+	}
+
+	err = a.serviceDistroDatabase.RemoveInitHook(ctx, container, hookID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.T_("Hook %d removed from container %s"), hookID, container),
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ListInitHooks возвращает хуки инициализации контейнера.
+func (a *Actions) ListInitHooks(ctx context.Context, container string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	container = strings.TrimSpace(container)
+	if container == "" {
+		return nil, fmt.Errorf(lib.T_("You must specify the container name"))
+	}
+
+	hooks, err := a.serviceDistroDatabase.GetInitHooks(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.TN_("%d hook found", "%d hooks found", len(hooks)), len(hooks)),
+			"hooks":   hooks,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ContainerAddVolume подключает каталог хоста hostPath в контейнер по пути containerPath. Тома задаются только
+// при создании контейнера, поэтому контейнер пересоздаётся, и без подтверждения recreate изменение не выполняется.
+func (a *Actions) ContainerAddVolume(ctx context.Context, container, hostPath, containerPath string, readonly bool,
+	recreate bool) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	container = strings.TrimSpace(container)
+	if container == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the container name"))
+	}
+
+	hostPath, err = volumePath(hostPath)
+	if err != nil {
+		return nil, err
+	}
+	containerPath, err = volumePath(containerPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(hostPath); err != nil {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Host path %s does not exist"), hostPath)
+	}
+
+	state, err := a.volumeContainerState(ctx, container, recreate)
+	if err != nil {
+		return nil, err
+	}
+
+	volume, err := a.serviceDistroDatabase.AddContainerVolume(ctx, container, hostPath, containerPath, readonly)
+	if err != nil {
+		return nil, err
+	}
+
+	// Без пересозданного контейнера сохранённый том не соответствовал бы действительности
+	if err = a.recreateWithVolumes(ctx, state); err != nil {
+		if _, errRemove := a.serviceDistroDatabase.RemoveContainerVolume(ctx, container, containerPath); errRemove != nil {
+			lib.Log.Warning(errRemove.Error())
+		}
+		return nil, err
+	}
+
+	volumes, err := a.serviceDistroDatabase.GetContainerVolumes(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.T_("Volume %s added to container %s, the container has been recreated"), volume.Spec(), container),
+			"volume":  volume,
+			"volumes": volumes,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ContainerRemoveVolume отключает том, подключённый в контейнер по пути containerPath. Как и при добавлении,
+// контейнер пересоздаётся только с подтверждением recreate.
+func (a *Actions) ContainerRemoveVolume(ctx context.Context, container, containerPath string, recreate bool) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	container = strings.TrimSpace(container)
+	if container == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the container name"))
+	}
+
+	containerPath, err = volumePath(containerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := a.volumeContainerState(ctx, container, recreate)
+	if err != nil {
+		return nil, err
+	}
+
+	volume, err := a.serviceDistroDatabase.RemoveContainerVolume(ctx, container, containerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.recreateWithVolumes(ctx, state); err != nil {
+		if errRestore := a.serviceDistroDatabase.RestoreContainerVolume(ctx, volume); errRestore != nil {
+			lib.Log.Warning(errRestore.Error())
+		}
+		return nil, err
+	}
+
+	volumes, err := a.serviceDistroDatabase.GetContainerVolumes(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.T_("Volume %s removed from container %s, the container has been recreated"), volume.Spec(), container),
+			"volume":  volume,
+			"volumes": volumes,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ContainerListVolumes возвращает дополнительные тома контейнера.
+func (a *Actions) ContainerListVolumes(ctx context.Context, container string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	container = strings.TrimSpace(container)
+	if container == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the container name"))
+	}
+
+	volumes, err := a.serviceDistroDatabase.GetContainerVolumes(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.TN_("%d volume found", "%d volumes found", len(volumes)), len(volumes)),
+			"volumes": volumes,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// volumePath проверяет путь тома: он должен быть абсолютным и не содержать двоеточий, разделяющих части --volume.
+func volumePath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || !filepath.IsAbs(path) {
+		return "", reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Volume path must be absolute: %s"), path)
+	}
+	if strings.Contains(path, ":") {
+		return "", reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Volume path cannot contain ':': %s"), path)
+	}
+
+	return filepath.Clean(path), nil
+}
+
+// volumeContainerState возвращает состояние контейнера, тома которого меняются. Изменение томов пересоздаёт
+// контейнер, поэтому без подтверждения recreate возвращается ошибка с предупреждением.
+func (a *Actions) volumeContainerState(ctx context.Context, container string, recreate bool) (service.ContainerState, error) {
+	states, err := a.serviceDistroAPI.GetContainerStates(ctx)
+	if err != nil {
+		return service.ContainerState{}, err
+	}
+
+	state, ok := states[container]
+	if !ok {
+		return service.ContainerState{}, reply.Errorf(reply.ErrorCodeContainerMissing, lib.T_("Container %s not found"), container)
+	}
+
+	if !recreate {
+		return service.ContainerState{}, reply.Errorf(reply.ErrorCodeConfirmationRequired,
+			lib.T_("Changing volumes requires recreating container %s: it will be stopped and created again from a snapshot of its current state. Run the command with --recreate to confirm"),
+			container)
+	}
+
+	return state, nil
+}
+
+// recreateWithVolumes пересоздаёт контейнер с сохранёнными томами и хуками и применяет к нему сохранённые
+// ограничения ресурсов.
+func (a *Actions) recreateWithVolumes(ctx context.Context, state service.ContainerState) error {
+	hooks, err := a.serviceDistroDatabase.GetInitHooks(ctx, state.Name)
+	if err != nil {
+		return err
+	}
+
+	volumes, err := a.serviceDistroDatabase.GetContainerVolumes(ctx, state.Name)
+	if err != nil {
+		return err
+	}
+
+	lib.Log.Warningf(lib.T_("Container %s is being recreated to apply the volume changes"), state.Name)
+	err = a.serviceDistroAPI.RecreateContainer(ctx, state, service.JoinInitHooks(hooks), volumes)
+	a.invalidateContainerCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	resources, err := a.serviceDistroDatabase.GetContainerResources(ctx, state.Name)
+	if err != nil {
+		return err
+	}
+	if resources != nil {
+		return a.applyResourceLimits(ctx, state.Name, *resources)
+	}
+
+	return nil
+}
+
+// ContainerCopyTo копирует файл или каталог хоста hostPath в запущенный контейнер по пути containerPath.
+// Копирование прерывается, если не уложилось в timeout.
+func (a *Actions) ContainerCopyTo(ctx context.Context, container, hostPath, containerPath string, timeout time.Duration) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	container, err = a.runningContainer(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	hostPath, err = copyHostPath(hostPath)
+	if err != nil {
+		return nil, err
+	}
+	containerPath, err = copyContainerPath(containerPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(hostPath); err != nil {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Host path %s does not exist"), hostPath)
+	}
+
+	size, checksum, err := service.HostFileSummary(hostPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.serviceDistroAPI.CopyToContainer(ctx, container, hostPath, containerPath, copyTimeout(timeout)); err != nil {
+		return nil, err
+	}
+
+	fileCopy := service.FileCopy{
+		Container:   container,
+		Source:      hostPath,
+		Destination: containerPath,
+		Size:        size,
+		Checksum:    checksum,
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.T_("%s copied to %s in container %s"), hostPath, containerPath, container),
+			"copy":    fileCopy,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ContainerCopyFrom копирует файл или каталог containerPath из запущенного контейнера на хост по пути hostPath.
+// Если hostPath существующий каталог, копия создаётся внутри него, как это делает podman cp.
+func (a *Actions) ContainerCopyFrom(ctx context.Context, container, containerPath, hostPath string, timeout time.Duration) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	container, err = a.runningContainer(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	containerPath, err = copyContainerPath(containerPath)
+	if err != nil {
+		return nil, err
+	}
+	hostPath, err = copyHostPath(hostPath)
+	if err != nil {
+		return nil, err
+	}
+
+	destination := hostPath
+	if info, errStat := os.Stat(hostPath); errStat == nil && info.IsDir() {
+		destination = filepath.Join(hostPath, filepath.Base(containerPath))
+	}
+
+	if err = a.serviceDistroAPI.CopyFromContainer(ctx, container, containerPath, hostPath, copyTimeout(timeout)); err != nil {
+		return nil, err
+	}
+
+	size, checksum, err := service.HostFileSummary(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	fileCopy := service.FileCopy{
+		Container:   container,
+		Source:      containerPath,
+		Destination: destination,
+		Size:        size,
+		Checksum:    checksum,
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.T_("%s copied from container %s to %s"), containerPath, container, destination),
+			"copy":    fileCopy,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// runningContainer проверяет, что контейнер существует и запущен: podman cp не работает с остановленным
+// контейнером distrobox, чьи каталоги подключаются только при входе.
+func (a *Actions) runningContainer(ctx context.Context, container string) (string, error) {
+	container = strings.TrimSpace(container)
+	if container == "" {
+		return "", reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the container name"))
+	}
+
+	states, err := a.serviceDistroAPI.GetContainerStates(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	state, ok := states[container]
+	if !ok {
+		return "", reply.Errorf(reply.ErrorCodeContainerMissing, lib.T_("Container %s not found"), container)
+	}
+	if !state.Running {
+		return "", reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Container %s is not running, start it with distrobox enter %s"),
+			container, container)
+	}
+
+	return container, nil
+}
+
+// copyHostPath возвращает абсолютный путь хоста для копирования, относительный путь считается от текущего каталога.
+func copyHostPath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the source and destination paths"))
+	}
+
+	return filepath.Abs(path)
+}
+
+// copyContainerPath проверяет путь внутри контейнера: он должен быть абсолютным.
+func copyContainerPath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the source and destination paths"))
+	}
+	if !filepath.IsAbs(path) {
+		return "", reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Container path must be absolute: %s"), path)
+	}
+
+	return filepath.Clean(path), nil
+}
+
+// copyTimeout возвращает тайм-аут копирования, по умолчанию service.DefaultCopyTimeout.
+func copyTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return service.DefaultCopyTimeout
+	}
+
+	return timeout
+}
+
+// ContainerRemove удаляет контейнер по имени.
+func (a *Actions) ContainerRemove(ctx context.Context, name string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		errMsg := lib.T_("You must specify the container name (--name)")
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	result, err := a.serviceDistroAPI.RemoveContainer(ctx, name)
+	a.invalidateContainerCache(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":       fmt.Sprintf(lib.T_("Container %s successfully deleted"), name),
+			"containerInfo": result,
+		},
+		Error: false,
+	}
+
+	err = a.serviceDistroDatabase.DeletePackagesFromContainer(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Error deleting container: %v"), err)
+	}
+
+	if err = a.serviceDistroDatabase.DeleteContainerHealth(ctx, name); err != nil {
+		lib.Log.Warning(err.Error())
+	}
+
+	return &resp, nil
+}
+
+// invalidateContainerCache сбрасывает кэш списка контейнеров. Ошибка только записывается в журнал,
+// так как кэш в любом случае устареет через containerListCacheTTL.
+func (a *Actions) invalidateContainerCache(ctx context.Context) {
+	if err := a.serviceDistroDatabase.InvalidateContainerCache(ctx); err != nil {
+		lib.Log.Warning(err.Error())
+	}
 }
 
 // GetFilterFields возвращает список свойств для фильтрации по названию контейнера. Метод для DBUS
@@ -487,7 +2049,7 @@ func (a *Actions) validateDatabase(ctx context.Context) error {
 func (a *Actions) validateContainer(ctx context.Context, container string) (service.ContainerInfo, error) {
 	container = strings.TrimSpace(container)
 	if container == "" {
-		return service.ContainerInfo{}, fmt.Errorf(lib.T_("You must specify the container name"))
+		return service.ContainerInfo{}, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the container name"))
 	}
 
 	// Если контейнер не найден через API, проверяем наличие записей в базе данных
@@ -500,7 +2062,7 @@ func (a *Actions) validateContainer(ctx context.Context, container string) (serv
 			}
 		}
 
-		return service.ContainerInfo{}, errInfo
+		return service.ContainerInfo{}, reply.WithErrorCode(reply.ErrorCodeContainerMissing, errInfo)
 	}
 
 	// Если база не содержит данные, обновляем пакеты.
@@ -520,7 +2082,7 @@ func (a *Actions) validateContainer(ctx context.Context, container string) (serv
 // checkRoot проверяет, запущен ли apm от имени root
 func (a *Actions) checkRoot() error {
 	if syscall.Geteuid() == 0 {
-		return fmt.Errorf(lib.T_("Elevated rights are required to perform this action. Please use sudo or su"))
+		return reply.Errorf(reply.ErrorCodeRootForbidden, lib.T_("Elevated rights are required to perform this action. Please use sudo or su"))
 	}
 
 	return nil
@@ -18,26 +18,57 @@ package distrobox
 
 import (
 	"apm/cmd/common/reply"
+	"apm/cmd/distrobox/service"
 	"apm/lib"
 	"context"
+	"strconv"
+	"strings"
 
 	"github.com/urfave/cli/v3"
 )
 
-// newErrorResponse создаёт ответ с ошибкой и указанным сообщением.
-func newErrorResponse(message string) reply.APIResponse {
-	lib.Log.Error(message)
+// newErrorResponse создаёт ответ с сообщением, кодом и подробностями ошибки.
+// Необязательный code заменяет код, определённый по самой ошибке.
+func newErrorResponse(err error, code ...string) reply.APIResponse {
+	lib.Log.Error(err.Error())
+
+	errorCode := reply.ErrorCode(err)
+	if len(code) > 0 && code[0] != "" {
+		errorCode = code[0]
+	}
 
 	return reply.APIResponse{
-		Data:  map[string]interface{}{"message": message},
-		Error: true,
+		Data:    map[string]interface{}{"message": err.Error()},
+		Error:   true,
+		Code:    errorCode,
+		Details: reply.ErrorDetails(err),
+	}
+}
+
+// columnsFlag флаг выбора колонок табличного формата вывода для команд, возвращающих списки.
+func columnsFlag(defaults ...string) *cli.StringSliceFlag {
+	return &cli.StringSliceFlag{
+		Name:    "columns",
+		Usage:   lib.T_("Columns for the table and csv formats, for example: --columns name,version,installed,size"),
+		Aliases: []string{"fields"},
+		Value:   defaults,
 	}
 }
 
 func withGlobalWrapper(action cli.ActionFunc) cli.ActionFunc {
 	return func(ctx context.Context, cmd *cli.Command) error {
 		lib.Env.Format = cmd.String("format")
+		reply.SetOutputFile(cmd.String("output"))
 		ctx = context.WithValue(ctx, "transaction", cmd.String("transaction"))
+		ctx = context.WithValue(ctx, "columns", cmd.StringSlice("columns"))
+		ctx = context.WithValue(ctx, "command", cmd.FullName())
+		if err := reply.ValidateFormat(lib.Env.Format); err != nil {
+			lib.Env.Format = "text"
+			return reply.CliResponse(ctx, newErrorResponse(err))
+		}
+		if err := reply.SetColorMode(cmd.String("color")); err != nil {
+			return reply.CliResponse(ctx, newErrorResponse(err))
+		}
 
 		reply.CreateSpinner()
 		return action(ctx, cmd)
@@ -45,7 +76,7 @@ func withGlobalWrapper(action cli.ActionFunc) cli.ActionFunc {
 }
 
 func CommandList() *cli.Command {
-	return &cli.Command{
+	command := &cli.Command{
 		Name:    "distrobox",
 		Aliases: []string{"d"},
 		Usage:   lib.T_("Managing packages and containers in distrobox"),
@@ -64,7 +95,7 @@ func CommandList() *cli.Command {
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
 					resp, err := NewActions().Update(ctx, cmd.String("container"))
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
@@ -85,7 +116,7 @@ func CommandList() *cli.Command {
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
 					resp, err := NewActions().Info(ctx, cmd.String("container"), cmd.Args().First())
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
@@ -101,11 +132,51 @@ func CommandList() *cli.Command {
 						Usage:   lib.T_("Container name. Optional flag"),
 						Aliases: []string{"c"},
 					},
+					&cli.BoolFlag{
+						Name:  "installed",
+						Usage: lib.T_("Show only installed packages"),
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "not-installed",
+						Usage: lib.T_("Show only packages that are not installed"),
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "interactive",
+						Usage: lib.T_("Select packages from the results and install them"),
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "export",
+						Usage: lib.T_("Export package"),
+						Value: true,
+					},
+					columnsFlag(),
 				},
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
-					resp, err := NewActions().Search(ctx, cmd.String("container"), cmd.Args().First())
+					if cmd.Bool("installed") && cmd.Bool("not-installed") {
+						return reply.CliResponse(ctx, newErrorResponse(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("The --installed and --not-installed flags cannot be used together"))))
+					}
+
+					var installed *bool
+					if cmd.Bool("installed") || cmd.Bool("not-installed") {
+						value := cmd.Bool("installed")
+						installed = &value
+					}
+
+					if cmd.Bool("interactive") {
+						resp, err := NewActions().SearchInteractive(ctx, cmd.String("container"), cmd.Args().First(), installed, cmd.Bool("export"))
+						if err != nil {
+							return reply.CliResponse(ctx, newErrorResponse(err))
+						}
+
+						return reply.CliResponse(ctx, *resp)
+					}
+
+					resp, err := NewActions().Search(ctx, cmd.String("container"), cmd.Args().First(), installed)
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
@@ -148,6 +219,7 @@ func CommandList() *cli.Command {
 						Usage: lib.T_("Force update all packages before the request"),
 						Value: false,
 					},
+					columnsFlag(),
 				},
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
 					params := ListParams{
@@ -162,7 +234,7 @@ func CommandList() *cli.Command {
 
 					resp, err := NewActions().List(ctx, params)
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
@@ -184,11 +256,28 @@ func CommandList() *cli.Command {
 						Usage: lib.T_("Export package"),
 						Value: true,
 					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: lib.T_("Show the planned changes without modifying the container"),
+					},
+					&cli.BoolFlag{
+						Name:  "reinstall",
+						Usage: lib.T_("Reinstall the package if it is already installed"),
+					},
 				},
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
-					resp, err := NewActions().Install(ctx, cmd.String("container"), cmd.Args().First(), cmd.Bool("export"))
+					if cmd.Bool("dry-run") {
+						resp, err := NewActions().DryRunInstall(ctx, cmd.String("container"), cmd.Args().First(), cmd.Bool("export"))
+						if err != nil {
+							return reply.CliResponse(ctx, newErrorResponse(err))
+						}
+
+						return reply.CliResponse(ctx, *resp)
+					}
+
+					resp, err := NewActions().Install(ctx, cmd.String("container"), cmd.Args().First(), cmd.Bool("export"), cmd.Bool("reinstall"))
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
@@ -211,16 +300,210 @@ func CommandList() *cli.Command {
 						Usage: lib.T_("Remove only the export, leave the package in the container"),
 						Value: false,
 					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: lib.T_("Show the planned changes without modifying the container"),
+					},
 				},
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Bool("dry-run") {
+						resp, err := NewActions().DryRunRemove(ctx, cmd.String("container"), cmd.Args().First(), cmd.Bool("only-export"))
+						if err != nil {
+							return reply.CliResponse(ctx, newErrorResponse(err))
+						}
+
+						return reply.CliResponse(ctx, *resp)
+					}
+
 					resp, err := NewActions().Remove(ctx, cmd.String("container"), cmd.Args().First(), cmd.Bool("only-export"))
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:  "import-packages",
+				Usage: lib.T_("Install packages listed in a text file, one name or name=version per line"),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "container",
+						Usage:    lib.T_("Container name. Required"),
+						Aliases:  []string{"c"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "file",
+						Usage:    lib.T_("Path to the package list file. Required"),
+						Aliases:  []string{"f"},
+						Required: true,
+					},
+				},
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().ImportPackageList(ctx, cmd.String("container"), cmd.String("file"))
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
 				}),
 			},
+			{
+				Name:  "export-all",
+				Usage: lib.T_("Export all installed applications of the container"),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "container",
+						Usage:    lib.T_("Container name. Required"),
+						Aliases:  []string{"c"},
+						Required: true,
+					},
+				},
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().ExportAll(ctx, cmd.String("container"))
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:  "rename-export",
+				Usage: lib.T_("Rename an exported application"),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "container",
+						Usage:    lib.T_("Container name. Required"),
+						Aliases:  []string{"c"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "package",
+						Usage:    lib.T_("Package name. Required"),
+						Aliases:  []string{"p"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "name",
+						Usage:    lib.T_("New application name. Required"),
+						Aliases:  []string{"n"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "icon",
+						Usage: lib.T_("Path to the icon that replaces the application icon"),
+					},
+				},
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().RenameExport(ctx, cmd.String("container"), cmd.String("package"), cmd.String("name"), cmd.String("icon"))
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:  "init-container",
+				Usage: lib.T_("Run hooks from a hook file in the container"),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "container",
+						Usage:    lib.T_("Container name. Required"),
+						Aliases:  []string{"c"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "hook-file",
+						Usage:    lib.T_("YAML file with a list of hooks to run in the container"),
+						Required: true,
+					},
+				},
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().InitContainer(ctx, cmd.String("container"), cmd.String("hook-file"))
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:  "hooks",
+				Usage: lib.T_("Container initialization hooks"),
+				Commands: []*cli.Command{
+					{
+						Name:      "add",
+						Usage:     lib.T_("Add a hook and run it in the container"),
+						ArgsUsage: "\"command\"",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "container",
+								Usage:    lib.T_("Container name. Required"),
+								Aliases:  []string{"c"},
+								Required: true,
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().AddInitHook(ctx, cmd.String("container"), strings.Join(cmd.Args().Slice(), " "))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:      "remove",
+						Usage:     lib.T_("Remove a hook by its id"),
+						ArgsUsage: "id",
+						Aliases:   []string{"rm"},
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "container",
+								Usage:    lib.T_("Container name. Required"),
+								Aliases:  []string{"c"},
+								Required: true,
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							hookID, err := strconv.ParseInt(cmd.Args().First(), 10, 64)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the numeric hook id"))))
+							}
+
+							resp, err := NewActions().RemoveInitHook(ctx, cmd.String("container"), hookID)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "list",
+						Usage: lib.T_("List of container hooks"),
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "container",
+								Usage:    lib.T_("Container name. Required"),
+								Aliases:  []string{"c"},
+								Required: true,
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ListInitHooks(ctx, cmd.String("container"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+				},
+			},
 			{
 				Name:    "container",
 				Usage:   lib.T_("Module for working with containers"),
@@ -229,10 +512,17 @@ func CommandList() *cli.Command {
 					{
 						Name:  "list",
 						Usage: lib.T_("List of containers"),
+						Flags: []cli.Flag{
+							columnsFlag("name", "status", "image", "packageCount", "autoStart"),
+							&cli.BoolFlag{
+								Name:  "sort-by-size",
+								Usage: lib.T_("Sort containers by size on disk, largest first"),
+							},
+						},
 						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
-							resp, err := NewActions().ContainerList(ctx)
+							resp, err := NewActions().ContainerList(ctx, cmd.Bool("sort-by-size"))
 							if err != nil {
-								return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+								return reply.CliResponse(ctx, newErrorResponse(err))
 							}
 
 							return reply.CliResponse(ctx, *resp)
@@ -260,7 +550,7 @@ func CommandList() *cli.Command {
 							}
 							if !valid {
 								return reply.CliResponse(ctx,
-									newErrorResponse(lib.T_("The value for image must be one of: alt, ubuntu, arch")))
+									newErrorResponse(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("The value for image must be one of: alt, ubuntu, arch"))))
 							}
 
 							var imageLink string
@@ -273,9 +563,9 @@ func CommandList() *cli.Command {
 								imageLink = "registry.altlinux.org/sisyphus/base:latest"
 							}
 
-							resp, err := NewActions().ContainerAdd(ctx, imageLink, "atomic-"+imageVal, "zsh mc nano", "")
+							resp, err := NewActions().ContainerAdd(ctx, imageLink, "atomic-"+imageVal, "zsh mc nano", "", "", false)
 							if err != nil {
-								return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+								return reply.CliResponse(ctx, newErrorResponse(err))
 							}
 
 							return reply.CliResponse(ctx, *resp)
@@ -304,6 +594,14 @@ func CommandList() *cli.Command {
 								Name:  "init-hooks",
 								Usage: lib.T_("Calling hook to execute commands"),
 							},
+							&cli.StringFlag{
+								Name:  "hook-file",
+								Usage: lib.T_("YAML file with a list of hooks to run in the container after creation"),
+							},
+							&cli.BoolFlag{
+								Name:  "strict-hooks",
+								Usage: lib.T_("Remove the container if a hook from the hook file fails"),
+							},
 						},
 						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
 							imageVal := cmd.String("image")
@@ -311,9 +609,242 @@ func CommandList() *cli.Command {
 							addPkgVal := cmd.String("additional-packages")
 							hookVal := cmd.String("init-hooks")
 
-							resp, err := NewActions().ContainerAdd(ctx, imageVal, nameVal, addPkgVal, hookVal)
+							resp, err := NewActions().ContainerAdd(ctx, imageVal, nameVal, addPkgVal, hookVal,
+								cmd.String("hook-file"), cmd.Bool("strict-hooks"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "set-network",
+						Usage: lib.T_("Change the container network mode"),
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "container",
+								Usage:    lib.T_("Container name. Required"),
+								Aliases:  []string{"c"},
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "mode",
+								Usage: lib.T_("Network mode: host, none or the name of a podman network"),
+								Value: "host",
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ContainerSetNetwork(ctx, cmd.String("container"), cmd.String("mode"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "set-limits",
+						Usage: lib.T_("Set the memory and CPU limits of the container"),
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "container",
+								Usage:    lib.T_("Container name. Required"),
+								Aliases:  []string{"c"},
+								Required: true,
+							},
+							&cli.IntFlag{
+								Name:  "memory",
+								Usage: lib.T_("Memory limit in megabytes"),
+							},
+							&cli.FloatFlag{
+								Name:  "cpu",
+								Usage: lib.T_("CPU limit in percent of one core, for example 50 or 200"),
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().SetResourceLimits(ctx, cmd.String("container"), int(cmd.Int("memory")), cmd.Float("cpu"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "limits",
+						Usage: lib.T_("Show the memory and CPU limits of the container"),
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "container",
+								Usage:    lib.T_("Container name. Required"),
+								Aliases:  []string{"c"},
+								Required: true,
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().GetResourceLimits(ctx, cmd.String("container"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "volume",
+						Usage: lib.T_("Additional volumes of the container"),
+						Commands: []*cli.Command{
+							{
+								Name:      "add",
+								Usage:     lib.T_("Mount a host directory into the container. The container is recreated"),
+								ArgsUsage: "host-path container-path",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "container",
+										Usage:    lib.T_("Container name. Required"),
+										Aliases:  []string{"c"},
+										Required: true,
+									},
+									&cli.BoolFlag{
+										Name:  "readonly",
+										Usage: lib.T_("Mount the volume read-only"),
+										Value: false,
+									},
+									&cli.BoolFlag{
+										Name:  "recreate",
+										Usage: lib.T_("Confirm recreating the container, which is required to change its volumes"),
+										Value: false,
+									},
+								},
+								Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+									resp, err := NewActions().ContainerAddVolume(ctx, cmd.String("container"), cmd.Args().Get(0), cmd.Args().Get(1),
+										cmd.Bool("readonly"), cmd.Bool("recreate"))
+									if err != nil {
+										return reply.CliResponse(ctx, newErrorResponse(err))
+									}
+
+									return reply.CliResponse(ctx, *resp)
+								}),
+							},
+							{
+								Name:      "remove",
+								Usage:     lib.T_("Unmount a volume from the container. The container is recreated"),
+								ArgsUsage: "container-path",
+								Aliases:   []string{"rm"},
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "container",
+										Usage:    lib.T_("Container name. Required"),
+										Aliases:  []string{"c"},
+										Required: true,
+									},
+									&cli.BoolFlag{
+										Name:  "recreate",
+										Usage: lib.T_("Confirm recreating the container, which is required to change its volumes"),
+										Value: false,
+									},
+								},
+								Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+									resp, err := NewActions().ContainerRemoveVolume(ctx, cmd.String("container"), cmd.Args().First(), cmd.Bool("recreate"))
+									if err != nil {
+										return reply.CliResponse(ctx, newErrorResponse(err))
+									}
+
+									return reply.CliResponse(ctx, *resp)
+								}),
+							},
+							{
+								Name:  "list",
+								Usage: lib.T_("List of container volumes"),
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "container",
+										Usage:    lib.T_("Container name. Required"),
+										Aliases:  []string{"c"},
+										Required: true,
+									},
+								},
+								Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+									resp, err := NewActions().ContainerListVolumes(ctx, cmd.String("container"))
+									if err != nil {
+										return reply.CliResponse(ctx, newErrorResponse(err))
+									}
+
+									return reply.CliResponse(ctx, *resp)
+								}),
+							},
+						},
+					},
+					{
+						Name:      "copy-to",
+						Usage:     lib.T_("Copy a host file or directory into a running container"),
+						ArgsUsage: "host-path container-path",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "container",
+								Usage:    lib.T_("Container name. Required"),
+								Aliases:  []string{"c"},
+								Required: true,
+							},
+							&cli.DurationFlag{
+								Name:  "timeout",
+								Usage: lib.T_("Maximum copy duration, for example 5m"),
+								Value: service.DefaultCopyTimeout,
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ContainerCopyTo(ctx, cmd.String("container"), cmd.Args().Get(0), cmd.Args().Get(1),
+								cmd.Duration("timeout"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:      "copy-from",
+						Usage:     lib.T_("Copy a file or directory from a running container to the host"),
+						ArgsUsage: "container-path host-path",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "container",
+								Usage:    lib.T_("Container name. Required"),
+								Aliases:  []string{"c"},
+								Required: true,
+							},
+							&cli.DurationFlag{
+								Name:  "timeout",
+								Usage: lib.T_("Maximum copy duration, for example 5m"),
+								Value: service.DefaultCopyTimeout,
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ContainerCopyFrom(ctx, cmd.String("container"), cmd.Args().Get(0), cmd.Args().Get(1),
+								cmd.Duration("timeout"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "health-check",
+						Usage: lib.T_("Check that the container works: podman, entering, package manager and package database"),
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "container",
+								Usage:    lib.T_("Container name. Required"),
+								Aliases:  []string{"c"},
+								Required: true,
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ContainerHealthCheck(ctx, cmd.String("container"))
 							if err != nil {
-								return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+								return reply.CliResponse(ctx, newErrorResponse(err))
 							}
 
 							return reply.CliResponse(ctx, *resp)
@@ -333,7 +864,7 @@ func CommandList() *cli.Command {
 						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
 							resp, err := NewActions().ContainerRemove(ctx, cmd.String("name"))
 							if err != nil {
-								return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+								return reply.CliResponse(ctx, newErrorResponse(err))
 							}
 
 							return reply.CliResponse(ctx, *resp)
@@ -343,4 +874,8 @@ func CommandList() *cli.Command {
 			},
 		},
 	}
+
+	setupCompletion(command)
+
+	return command
 }
@@ -0,0 +1,77 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package distrobox
+
+import (
+	"apm/cmd/common/helper"
+	"apm/cmd/distrobox/service"
+	"apm/lib"
+	"context"
+
+	"github.com/urfave/cli/v3"
+)
+
+// completeContainers дополняет имена контейнеров из кэша списка контейнеров. Distrobox опрашивается
+// только при пустом кэше, и полученный список сохраняется для следующих дополнений.
+func completeContainers(ctx context.Context, prefix string) []string {
+	a := NewActions()
+
+	containers, _, err := a.serviceDistroDatabase.GetCachedContainers(ctx, service.ContainerListCacheTTL())
+	if err != nil {
+		lib.Log.Debug(err.Error())
+	}
+
+	if len(containers) == 0 {
+		containers, err = a.fetchContainers(ctx, false)
+		if err != nil {
+			lib.Log.Debug(err.Error())
+			return nil
+		}
+		if err = a.serviceDistroDatabase.SaveCachedContainers(ctx, containers); err != nil {
+			lib.Log.Debug(err.Error())
+		}
+	}
+
+	names := make([]string, 0, len(containers))
+	for _, container := range containers {
+		names = append(names, container.Name)
+	}
+
+	return names
+}
+
+// completeFilterFields дополняет поля фильтра в виде "поле=".
+func completeFilterFields(ctx context.Context, prefix string) []string {
+	var fields []string
+	for _, field := range service.FilterFields() {
+		fields = append(fields, field+"=")
+	}
+
+	return fields
+}
+
+// setupCompletion назначает дополнение командам distrobox.
+func setupCompletion(command *cli.Command) {
+	helper.SetupCompletion(command,
+		map[string]helper.Completer{
+			"container": completeContainers,
+			"sort":      helper.StaticCompleter(service.SortFields()),
+			"filter":    completeFilterFields,
+		},
+		nil,
+	)
+}
@@ -18,10 +18,9 @@ package distrobox
 
 import (
 	"apm/cmd/common/icon"
+	"apm/cmd/common/reply"
 	"apm/lib"
-	"context"
 	"encoding/json"
-	"fmt"
 
 	"github.com/godbus/dbus/v5"
 )
@@ -41,7 +40,7 @@ func NewDBusWrapper(a *Actions, i *icon.Service) *DBusWrapper {
 func (w *DBusWrapper) GetIconByPackage(packageName string, container string) ([]byte, *dbus.Error) {
 	bytes, err := w.iconService.GetIcon(packageName, container)
 	if err != nil {
-		return nil, dbus.MakeFailedError(err)
+		return nil, reply.DBusError(err)
 	}
 
 	return bytes, nil
@@ -49,147 +48,301 @@ func (w *DBusWrapper) GetIconByPackage(packageName string, container string) ([]
 
 // GetFilterFields обёртка над actions.GetFilterFields
 func (w *DBusWrapper) GetFilterFields(container string, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	resp, err := w.actions.GetFilterFields(ctx, container)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
 
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
-	}
-
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // Update обёртка над actions.Update
 func (w *DBusWrapper) Update(container string, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	resp, err := w.actions.Update(ctx, container)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
-	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // Info обёртка над actions.Info
 func (w *DBusWrapper) Info(container string, packageName string, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	resp, err := w.actions.Info(ctx, container, packageName)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
-	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
-// Search обёртка над actions.Search
-func (w *DBusWrapper) Search(container string, packageName string, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
-	resp, err := w.actions.Search(ctx, container, packageName)
-	if err != nil {
-		return "", dbus.MakeFailedError(err)
+// Search обёртка над actions.Search. installed: -1 - любые пакеты, 0 - неустановленные, 1 - установленные
+func (w *DBusWrapper) Search(container string, packageName string, installed int32, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+
+	var installedFilter *bool
+	if installed >= 0 {
+		value := installed == 1
+		installedFilter = &value
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+
+	resp, err := w.actions.Search(ctx, container, packageName, installedFilter)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // List принимает JSON‑строку с параметрами ListParams, а возвращает JSON с reply.APIResponse.
 func (w *DBusWrapper) List(paramsJSON string, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	var params ListParams
 	if err := json.Unmarshal([]byte(paramsJSON), &params); err != nil {
-		return "", dbus.MakeFailedError(fmt.Errorf(lib.T_("Failed to parse JSON: %w"), err))
+		return "", reply.DBusError(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Failed to parse JSON: %w"), err))
 	}
 
 	resp, err := w.actions.List(ctx, params)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// CountDistroPackages обёртка над actions.Count. filtersJSON — массив фильтров вида ["key=value"].
+func (w *DBusWrapper) CountDistroPackages(container string, filtersJSON string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	var filters []string
+	if filtersJSON != "" {
+		if err := json.Unmarshal([]byte(filtersJSON), &filters); err != nil {
+			return "", reply.DBusError(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Failed to parse JSON: %w"), err))
+		}
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+
+	resp, err := w.actions.Count(ctx, ListParams{Container: container, Filters: filters})
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // Install обёртка над actions.Install
 func (w *DBusWrapper) Install(container string, packageName string, export bool, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
-	resp, err := w.actions.Install(ctx, container, packageName, export)
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.Install(ctx, container, packageName, export, false)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ReinstallPackage обёртка над actions.Install с переустановкой уже установленного пакета
+func (w *DBusWrapper) ReinstallPackage(container string, packageName string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.Install(ctx, container, packageName, false, true)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // Remove обёртка над actions.Remove
 func (w *DBusWrapper) Remove(container string, packageName string, onlyExport bool, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	resp, err := w.actions.Remove(ctx, container, packageName, onlyExport)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImportPackageList обёртка над actions.ImportPackageList
+func (w *DBusWrapper) ImportPackageList(container string, filePath string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImportPackageList(ctx, container, filePath)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ExportAll обёртка над actions.ExportAll
+func (w *DBusWrapper) ExportAll(container string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ExportAll(ctx, container)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // ContainerList обёртка над actions.ContainerList
 func (w *DBusWrapper) ContainerList(transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
-	resp, err := w.actions.ContainerList(ctx)
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ContainerList(ctx, false)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
-	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // ContainerAdd обёртка над actions.ContainerAdd
 func (w *DBusWrapper) ContainerAdd(image, name, additionalPackages, initHooks string, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
-	resp, err := w.actions.ContainerAdd(ctx, image, name, additionalPackages, initHooks)
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ContainerAdd(ctx, image, name, additionalPackages, initHooks, "", false)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// InitContainer обёртка над actions.InitContainer
+func (w *DBusWrapper) InitContainer(containerName, hookFile string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.InitContainer(ctx, containerName, hookFile)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ContainerSetNetwork обёртка над actions.ContainerSetNetwork
+func (w *DBusWrapper) ContainerSetNetwork(containerName, networkMode string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ContainerSetNetwork(ctx, containerName, networkMode)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// SetResourceLimits обёртка над actions.SetResourceLimits
+func (w *DBusWrapper) SetResourceLimits(containerName string, memoryMB int32, cpuPercent float64, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.SetResourceLimits(ctx, containerName, int(memoryMB), cpuPercent)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// GetResourceLimits обёртка над actions.GetResourceLimits
+func (w *DBusWrapper) GetResourceLimits(containerName string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.GetResourceLimits(ctx, containerName)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ContainerHealthCheck обёртка над actions.ContainerHealthCheck
+func (w *DBusWrapper) ContainerHealthCheck(containerName string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ContainerHealthCheck(ctx, containerName)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ContainerAddVolume обёртка над actions.ContainerAddVolume
+func (w *DBusWrapper) ContainerAddVolume(containerName, hostPath, containerPath string, readonly bool, recreate bool,
+	transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ContainerAddVolume(ctx, containerName, hostPath, containerPath, readonly, recreate)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ContainerRemoveVolume обёртка над actions.ContainerRemoveVolume
+func (w *DBusWrapper) ContainerRemoveVolume(containerName, containerPath string, recreate bool, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ContainerRemoveVolume(ctx, containerName, containerPath, recreate)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ContainerListVolumes обёртка над actions.ContainerListVolumes
+func (w *DBusWrapper) ContainerListVolumes(containerName string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ContainerListVolumes(ctx, containerName)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// DryRunInstall обёртка над actions.DryRunInstall
+func (w *DBusWrapper) DryRunInstall(container, packageName string, export bool, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.DryRunInstall(ctx, container, packageName, export)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// DryRunRemove обёртка над actions.DryRunRemove
+func (w *DBusWrapper) DryRunRemove(container, packageName string, onlyExport bool, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.DryRunRemove(ctx, container, packageName, onlyExport)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// RenameExport обёртка над actions.RenameExport
+func (w *DBusWrapper) RenameExport(container, packageName, displayName, icon string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.RenameExport(ctx, container, packageName, displayName, icon)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// AddInitHook обёртка над actions.AddInitHook
+func (w *DBusWrapper) AddInitHook(container, hookCommand string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.AddInitHook(ctx, container, hookCommand)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// RemoveInitHook обёртка над actions.RemoveInitHook
+func (w *DBusWrapper) RemoveInitHook(container string, hookID int64, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.RemoveInitHook(ctx, container, hookID)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ListInitHooks обёртка над actions.ListInitHooks
+func (w *DBusWrapper) ListInitHooks(container string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ListInitHooks(ctx, container)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
 }
 
 // ContainerRemove обёртка над actions.ContainerRemove
 func (w *DBusWrapper) ContainerRemove(name string, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	resp, err := w.actions.ContainerRemove(ctx, name)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
-	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
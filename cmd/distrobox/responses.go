@@ -0,0 +1,55 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package distrobox
+
+import "apm/cmd/distrobox/service"
+
+// Типизированные ответы основных команд. Имена полей входят в контракт версии reply.APIVersion:
+// их нельзя переименовывать или удалять без повышения версии. Поле message в формате json не выводится.
+
+// PackageListResponse ответ команды list. Ответ только с подсчётом пакетов содержит лишь message и totalCount.
+type PackageListResponse struct {
+	Message    string                `json:"message"`
+	Packages   []service.PackageInfo `json:"packages"`
+	TotalCount int                   `json:"totalCount"`
+}
+
+// PackageSearchResponse ответ команды search.
+type PackageSearchResponse struct {
+	Message  string                `json:"message"`
+	Packages []service.PackageInfo `json:"packages"`
+}
+
+// PackageInfoResponse ответ команды info.
+type PackageInfoResponse struct {
+	Message     string                    `json:"message"`
+	PackageInfo service.InfoPackageAnswer `json:"packageInfo"`
+	// Timings длительность этапов установки в миллисекундах
+	Timings map[string]int64 `json:"timings,omitempty"`
+}
+
+// ContainerListResponse ответ команды container list.
+type ContainerListResponse struct {
+	Containers []ContainerListItem `json:"containers"`
+}
+
+// InitContainerResponse ответ команды init-container.
+type InitContainerResponse struct {
+	Message     string                        `json:"message"`
+	HooksRun    int                           `json:"hooksRun"`
+	HookResults map[string]service.HookResult `json:"hookResults"`
+}
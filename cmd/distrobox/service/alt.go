@@ -47,13 +47,14 @@ func (p *AltProvider) GetPackages(ctx context.Context, containerInfo ContainerIn
 	}
 
 	command := fmt.Sprintf("%s apt-cache dumpavail", lib.Env.CommandPrefix)
-	cmd := exec.Command("sh", "-c", command)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return nil, fmt.Errorf(lib.T_("Error opening stdout pipe: %w"), err)
 	}
-	if err = cmd.Start(); err != nil {
+	wait, err := lib.CommandStart(cmd)
+	if err != nil {
 		return nil, fmt.Errorf(lib.T_("Error executing command: %w"), err)
 	}
 
@@ -65,7 +66,7 @@ func (p *AltProvider) GetPackages(ctx context.Context, containerInfo ContainerIn
 	}
 
 	// Получаем карту установленных пакетов
-	installedPackages, err := p.getInstalledPackages(containerInfo)
+	installedPackages, err := p.getInstalledPackages(ctx, containerInfo)
 	if err != nil {
 		installedPackages = []string{}
 	}
@@ -140,7 +141,8 @@ func (p *AltProvider) GetPackages(ctx context.Context, containerInfo ContainerIn
 		return nil, fmt.Errorf(lib.T_("Scanner error: %w"), err)
 	}
 
-	if err = cmd.Wait(); err != nil {
+	err = wait()
+	if err != nil {
 		return nil, fmt.Errorf(lib.T_("Command execution error: %w"), err)
 	}
 
@@ -178,6 +180,27 @@ func (p *AltProvider) InstallPackage(ctx context.Context, containerInfo Containe
 	return nil
 }
 
+// ReinstallPackage переустанавливает пакет через apt-get install --reinstall.
+func (p *AltProvider) ReinstallPackage(ctx context.Context, containerInfo ContainerInfo, packageName string) error {
+	cmdStr := fmt.Sprintf("%s distrobox enter %s -- sudo apt-get install --reinstall -y %s", lib.Env.CommandPrefix, containerInfo.ContainerName, packageName)
+	_, stderr, err := helper.RunCommand(ctx, cmdStr)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to reinstall package %s: %v, stderr: %s"), packageName, err, stderr)
+	}
+	return nil
+}
+
+// InstallPackages устанавливает несколько пакетов одной командой apt-get install с учётом закреплённых версий.
+func (p *AltProvider) InstallPackages(ctx context.Context, containerInfo ContainerInfo, packages []PackageSpec) error {
+	targets := aptPackageTargets(packages)
+	cmdStr := fmt.Sprintf("%s distrobox enter %s -- sudo apt-get install -y %s", lib.Env.CommandPrefix, containerInfo.ContainerName, targets)
+	_, stderr, err := helper.RunCommand(ctx, cmdStr)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to install package %s: %v, stderr: %s"), targets, err, stderr)
+	}
+	return nil
+}
+
 // GetPathByPackageName возвращает список путей для файла пакета, найденных через rpm -ql.
 func (p *AltProvider) GetPathByPackageName(ctx context.Context, containerInfo ContainerInfo, packageName, filePath string) ([]string, error) {
 	command := fmt.Sprintf("%s distrobox enter %s -- rpm -ql %s | grep '%s'", lib.Env.CommandPrefix, containerInfo.ContainerName, packageName, filePath)
@@ -210,12 +233,12 @@ func (p *AltProvider) GetPackageOwner(ctx context.Context, containerInfo Contain
 }
 
 // getInstalledPackages возвращает карту установленных пакетов
-func (p *AltProvider) getInstalledPackages(containerInfo ContainerInfo) ([]string, error) {
+func (p *AltProvider) getInstalledPackages(ctx context.Context, containerInfo ContainerInfo) ([]string, error) {
 	command := fmt.Sprintf("%s distrobox enter %s -- rpm -qia", lib.Env.CommandPrefix, containerInfo.ContainerName)
-	cmd := exec.Command("sh", "-c", command)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Env = []string{"LC_ALL=C"}
 
-	output, err := cmd.CombinedOutput()
+	output, err := lib.CommandCombinedOutput(cmd)
 	if err != nil {
 		return nil, fmt.Errorf(lib.T_("Error executing command rpm -qia: %w"), err)
 	}
@@ -91,6 +91,37 @@ func (p *ArchProvider) InstallPackage(ctx context.Context, containerInfo Contain
 	return nil
 }
 
+// ReinstallPackage переустанавливает пакет с помощью pacman -S: для установленного пакета pacman
+// выполняет переустановку.
+func (p *ArchProvider) ReinstallPackage(ctx context.Context, containerInfo ContainerInfo, packageName string) error {
+	cmdStr := fmt.Sprintf("%s distrobox enter %s -- sudo pacman -S --noconfirm %s", lib.Env.CommandPrefix, containerInfo.ContainerName, packageName)
+	_, stderr, err := helper.RunCommand(ctx, cmdStr)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to reinstall package %s: %v, stderr: %s"), packageName, err, stderr)
+	}
+	return nil
+}
+
+// InstallPackages устанавливает несколько пакетов одной командой pacman -S. В репозиториях Arch
+// хранится только последняя версия пакета, поэтому закреплённые версии не учитываются.
+func (p *ArchProvider) InstallPackages(ctx context.Context, containerInfo ContainerInfo, packages []PackageSpec) error {
+	names := make([]string, 0, len(packages))
+	for _, pkg := range packages {
+		if pkg.Version != "" {
+			lib.Log.Warningf(lib.T_("pacman cannot install a specific version, the latest version of %s will be installed"), pkg.Name)
+		}
+		names = append(names, pkg.Name)
+	}
+
+	targets := strings.Join(names, " ")
+	cmdStr := fmt.Sprintf("%s distrobox enter %s -- sudo pacman -S --noconfirm %s", lib.Env.CommandPrefix, containerInfo.ContainerName, targets)
+	_, stderr, err := helper.RunCommand(ctx, cmdStr)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to install package %s: %v, stderr: %s"), targets, err, stderr)
+	}
+	return nil
+}
+
 // GetPackageOwner определяет, какому пакету принадлежит указанный файл.
 // Сначала используется pacman -Qo для поиска установленного пакета,
 // затем, если не найден, выполняется поиск через pacman -F.
@@ -0,0 +1,192 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const cachedContainersTableName = "cached_containers"
+
+// DefaultContainerListCacheTTL время жизни кэша списка контейнеров, если containerListCacheTTL не задан в конфигурации.
+const DefaultContainerListCacheTTL = 30 * time.Second
+
+// CachedContainer запись кэша списка контейнеров.
+type CachedContainer struct {
+	Name      string
+	OS        string
+	Image     string
+	Status    string
+	Running   bool
+	AutoStart bool
+	Network   string
+	SizeBytes int64
+}
+
+// ContainerListCacheTTL возвращает время жизни кэша списка контейнеров.
+func ContainerListCacheTTL() time.Duration {
+	if lib.Env.ContainerListCacheTTL > 0 {
+		return time.Duration(lib.Env.ContainerListCacheTTL) * time.Second
+	}
+
+	return DefaultContainerListCacheTTL
+}
+
+// createCachedContainersTable создаёт таблицу кэша списка контейнеров, если её ещё нет.
+func (s *DistroDBService) createCachedContainersTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		name TEXT PRIMARY KEY,
+		os TEXT,
+		image TEXT,
+		status TEXT,
+		running INTEGER,
+		autostart INTEGER,
+		network TEXT,
+		size_bytes INTEGER DEFAULT 0,
+		cached_at INTEGER
+	)`, cachedContainersTableName)
+
+	if _, err := s.dbConn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return s.migrateCachedContainersTable(ctx)
+}
+
+// migrateCachedContainersTable добавляет колонку размера в таблицу кэша, созданную прежними версиями.
+func (s *DistroDBService) migrateCachedContainersTable(ctx context.Context) error {
+	rows, err := s.dbConn.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", cachedContainersTableName))
+	if err != nil {
+		return fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+
+	hasSize := false
+	for rows.Next() {
+		var cid int
+		var name, columnType string
+		var notNull, pk int
+		var defaultValue sql.NullString
+		if err = rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		if name == "size_bytes" {
+			hasSize = true
+		}
+	}
+	rows.Close()
+
+	if hasSize {
+		return nil
+	}
+
+	alterQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN size_bytes INTEGER DEFAULT 0", cachedContainersTableName)
+	if _, err = s.dbConn.ExecContext(ctx, alterQuery); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return nil
+}
+
+// GetCachedContainers возвращает закэшированный список контейнеров и признак того,
+// что кэш моложе ttl. Устаревший список тоже возвращается, чтобы его можно было сравнить с актуальным.
+func (s *DistroDBService) GetCachedContainers(ctx context.Context, ttl time.Duration) ([]CachedContainer, bool, error) {
+	if err := s.createCachedContainersTable(ctx); err != nil {
+		return nil, false, err
+	}
+
+	query := fmt.Sprintf("SELECT name, os, image, status, running, autostart, network, size_bytes, cached_at FROM %s ORDER BY name", cachedContainersTableName)
+	rows, err := s.dbConn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, false, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+	defer rows.Close()
+
+	var containers []CachedContainer
+	var newest int64
+	for rows.Next() {
+		var container CachedContainer
+		var cachedAt int64
+		if err = rows.Scan(&container.Name, &container.OS, &container.Image, &container.Status, &container.Running,
+			&container.AutoStart, &container.Network, &container.SizeBytes, &cachedAt); err != nil {
+			return nil, false, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		if cachedAt > newest {
+			newest = cachedAt
+		}
+		containers = append(containers, container)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, false, fmt.Errorf(lib.T_("String processing error: %v"), err)
+	}
+
+	fresh := len(containers) > 0 && time.Since(time.Unix(newest, 0)) < ttl
+
+	return containers, fresh, nil
+}
+
+// SaveCachedContainers заменяет закэшированный список контейнеров.
+func (s *DistroDBService) SaveCachedContainers(ctx context.Context, containers []CachedContainer) error {
+	if err := s.createCachedContainersTable(ctx); err != nil {
+		return err
+	}
+
+	tx, err := s.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Error starting transaction: %v"), err)
+	}
+
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", cachedContainersTableName)); err != nil {
+		tx.Rollback()
+		return fmt.Errorf(lib.T_("Table cleanup error: %w"), err)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (name, os, image, status, running, autostart, network, size_bytes, cached_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, cachedContainersTableName)
+	cachedAt := time.Now().Unix()
+	for _, container := range containers {
+		if _, err = tx.ExecContext(ctx, query, container.Name, container.OS, container.Image, container.Status, container.Running,
+			container.AutoStart, container.Network, container.SizeBytes, cachedAt); err != nil {
+			tx.Rollback()
+			return fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf(lib.T_("Transaction commit error: %v"), err)
+	}
+
+	return nil
+}
+
+// InvalidateContainerCache сбрасывает кэш списка контейнеров после их изменения.
+func (s *DistroDBService) InvalidateContainerCache(ctx context.Context) error {
+	if err := s.createCachedContainersTable(ctx); err != nil {
+		return err
+	}
+
+	if _, err := s.dbConn.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", cachedContainersTableName)); err != nil {
+		return fmt.Errorf(lib.T_("Table cleanup error: %w"), err)
+	}
+
+	return nil
+}
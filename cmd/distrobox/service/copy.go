@@ -0,0 +1,125 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/cmd/common/helper"
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultCopyTimeout время, за которое должно завершиться копирование файлов между хостом и контейнером.
+const DefaultCopyTimeout = 30 * time.Second
+
+// FileCopy результат копирования файла между хостом и контейнером. Размер и контрольная сумма считаются
+// по копии на стороне хоста, для каталога размер равен сумме размеров файлов, а сумма не считается.
+type FileCopy struct {
+	Container   string `json:"container"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Size        int64  `json:"size"`
+	Checksum    string `json:"checksum,omitempty"`
+}
+
+// CopyToContainer копирует файл или каталог хоста hostPath в контейнер по пути containerPath через podman cp.
+func (d *DistroAPIService) CopyToContainer(ctx context.Context, containerName string, hostPath string, containerPath string,
+	timeout time.Duration) error {
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("distro.CopyToContainer"))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("distro.CopyToContainer"))
+
+	return podmanCopy(ctx, hostPath, containerName+":"+containerPath, timeout)
+}
+
+// CopyFromContainer копирует файл или каталог контейнера containerPath на хост по пути hostPath через podman cp.
+func (d *DistroAPIService) CopyFromContainer(ctx context.Context, containerName string, containerPath string, hostPath string,
+	timeout time.Duration) error {
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("distro.CopyFromContainer"))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("distro.CopyFromContainer"))
+
+	return podmanCopy(ctx, containerName+":"+containerPath, hostPath, timeout)
+}
+
+// podmanCopy выполняет podman cp и прерывает его, если копирование не уложилось в timeout.
+func podmanCopy(ctx context.Context, source string, destination string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	command := fmt.Sprintf("%s podman cp %s %s", lib.Env.CommandPrefix, shellQuote(source), shellQuote(destination))
+	_, stderr, err := helper.RunCommand(ctx, command)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf(lib.T_("Copying did not finish within %s, use --timeout to allow more time"), timeout)
+	}
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to copy %s to %s: %v, stderr: %s"), source, destination, err, strings.TrimSpace(stderr))
+	}
+
+	return nil
+}
+
+// HostFileSummary возвращает размер и контрольную сумму SHA-256 файла хоста. Для каталога возвращается
+// суммарный размер вложенных файлов без контрольной суммы.
+func HostFileSummary(path string) (int64, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if info.IsDir() {
+		var size int64
+		err = filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			entryInfo, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += entryInfo.Size()
+			return nil
+		})
+
+		return size, "", err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
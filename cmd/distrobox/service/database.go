@@ -41,6 +41,16 @@ func NewDistroDBService(db *sql.DB) *DistroDBService {
 	}
 }
 
+// SortFields возвращает поля, по которым разрешена сортировка.
+func SortFields() []string {
+	return append([]string(nil), allowedSortFields...)
+}
+
+// FilterFields возвращает поля, по которым разрешена фильтрация.
+func FilterFields() []string {
+	return append([]string(nil), allowedFilterFields...)
+}
+
 // Списки разрешённых полей для сортировки
 var allowedSortFields = []string{
 	"name",
@@ -221,6 +231,38 @@ func (s *DistroDBService) CountTotalPackages(containerName string, filters map[s
 	return total, nil
 }
 
+// PackageCount количество пакетов контейнера в базе и его пакетный менеджер.
+type PackageCount struct {
+	Total     int
+	Installed int
+	Exported  int
+	Manager   string
+}
+
+// CountPackagesByContainer возвращает количество пакетов, установленных и экспортированных пакетов
+// всех контейнеров одним запросом.
+func (s *DistroDBService) CountPackagesByContainer(ctx context.Context) (map[string]PackageCount, error) {
+	query := fmt.Sprintf(`SELECT container, COUNT(*), COALESCE(SUM(installed), 0), COALESCE(SUM(exporting), 0), COALESCE(MAX(manager), '')
+		FROM %s GROUP BY container`, s.packagesTableName)
+	rows, err := s.dbConn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]PackageCount)
+	for rows.Next() {
+		var container string
+		var count PackageCount
+		if err = rows.Scan(&container, &count.Total, &count.Installed, &count.Exported, &count.Manager); err != nil {
+			return nil, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		counts[container] = count
+	}
+
+	return counts, rows.Err()
+}
+
 // QueryPackages возвращает пакеты из таблицы контейнера с возможностью фильтрации, сортировки, limit и offset.
 func (s *DistroDBService) QueryPackages(containerName string, filters map[string]interface{}, sortField, sortOrder string, limit, offset int64) ([]PackageInfo, error) {
 	// Начинаем базовый запрос без условия WHERE.
@@ -321,7 +363,8 @@ func (s *DistroDBService) QueryPackages(containerName string, filters map[string
 
 // FindPackagesByName ищет пакеты в таблице контейнера по неточному совпадению имени.
 // Используется оператор LIKE для поиска, возвращается срез найденных записей.
-func (s *DistroDBService) FindPackagesByName(containerName string, partialName string) ([]PackageInfo, error) {
+// installed ограничивает выборку установленными (true) или неустановленными (false) пакетами, nil - без ограничения.
+func (s *DistroDBService) FindPackagesByName(containerName string, partialName string, installed *bool) ([]PackageInfo, error) {
 	query := fmt.Sprintf("SELECT name, version, description, container, installed, exporting, manager FROM %s", s.packagesTableName)
 	var conditions []string
 	var args []interface{}
@@ -336,6 +379,15 @@ func (s *DistroDBService) FindPackagesByName(containerName string, partialName s
 		args = append(args, "%"+partialName+"%")
 	}
 
+	if installed != nil {
+		conditions = append(conditions, "installed = ?")
+		if *installed {
+			args = append(args, 1)
+		} else {
+			args = append(args, 0)
+		}
+	}
+
 	// Если есть условия, формируем часть WHERE
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
@@ -22,11 +22,13 @@ import (
 	"apm/lib"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
 	"sync"
+	"time"
 )
 
 // DistroAPIService реализует методы для работы с пакетами в Arch
@@ -86,7 +88,7 @@ func (d *DistroAPIService) GetContainerList(ctx context.Context, getFullInfo boo
 			wg.Add(1)
 			go func(n string) {
 				defer wg.Done()
-				info, err := d.fetchOsInfo(n)
+				info, err := d.fetchOsInfo(ctx, n)
 				if err != nil {
 					lib.Log.Error(err)
 					info = ContainerInfo{ContainerName: n, OS: "", Active: false}
@@ -110,6 +112,149 @@ func (d *DistroAPIService) GetContainerList(ctx context.Context, getFullInfo boo
 	return containers, nil
 }
 
+// ContainerState текущее состояние контейнера по данным distrobox и podman.
+type ContainerState struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Image     string `json:"image"`
+	Running   bool   `json:"running"`
+	AutoStart bool   `json:"autoStart"`
+	Network   string `json:"network"`
+}
+
+// containerStatesTTL время жизни кэша состояний контейнеров
+const containerStatesTTL = 10 * time.Second
+
+var (
+	containerStatesMutex    sync.Mutex
+	containerStatesCache    map[string]ContainerState
+	containerStatesCachedAt time.Time
+)
+
+// GetContainerStates возвращает состояние всех контейнеров. Результат кэшируется на containerStatesTTL,
+// чтобы не вызывать distrobox и podman для каждого контейнера отдельно.
+func (d *DistroAPIService) GetContainerStates(ctx context.Context) (map[string]ContainerState, error) {
+	containerStatesMutex.Lock()
+	defer containerStatesMutex.Unlock()
+
+	if containerStatesCache != nil && time.Since(containerStatesCachedAt) < containerStatesTTL {
+		return containerStatesCache, nil
+	}
+
+	command := fmt.Sprintf("%s distrobox ls", lib.Env.CommandPrefix)
+	stdout, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return nil, errors.New(lib.T_("Failed to retrieve the list of containers: ") + stderr)
+	}
+
+	states := make(map[string]ContainerState)
+	var names []string
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	for i, line := range lines {
+		// Первая строка - заголовок: ID | NAME | STATUS | IMAGE
+		if i == 0 {
+			continue
+		}
+
+		parts := strings.Split(line, "|")
+		if len(parts) < 4 {
+			continue
+		}
+
+		name := strings.TrimSpace(parts[1])
+		if name == "" {
+			continue
+		}
+
+		status := strings.TrimSpace(parts[2])
+		states[name] = ContainerState{
+			Name:    name,
+			Status:  status,
+			Image:   strings.TrimSpace(parts[3]),
+			Running: strings.HasPrefix(status, "Up"),
+		}
+		names = append(names, name)
+	}
+
+	if len(names) > 0 {
+		command = fmt.Sprintf("%s podman inspect --type container --format '{{.Name}}|{{.HostConfig.RestartPolicy.Name}}|{{.HostConfig.NetworkMode}}' %s",
+			lib.Env.CommandPrefix, strings.Join(names, " "))
+		stdout, stderr, err = helper.RunCommand(ctx, command)
+		if err != nil {
+			lib.Log.Errorf(lib.T_("Failed to get the restart policy of containers: %s"), stderr)
+		} else {
+			for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+				parts := strings.SplitN(line, "|", 3)
+				if len(parts) != 3 {
+					continue
+				}
+
+				state, ok := states[strings.TrimSpace(parts[0])]
+				if !ok {
+					continue
+				}
+
+				policy := strings.TrimSpace(parts[1])
+				state.AutoStart = policy == "always" || policy == "unless-stopped"
+				state.Network = strings.TrimSpace(parts[2])
+				states[state.Name] = state
+			}
+		}
+	}
+
+	containerStatesCache = states
+	containerStatesCachedAt = time.Now()
+
+	return states, nil
+}
+
+// podmanDiskUsage вывод podman system df -v --format json, из которого нужны только контейнеры.
+type podmanDiskUsage struct {
+	Containers []struct {
+		Names string `json:"Names"`
+		Size  int64  `json:"Size"`
+	} `json:"Containers"`
+}
+
+// GetContainerSizes возвращает размер каждого контейнера на диске в байтах по данным podman system df.
+// Размеры по контейнерам podman выводит только в подробном режиме.
+func (d *DistroAPIService) GetContainerSizes(ctx context.Context) (map[string]int64, error) {
+	command := fmt.Sprintf("%s podman system df -v --format json", lib.Env.CommandPrefix)
+	stdout, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to get the size of containers: %s"), stderr)
+	}
+
+	return ParseContainerSizes(stdout)
+}
+
+// ParseContainerSizes разбирает вывод podman system df -v --format json в размеры контейнеров по имени.
+func ParseContainerSizes(output string) (map[string]int64, error) {
+	var usage podmanDiskUsage
+	if err := json.Unmarshal([]byte(output), &usage); err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to parse JSON: %v"), err)
+	}
+
+	sizes := make(map[string]int64, len(usage.Containers))
+	for _, container := range usage.Containers {
+		name := strings.TrimPrefix(strings.TrimSpace(container.Names), "/")
+		if name == "" {
+			continue
+		}
+		sizes[name] = container.Size
+	}
+
+	return sizes, nil
+}
+
+// InvalidateContainerStates сбрасывает кэш состояний контейнеров после их изменения.
+func InvalidateContainerStates() {
+	containerStatesMutex.Lock()
+	defer containerStatesMutex.Unlock()
+
+	containerStatesCache = nil
+}
+
 // ExportingApp экспортирует пакет в хост-систему.
 // Если isConsole == false, формируется команда экспорта GUI приложения;
 // если isConsole == true, формируются команды для каждого пути из pathList.
@@ -146,12 +291,12 @@ func (d *DistroAPIService) ExportingApp(ctx context.Context, containerInfo Conta
 		wg.Add(1)
 		go func(command string) {
 			defer wg.Done()
-			cmd := exec.Command("sh", "-c", command)
+			cmd := exec.CommandContext(ctx, "sh", "-c", command)
 			var stdout, stderr bytes.Buffer
 			cmd.Stdout = &stdout
 			cmd.Stderr = &stderr
 
-			if err := cmd.Run(); err != nil {
+			if err := lib.CommandRun(cmd); err != nil {
 				errChan <- fmt.Errorf(lib.T_("Error executing command %q: %v"), command, err)
 			}
 		}(cmdStr)
@@ -168,16 +313,17 @@ func (d *DistroAPIService) ExportingApp(ctx context.Context, containerInfo Conta
 
 // fetchOsInfo выполняет команду для получения информации об ОС контейнера
 // и возвращает объект ContainerInfo.
-func (d *DistroAPIService) fetchOsInfo(containerName string) (ContainerInfo, error) {
+func (d *DistroAPIService) fetchOsInfo(ctx context.Context, containerName string) (ContainerInfo, error) {
 	command := fmt.Sprintf("%s distrobox enter %s -- cat /etc/os-release", lib.Env.CommandPrefix, containerName)
-	cmd := exec.Command("sh", "-c", command)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
-		lib.Log.Errorf(lib.T_("Error getting OS information for container %s: %v, stderr: %s"), containerName, err, stderr.String())
+	if err := lib.CommandRun(cmd); err != nil {
+		lib.Log.WithField("container", containerName).
+			Errorf(lib.T_("Error getting OS information for container %s: %v, stderr: %s"), containerName, err, stderr.String())
 		return ContainerInfo{ContainerName: containerName, OS: "", Active: false}, err
 	}
 
@@ -240,14 +386,15 @@ func (d *DistroAPIService) GetContainerOsInfo(ctx context.Context, containerName
 	}
 
 	if !found {
-		return ContainerInfo{}, fmt.Errorf(lib.T_("Container %s not found"), containerName)
+		return ContainerInfo{}, reply.Errorf(reply.ErrorCodeContainerMissing, lib.T_("Container %s not found"), containerName)
 	}
 
-	return d.fetchOsInfo(containerName)
+	return d.fetchOsInfo(ctx, containerName)
 }
 
 // CreateContainer создает контейнер, выполняя команду создания, и затем возвращает информацию о контейнере.
-func (d *DistroAPIService) CreateContainer(ctx context.Context, image, containerName string, addPkg string, hook string) (ContainerInfo, error) {
+func (d *DistroAPIService) CreateContainer(ctx context.Context, image, containerName string, addPkg string, hook string,
+	volumes []ContainerVolume) (ContainerInfo, error) {
 	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("distro.CreateContainer"))
 	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("distro.CreateContainer"))
 
@@ -285,17 +432,21 @@ func (d *DistroAPIService) CreateContainer(ctx context.Context, image, container
 		cmdParts = append(cmdParts, "--init-hooks", fmt.Sprintf("'%s'", hook))
 	}
 
+	// Дополнительные тома подключаются параметрами --volume
+	cmdParts = append(cmdParts, volumeArgs(volumes)...)
+
 	command := strings.Join(cmdParts, " ")
 
 	lib.Log.Debug(command)
-	cmd := exec.Command("sh", "-c", command)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	// Выполнение команды создания контейнера
-	if err := cmd.Run(); err != nil {
-		lib.Log.Errorf(lib.T_("Failed to create container %s: %v, stderr: %s"), containerName, err, stderr.String())
+	if err := lib.CommandRun(cmd); err != nil {
+		reply.Logger(ctx).WithField("container", containerName).
+			Errorf(lib.T_("Failed to create container %s: %v, stderr: %s"), containerName, err, stderr.String())
 		return ContainerInfo{}, fmt.Errorf(lib.T_("Failed to create container %s: %v"), containerName, err)
 	}
 
@@ -318,7 +469,7 @@ func (d *DistroAPIService) RemoveContainer(ctx context.Context, containerName st
 		return osInfo, err
 	}
 
-	if err = cmd.Run(); err != nil {
+	if err = lib.CommandRun(cmd); err != nil {
 		return ContainerInfo{}, fmt.Errorf(lib.T_("Failed to delete container %s: %v, stderr: %s"), containerName, err, stderr.String())
 	}
 
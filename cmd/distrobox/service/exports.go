@@ -0,0 +1,216 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const exportOverridesTableName = "export_overrides"
+
+// desktopEntryGroup основная группа .desktop файла.
+const desktopEntryGroup = "[Desktop Entry]"
+
+// ExportOverride пользовательские название и значок экспортированного приложения.
+// Хранятся отдельно от таблицы пакетов, поэтому переживают её пересинхронизацию.
+type ExportOverride struct {
+	Container string `json:"container"`
+	Package   string `json:"package"`
+	Name      string `json:"name"`
+	Icon      string `json:"icon"`
+}
+
+// createExportOverridesTable создаёт таблицу переопределений экспорта, если её ещё нет.
+func (s *DistroDBService) createExportOverridesTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		container TEXT,
+		package TEXT,
+		name TEXT,
+		icon TEXT,
+		PRIMARY KEY (container, package)
+	)`, exportOverridesTableName)
+
+	if _, err := s.dbConn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return nil
+}
+
+// SaveExportOverride сохраняет переопределение экспорта приложения.
+func (s *DistroDBService) SaveExportOverride(ctx context.Context, override ExportOverride) error {
+	if err := s.createExportOverridesTable(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (container, package, name, icon) VALUES (?, ?, ?, ?)
+		ON CONFLICT(container, package) DO UPDATE SET name = excluded.name, icon = excluded.icon`, exportOverridesTableName)
+	if _, err := s.dbConn.ExecContext(ctx, query, override.Container, override.Package, override.Name, override.Icon); err != nil {
+		return fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	return nil
+}
+
+// GetExportOverride возвращает переопределение экспорта приложения или nil, если его нет.
+func (s *DistroDBService) GetExportOverride(ctx context.Context, containerName string, packageName string) (*ExportOverride, error) {
+	if err := s.createExportOverridesTable(ctx); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT name, icon FROM %s WHERE container = ? AND package = ?", exportOverridesTableName)
+
+	override := ExportOverride{Container: containerName, Package: packageName}
+	err := s.dbConn.QueryRowContext(ctx, query, containerName, packageName).Scan(&override.Name, &override.Icon)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+
+	return &override, nil
+}
+
+// ExportedDesktopFiles возвращает пути .desktop файлов, экспортированных distrobox-export на хост.
+// distrobox-export сохраняет файл /usr/share/applications/<file> как ~/.local/share/applications/<container>-<file>.
+func ExportedDesktopFiles(containerInfo ContainerInfo, desktopPaths []string) ([]string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to retrieve home directory: %v"), err)
+	}
+
+	localShareApps := filepath.Join(homeDir, ".local", "share", "applications")
+
+	var files []string
+	for _, path := range desktopPaths {
+		if !strings.HasSuffix(path, ".desktop") {
+			continue
+		}
+
+		exported := filepath.Join(localShareApps, containerInfo.ContainerName+"-"+filepath.Base(path))
+		if _, err = os.Stat(exported); err == nil {
+			files = append(files, exported)
+		}
+	}
+
+	return files, nil
+}
+
+// ApplyExportOverride записывает название и значок из override в экспортированные .desktop файлы.
+func ApplyExportOverride(files []string, override ExportOverride) error {
+	values := map[string]string{}
+	if override.Name != "" {
+		values["Name"] = override.Name
+	}
+	if override.Icon != "" {
+		values["Icon"] = override.Icon
+	}
+
+	for _, file := range files {
+		if err := setDesktopEntryKeys(file, values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setDesktopEntryKeys изменяет ключи группы [Desktop Entry], сохраняя остальное содержимое файла.
+// Локализованные варианты изменяемых ключей (Name[ru] и т.п.) удаляются, иначе они перекроют новое значение.
+func setDesktopEntryKeys(path string, values map[string]string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to open file %s: %w"), path, err)
+	}
+
+	var lines []string
+	written := map[string]bool{}
+	inEntry := false
+	entryEnd := -1
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") {
+			if inEntry {
+				entryEnd = len(lines)
+			}
+			inEntry = trimmed == desktopEntryGroup
+			lines = append(lines, line)
+			continue
+		}
+
+		if inEntry {
+			if key, _, found := strings.Cut(trimmed, "="); found {
+				key = strings.TrimSpace(key)
+				baseKey, _, _ := strings.Cut(key, "[")
+				if value, ok := values[baseKey]; ok {
+					if key != baseKey || written[baseKey] {
+						continue
+					}
+					line = baseKey + "=" + value
+					written[baseKey] = true
+				}
+			}
+		}
+
+		lines = append(lines, line)
+	}
+	file.Close()
+
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf(lib.T_("Error reading file %s: %v"), path, err)
+	}
+
+	if entryEnd < 0 {
+		entryEnd = len(lines)
+	}
+	for entryEnd > 0 && strings.TrimSpace(lines[entryEnd-1]) == "" {
+		entryEnd--
+	}
+
+	// Ключи, которых не было в файле, добавляются в конец группы [Desktop Entry]
+	var missing []string
+	for _, key := range []string{"Name", "Icon"} {
+		if value, ok := values[key]; ok && !written[key] {
+			missing = append(missing, key+"="+value)
+		}
+	}
+	lines = append(lines[:entryEnd], append(missing, lines[entryEnd:]...)...)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to open file %s: %w"), path, err)
+	}
+
+	if err = os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), info.Mode()); err != nil {
+		return fmt.Errorf(lib.T_("Error writing file %s: %v"), path, err)
+	}
+
+	return nil
+}
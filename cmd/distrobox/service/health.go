@@ -0,0 +1,185 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/cmd/common/helper"
+	"apm/lib"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const containerHealthTableName = "container_health"
+
+// containerEnterTimeout время, за которое контейнер должен ответить на вход при проверке состояния.
+const containerEnterTimeout = 10 * time.Second
+
+// Статусы отдельной проверки состояния контейнера.
+const (
+	HealthStatusOK      = "ok"
+	HealthStatusFailed  = "failed"
+	HealthStatusSkipped = "skipped"
+)
+
+// HealthCheckResult результат отдельной проверки состояния контейнера.
+type HealthCheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// createContainerHealthTable создаёт таблицу результатов последней проверки состояния контейнеров, если её ещё нет.
+func (s *DistroDBService) createContainerHealthTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		container TEXT PRIMARY KEY,
+		healthy INTEGER NOT NULL DEFAULT 1,
+		checked_at TEXT NOT NULL
+	)`, containerHealthTableName)
+
+	if _, err := s.dbConn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return nil
+}
+
+// SaveContainerHealth сохраняет итог последней проверки состояния контейнера.
+func (s *DistroDBService) SaveContainerHealth(ctx context.Context, containerName string, healthy bool) error {
+	if err := s.createContainerHealthTable(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (container, healthy, checked_at) VALUES (?, ?, ?)
+		ON CONFLICT(container) DO UPDATE SET healthy = excluded.healthy, checked_at = excluded.checked_at`,
+		containerHealthTableName)
+	if _, err := s.dbConn.ExecContext(ctx, query, containerName, healthy, time.Now().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	return nil
+}
+
+// GetUnhealthyContainers возвращает имена контейнеров, не прошедших последнюю проверку состояния.
+func (s *DistroDBService) GetUnhealthyContainers(ctx context.Context) (map[string]bool, error) {
+	if err := s.createContainerHealthTable(ctx); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT container FROM %s WHERE healthy = 0", containerHealthTableName)
+	rows, err := s.dbConn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+	defer rows.Close()
+
+	unhealthy := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		unhealthy[name] = true
+	}
+
+	return unhealthy, rows.Err()
+}
+
+// DeleteContainerHealth удаляет результат проверки состояния удалённого контейнера.
+func (s *DistroDBService) DeleteContainerHealth(ctx context.Context, containerName string) error {
+	if err := s.createContainerHealthTable(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE container = ?", containerHealthTableName)
+	if _, err := s.dbConn.ExecContext(ctx, query, containerName); err != nil {
+		return fmt.Errorf(lib.T_("Error deleting container records %s: %v"), containerName, err)
+	}
+
+	return nil
+}
+
+// CheckContainerEnter проверяет, что в контейнер можно войти и выполнить команду за отведённое время.
+func (d *DistroAPIService) CheckContainerEnter(ctx context.Context, containerName string) error {
+	ctx, cancel := context.WithTimeout(ctx, containerEnterTimeout)
+	defer cancel()
+
+	command := fmt.Sprintf("%s distrobox enter %s -- true", lib.Env.CommandPrefix, containerName)
+	_, stderr, err := helper.RunCommand(ctx, command)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf(lib.T_("The container did not respond within %s"), containerEnterTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to enter the container: %v, stderr: %s"), err, strings.TrimSpace(stderr))
+	}
+
+	return nil
+}
+
+// packageManagerProbe возвращает бинарный файл пакетного менеджера провайдера и команду,
+// печатающую количество установленных пакетов.
+func packageManagerProbe(provider PackageProvider) (string, string) {
+	switch provider.(type) {
+	case *UbuntuProvider:
+		return "apt-get", "dpkg-query -f '.\\n' -W | wc -l"
+	case *ArchProvider:
+		return "pacman", "pacman -Q | wc -l"
+	default:
+		return "apt-get", "rpm -qa | wc -l"
+	}
+}
+
+// CheckPackageManager проверяет, что бинарный файл пакетного менеджера есть внутри контейнера, и возвращает его имя.
+func (p *PackageService) CheckPackageManager(ctx context.Context, containerInfo ContainerInfo) (string, error) {
+	provider, err := getProvider(p, containerInfo.OS)
+	if err != nil {
+		return "", err
+	}
+
+	binary, _ := packageManagerProbe(provider)
+	command := fmt.Sprintf("%s distrobox enter %s -- sh -c 'command -v %s'", lib.Env.CommandPrefix, containerInfo.ContainerName, binary)
+	if _, _, err = helper.RunCommand(ctx, command); err != nil {
+		return binary, fmt.Errorf(lib.T_("Package manager %s not found in the container"), binary)
+	}
+
+	return binary, nil
+}
+
+// CountInstalledPackages возвращает количество установленных пакетов по данным пакетного менеджера контейнера.
+func (p *PackageService) CountInstalledPackages(ctx context.Context, containerInfo ContainerInfo) (int, error) {
+	provider, err := getProvider(p, containerInfo.OS)
+	if err != nil {
+		return 0, err
+	}
+
+	_, countCommand := packageManagerProbe(provider)
+	command := fmt.Sprintf("%s distrobox enter %s -- sh -c \"%s\"", lib.Env.CommandPrefix, containerInfo.ContainerName, countCommand)
+	stdout, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return 0, fmt.Errorf(lib.T_("Failed to count installed packages: %v, stderr: %s"), err, strings.TrimSpace(stderr))
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(stdout))
+	if err != nil {
+		return 0, fmt.Errorf(lib.T_("Failed to count installed packages: %v, stderr: %s"), err, strings.TrimSpace(stdout))
+	}
+
+	return count, nil
+}
@@ -0,0 +1,284 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const hooksTableName = "container_hooks"
+
+// Виды записей таблицы хуков. Записи без вида созданы предыдущими версиями и считаются хуками инициализации.
+const (
+	// HookKindInit хук, передаваемый distrobox через --init-hooks при каждом создании контейнера
+	HookKindInit = "init"
+	// HookKindPostCreate результат однократного выполнения хука из файла после создания контейнера
+	HookKindPostCreate = "post-create"
+)
+
+// hooksMigrationColumns колонки, отсутствующие в таблице хуков предыдущих версий.
+var hooksMigrationColumns = []string{"kind", "success", "output", "ran_at"}
+
+// initHookCondition отбирает только хуки инициализации.
+const initHookCondition = "(kind IS NULL OR kind = '" + HookKindInit + "')"
+
+// InitHook описывает команду, выполняемую при инициализации контейнера.
+type InitHook struct {
+	ID        int64  `json:"id"`
+	Container string `json:"container"`
+	Command   string `json:"command"`
+}
+
+// HookResult результат выполнения хука из файла хуков внутри контейнера.
+type HookResult struct {
+	Command string `json:"command"`
+	Success bool   `json:"success"`
+	Output  string `json:"output"`
+	Error   string `json:"error,omitempty"`
+}
+
+// hookFile содержимое файла хуков: список команд, выполняемых в контейнере по порядку.
+type hookFile struct {
+	Hooks []string `yaml:"hooks"`
+}
+
+// createHooksTable создаёт таблицу хуков, если её ещё нет, и добавляет колонки, появившиеся в новых версиях.
+func (s *DistroDBService) createHooksTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container TEXT,
+		command TEXT,
+		kind TEXT,
+		success TEXT,
+		output TEXT,
+		ran_at TEXT
+	)`, hooksTableName)
+
+	if _, err := s.dbConn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return s.migrateHooksTable(ctx)
+}
+
+// migrateHooksTable добавляет недостающие колонки в таблицу хуков, созданную предыдущими версиями.
+func (s *DistroDBService) migrateHooksTable(ctx context.Context) error {
+	rows, err := s.dbConn.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", hooksTableName))
+	if err != nil {
+		return fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+
+	existingColumns := make(map[string]bool)
+	for rows.Next() {
+		var cid int
+		var name, columnType string
+		var notNull, pk int
+		var defaultValue sql.NullString
+		if err = rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		existingColumns[name] = true
+	}
+	rows.Close()
+
+	for _, column := range hooksMigrationColumns {
+		if existingColumns[column] {
+			continue
+		}
+
+		alterQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", hooksTableName, column)
+		if _, err = s.dbConn.ExecContext(ctx, alterQuery); err != nil {
+			return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+		}
+	}
+
+	return nil
+}
+
+// AddInitHook сохраняет хук инициализации для контейнера.
+func (s *DistroDBService) AddInitHook(ctx context.Context, containerName string, command string) (InitHook, error) {
+	if err := s.createHooksTable(ctx); err != nil {
+		return InitHook{}, err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (container, command, kind) VALUES (?, ?, ?)", hooksTableName)
+	result, err := s.dbConn.ExecContext(ctx, query, containerName, command, HookKindInit)
+	if err != nil {
+		return InitHook{}, fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return InitHook{}, fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	return InitHook{ID: id, Container: containerName, Command: command}, nil
+}
+
+// RemoveInitHook удаляет хук контейнера по идентификатору.
+func (s *DistroDBService) RemoveInitHook(ctx context.Context, containerName string, id int64) error {
+	if err := s.createHooksTable(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE container = ? AND id = ? AND %s", hooksTableName, initHookCondition)
+	result, err := s.dbConn.ExecContext(ctx, query, containerName, id)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Error deleting container records %s: %v"), containerName, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		return fmt.Errorf(lib.T_("Hook %d not found for container %s"), id, containerName)
+	}
+
+	return nil
+}
+
+// GetInitHooks возвращает хуки контейнера в порядке добавления.
+func (s *DistroDBService) GetInitHooks(ctx context.Context, containerName string) ([]InitHook, error) {
+	if err := s.createHooksTable(ctx); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT id, container, command FROM %s WHERE container = ? AND %s ORDER BY id",
+		hooksTableName, initHookCondition)
+	rows, err := s.dbConn.QueryContext(ctx, query, containerName)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+	defer rows.Close()
+
+	hooks := []InitHook{}
+	for rows.Next() {
+		var hook InitHook
+		if err = rows.Scan(&hook.ID, &hook.Container, &hook.Command); err != nil {
+			return nil, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+// SaveHookResult сохраняет результат выполнения хука из файла хуков.
+func (s *DistroDBService) SaveHookResult(ctx context.Context, containerName string, result HookResult) error {
+	if err := s.createHooksTable(ctx); err != nil {
+		return err
+	}
+
+	output := result.Output
+	if result.Error != "" {
+		output = strings.TrimSpace(output + "\n" + result.Error)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (container, command, kind, success, output, ran_at) VALUES (?, ?, ?, ?, ?, ?)",
+		hooksTableName)
+	_, err := s.dbConn.ExecContext(ctx, query, containerName, result.Command, HookKindPostCreate,
+		fmt.Sprint(result.Success), output, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	return nil
+}
+
+// ReadHookFile читает файл хуков в формате YAML со списком команд hooks.
+func ReadHookFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Error reading file %s: %v"), path, err)
+	}
+
+	var file hookFile
+	if err = yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf(lib.T_("Error parsing hook file %s: %v"), path, err)
+	}
+
+	hooks := make([]string, 0, len(file.Hooks))
+	for _, hook := range file.Hooks {
+		if hook = strings.TrimSpace(hook); hook != "" {
+			hooks = append(hooks, hook)
+		}
+	}
+	if len(hooks) == 0 {
+		return nil, fmt.Errorf(lib.T_("Hook file %s contains no hooks"), path)
+	}
+
+	return hooks, nil
+}
+
+// JoinInitHooks объединяет команды хуков в одну строку для параметра --init-hooks.
+func JoinInitHooks(hooks []InitHook) string {
+	commands := make([]string, 0, len(hooks))
+	for _, hook := range hooks {
+		commands = append(commands, hook.Command)
+	}
+
+	return strings.Join(commands, " && ")
+}
+
+// RunHook выполняет команду хука внутри существующего контейнера.
+func (d *DistroAPIService) RunHook(ctx context.Context, containerName string, command string) error {
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("distro.RunHook"))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("distro.RunHook"))
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", fmt.Sprintf("%s distrobox enter %s -- sh -c %s",
+		lib.Env.CommandPrefix, containerName, shellQuote(command)))
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := lib.CommandRun(cmd); err != nil {
+		return fmt.Errorf(lib.T_("Failed to run hook in container %s: %v, stderr: %s"), containerName, err, stderr.String())
+	}
+
+	return nil
+}
+
+// ContainerExec выполняет команду внутри контейнера и возвращает её объединённый вывод stdout и stderr.
+func (d *DistroAPIService) ContainerExec(ctx context.Context, containerName string, command string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", fmt.Sprintf("%s distrobox enter %s -- sh -c %s",
+		lib.Env.CommandPrefix, containerName, shellQuote(command)))
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := lib.CommandRun(cmd); err != nil {
+		return strings.TrimSpace(output.String()), fmt.Errorf(lib.T_("Failed to run hook in container %s: %v"), containerName, err)
+	}
+
+	return strings.TrimSpace(output.String()), nil
+}
+
+// shellQuote заключает строку в одинарные кавычки для передачи в sh.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
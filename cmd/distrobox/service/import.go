@@ -0,0 +1,105 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PackageSpec пакет для установки и, если задана, его версия.
+type PackageSpec struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// ParsePackageList читает список пакетов: по одному имени или паре "имя=версия" в строке.
+// Пустые строки и строки, начинающиеся с #, пропускаются, повторы имён убираются.
+func ParsePackageList(filePath string) ([]PackageSpec, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to read the package list %s: %v"), filePath, err)
+	}
+	defer file.Close()
+
+	var specs []PackageSpec
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, version, _ := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		version = strings.TrimSpace(version)
+		if name == "" || strings.ContainsAny(name, " \t") {
+			return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Invalid package name on line %d of %s: %s"),
+				lineNumber, filePath, line)
+		}
+
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		specs = append(specs, PackageSpec{Name: name, Version: version})
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to read the package list %s: %v"), filePath, err)
+	}
+
+	if len(specs) == 0 {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("The package list %s is empty"), filePath)
+	}
+
+	return specs, nil
+}
+
+// aptPackageTargets возвращает аргументы apt-get install, закрепляя версию в виде "имя=версия".
+func aptPackageTargets(packages []PackageSpec) string {
+	targets := make([]string, 0, len(packages))
+	for _, pkg := range packages {
+		if pkg.Version != "" {
+			targets = append(targets, pkg.Name+"="+pkg.Version)
+			continue
+		}
+		targets = append(targets, pkg.Name)
+	}
+
+	return strings.Join(targets, " ")
+}
+
+// InstallPackages устанавливает несколько пакетов одним запуском пакетного менеджера.
+func (p *PackageService) InstallPackages(ctx context.Context, containerInfo ContainerInfo, packages []PackageSpec) error {
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("distro.InstallPackages"))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("distro.InstallPackages"))
+	provider, err := getProvider(p, containerInfo.OS)
+	if err != nil {
+		return err
+	}
+
+	return provider.InstallPackages(ctx, containerInfo, packages)
+}
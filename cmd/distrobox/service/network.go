@@ -0,0 +1,161 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/cmd/common/helper"
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const containerConfigTableName = "container_config"
+
+// Сетевые режимы контейнера, не являющиеся именованными сетями podman.
+const (
+	NetworkHost = "host"
+	NetworkNone = "none"
+)
+
+// createContainerConfigTable создаёт таблицу настроек контейнеров, если её ещё нет.
+func (s *DistroDBService) createContainerConfigTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		container TEXT PRIMARY KEY,
+		network TEXT
+	)`, containerConfigTableName)
+
+	if _, err := s.dbConn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return nil
+}
+
+// SaveContainerNetwork сохраняет сетевой режим контейнера.
+func (s *DistroDBService) SaveContainerNetwork(ctx context.Context, containerName string, network string) error {
+	if err := s.createContainerConfigTable(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (container, network) VALUES (?, ?)
+		ON CONFLICT(container) DO UPDATE SET network = excluded.network`, containerConfigTableName)
+	if _, err := s.dbConn.ExecContext(ctx, query, containerName, network); err != nil {
+		return fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	return nil
+}
+
+// GetContainerNetwork возвращает сохранённый сетевой режим контейнера или пустую строку.
+func (s *DistroDBService) GetContainerNetwork(ctx context.Context, containerName string) (string, error) {
+	if err := s.createContainerConfigTable(ctx); err != nil {
+		return "", err
+	}
+
+	query := fmt.Sprintf("SELECT network FROM %s WHERE container = ?", containerConfigTableName)
+
+	var network string
+	err := s.dbConn.QueryRowContext(ctx, query, containerName).Scan(&network)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+
+	return network, nil
+}
+
+// ValidateNetworkMode проверяет, что режим равен host, none или существующей сети podman.
+func (d *DistroAPIService) ValidateNetworkMode(ctx context.Context, networkMode string) error {
+	if networkMode == NetworkHost || networkMode == NetworkNone {
+		return nil
+	}
+
+	command := fmt.Sprintf("%s podman network exists %s", lib.Env.CommandPrefix, networkMode)
+	if _, _, err := helper.RunCommand(ctx, command); err != nil {
+		return fmt.Errorf(lib.T_("Network %s not found. Allowed values: host, none or the name of an existing podman network"), networkMode)
+	}
+
+	return nil
+}
+
+// SetContainerNetwork переключает сетевой режим контейнера.
+// Между именованными сетями контейнер переподключается без пересоздания. Для режимов host и none
+// podman не позволяет сменить сеть на лету, поэтому состояние контейнера сохраняется в образ
+// через podman commit, и контейнер пересоздаётся из него с новой сетью, сохранёнными хуками и томами.
+func (d *DistroAPIService) SetContainerNetwork(ctx context.Context, state ContainerState, networkMode string, hooks string,
+	volumes []ContainerVolume) error {
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("distro.SetContainerNetwork"))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("distro.SetContainerNetwork"))
+	defer InvalidateContainerStates()
+
+	prefix := lib.Env.CommandPrefix
+	current := state.Network
+	if isNamedNetwork(current) && isNamedNetwork(networkMode) {
+		commands := []string{
+			fmt.Sprintf("%s podman network disconnect %s %s", prefix, current, state.Name),
+			fmt.Sprintf("%s podman network connect %s %s", prefix, networkMode, state.Name),
+		}
+
+		return runNetworkCommands(ctx, commands)
+	}
+
+	snapshot := fmt.Sprintf("localhost/apm-%s:network", state.Name)
+	createCommand := fmt.Sprintf("%s distrobox create -i %s -n %s --yes --additional-flags '--network %s'",
+		prefix, snapshot, state.Name, networkMode)
+	if len(volumes) > 0 {
+		createCommand += " " + strings.Join(volumeArgs(volumes), " ")
+	}
+	if hooks != "" {
+		createCommand += fmt.Sprintf(" --init-hooks %s", shellQuote(hooks))
+	}
+
+	commands := []string{
+		fmt.Sprintf("%s podman stop %s", prefix, state.Name),
+		fmt.Sprintf("%s podman commit %s %s", prefix, state.Name, snapshot),
+		fmt.Sprintf("%s distrobox rm --force %s", prefix, state.Name),
+		createCommand,
+	}
+
+	return runNetworkCommands(ctx, commands)
+}
+
+// isNamedNetwork проверяет, является ли режим именованной сетью podman.
+func isNamedNetwork(mode string) bool {
+	switch strings.TrimSpace(mode) {
+	case "", NetworkHost, NetworkNone, "bridge", "default", "private", "slirp4netns", "pasta":
+		return false
+	}
+
+	return !strings.Contains(mode, ":")
+}
+
+// runNetworkCommands последовательно выполняет команды, останавливаясь на первой ошибке.
+func runNetworkCommands(ctx context.Context, commands []string) error {
+	for _, command := range commands {
+		if _, stderr, err := helper.RunCommand(ctx, command); err != nil {
+			return fmt.Errorf(lib.T_("Failed to change the container network: %v, stderr: %s"), err, stderr)
+		}
+	}
+
+	return nil
+}
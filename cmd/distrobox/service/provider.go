@@ -75,6 +75,8 @@ type PackageProvider interface {
 	GetPackages(ctx context.Context, containerInfo ContainerInfo) ([]PackageInfo, error)
 	RemovePackage(ctx context.Context, containerInfo ContainerInfo, packageName string) error
 	InstallPackage(ctx context.Context, containerInfo ContainerInfo, packageName string) error
+	ReinstallPackage(ctx context.Context, containerInfo ContainerInfo, packageName string) error
+	InstallPackages(ctx context.Context, containerInfo ContainerInfo, packages []PackageSpec) error
 	GetPackageOwner(ctx context.Context, containerInfo ContainerInfo, fileName string) (string, error)
 	GetPathByPackageName(ctx context.Context, containerInfo ContainerInfo, packageName, filePath string) ([]string, error)
 }
@@ -106,6 +108,18 @@ func (p *PackageService) InstallPackage(ctx context.Context, containerInfo Conta
 	return provider.InstallPackage(ctx, containerInfo, packageName)
 }
 
+// ReinstallPackage переустановка уже установленного пакета
+func (p *PackageService) ReinstallPackage(ctx context.Context, containerInfo ContainerInfo, packageName string) error {
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("distro.ReinstallPackage"))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("distro.ReinstallPackage"))
+	provider, err := getProvider(p, containerInfo.OS)
+	if err != nil {
+		return err
+	}
+
+	return provider.ReinstallPackage(ctx, containerInfo, packageName)
+}
+
 // RemovePackage удаление пакета
 func (p *PackageService) RemovePackage(ctx context.Context, containerInfo ContainerInfo, packageName string) error {
 	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("distro.RemovePackage"))
@@ -241,9 +255,9 @@ func (p *PackageService) GetPackagesQuery(ctx context.Context, containerInfo Con
 	}, nil
 }
 
-// GetPackageByName поиска пакета по неточному совпадению имени
-func (p *PackageService) GetPackageByName(ctx context.Context, containerInfo ContainerInfo, packageName string) (PackageQueryResult, error) {
-	packages, err := p.serviceDistroDatabase.FindPackagesByName(containerInfo.ContainerName, packageName)
+// GetPackageByName поиска пакета по неточному совпадению имени. installed - фильтр по установке, nil - любые пакеты
+func (p *PackageService) GetPackageByName(ctx context.Context, containerInfo ContainerInfo, packageName string, installed *bool) (PackageQueryResult, error) {
+	packages, err := p.serviceDistroDatabase.FindPackagesByName(containerInfo.ContainerName, packageName, installed)
 	if err != nil {
 		return PackageQueryResult{}, err
 	}
@@ -0,0 +1,158 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/cmd/common/helper"
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const containerResourcesTableName = "container_resources"
+
+// ContainerResources ограничения ресурсов контейнера. Нулевое значение означает отсутствие ограничения.
+type ContainerResources struct {
+	MemoryMB   int     `json:"memoryMB"`
+	CPUPercent float64 `json:"cpuPercent"`
+}
+
+// createContainerResourcesTable создаёт таблицу ограничений ресурсов контейнеров, если её ещё нет.
+func (s *DistroDBService) createContainerResourcesTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		container TEXT PRIMARY KEY,
+		memory_mb INTEGER NOT NULL DEFAULT 0,
+		cpu_percent REAL NOT NULL DEFAULT 0
+	)`, containerResourcesTableName)
+
+	if _, err := s.dbConn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return nil
+}
+
+// SaveContainerResources сохраняет ограничения ресурсов контейнера.
+func (s *DistroDBService) SaveContainerResources(ctx context.Context, containerName string, resources ContainerResources) error {
+	if err := s.createContainerResourcesTable(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (container, memory_mb, cpu_percent) VALUES (?, ?, ?)
+		ON CONFLICT(container) DO UPDATE SET memory_mb = excluded.memory_mb, cpu_percent = excluded.cpu_percent`,
+		containerResourcesTableName)
+	if _, err := s.dbConn.ExecContext(ctx, query, containerName, resources.MemoryMB, resources.CPUPercent); err != nil {
+		return fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	return nil
+}
+
+// GetContainerResources возвращает сохранённые ограничения ресурсов контейнера или nil, если они не заданы.
+func (s *DistroDBService) GetContainerResources(ctx context.Context, containerName string) (*ContainerResources, error) {
+	if err := s.createContainerResourcesTable(ctx); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT memory_mb, cpu_percent FROM %s WHERE container = ?", containerResourcesTableName)
+
+	var resources ContainerResources
+	err := s.dbConn.QueryRowContext(ctx, query, containerName).Scan(&resources.MemoryMB, &resources.CPUPercent)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+
+	return &resources, nil
+}
+
+// GetContainerID возвращает идентификатор podman контейнера по имени.
+func (d *DistroAPIService) GetContainerID(ctx context.Context, containerName string) (string, error) {
+	command := fmt.Sprintf("%s podman ps --all --filter name=^%s$ --format {{.ID}}", lib.Env.CommandPrefix, containerName)
+	stdout, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return "", fmt.Errorf(lib.T_("Failed to get the container ID: %v, stderr: %s"), err, stderr)
+	}
+
+	containerID := strings.TrimSpace(strings.Split(strings.TrimSpace(stdout), "\n")[0])
+	if containerID == "" {
+		return "", reply.Errorf(reply.ErrorCodeContainerMissing, lib.T_("Container %s not found"), containerName)
+	}
+
+	return containerID, nil
+}
+
+// UpdateContainerResources применяет ограничения ресурсов к контейнеру через podman update.
+// Процент CPU переводится в число ядер: 50 — половина ядра, 200 — два ядра.
+func (d *DistroAPIService) UpdateContainerResources(ctx context.Context, containerID string, resources ContainerResources) error {
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("distro.UpdateContainerResources"))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("distro.UpdateContainerResources"))
+
+	var args []string
+	if resources.MemoryMB > 0 {
+		args = append(args, fmt.Sprintf("--memory %dm", resources.MemoryMB))
+	}
+	if resources.CPUPercent > 0 {
+		args = append(args, "--cpus "+strconv.FormatFloat(resources.CPUPercent/100, 'f', -1, 64))
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	command := fmt.Sprintf("%s podman update %s %s", lib.Env.CommandPrefix, strings.Join(args, " "), containerID)
+	if _, stderr, err := helper.RunCommand(ctx, command); err != nil {
+		return fmt.Errorf(lib.T_("Failed to update the container resource limits: %v, stderr: %s"), err, stderr)
+	}
+
+	return nil
+}
+
+// InspectContainerResources возвращает ограничения ресурсов, действующие в podman для контейнера.
+func (d *DistroAPIService) InspectContainerResources(ctx context.Context, containerID string) (ContainerResources, error) {
+	command := fmt.Sprintf("%s podman inspect --format '{{.HostConfig.Memory}} {{.HostConfig.NanoCpus}}' %s",
+		lib.Env.CommandPrefix, containerID)
+	stdout, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return ContainerResources{}, fmt.Errorf(lib.T_("Failed to inspect the container: %v, stderr: %s"), err, stderr)
+	}
+
+	fields := strings.Fields(stdout)
+	if len(fields) != 2 {
+		return ContainerResources{}, fmt.Errorf(lib.T_("Unexpected podman inspect output: %s"), strings.TrimSpace(stdout))
+	}
+
+	memoryBytes, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return ContainerResources{}, fmt.Errorf(lib.T_("Unexpected podman inspect output: %s"), strings.TrimSpace(stdout))
+	}
+	nanoCPUs, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return ContainerResources{}, fmt.Errorf(lib.T_("Unexpected podman inspect output: %s"), strings.TrimSpace(stdout))
+	}
+
+	return ContainerResources{
+		MemoryMB:   int(memoryBytes / (1024 * 1024)),
+		CPUPercent: float64(nanoCPUs) / 1e7,
+	}, nil
+}
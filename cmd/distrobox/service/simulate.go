@@ -0,0 +1,214 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/cmd/common/helper"
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SimulationResult планируемые изменения пакетов контейнера, полученные симуляцией пакетного менеджера.
+type SimulationResult struct {
+	Install      []string `json:"install"`
+	Upgrade      []string `json:"upgrade"`
+	Remove       []string `json:"remove"`
+	DownloadSize string   `json:"downloadSize,omitempty"`
+}
+
+// PackageSimulator провайдер, пакетный менеджер которого умеет показать изменения без их выполнения.
+type PackageSimulator interface {
+	SimulateInstall(ctx context.Context, containerInfo ContainerInfo, packageName string) (SimulationResult, error)
+	SimulateRemove(ctx context.Context, containerInfo ContainerInfo, packageName string) (SimulationResult, error)
+}
+
+// getSimulator возвращает провайдер контейнера, если он поддерживает симуляцию.
+// Провайдер без симуляции не должен выполнять изменения вместо неё, поэтому возвращается ошибка.
+func getSimulator(servicePackage *PackageService, containerInfo ContainerInfo) (PackageSimulator, error) {
+	provider, err := getProvider(servicePackage, containerInfo.OS)
+	if err != nil {
+		return nil, err
+	}
+
+	simulator, ok := provider.(PackageSimulator)
+	if !ok {
+		return nil, reply.Errorf(reply.ErrorCodeNotSupported,
+			lib.T_("The package manager of container %s cannot simulate changes"), containerInfo.ContainerName)
+	}
+
+	return simulator, nil
+}
+
+// SimulateInstall возвращает изменения, которые произойдут при установке пакета, не меняя контейнер.
+func (p *PackageService) SimulateInstall(ctx context.Context, containerInfo ContainerInfo, packageName string) (SimulationResult, error) {
+	simulator, err := getSimulator(p, containerInfo)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	return simulator.SimulateInstall(ctx, containerInfo, packageName)
+}
+
+// SimulateRemove возвращает изменения, которые произойдут при удалении пакета, не меняя контейнер.
+func (p *PackageService) SimulateRemove(ctx context.Context, containerInfo ContainerInfo, packageName string) (SimulationResult, error) {
+	simulator, err := getSimulator(p, containerInfo)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	return simulator.SimulateRemove(ctx, containerInfo, packageName)
+}
+
+// aptSimulateLineRegex строка симуляции apt-get -s, например "Inst foo [1.0] (1.1 Ubuntu:24.04/noble [amd64])" или "Remv foo [1.0]".
+var aptSimulateLineRegex = regexp.MustCompile(`^(Inst|Remv)\s+(\S+)(\s+\[[^\]]*\])?`)
+
+// aptDownloadSizeRegex строка apt с объёмом загрузки, например "Need to get 1,234 kB of archives.".
+var aptDownloadSizeRegex = regexp.MustCompile(`^Need to get (\S+ \S+?)(?:/\S+ \S+)? of archives`)
+
+// simulateApt выполняет apt-get -s в контейнере. Используется провайдерами Ubuntu и ALT.
+func simulateApt(ctx context.Context, containerInfo ContainerInfo, aptCommand string, packageName string) (SimulationResult, error) {
+	command := fmt.Sprintf("%s distrobox enter %s -- env LC_ALL=C apt-get -s %s %s",
+		lib.Env.CommandPrefix, containerInfo.ContainerName, aptCommand, packageName)
+	stdout, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return SimulationResult{}, fmt.Errorf(lib.T_("Failed to simulate the package change: %v, stderr: %s"), err, strings.TrimSpace(stderr))
+	}
+
+	return parseAptSimulation(stdout), nil
+}
+
+// parseAptSimulation разбирает вывод apt-get -s.
+func parseAptSimulation(output string) SimulationResult {
+	result := SimulationResult{Install: []string{}, Upgrade: []string{}, Remove: []string{}}
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if matches := aptDownloadSizeRegex.FindStringSubmatch(line); matches != nil {
+			result.DownloadSize = matches[1]
+			continue
+		}
+
+		matches := aptSimulateLineRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		switch {
+		case matches[1] == "Remv":
+			result.Remove = append(result.Remove, matches[2])
+		case matches[3] != "":
+			result.Upgrade = append(result.Upgrade, matches[2])
+		default:
+			result.Install = append(result.Install, matches[2])
+		}
+	}
+
+	return result
+}
+
+// SimulateInstall симулирует установку пакета через apt-get -s.
+func (p *UbuntuProvider) SimulateInstall(ctx context.Context, containerInfo ContainerInfo, packageName string) (SimulationResult, error) {
+	return simulateApt(ctx, containerInfo, "install", packageName)
+}
+
+// SimulateRemove симулирует удаление пакета через apt-get -s.
+func (p *UbuntuProvider) SimulateRemove(ctx context.Context, containerInfo ContainerInfo, packageName string) (SimulationResult, error) {
+	return simulateApt(ctx, containerInfo, "remove", packageName)
+}
+
+// SimulateInstall симулирует установку пакета через apt-get -s.
+func (p *AltProvider) SimulateInstall(ctx context.Context, containerInfo ContainerInfo, packageName string) (SimulationResult, error) {
+	return simulateApt(ctx, containerInfo, "install", packageName)
+}
+
+// SimulateRemove симулирует удаление пакета через apt-get -s.
+func (p *AltProvider) SimulateRemove(ctx context.Context, containerInfo ContainerInfo, packageName string) (SimulationResult, error) {
+	return simulateApt(ctx, containerInfo, "remove", packageName)
+}
+
+// SimulateInstall выводит пакеты, которые установит pacman, через --print. Пакеты, уже установленные
+// в контейнере, считаются обновляемыми, объём загрузки суммируется по размерам пакетов.
+func (p *ArchProvider) SimulateInstall(ctx context.Context, containerInfo ContainerInfo, packageName string) (SimulationResult, error) {
+	result := SimulationResult{Install: []string{}, Upgrade: []string{}, Remove: []string{}}
+
+	command := fmt.Sprintf("%s distrobox enter %s -- pacman -S --print --print-format '%%n %%s' %s",
+		lib.Env.CommandPrefix, containerInfo.ContainerName, packageName)
+	stdout, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return result, fmt.Errorf(lib.T_("Failed to simulate the package change: %v, stderr: %s"), err, strings.TrimSpace(stderr))
+	}
+
+	installedCommand := fmt.Sprintf("%s distrobox enter %s -- pacman -Qq", lib.Env.CommandPrefix, containerInfo.ContainerName)
+	installedOutput, stderr, err := helper.RunCommand(ctx, installedCommand)
+	if err != nil {
+		return result, fmt.Errorf(lib.T_("Failed to simulate the package change: %v, stderr: %s"), err, strings.TrimSpace(stderr))
+	}
+
+	installed := make(map[string]bool)
+	for _, name := range strings.Fields(installedOutput) {
+		installed[name] = true
+	}
+
+	var downloadSize int64
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		if installed[fields[0]] {
+			result.Upgrade = append(result.Upgrade, fields[0])
+		} else {
+			result.Install = append(result.Install, fields[0])
+		}
+
+		if size, errSize := strconv.ParseInt(fields[1], 10, 64); errSize == nil {
+			downloadSize += size
+		}
+	}
+
+	if downloadSize > 0 {
+		result.DownloadSize = helper.AutoSize(int(downloadSize))
+	}
+
+	return result, nil
+}
+
+// SimulateRemove выводит пакеты, которые удалит pacman вместе с ненужными зависимостями, через --print.
+func (p *ArchProvider) SimulateRemove(ctx context.Context, containerInfo ContainerInfo, packageName string) (SimulationResult, error) {
+	result := SimulationResult{Install: []string{}, Upgrade: []string{}, Remove: []string{}}
+
+	command := fmt.Sprintf("%s distrobox enter %s -- pacman -Rs --print --print-format '%%n' %s",
+		lib.Env.CommandPrefix, containerInfo.ContainerName, packageName)
+	stdout, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return result, fmt.Errorf(lib.T_("Failed to simulate the package change: %v, stderr: %s"), err, strings.TrimSpace(stderr))
+	}
+
+	for _, line := range strings.Split(stdout, "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			result.Remove = append(result.Remove, name)
+		}
+	}
+
+	return result, nil
+}
@@ -114,6 +114,29 @@ func (p *UbuntuProvider) InstallPackage(ctx context.Context, containerInfo Conta
 	return nil
 }
 
+// ReinstallPackage переустанавливает пакет внутри контейнера через apt-get install --reinstall.
+func (p *UbuntuProvider) ReinstallPackage(ctx context.Context, containerInfo ContainerInfo, packageName string) error {
+	command := fmt.Sprintf("%s distrobox enter %s -- sudo apt-get install --reinstall -y %s", lib.Env.CommandPrefix, containerInfo.ContainerName, packageName)
+	_, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to reinstall package %s: %v, stderr: %s"), packageName, err, stderr)
+	}
+
+	return nil
+}
+
+// InstallPackages устанавливает несколько пакетов одной командой apt-get install с учётом закреплённых версий.
+func (p *UbuntuProvider) InstallPackages(ctx context.Context, containerInfo ContainerInfo, packages []PackageSpec) error {
+	targets := aptPackageTargets(packages)
+	command := fmt.Sprintf("%s distrobox enter %s -- sudo apt-get install -y %s", lib.Env.CommandPrefix, containerInfo.ContainerName, targets)
+	_, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Failed to install package %s: %v, stderr: %s"), targets, err, stderr)
+	}
+
+	return nil
+}
+
 // RemovePackage удаляет указанный пакет внутри контейнера через apt-get remove.
 func (p *UbuntuProvider) RemovePackage(ctx context.Context, containerInfo ContainerInfo, packageName string) error {
 	command := fmt.Sprintf("%s distrobox enter %s -- sudo apt-get remove -y %s", lib.Env.CommandPrefix, containerInfo.ContainerName, packageName)
@@ -0,0 +1,199 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/cmd/common/helper"
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"fmt"
+	"strings"
+)
+
+const volumesTableName = "container_volumes"
+
+// ContainerVolume дополнительный каталог хоста, подключаемый в контейнер при создании.
+type ContainerVolume struct {
+	ID            int64  `json:"id"`
+	Container     string `json:"container"`
+	HostPath      string `json:"hostPath"`
+	ContainerPath string `json:"containerPath"`
+	ReadOnly      bool   `json:"readOnly"`
+}
+
+// Spec возвращает описание тома в формате параметра --volume: host:container[:ro].
+func (v ContainerVolume) Spec() string {
+	spec := v.HostPath + ":" + v.ContainerPath
+	if v.ReadOnly {
+		spec += ":ro"
+	}
+
+	return spec
+}
+
+// createVolumesTable создаёт таблицу томов контейнеров, если её ещё нет.
+func (s *DistroDBService) createVolumesTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container TEXT,
+		host_path TEXT,
+		container_path TEXT,
+		readonly INTEGER,
+		UNIQUE(container, container_path)
+	)`, volumesTableName)
+
+	if _, err := s.dbConn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return nil
+}
+
+// AddContainerVolume сохраняет том контейнера. В один путь контейнера можно подключить только один том.
+func (s *DistroDBService) AddContainerVolume(ctx context.Context, containerName string, hostPath string, containerPath string,
+	readonly bool) (ContainerVolume, error) {
+	if err := s.createVolumesTable(ctx); err != nil {
+		return ContainerVolume{}, err
+	}
+
+	var exists int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE container = ? AND container_path = ?", volumesTableName)
+	if err := s.dbConn.QueryRowContext(ctx, query, containerName, containerPath).Scan(&exists); err != nil {
+		return ContainerVolume{}, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+	if exists > 0 {
+		return ContainerVolume{}, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Path %s of container %s already has a volume"),
+			containerPath, containerName)
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (container, host_path, container_path, readonly) VALUES (?, ?, ?, ?)", volumesTableName)
+	result, err := s.dbConn.ExecContext(ctx, query, containerName, hostPath, containerPath, readonly)
+	if err != nil {
+		return ContainerVolume{}, fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return ContainerVolume{}, fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	return ContainerVolume{ID: id, Container: containerName, HostPath: hostPath, ContainerPath: containerPath, ReadOnly: readonly}, nil
+}
+
+// RemoveContainerVolume удаляет том контейнера по пути внутри контейнера и возвращает удалённый том.
+func (s *DistroDBService) RemoveContainerVolume(ctx context.Context, containerName string, containerPath string) (ContainerVolume, error) {
+	volumes, err := s.GetContainerVolumes(ctx, containerName)
+	if err != nil {
+		return ContainerVolume{}, err
+	}
+
+	for _, volume := range volumes {
+		if volume.ContainerPath != containerPath {
+			continue
+		}
+
+		query := fmt.Sprintf("DELETE FROM %s WHERE id = ?", volumesTableName)
+		if _, err = s.dbConn.ExecContext(ctx, query, volume.ID); err != nil {
+			return ContainerVolume{}, fmt.Errorf(lib.T_("Error deleting container records %s: %v"), containerName, err)
+		}
+
+		return volume, nil
+	}
+
+	return ContainerVolume{}, reply.Errorf(reply.ErrorCodeNotFound, lib.T_("Volume %s not found for container %s"), containerPath, containerName)
+}
+
+// RestoreContainerVolume возвращает удалённый том с прежним идентификатором, если пересоздать контейнер не удалось.
+func (s *DistroDBService) RestoreContainerVolume(ctx context.Context, volume ContainerVolume) error {
+	query := fmt.Sprintf("INSERT INTO %s (id, container, host_path, container_path, readonly) VALUES (?, ?, ?, ?, ?)", volumesTableName)
+	if _, err := s.dbConn.ExecContext(ctx, query, volume.ID, volume.Container, volume.HostPath, volume.ContainerPath, volume.ReadOnly); err != nil {
+		return fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	return nil
+}
+
+// GetContainerVolumes возвращает тома контейнера в порядке добавления.
+func (s *DistroDBService) GetContainerVolumes(ctx context.Context, containerName string) ([]ContainerVolume, error) {
+	if err := s.createVolumesTable(ctx); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT id, container, host_path, container_path, readonly FROM %s WHERE container = ? ORDER BY id", volumesTableName)
+	rows, err := s.dbConn.QueryContext(ctx, query, containerName)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+	defer rows.Close()
+
+	volumes := []ContainerVolume{}
+	for rows.Next() {
+		var volume ContainerVolume
+		if err = rows.Scan(&volume.ID, &volume.Container, &volume.HostPath, &volume.ContainerPath, &volume.ReadOnly); err != nil {
+			return nil, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		volumes = append(volumes, volume)
+	}
+
+	return volumes, rows.Err()
+}
+
+// volumeArgs возвращает параметры --volume команды distrobox create для томов контейнера.
+func volumeArgs(volumes []ContainerVolume) []string {
+	args := make([]string, 0, len(volumes)*2)
+	for _, volume := range volumes {
+		args = append(args, "--volume", shellQuote(volume.Spec()))
+	}
+
+	return args
+}
+
+// RecreateContainer пересоздаёт контейнер с новым набором томов. Состояние контейнера сохраняется в образ
+// через podman commit, и контейнер создаётся из него заново с прежней сетью и сохранёнными хуками.
+func (d *DistroAPIService) RecreateContainer(ctx context.Context, state ContainerState, hooks string, volumes []ContainerVolume) error {
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("distro.RecreateContainer"))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("distro.RecreateContainer"))
+	defer InvalidateContainerStates()
+
+	prefix := lib.Env.CommandPrefix
+	snapshot := fmt.Sprintf("localhost/apm-%s:recreate", state.Name)
+
+	createParts := []string{prefix, "distrobox", "create", "-i", snapshot, "-n", state.Name, "--yes"}
+	if state.Network != "" {
+		createParts = append(createParts, "--additional-flags", shellQuote("--network "+state.Network))
+	}
+	createParts = append(createParts, volumeArgs(volumes)...)
+	if hooks != "" {
+		createParts = append(createParts, "--init-hooks", shellQuote(hooks))
+	}
+
+	commands := []string{
+		fmt.Sprintf("%s podman stop %s", prefix, state.Name),
+		fmt.Sprintf("%s podman commit %s %s", prefix, state.Name, snapshot),
+		fmt.Sprintf("%s distrobox rm --force %s", prefix, state.Name),
+		strings.Join(createParts, " "),
+	}
+
+	for _, command := range commands {
+		if _, stderr, err := helper.RunCommand(ctx, command); err != nil {
+			return fmt.Errorf(lib.T_("Failed to recreate container %s: %v, stderr: %s"), state.Name, err, stderr)
+		}
+	}
+
+	return nil
+}
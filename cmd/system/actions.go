@@ -17,15 +17,22 @@
 package system
 
 import (
+	"apm/cmd/common/helper"
 	"apm/cmd/common/reply"
 	"apm/cmd/system/apt"
 	"apm/cmd/system/service"
 	"apm/lib"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
 	"strings"
 	"syscall"
+	"time"
 )
 
 // Actions объединяет методы для выполнения системных действий.
@@ -56,8 +63,8 @@ func NewActionsWithDeps(
 
 // NewActions создаёт новый экземпляр Actions.
 func NewActions() *Actions {
-	hostPackageDBSvc := apt.NewPackageDBService(lib.GetDB())
-	hostDBSvc := service.NewHostDBService(lib.GetDB())
+	hostPackageDBSvc := apt.NewPackageDBService(lib.GetSystemDB())
+	hostDBSvc := service.NewHostDBService(lib.GetSystemDB())
 	hostConfigSvc := service.NewHostConfigService(lib.Env.PathImageFile, hostDBSvc)
 	hostImageSvc := service.NewHostImageService(hostConfigSvc)
 	hostAptSvc := apt.NewActions(hostPackageDBSvc)
@@ -72,9 +79,10 @@ func NewActions() *Actions {
 }
 
 type ImageStatus struct {
-	Image  service.HostImage `json:"image"`
-	Status string            `json:"status"`
-	Config service.Config    `json:"config"`
+	Image   service.HostImage     `json:"image"`
+	Status  string                `json:"status"`
+	Config  service.Config        `json:"config"`
+	History *service.ImageHistory `json:"history,omitempty"`
 }
 
 // CheckRemove проверяем пакеты перед удалением
@@ -117,8 +125,30 @@ func (a *Actions) CheckInstall(ctx context.Context, packages []string) (*reply.A
 	return &resp, nil
 }
 
-// Remove удаляет системный пакет.
-func (a *Actions) Remove(ctx context.Context, packages []string, apply bool) (*reply.APIResponse, error) {
+// Remove удаляет системный пакет. Каждый вызов записывается в историю операций.
+// skipValidation отключает проверку пакетов конфигурации образа по репозиторию при apply.
+// reboot планирует перезагрузку после успешного применения изменений к образу.
+func (a *Actions) Remove(ctx context.Context, packages []string, apply bool, skipValidation bool, reboot RebootParams) (*reply.APIResponse, error) {
+	if err := a.validateReboot(reboot, apply); err != nil {
+		return nil, err
+	}
+
+	resp, err := a.remove(ctx, packages, apply, skipValidation)
+	if err == nil && confirmationRequired(resp) {
+		return resp, nil
+	}
+
+	a.saveOperation(ctx, "remove", packages, resp, err)
+	if err != nil {
+		return resp, err
+	}
+
+	return a.scheduleReboot(reboot, resp)
+}
+
+// remove выполняет удаление пакетов.
+func (a *Actions) remove(ctx context.Context, packages []string, apply bool, skipValidation bool) (*reply.APIResponse, error) {
+	ctx, timings := reply.WithTimings(ctx)
 	err := a.checkRoot()
 	if err != nil {
 		return nil, err
@@ -129,6 +159,11 @@ func (a *Actions) Remove(ctx context.Context, packages []string, apply bool) (*r
 		return nil, err
 	}
 
+	err = a.recoverBeforeOperation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(packages) == 0 {
 		errPackageNotFound := fmt.Errorf(lib.T_("At least one package must be specified, for example, remove package"))
 
@@ -148,12 +183,20 @@ func (a *Actions) Remove(ctx context.Context, packages []string, apply bool) (*r
 	}
 
 	allPackageNames := strings.Join(names, " ")
+	stopTiming := timings.Start(reply.TimingAptCheck)
 	packageParse, aptErrors := a.serviceAptActions.Check(ctx, allPackageNames, "remove")
+	stopTiming()
 	criticalError := apt.FindCriticalError(aptErrors)
 	if criticalError != nil {
 		return nil, criticalError
 	}
 
+	if apply && lib.IsAtomic() && !skipValidation {
+		if err = a.validateConfigChanges(ctx, packages, false); err != nil {
+			return nil, err
+		}
+	}
+
 	// Достанем все кастомные ошибки apt
 	var customErrorList []*apt.MatchedError
 	for _, err = range aptErrors {
@@ -168,12 +211,12 @@ func (a *Actions) Remove(ctx context.Context, packages []string, apply bool) (*r
 		var alreadyRemovedPackages []string
 
 		for _, customError := range customErrorList {
-			if customError.Entry.Code == apt.ErrPackageNotInstalled && apply && lib.Env.IsAtomic {
+			if customError.Entry.Code == apt.ErrPackageNotInstalled && apply && lib.IsAtomic() {
 				alreadyRemovedPackages = append(alreadyRemovedPackages, customError.Params[0])
 			}
 		}
 
-		if apply && lib.Env.IsAtomic {
+		if apply && lib.IsAtomic() {
 			diffPackageFound := false
 			err = a.serviceHostConfig.LoadConfig()
 			if err != nil {
@@ -203,20 +246,18 @@ func (a *Actions) Remove(ctx context.Context, packages []string, apply bool) (*r
 		return nil, fmt.Errorf(messageNothingDo)
 	}
 
-	reply.StopSpinner()
-	dialogStatus, err := apt.NewDialog(packagesInfo, packageParse, apt.ActionRemove)
-	if err != nil {
-		return nil, err
-	}
-
-	if !dialogStatus {
-		errDialog := fmt.Errorf(lib.T_("Cancel dialog"))
-
-		return nil, errDialog
+	stopTiming = timings.Start(reply.TimingDialog)
+	confirmResp, err := a.confirmChanges(packagesInfo, packageParse, apt.ActionRemove)
+	stopTiming()
+	if err != nil || confirmResp != nil {
+		return confirmResp, err
 	}
 
-	reply.CreateSpinner()
+	pending := beginOperation("remove", packages, apply)
+	defer abandonOperation(pending)
+	stopTiming = timings.Start(reply.TimingApt)
 	errList := a.serviceAptActions.Remove(ctx, allPackageNames)
+	stopTiming()
 	criticalError = apt.FindCriticalError(errList)
 	if criticalError != nil {
 		var matchedErr *apt.MatchedError
@@ -234,11 +275,16 @@ func (a *Actions) Remove(ctx context.Context, packages []string, apply bool) (*r
 		return nil, criticalError
 	}
 
+	completeStep(pending, OperationStepApt)
+
 	removePackageNames := strings.Join(packageParse.RemovedPackages, ",")
+	stopTiming = timings.Start(reply.TimingPackagesDB)
 	err = a.updateAllPackagesDB(ctx)
+	stopTiming()
 	if err != nil {
 		return nil, err
 	}
+	completeStep(pending, OperationStepPackagesDB)
 
 	messageAnswer := fmt.Sprintf(lib.TN_("%s removed successfully", "%s removed successfully", packageParse.RemovedCount), removePackageNames)
 	if apply {
@@ -248,24 +294,53 @@ func (a *Actions) Remove(ctx context.Context, packages []string, apply bool) (*r
 		}
 		messageAnswer += lib.T_(". The system image has been modified")
 	}
+	finishOperation()
 
-	if !apply && lib.Env.IsAtomic {
+	if !apply && lib.IsAtomic() {
 		messageAnswer += lib.T_(". The system image has not been modified! To apply changes, run with the -a flag")
 	}
 
+	data := &PackageChangesResponse{
+		Message: messageAnswer,
+		Info:    packageParse,
+		Timings: timings.Milliseconds(),
+	}
+	if apply {
+		data.BuildResult = a.buildResult("")
+	}
+
 	resp := reply.APIResponse{
-		Data: map[string]interface{}{
-			"message": messageAnswer,
-			"info":    packageParse,
-		},
+		Data:  data,
 		Error: false,
 	}
 
 	return &resp, nil
 }
 
-// Install осуществляет установку системного пакета.
-func (a *Actions) Install(ctx context.Context, packages []string, apply bool) (*reply.APIResponse, error) {
+// Install осуществляет установку системного пакета. Каждый вызов записывается в историю операций.
+// skipValidation отключает проверку пакетов конфигурации образа по репозиторию при apply.
+// reboot планирует перезагрузку после успешного применения изменений к образу.
+func (a *Actions) Install(ctx context.Context, packages []string, apply bool, skipValidation bool, reboot RebootParams) (*reply.APIResponse, error) {
+	if err := a.validateReboot(reboot, apply); err != nil {
+		return nil, err
+	}
+
+	resp, err := a.install(ctx, packages, apply, skipValidation)
+	if err == nil && confirmationRequired(resp) {
+		return resp, nil
+	}
+
+	a.saveOperation(ctx, "install", packages, resp, err)
+	if err != nil {
+		return resp, err
+	}
+
+	return a.scheduleReboot(reboot, resp)
+}
+
+// install выполняет установку пакетов.
+func (a *Actions) install(ctx context.Context, packages []string, apply bool, skipValidation bool) (*reply.APIResponse, error) {
+	ctx, timings := reply.WithTimings(ctx)
 	err := a.checkRoot()
 	if err != nil {
 		return nil, err
@@ -276,6 +351,11 @@ func (a *Actions) Install(ctx context.Context, packages []string, apply bool) (*
 		return nil, err
 	}
 
+	err = a.recoverBeforeOperation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(packages) == 0 {
 		errPackageNotFound := fmt.Errorf(lib.T_("You must specify at least one package, for example, remove package"))
 
@@ -315,7 +395,7 @@ func (a *Actions) Install(ctx context.Context, packages []string, apply bool) (*
 
 			if len(alternativePackages) == 0 {
 				errorFindPackage := fmt.Sprintf(lib.T_("Failed to retrieve information about the package %s"), originalPkg)
-				return nil, fmt.Errorf(errorFindPackage)
+				return nil, reply.Errorf(reply.ErrorCodePackageNotFound, errorFindPackage)
 			}
 
 			var altNames []string
@@ -325,7 +405,7 @@ func (a *Actions) Install(ctx context.Context, packages []string, apply bool) (*
 
 			message := err.Error() + lib.T_(". Maybe you were looking for: ")
 
-			errPackageNotFound := fmt.Errorf(message+"%s", strings.Join(altNames, " "))
+			errPackageNotFound := reply.Errorf(reply.ErrorCodePackageNotFound, message+"%s", strings.Join(altNames, " "))
 
 			return nil, errPackageNotFound
 		}
@@ -334,12 +414,20 @@ func (a *Actions) Install(ctx context.Context, packages []string, apply bool) (*
 	}
 
 	allPackageNames := strings.Join(packageNames, " ")
+	stopTiming := timings.Start(reply.TimingAptCheck)
 	packageParse, aptErrors := a.serviceAptActions.Check(ctx, allPackageNames, "install")
+	stopTiming()
 	criticalError := apt.FindCriticalError(aptErrors)
 	if criticalError != nil {
 		return nil, criticalError
 	}
 
+	if apply && lib.IsAtomic() && !skipValidation {
+		if err = a.validateConfigChanges(ctx, packageNames, true); err != nil {
+			return nil, err
+		}
+	}
+
 	// Достанем все кастомные ошибки apt
 	var customErrorList []*apt.MatchedError
 	for _, err = range aptErrors {
@@ -355,18 +443,18 @@ func (a *Actions) Install(ctx context.Context, packages []string, apply bool) (*
 		var alreadyRemovedPackages []string
 
 		for _, customError := range customErrorList {
-			if customError.Entry.Code == apt.ErrPackageIsAlreadyNewest && apply && lib.Env.IsAtomic {
+			if customError.Entry.Code == apt.ErrPackageIsAlreadyNewest && apply && lib.IsAtomic() {
 				alreadyInstalledPackages = append(alreadyInstalledPackages, customError.Params[0])
 			}
 
-			if customError.Entry.Code == apt.ErrPackageNotInstalled && apply && lib.Env.IsAtomic {
+			if customError.Entry.Code == apt.ErrPackageNotInstalled && apply && lib.IsAtomic() {
 				alreadyRemovedPackages = append(alreadyRemovedPackages, customError.Params[0])
 			}
 
 			messageNothingDo += customError.Error() + "\n"
 		}
 
-		if apply && lib.Env.IsAtomic {
+		if apply && lib.IsAtomic() {
 			diffPackageFound := false
 			err = a.serviceHostConfig.LoadConfig()
 			if err != nil {
@@ -408,27 +496,43 @@ func (a *Actions) Install(ctx context.Context, packages []string, apply bool) (*
 		return nil, fmt.Errorf(messageNothingDo)
 	}
 
-	reply.StopSpinner()
 	dialogAction := apt.ActionInstall
 	if isMultiInstall {
 		dialogAction = apt.ActionMultiInstall
 	}
 
-	dialogStatus, err := apt.NewDialog(packagesInfo, packageParse, dialogAction)
-	if err != nil {
-		return nil, err
-	}
-
-	if !dialogStatus {
-		errDialog := fmt.Errorf(lib.T_("Cancel dialog"))
-
-		return nil, errDialog
+	stopTiming = timings.Start(reply.TimingDialog)
+	confirmResp, err := a.confirmChanges(packagesInfo, packageParse, dialogAction)
+	stopTiming()
+	if err != nil || confirmResp != nil {
+		return confirmResp, err
 	}
 
-	reply.CreateSpinner()
+	progressCh := make(chan apt.InstallProgress)
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		for progress := range progressCh {
+			state := reply.StateBefore
+			if progress.PercentDone >= 100 {
+				state = reply.StateAfter
+			}
 
-	errList := a.serviceAptActions.Install(ctx, allPackageNames)
-	criticalError = apt.FindCriticalError(errList)
+			reply.CreateEventNotification(ctx, state,
+				reply.WithEventName("system.packageProgress-"+progress.Package),
+				reply.WithProgress(float64(progress.PercentDone)),
+				reply.WithMessage(fmt.Sprintf("%s: %s", progress.Stage, progress.Package)),
+				reply.WithProgressDoneText(progress.Package),
+			)
+		}
+	}()
+
+	pending := beginOperation("install", packageNames, apply)
+	defer abandonOperation(pending)
+	stopTiming = timings.Start(reply.TimingApt)
+	criticalError = a.serviceAptActions.InstallWithProgress(ctx, allPackageNames, progressCh)
+	<-progressDone
+	stopTiming()
 	if criticalError != nil {
 		var matchedErr *apt.MatchedError
 		if errors.As(criticalError, &matchedErr) && matchedErr.NeedUpdate() {
@@ -445,10 +549,15 @@ func (a *Actions) Install(ctx context.Context, packages []string, apply bool) (*
 		return nil, criticalError
 	}
 
+	completeStep(pending, OperationStepApt)
+
+	stopTiming = timings.Start(reply.TimingPackagesDB)
 	err = a.updateAllPackagesDB(ctx)
+	stopTiming()
 	if err != nil {
 		return nil, err
 	}
+	completeStep(pending, OperationStepPackagesDB)
 
 	messageAnswer := fmt.Sprintf(
 		"%s %s",
@@ -464,163 +573,114 @@ func (a *Actions) Install(ctx context.Context, packages []string, apply bool) (*
 
 		messageAnswer += lib.T_(". The system image has been changed.")
 	}
+	finishOperation()
 
-	if !apply && lib.Env.IsAtomic {
+	if !apply && lib.IsAtomic() {
 		messageAnswer += lib.T_(". The system image has not been changed! To apply changes, you need to run with the -a flag.")
 	}
 
-	resp := reply.APIResponse{
-		Data: map[string]interface{}{
-			"message": messageAnswer,
-			"info":    packageParse,
-		},
-		Error: false,
-	}
-
-	return &resp, nil
-}
-
-// Update обновляет информацию или базу данных пакетов.
-func (a *Actions) Update(ctx context.Context) (*reply.APIResponse, error) {
-	err := a.checkRoot()
-	if err != nil {
-		return nil, err
-	}
-
-	err = a.validateDB(ctx)
-	if err != nil {
-		return nil, err
+	data := &PackageChangesResponse{
+		Message: messageAnswer,
+		Info:    packageParse,
+		Timings: timings.Milliseconds(),
 	}
-
-	packages, err := a.serviceAptActions.Update(ctx)
-	if err != nil {
-		return nil, err
+	if apply {
+		data.BuildResult = a.buildResult("")
 	}
 
 	resp := reply.APIResponse{
-		Data: map[string]interface{}{
-			"message": lib.T_("Package list updated successfully"),
-			"count":   len(packages),
-		},
+		Data:  data,
 		Error: false,
 	}
 
 	return &resp, nil
 }
 
-// Info возвращает информацию о системном пакете.
-func (a *Actions) Info(ctx context.Context, packageName string, isFullFormat bool) (*reply.APIResponse, error) {
-	packageName = strings.TrimSpace(packageName)
-	if packageName == "" {
-		errMsg := lib.T_("Package name must be specified, for example info package")
-		return nil, fmt.Errorf(errMsg)
-	}
-
-	err := a.validateDB(ctx)
+// confirmChanges запрашивает подтверждение планируемых изменений. Если подтверждение отложено
+// до повторного вызова с --yes, возвращается ответ с планируемыми изменениями и отметкой confirmationRequired.
+func (a *Actions) confirmChanges(packagesInfo []apt.Package, packageParse apt.PackageChanges, action apt.DialogAction) (*reply.APIResponse, error) {
+	mode, err := reply.GetConfirmationMode()
 	if err != nil {
 		return nil, err
 	}
 
-	packageInfo, err := a.serviceAptDatabase.GetPackageByName(ctx, packageName)
-	if err != nil {
-		filters := map[string]interface{}{
-			"provides": packageName,
+	switch mode {
+	case reply.ConfirmationDeferred:
+		resp := reply.APIResponse{
+			Data: &PackageChangesResponse{
+				Message:              lib.T_("Confirmation required. Rerun the command with --yes to apply the changes"),
+				Info:                 packageParse,
+				ConfirmationRequired: true,
+			},
+			Error: false,
 		}
 
-		alternativePackages, errFind := a.serviceAptDatabase.QueryHostImagePackages(ctx, filters, "", "", 5, 0)
-		if errFind != nil {
-			return nil, errFind
+		return &resp, nil
+	case reply.ConfirmationDialog:
+		reply.StopSpinner()
+		dialogStatus, err := apt.NewDialog(packagesInfo, packageParse, action)
+		if err != nil {
+			return nil, err
 		}
 
-		if len(alternativePackages) == 0 {
-			errorFindPackage := fmt.Sprintf(lib.T_("Failed to retrieve information about the package %s"), packageName)
-			return nil, fmt.Errorf(errorFindPackage)
-		}
+		if !dialogStatus {
+			errDialog := fmt.Errorf(lib.T_("Cancel dialog"))
 
-		var altNames []string
-		for _, altPkg := range alternativePackages {
-			altNames = append(altNames, altPkg.Name)
+			return nil, errDialog
 		}
 
-		message := err.Error() + lib.T_(". Maybe you were looking for: ")
-
-		errPackageNotFound := fmt.Errorf(message+"%s", strings.Join(altNames, " "))
-
-		return nil, errPackageNotFound
-	}
-
-	resp := reply.APIResponse{
-		Data: map[string]interface{}{
-			"message":     lib.T_("Package found"),
-			"packageInfo": a.FormatPackageOutput(packageInfo, isFullFormat),
-		},
-		Error: false,
+		reply.CreateSpinner()
 	}
 
-	return &resp, nil
+	return nil, nil
 }
 
-// ListParams задаёт параметры для запроса списка пакетов.
-type ListParams struct {
-	Sort        string   `json:"sort"`
-	Order       string   `json:"order"`
-	Limit       int64    `json:"limit"`
-	Offset      int64    `json:"offset"`
-	Filters     []string `json:"filters"`
-	ForceUpdate bool     `json:"forceUpdate"`
+// confirmationRequired сообщает, что ответ содержит только планируемые изменения, ожидающие подтверждения.
+func confirmationRequired(resp *reply.APIResponse) bool {
+	if resp == nil {
+		return false
+	}
+
+	data, ok := resp.Data.(*PackageChangesResponse)
+	return ok && data.ConfirmationRequired
 }
 
-func (a *Actions) List(ctx context.Context, params ListParams, isFullFormat bool) (*reply.APIResponse, error) {
-	if params.ForceUpdate {
-		_, err := a.serviceAptActions.Update(ctx)
-		if err != nil {
-			return nil, err
-		}
-	}
+// AptCacheStats возвращает сводку кэша apt и расхождение числа пакетов в кэше и в базе apm.
+// Положительное расхождение означает, что в кэше apt больше пакетов, чем в базе.
+func (a *Actions) AptCacheStats(ctx context.Context) (*reply.APIResponse, error) {
 	err := a.validateDB(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Формируем фильтры (map[string]interface{})
-	filters := make(map[string]interface{})
-	for _, filter := range params.Filters {
-		filter = strings.TrimSpace(filter)
-		if filter == "" {
-			continue
-		}
-		parts := strings.SplitN(filter, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		if key != "" && value != "" {
-			filters[key] = value
-		}
-	}
-
-	totalCount, err := a.serviceAptDatabase.CountHostImagePackages(ctx, filters)
+	stats, err := a.serviceAptActions.GetCacheStats(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	packages, err := a.serviceAptDatabase.QueryHostImagePackages(ctx, filters, params.Sort, params.Order, params.Limit, params.Offset)
+	dbCount, err := a.serviceAptDatabase.CountHostImagePackages(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(packages) == 0 {
-		return nil, fmt.Errorf(lib.T_("Nothing found"))
+	drift := stats.NormalPackages - int(dbCount)
+	message := lib.T_("The package database matches the apt cache")
+	if drift != 0 {
+		message = lib.T_("The package database differs from the apt cache, run apm system update")
 	}
 
-	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(packages)), len(packages))
+	pending := pendingRecovery()
+	if pending != nil {
+		message += fmt.Sprintf(lib.T_(". The operation %s was interrupted, run apm recover"), pending.Operation)
+	}
 
 	resp := reply.APIResponse{
 		Data: map[string]interface{}{
-			"message":    msg,
-			"packages":   a.FormatPackageOutput(packages, isFullFormat),
-			"totalCount": int(totalCount),
+			"message":         message,
+			"stats":           stats,
+			"dbPackages":      dbCount,
+			"drift":           drift,
+			"pendingRecovery": pending,
 		},
 		Error: false,
 	}
@@ -628,57 +688,70 @@ func (a *Actions) List(ctx context.Context, params ListParams, isFullFormat bool
 	return &resp, nil
 }
 
-// Search осуществляет поиск системного пакета по названию.
-func (a *Actions) Search(ctx context.Context, packageName string, installed bool, isFullFormat bool) (*reply.APIResponse, error) {
-	err := a.validateDB(ctx)
+// Upgrade обновляет все пакеты, для которых есть новые версии. Каждый вызов записывается в историю операций.
+func (a *Actions) Upgrade(ctx context.Context, apply bool) (*reply.APIResponse, error) {
+	err := a.checkRoot()
 	if err != nil {
 		return nil, err
 	}
 
-	packageName = strings.TrimSpace(packageName)
-	if packageName == "" {
-		errMsg := fmt.Sprintf(lib.T_("You must specify the package name, for example `%s package`"), "search")
-		return nil, fmt.Errorf(errMsg)
+	packageParse, aptErrors := a.serviceAptActions.Check(ctx, "", "dist-upgrade")
+	criticalError := apt.FindCriticalError(aptErrors)
+	if criticalError != nil {
+		return nil, criticalError
 	}
 
-	packages, err := a.serviceAptDatabase.SearchPackagesByName(ctx, packageName, installed)
+	return a.upgrade(ctx, packageParse.UpgradedPackages, apply, lib.T_("No updates available"))
+}
+
+// SecurityUpgrade обновляет только пакеты из репозиториев безопасности. Каждый вызов записывается в историю операций.
+func (a *Actions) SecurityUpgrade(ctx context.Context, apply bool) (*reply.APIResponse, error) {
+	err := a.checkRoot()
 	if err != nil {
 		return nil, err
 	}
 
-	if len(packages) == 0 {
-		return nil, fmt.Errorf(lib.T_("Nothing found"))
-	}
-
-	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(packages)), len(packages))
-
-	resp := reply.APIResponse{
-		Data: map[string]interface{}{
-			"message":  msg,
-			"packages": a.FormatPackageOutput(packages, isFullFormat),
-		},
-		Error: false,
+	packages, err := a.serviceAptActions.GetSecurityUpgrades(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return &resp, nil
+	return a.upgrade(ctx, packages, apply, lib.T_("No security updates available"))
 }
 
-// ImageStatus возвращает статус актуального образа
-func (a *Actions) ImageStatus(ctx context.Context) (*reply.APIResponse, error) {
-	err := a.checkRoot()
+// UpgradeDryRun рассчитывает изменения, которые внесёт Upgrade, ничего не устанавливая. Ответ совпадает
+// по структуре с ответом Upgrade и дополнительно содержит отметку simulated и изменение занятого места.
+func (a *Actions) UpgradeDryRun(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.validateDB(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	imageStatus, err := a.getImageStatus(ctx)
+	// Пакеты определяются так же, как в Upgrade, чтобы проверка совпадала с настоящим обновлением
+	packageParse, aptErrors := a.serviceAptActions.Check(ctx, "", "dist-upgrade")
+	criticalError := apt.FindCriticalError(aptErrors)
+	if criticalError != nil {
+		return nil, criticalError
+	}
+
+	diskSpaceDelta, err := a.diskSpaceDeltaMB(ctx, packageParse.UpgradedPackages)
 	if err != nil {
 		return nil, err
 	}
 
+	message := lib.T_("No updates available")
+	if len(packageParse.UpgradedPackages) > 0 {
+		message = fmt.Sprintf(lib.TN_("%d package will be upgraded", "%d packages will be upgraded", packageParse.UpgradedCount),
+			packageParse.UpgradedCount)
+	}
+
 	resp := reply.APIResponse{
-		Data: map[string]interface{}{
-			"message":     lib.T_("Image status"),
-			"bootedImage": imageStatus,
+		Data: &PackageChangesResponse{
+			Message:          message,
+			Info:             packageParse,
+			Packages:         packageParse.UpgradedPackages,
+			Simulated:        true,
+			DiskSpaceDeltaMB: &diskSpaceDelta,
 		},
 		Error: false,
 	}
@@ -686,70 +759,78 @@ func (a *Actions) ImageStatus(ctx context.Context) (*reply.APIResponse, error) {
 	return &resp, nil
 }
 
-// ImageUpdate обновляет образ.
-func (a *Actions) ImageUpdate(ctx context.Context) (*reply.APIResponse, error) {
-	err := a.checkRoot()
+// diskSpaceDeltaMB суммирует разницу размера пакета и занимаемого им места по базе пакетов
+// и возвращает её в мегабайтах с точностью до сотых.
+func (a *Actions) diskSpaceDeltaMB(ctx context.Context, packageNames []string) (float64, error) {
+	packages, err := a.serviceAptDatabase.GetPackagesByNames(ctx, packageNames)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	err = a.serviceHostConfig.LoadConfig()
-	if err != nil {
-		return nil, err
+	delta := 0
+	for _, pkg := range packages {
+		delta += pkg.Size - pkg.InstalledSize
 	}
 
-	err = a.serviceHostImage.CheckAndUpdateBaseImage(ctx, true, *a.serviceHostConfig.Config)
-	if err != nil {
-		return nil, err
+	return math.Round(float64(delta)/(1024*1024)*100) / 100, nil
+}
+
+// upgrade устанавливает новые версии пакетов так же, как install, и записывает операцию в историю.
+func (a *Actions) upgrade(ctx context.Context, packages []string, apply bool, nothingMessage string) (*reply.APIResponse, error) {
+	if len(packages) == 0 {
+		resp := reply.APIResponse{
+			Data: map[string]interface{}{
+				"message":  nothingMessage,
+				"packages": packages,
+			},
+			Error: false,
+		}
+
+		return &resp, nil
 	}
 
-	imageStatus, err := a.getImageStatus(ctx)
+	resp, err := a.install(ctx, packages, apply, false)
+	a.saveOperation(ctx, "upgrade", packages, resp, err)
 	if err != nil {
 		return nil, err
 	}
 
-	resp := reply.APIResponse{
-		Data: map[string]interface{}{
-			"message":     lib.T_("Command executed successfully"),
-			"bootedImage": imageStatus,
-		},
-		Error: false,
+	if data, ok := resp.Data.(*PackageChangesResponse); ok {
+		data.Packages = packages
 	}
 
-	return &resp, nil
+	return resp, nil
 }
 
-// ImageApply применить изменения к хосту
-func (a *Actions) ImageApply(ctx context.Context) (*reply.APIResponse, error) {
-	err := a.checkRoot()
-	if err != nil {
-		return nil, err
-	}
+// Update обновляет информацию или базу данных пакетов. Каждый вызов записывается в историю операций.
+func (a *Actions) Update(ctx context.Context) (*reply.APIResponse, error) {
+	resp, err := a.update(ctx)
+	a.saveOperation(ctx, "update", nil, resp, err)
 
-	err = a.serviceHostConfig.LoadConfig()
-	if err != nil {
-		return nil, err
-	}
+	return resp, err
+}
 
-	err = a.serviceHostConfig.GenerateDockerfile()
+// update выполняет обновление списка пакетов.
+func (a *Actions) update(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
 	if err != nil {
 		return nil, err
 	}
 
-	imageStatus, err := a.getImageStatus(ctx)
+	err = a.validateDB(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	err = a.serviceHostImage.BuildAndSwitch(ctx, true, *a.serviceHostConfig.Config, true)
+	packages, err := a.serviceAptActions.Update(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	resp := reply.APIResponse{
 		Data: map[string]interface{}{
-			"message":     lib.T_("Changes applied successfully. A reboot is required"),
-			"bootedImage": imageStatus,
+			"message": lib.T_("Package list updated successfully"),
+			"count":   len(packages),
 		},
 		Error: false,
 	}
@@ -757,30 +838,28 @@ func (a *Actions) ImageApply(ctx context.Context) (*reply.APIResponse, error) {
 	return &resp, nil
 }
 
-// ImageHistory история изменений образа
-func (a *Actions) ImageHistory(ctx context.Context, imageName string, limit int64, offset int64) (*reply.APIResponse, error) {
-	err := a.checkRoot()
-	if err != nil {
-		return nil, err
+// Info возвращает информацию о системном пакете.
+// AllVersions возвращает все доступные в репозиториях версии пакета по данным apt-cache policy.
+func (a *Actions) AllVersions(ctx context.Context, packageName string) (*reply.APIResponse, error) {
+	packageName = strings.TrimSpace(packageName)
+	if packageName == "" {
+		errMsg := lib.T_("Package name must be specified, for example info package")
+		return nil, fmt.Errorf(errMsg)
 	}
 
-	history, err := a.serviceHostDatabase.GetImageHistoriesFiltered(ctx, imageName, limit, offset)
+	versions, err := a.serviceAptActions.GetVersionTable(ctx, packageName)
 	if err != nil {
 		return nil, err
 	}
 
-	totalCount, err := a.serviceHostDatabase.CountImageHistoriesFiltered(ctx, imageName)
-	if err != nil {
-		return nil, err
+	if len(versions) == 0 {
+		return nil, reply.Errorf(reply.ErrorCodePackageNotFound, lib.T_("Failed to retrieve information about the package %s"), packageName)
 	}
 
-	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(history)), len(history))
-
 	resp := reply.APIResponse{
 		Data: map[string]interface{}{
-			"message":    msg,
-			"history":    history,
-			"totalCount": totalCount,
+			"message":  fmt.Sprintf(lib.TN_("%d version found", "%d versions found", len(versions)), len(versions)),
+			"versions": versions,
 		},
 		Error: false,
 	}
@@ -788,15 +867,2363 @@ func (a *Actions) ImageHistory(ctx context.Context, imageName string, limit int6
 	return &resp, nil
 }
 
-// checkRoot проверяет, запущен ли установщик от имени root
-func (a *Actions) checkRoot() error {
-	if syscall.Geteuid() != 0 {
-		return fmt.Errorf(lib.T_("Elevated rights are required to perform this action. Please use sudo or su"))
-	}
-
-	if lib.Env.IsAtomic {
-		err := a.serviceHostImage.EnableOverlay()
-		if err != nil {
+func (a *Actions) Info(ctx context.Context, packageName string, isFullFormat bool) (*reply.APIResponse, error) {
+	packageName = strings.TrimSpace(packageName)
+	if packageName == "" {
+		errMsg := lib.T_("Package name must be specified, for example info package")
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	err := a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	packageInfo, err := a.serviceAptDatabase.GetPackageByName(ctx, packageName)
+	if err != nil {
+		filters := map[string]interface{}{
+			"provides": packageName,
+		}
+
+		alternativePackages, errFind := a.serviceAptDatabase.QueryHostImagePackages(ctx, filters, "", "", 5, 0)
+		if errFind != nil {
+			return nil, errFind
+		}
+
+		if len(alternativePackages) == 0 {
+			errorFindPackage := fmt.Sprintf(lib.T_("Failed to retrieve information about the package %s"), packageName)
+			return nil, reply.Errorf(reply.ErrorCodePackageNotFound, errorFindPackage)
+		}
+
+		var altNames []string
+		for _, altPkg := range alternativePackages {
+			altNames = append(altNames, altPkg.Name)
+		}
+
+		message := err.Error() + lib.T_(". Maybe you were looking for: ")
+
+		errPackageNotFound := reply.Errorf(reply.ErrorCodePackageNotFound, message+"%s", strings.Join(altNames, " "))
+
+		return nil, errPackageNotFound
+	}
+
+	var data interface{} = PackageInfoResponse{
+		Message:     lib.T_("Package found"),
+		PackageInfo: shortPackage(packageInfo),
+	}
+	if isFullFormat {
+		data = PackageInfoFullResponse{
+			Message:     lib.T_("Package found"),
+			PackageInfo: packageInfo,
+		}
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ApplyOptions дополнительные параметры применения изменений к образу.
+type ApplyOptions struct {
+	// BaseImageOverride базовый образ только для этой сборки, конфигурация при этом не меняется
+	BaseImageOverride string `json:"baseImageOverride"`
+	// NoCache собирает образ без кеша слоёв podman
+	NoCache bool `json:"noCache"`
+	// PullAlways заново загружает базовый образ из реестра при сборке
+	PullAlways bool `json:"pullAlways"`
+	// Arch целевая архитектура образа, пустое значение берёт targetArch из конфигурации
+	Arch string `json:"arch"`
+	// PackagesOnly собирает образ поверх текущего базового образа, указанного по дайджесту, не проверяя
+	// его обновления. Включается по умолчанию при pinned в конфигурации
+	PackagesOnly bool `json:"packagesOnly"`
+}
+
+// RebootParams задаёт перезагрузку после применения изменений к образу.
+type RebootParams struct {
+	Enabled bool          `json:"enabled"`
+	Delay   time.Duration `json:"delay"`
+}
+
+// validateReboot проверяет, что перезагрузку можно запланировать: нужны права root и применение изменений к образу.
+func (a *Actions) validateReboot(reboot RebootParams, apply bool) error {
+	if !reboot.Enabled {
+		return nil
+	}
+
+	if syscall.Geteuid() != 0 {
+		return fmt.Errorf(lib.T_("Elevated rights are required to schedule a reboot. Please use sudo or su"))
+	}
+
+	if !apply || !lib.IsAtomic() {
+		return fmt.Errorf(lib.T_("A reboot can only be scheduled when changes are applied to the image"))
+	}
+
+	return nil
+}
+
+// scheduleReboot после успешной сборки планирует перезагрузку и дополняет ответ её временем.
+func (a *Actions) scheduleReboot(reboot RebootParams, resp *reply.APIResponse) (*reply.APIResponse, error) {
+	if !reboot.Enabled || resp == nil {
+		return resp, nil
+	}
+
+	at, err := service.ScheduleReboot(reboot.Delay, lib.T_("The system image has been updated by apm, the system will reboot. Cancel with: apm system image cancel-reboot"))
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled := &service.ScheduledReboot{Type: "reboot", At: at.Format(time.RFC3339)}
+	message := " " + fmt.Sprintf(lib.T_("Reboot scheduled for %s"), at.Format("15:04:05"))
+	switch data := resp.Data.(type) {
+	case *PackageChangesResponse:
+		data.ScheduledReboot = scheduled
+		data.Message += message
+	case *ImageApplyResponse:
+		data.ScheduledReboot = scheduled
+		data.Message += message
+	}
+
+	return resp, nil
+}
+
+// ListParams задаёт параметры для запроса списка пакетов.
+type ListParams struct {
+	Sort        string   `json:"sort"`
+	Order       string   `json:"order"`
+	Limit       int64    `json:"limit"`
+	Offset      int64    `json:"offset"`
+	Filters     []string `json:"filters"`
+	ForceUpdate bool     `json:"forceUpdate"`
+	// IncludeChangelog добавляет в сокращённый вывод последнюю запись журнала изменений
+	IncludeChangelog bool `json:"includeChangelog"`
+}
+
+func (a *Actions) List(ctx context.Context, params ListParams, isFullFormat bool) (*reply.APIResponse, error) {
+	if params.ForceUpdate {
+		_, err := a.serviceAptActions.Update(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := parseListFilters(params.Filters)
+
+	totalCount, err := a.serviceAptDatabase.CountHostImagePackages(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	packages, err := a.serviceAptDatabase.QueryHostImagePackages(ctx, filters, params.Sort, params.Order, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(packages) == 0 {
+		return nil, fmt.Errorf(lib.T_("Nothing found"))
+	}
+
+	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(packages)), len(packages))
+
+	var data interface{}
+	if isFullFormat {
+		if params.IncludeChangelog {
+			a.fetchMissingChangelogs(packages)
+		}
+		data = PackageListFullResponse{
+			Message:    msg,
+			Packages:   packages,
+			TotalCount: int(totalCount),
+		}
+	} else {
+		output := shortPackages(packages)
+		if params.IncludeChangelog {
+			output = a.withChangelog(output, packages)
+		}
+		data = PackageListResponse{
+			Message:    msg,
+			Packages:   output,
+			TotalCount: int(totalCount),
+		}
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// Count возвращает только число пакетов, подходящих под фильтры, без выборки самих пакетов.
+func (a *Actions) Count(ctx context.Context, params ListParams) (*reply.APIResponse, error) {
+	err := a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	totalCount, err := a.serviceAptDatabase.CountHostImagePackages(ctx, parseListFilters(params.Filters))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":    fmt.Sprintf(lib.TN_("%d record found", "%d records found", int(totalCount)), totalCount),
+			"totalCount": int(totalCount),
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ListUpgradablePackages возвращает пакеты, для которых доступно обновление. Список берётся из симуляции
+// apt-get upgrade, а из базы читаются только найденные в ней пакеты, без полного просмотра таблицы.
+func (a *Actions) ListUpgradablePackages(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	packageParse, aptErrors := a.serviceAptActions.Check(ctx, "", "upgrade")
+	criticalError := apt.FindCriticalError(aptErrors)
+	if criticalError != nil {
+		return nil, criticalError
+	}
+
+	packages, err := a.serviceAptDatabase.GetPackagesByNames(ctx, packageParse.UpgradedPackages)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+
+	output := make([]UpgradablePackageResponse, 0, len(packages))
+	for _, pkg := range packages {
+		output = append(output, UpgradablePackageResponse{
+			Name:             pkg.Name,
+			Version:          pkg.Version,
+			VersionInstalled: pkg.VersionInstalled,
+			Description:      pkg.Description,
+		})
+	}
+
+	msg := lib.T_("No updates available")
+	if len(output) > 0 {
+		msg = fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(output)), len(output))
+	}
+
+	resp := reply.APIResponse{
+		Data: UpgradableListResponse{
+			Message:    msg,
+			Packages:   output,
+			TotalCount: len(output),
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// parseListFilters преобразует фильтры вида key=value в условия запроса. Пустые и некорректные фильтры пропускаются.
+func parseListFilters(params []string) map[string]interface{} {
+	filters := make(map[string]interface{})
+	for _, filter := range params {
+		filter = strings.TrimSpace(filter)
+		if filter == "" {
+			continue
+		}
+		parts := strings.SplitN(filter, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if key != "" && value != "" {
+			filters[key] = value
+		}
+	}
+
+	return filters
+}
+
+// ManuallyInstalledPackages возвращает списки пакетов, установленных вручную и автоматически, по данным apt-mark.
+func (a *Actions) ManuallyInstalledPackages(ctx context.Context) (*reply.APIResponse, error) {
+	manual, err := a.serviceAptActions.GetMarkedPackages(ctx, apt.InstallReasonManual)
+	if err != nil {
+		return nil, err
+	}
+
+	auto, err := a.serviceAptActions.GetMarkedPackages(ctx, apt.InstallReasonAuto)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf(lib.T_("Manually installed: %d, automatically installed: %d"), len(manual), len(auto))
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": msg,
+			"manual":  manual,
+			"auto":    auto,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// HistogramBucket интервал гистограммы размеров пакетов.
+type HistogramBucket struct {
+	RangeLow    float64 `json:"rangeLow"`
+	RangeHigh   float64 `json:"rangeHigh"`
+	Range       string  `json:"range"`
+	Count       int     `json:"count"`
+	TotalSizeMB float64 `json:"totalSizeMB"`
+}
+
+// maxHistogramBuckets количество интервалов гистограммы, последний интервал включает все пакеты крупнее.
+const maxHistogramBuckets = 20
+
+// SizeHistogram строит гистограмму размеров установленных пакетов с интервалами шириной bucketSizeMB.
+func (a *Actions) SizeHistogram(ctx context.Context, bucketSizeMB float64) (*reply.APIResponse, error) {
+	if bucketSizeMB <= 0 {
+		return nil, fmt.Errorf(lib.T_("Bucket size must be greater than zero"))
+	}
+
+	err := a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes, err := a.serviceAptDatabase.GetInstalledPackageSizes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf(lib.T_("No installed packages found"))
+	}
+
+	sort.Ints(sizes)
+	bytesInMB := float64(1024 * 1024)
+	maxSizeMB := float64(sizes[len(sizes)-1]) / bytesInMB
+
+	bucketCount := int(maxSizeMB/bucketSizeMB) + 1
+	if bucketCount > maxHistogramBuckets {
+		bucketCount = maxHistogramBuckets
+	}
+
+	buckets := make([]HistogramBucket, bucketCount)
+	for i := range buckets {
+		buckets[i].RangeLow = float64(i) * bucketSizeMB
+		buckets[i].RangeHigh = float64(i+1) * bucketSizeMB
+	}
+	buckets[bucketCount-1].RangeHigh = math.Max(buckets[bucketCount-1].RangeHigh, maxSizeMB)
+
+	for _, size := range sizes {
+		sizeMB := float64(size) / bytesInMB
+		index := int(sizeMB / bucketSizeMB)
+		if index >= bucketCount {
+			index = bucketCount - 1
+		}
+
+		buckets[index].Count++
+		buckets[index].TotalSizeMB += sizeMB
+	}
+
+	for i := range buckets {
+		low := helper.AutoSize(int(buckets[i].RangeLow * bytesInMB))
+		high := helper.AutoSize(int(buckets[i].RangeHigh * bytesInMB))
+		buckets[i].Range = fmt.Sprintf("%s - %s", low, high)
+		buckets[i].TotalSizeMB = math.Round(buckets[i].TotalSizeMB*100) / 100
+	}
+
+	percentile := func(p float64) int {
+		index := int(math.Ceil(p/100*float64(len(sizes)))) - 1
+		if index < 0 {
+			index = 0
+		}
+		return sizes[index]
+	}
+
+	p50, p90, p99 := percentile(50), percentile(90), percentile(99)
+	msg := fmt.Sprintf(lib.T_("Installed packages: %d. P50: %s, P90: %s, P99: %s"),
+		len(sizes), helper.AutoSize(p50), helper.AutoSize(p90), helper.AutoSize(p99))
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":   msg,
+			"histogram": buckets,
+			"p50":       math.Round(float64(p50)/bytesInMB*100) / 100,
+			"p90":       math.Round(float64(p90)/bytesInMB*100) / 100,
+			"p99":       math.Round(float64(p99)/bytesInMB*100) / 100,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ListMaintainers возвращает сопровождающих пакетов с числом их пакетов, начиная с самых крупных.
+func (a *Actions) ListMaintainers(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	maintainers, err := a.serviceAptDatabase.GroupByMaintainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(maintainers) == 0 {
+		return nil, fmt.Errorf(lib.T_("Nothing found"))
+	}
+
+	resp := reply.APIResponse{
+		Data: MaintainerListResponse{
+			Message:     fmt.Sprintf(lib.TN_("%d maintainer found", "%d maintainers found", len(maintainers)), len(maintainers)),
+			Maintainers: maintainers,
+			TotalCount:  len(maintainers),
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// PackagesByMaintainer возвращает пакеты, в поле сопровождающего которых встречается maintainer.
+func (a *Actions) PackagesByMaintainer(ctx context.Context, maintainer string, isFullFormat bool) (*reply.APIResponse, error) {
+	maintainer = strings.TrimSpace(maintainer)
+	if maintainer == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the maintainer"))
+	}
+
+	err := a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	packages, err := a.serviceAptDatabase.QueryHostImagePackages(ctx, map[string]interface{}{"maintainer": maintainer}, "name", "ASC", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(packages) == 0 {
+		return nil, fmt.Errorf(lib.T_("Nothing found"))
+	}
+
+	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(packages)), len(packages))
+
+	var data interface{} = PackageListResponse{
+		Message:    msg,
+		Packages:   shortPackages(packages),
+		TotalCount: len(packages),
+	}
+	if isFullFormat {
+		data = PackageListFullResponse{
+			Message:    msg,
+			Packages:   packages,
+			TotalCount: len(packages),
+		}
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// Search осуществляет поиск системного пакета по названию и описанию, совпадения в названии идут первыми.
+// С nameOnly поиск ведётся только по названию.
+func (a *Actions) Search(ctx context.Context, packageName string, installed bool, isFullFormat bool, nameOnly bool) (*reply.APIResponse, error) {
+	packageName = strings.TrimSpace(packageName)
+	packages, err := a.searchPackages(ctx, packageName, installed, nameOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(packages)), len(packages))
+
+	var data interface{}
+	if isFullFormat {
+		result := make([]SearchPackageResponse, 0, len(packages))
+		for _, pkg := range packages {
+			result = append(result, SearchPackageResponse{Package: pkg, MatchedOn: apt.SearchMatch(pkg, packageName)})
+		}
+		data = PackageSearchFullResponse{Message: msg, Packages: result}
+	} else {
+		result := shortPackages(packages)
+		for i := range result {
+			result[i].MatchedOn = apt.SearchMatch(packages[i], packageName)
+		}
+		data = PackageSearchResponse{Message: msg, Packages: result}
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// SearchInteractive ищет пакеты как Search, показывает найденные в списке выбора и устанавливает
+// отмеченные пакеты через Install с обычным подтверждением изменений.
+func (a *Actions) SearchInteractive(ctx context.Context, packageName string, installed bool, nameOnly bool, apply bool) (*reply.APIResponse, error) {
+	if err := reply.CheckInteractive(); err != nil {
+		return nil, err
+	}
+
+	packages, err := a.searchPackages(ctx, strings.TrimSpace(packageName), installed, nameOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]reply.PickerItem, 0, len(packages))
+	for _, pkg := range packages {
+		items = append(items, reply.PickerItem{Title: pkg.Name, Description: pkg.Description})
+	}
+
+	indexes, err := reply.PickItems(lib.T_("Select packages to install"), items)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		selected = append(selected, packages[index].Name)
+	}
+
+	installResp, err := a.Install(ctx, selected, apply, false, RebootParams{})
+	if err != nil {
+		return nil, err
+	}
+
+	if data, ok := installResp.Data.(*PackageChangesResponse); ok {
+		data.Selected = selected
+	}
+
+	return installResp, nil
+}
+
+// searchPackages ищет пакеты по названию или, без nameOnly, по названию и описанию и записывает запрос
+// в историю поиска. Пустой результат возвращается ошибкой.
+func (a *Actions) searchPackages(ctx context.Context, packageName string, installed bool, nameOnly bool) ([]apt.Package, error) {
+	err := a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if packageName == "" {
+		errMsg := fmt.Sprintf(lib.T_("You must specify the package name, for example `%s package`"), "search")
+		return nil, fmt.Errorf(errMsg)
+	}
+
+	var packages []apt.Package
+	if nameOnly {
+		packages, err = a.serviceAptDatabase.SearchPackagesByName(ctx, packageName, installed)
+	} else {
+		packages, err = a.serviceAptDatabase.SearchByNameOrDescription(ctx, packageName, installed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(packages) == 0 {
+		return nil, fmt.Errorf(lib.T_("Nothing found"))
+	}
+
+	if err = a.searchHistory().SaveSearch(ctx, packageName, len(packages)); err != nil {
+		lib.Log.Debug(err.Error())
+	}
+
+	return packages, nil
+}
+
+// searchHistory возвращает сервис истории поиска текущего пользователя.
+func (a *Actions) searchHistory() *service.SearchHistoryService {
+	return service.NewSearchHistoryService(lib.GetDB())
+}
+
+// SearchHistory возвращает последние уникальные поисковые запросы
+func (a *Actions) SearchHistory(ctx context.Context, limit int) (*reply.APIResponse, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf(lib.T_("The limit must be greater than zero"))
+	}
+
+	entries, err := a.searchHistory().GetSearchHistory(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(entries)), len(entries))
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":       msg,
+			"searchHistory": entries,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ClearSearchHistory удаляет историю поисковых запросов
+func (a *Actions) ClearSearchHistory(ctx context.Context) (*reply.APIResponse, error) {
+	if err := a.searchHistory().ClearSearchHistory(ctx); err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": lib.T_("Search history cleared"),
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// RecentSearchQueries возвращает последние поисковые запросы для автодополнения
+func (a *Actions) RecentSearchQueries(ctx context.Context, limit int) []string {
+	entries, err := a.searchHistory().GetSearchHistory(ctx, limit)
+	if err != nil {
+		lib.Log.Debug(err.Error())
+		return nil
+	}
+
+	queries := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		queries = append(queries, entry.Query)
+	}
+
+	return queries
+}
+
+// ImageStatus возвращает статус актуального образа
+func (a *Actions) ImageStatus(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	imageStatus, err := a.getImageStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingImage, err := a.serviceHostDatabase.GetPendingImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	baseSignature, err := service.VerifyBaseImageSignature(imageStatus.Config.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	status := ImageStatusResponse{
+		Message:       lib.T_("Image status"),
+		BootedImage:   imageStatus,
+		BaseSignature: baseSignature,
+		PendingImage:  pendingImage,
+		Atomic:        lib.GetAtomicDetection(),
+	}
+
+	if pendingImage != nil {
+		status.Message = lib.T_("Image status. A built image is waiting to be deployed, run the image switch command to use it")
+	}
+
+	// Недоступность systemd-logind не мешает показу статуса образа
+	scheduledReboot, err := service.GetScheduledReboot()
+	if err != nil {
+		lib.Log.Debug(err.Error())
+	} else {
+		status.ScheduledReboot = scheduledReboot
+	}
+
+	resp := reply.APIResponse{
+		Data:  status,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImageBuild собирает образ по локальной конфигурации без переключения хоста.
+// buildTimeout переопределяет тайм-аут сборки из конфигурации, если больше нуля.
+// labels в виде key=value добавляются только к этой сборке и не сохраняются в конфигурации.
+func (a *Actions) ImageBuild(ctx context.Context, skipValidation bool, allowUnsigned bool, buildTimeout time.Duration,
+	labels []string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	extraLabels, err := parseLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if !skipValidation {
+		if err = a.validateConfigPackages(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	baseSignature, warning, err := a.checkBaseSignature(a.serviceHostConfig.Config.Image, allowUnsigned)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.serviceHostConfig.GenerateDockerfile("")
+	if err != nil {
+		return nil, err
+	}
+
+	a.serviceHostImage.SetExtraLabels(extraLabels)
+	buildOptions := service.BuildOptions{PullAlways: true, Arch: a.serviceHostConfig.Config.TargetArch, Timeout: buildTimeout}
+	builtImage, err := a.serviceHostImage.BuildOnly(ctx, true, *a.serviceHostConfig.Config, buildOptions)
+	if err != nil {
+		return nil, a.buildError(err, buildTimeout)
+	}
+
+	data := ImageBuildResponse{
+		Message:       lib.T_("Image built successfully. Run the image switch command to deploy it"),
+		PendingImage:  builtImage,
+		BaseSignature: baseSignature,
+		BuildResult:   a.buildResult(warning),
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImageSwitch переключает хост на ранее собранный образ без повторной сборки.
+// Без to используется образ, ожидающий установки, иначе — локальный образ apm по тегу,
+// дайджесту, идентификатору или номеру записи истории.
+func (a *Actions) ImageSwitch(ctx context.Context, to string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	var deployedImage service.ImageHistory
+	if strings.TrimSpace(to) == "" {
+		deployedImage, err = a.serviceHostImage.SwitchToBuilt(ctx)
+	} else {
+		deployedImage, err = a.serviceHostImage.SwitchTo(ctx, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	imageStatus, err := a.getImageStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":       lib.T_("Switched to the built image. A reboot is required"),
+			"deployedImage": deployedImage,
+			"bootedImage":   imageStatus,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImageUpdate обновляет образ. skipValidation отключает проверку пакетов конфигурации по репозиторию,
+// allowUnsigned разрешает неподписанный базовый образ при включённом requireSignedBase.
+// Если базовый образ не изменился, сборка пропускается. force пересобирает образ без этой проверки, например
+// после ручных изменений, которые не затронули списки пакетов, а noSwitch только собирает образ, оставляя
+// его ожидающим установки.
+func (a *Actions) ImageUpdate(ctx context.Context, skipValidation bool, allowUnsigned bool, force bool, noSwitch bool) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if !skipValidation {
+		if err = a.validateConfigPackages(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	baseSignature, warning, err := a.checkBaseSignature(a.serviceHostConfig.Config.Image, allowUnsigned)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := a.serviceHostImage.CheckAndUpdateBaseImage(ctx, true, *a.serviceHostConfig.Config, force, noSwitch)
+	if err != nil {
+		return nil, err
+	}
+
+	imageStatus, err := a.getImageStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := lib.T_("Command executed successfully")
+	switch {
+	case !updated:
+		msg = lib.T_("The base image has not changed, the image is up to date. Use --force to rebuild it anyway")
+	case noSwitch:
+		msg = lib.T_("Image built successfully. Run the image switch command to deploy it")
+	}
+
+	data := ImageUpdateResponse{
+		Message:       msg,
+		Updated:       updated,
+		Forced:        force,
+		BootedImage:   imageStatus,
+		BaseSignature: baseSignature,
+		BuildResult:   a.buildResult(warning),
+	}
+	if noSwitch && updated {
+		data.PendingImage, err = a.serviceHostDatabase.GetPendingImage(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImageCancelReboot отменяет перезагрузку, запланированную после применения образа.
+func (a *Actions) ImageCancelReboot(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled, err := service.CancelScheduledReboot()
+	if err != nil {
+		return nil, err
+	}
+
+	msg := lib.T_("The scheduled reboot has been cancelled")
+	if !cancelled {
+		msg = lib.T_("No reboot is scheduled")
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":   msg,
+			"cancelled": cancelled,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImageCheck проверяет, есть ли в реестре более новый базовый образ, ничего не загружая и не применяя.
+func (a *Actions) ImageCheck(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	check, err := a.serviceHostImage.CheckImageUpdate(ctx, a.serviceHostConfig.Config.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg string
+	switch check.Status {
+	case service.ImageUpdateAvailable:
+		msg = lib.T_("A newer base image is available")
+	case service.ImageUpToDate:
+		msg = lib.T_("The base image is up to date")
+	default:
+		msg = lib.T_("Unable to determine whether a newer base image is available")
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":         msg,
+			"status":          check.Status,
+			"updateAvailable": check.UpdateAvailable,
+			"image":           check,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImageApply применить изменения к хосту. skipValidation отключает проверку пакетов конфигурации по репозиторию.
+// Если хеш конфигурации совпадает с хешем текущего образа, сборка пропускается, force отключает эту проверку.
+// allowUnsigned разрешает неподписанный базовый образ при включённом requireSignedBase.
+// buildTimeout переопределяет тайм-аут сборки из конфигурации, если больше нуля.
+// reboot планирует перезагрузку после успешной сборки и переключения.
+// options.BaseImageOverride заменяет базовый образ только для этой сборки.
+// options.Arch собирает образ для другой архитектуры, доступность базового образа для неё проверяется заранее.
+func (a *Actions) ImageApply(ctx context.Context, skipValidation bool, force bool, allowUnsigned bool, buildTimeout time.Duration,
+	reboot RebootParams, options ApplyOptions) (*reply.APIResponse, error) {
+	ctx, timings := reply.WithTimings(ctx)
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.validateReboot(reboot, true); err != nil {
+		return nil, err
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Подменённый базовый образ учитывается в хеше и сборке, но не записывается в конфигурацию
+	config := *a.serviceHostConfig.Config
+	baseImageOverride := strings.TrimSpace(options.BaseImageOverride)
+	if baseImageOverride != "" {
+		config.Image = baseImageOverride
+	}
+	if options.PackagesOnly && (baseImageOverride != "" || options.PullAlways) {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("--packages-only cannot be combined with --from or --pull-always"))
+	}
+
+	// Архитектура из флага действует только на эту сборку, как и подменённый базовый образ
+	arch := strings.TrimSpace(options.Arch)
+	if arch == "" {
+		arch = config.TargetArch
+	}
+	if err = service.ValidateTargetArch(arch); err != nil {
+		return nil, err
+	}
+	config.TargetArch = arch
+
+	if !skipValidation {
+		if err = a.validateConfigPackages(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	imageStatus, err := a.getImageStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Явно переданный базовый образ или загрузка обновлений отменяют закрепление из конфигурации
+	packagesOnly := options.PackagesOnly || (config.Pinned && baseImageOverride == "" && !options.PullAlways)
+	dockerfileImage := baseImageOverride
+	baseDigest := ""
+	if packagesOnly {
+		dockerfileImage, baseDigest, err = a.serviceHostImage.CurrentBaseImageRef(ctx, config.Image)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !force {
+		// Хеш считается так же, как при сборке: сборка от закреплённого дайджеста использует его, а без
+		// закрепления сборка загружает базовый образ, поэтому берётся его дайджест в реестре
+		var configHash string
+		if packagesOnly {
+			configHash, err = service.HashConfig(config, baseDigest)
+		} else {
+			configHash, err = a.serviceHostImage.ExpectedConfigHash(ctx, config)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		deployedHash, err := a.serviceHostDatabase.GetDeployedConfigHash(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if deployedHash != "" && deployedHash == configHash {
+			if reboot.Enabled {
+				return nil, fmt.Errorf(lib.T_("No changes since the current image, configuration hash %s matches. Nothing was built, so a reboot is not scheduled. Use --force to rebuild"), configHash)
+			}
+
+			resp := reply.APIResponse{
+				Data: &ImageApplyResponse{
+					Message:     fmt.Sprintf(lib.T_("No changes since the current image, configuration hash %s matches. Use --force to rebuild"), configHash),
+					BootedImage: imageStatus,
+					ImageName:   service.BuiltImageName,
+					NoChanges:   true,
+					ConfigHash:  configHash,
+				},
+				Error: false,
+			}
+
+			return &resp, nil
+		}
+	}
+
+	baseSignature, warning, err := a.checkBaseSignature(config.Image, allowUnsigned)
+	if err != nil {
+		return nil, err
+	}
+
+	if arch != "" {
+		if err = a.serviceHostImage.CheckBaseImageArch(ctx, config.Image, arch); err != nil {
+			return nil, err
+		}
+	}
+
+	stopTiming := timings.Start(reply.TimingDockerfile)
+	err = a.serviceHostConfig.GenerateDockerfileForArch(dockerfileImage, arch)
+	stopTiming()
+	if err != nil {
+		return nil, err
+	}
+
+	// Без изменений в файле конфигурации сборка с другим базовым образом всё равно нужна
+	buildOptions := service.BuildOptions{NoCacheFlag: options.NoCache, PullAlways: options.PullAlways, Arch: arch, PackagesOnly: packagesOnly,
+		BaseDigest: baseDigest, Timeout: buildTimeout}
+	stopTiming = timings.Start(reply.TimingImageBuild)
+	err = a.serviceHostImage.BuildAndSwitch(ctx, true, config, !force && baseImageOverride == "", buildOptions)
+	stopTiming()
+	if err != nil {
+		return nil, a.buildError(err, buildTimeout)
+	}
+
+	if baseDigest == "" {
+		// Дайджест не входит в обязательные поля ответа, ошибка чтения не отменяет сборку
+		if baseDigest, err = service.LocalImageDigest(ctx, config.Image); err != nil {
+			lib.Log.Debug(err.Error())
+		}
+	}
+
+	data := &ImageApplyResponse{
+		Message:         lib.T_("Changes applied successfully. A reboot is required"),
+		BootedImage:     imageStatus,
+		BaseSignature:   baseSignature,
+		ImageName:       service.BuiltImageName,
+		Arch:            service.HostArch(),
+		BaseImageDigest: baseDigest,
+		PackagesOnly:    packagesOnly,
+		Timings:         timings.Milliseconds(),
+		BuildResult:     a.buildResult(warning),
+	}
+	if arch != "" {
+		data.Arch = arch
+	}
+	if baseImageOverride != "" {
+		data.BaseImageOverride = baseImageOverride
+		data.ConfigImage = a.serviceHostConfig.Config.Image
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return a.scheduleReboot(reboot, &resp)
+}
+
+// ImagePrune удаляет старые собранные apm образы из локального хранилища, оставляя keepLast последних.
+// Загруженный, подготовленный к загрузке и ожидающий установки образы не удаляются.
+func (a *Actions) ImagePrune(ctx context.Context, keepLast int) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if keepLast < 0 {
+		return nil, fmt.Errorf(lib.T_("The number of kept images cannot be negative"))
+	}
+
+	protected, err := a.serviceHostImage.ProtectedImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prunedImages, freedBytes, err := a.serviceHostImage.PruneImages(ctx, keepLast, protected)
+	if err != nil {
+		return nil, err
+	}
+
+	freedSpaceMB := math.Round(float64(freedBytes)/(1024*1024)*100) / 100
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.TN_("%d image removed, %s freed", "%d images removed, %s freed", len(prunedImages)),
+				len(prunedImages), helper.AutoSize(int(freedBytes))),
+			"prunedImages": prunedImages,
+			"freedSpaceMB": freedSpaceMB,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImageCacheSize возвращает размер кеша сборки podman, который растёт с каждой сборкой образа.
+func (a *Actions) ImageCacheSize(ctx context.Context) (*reply.APIResponse, error) {
+	size, err := a.serviceHostImage.BuildCacheSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":          fmt.Sprintf(lib.T_("The build cache takes %s"), helper.AutoSize(int(size))),
+			"buildCacheSize":   helper.AutoSize(int(size)),
+			"buildCacheSizeMB": math.Round(float64(size)/(1024*1024)*100) / 100,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImageCachePrune удаляет кеш сборки podman и возвращает освобождённое место.
+func (a *Actions) ImageCachePrune(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	freedBytes, err := a.serviceHostImage.PruneBuildCache(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	freedSpace := helper.AutoSize(int(freedBytes))
+	lib.Log.Infof(lib.T_("Build cache pruned, %s freed"), freedSpace)
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":      fmt.Sprintf(lib.T_("Build cache pruned, %s freed"), freedSpace),
+			"freedSpace":   freedSpace,
+			"freedSpaceMB": math.Round(float64(freedBytes)/(1024*1024)*100) / 100,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImageGC применяет правила хранения keepImages и keepHistoryDays: удаляет лишние собранные образы
+// и старые записи истории. Загруженный, подготовленный к загрузке и закреплённый образы не удаляются.
+func (a *Actions) ImageGC(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	policy := service.DefaultRetentionPolicy()
+	result, err := a.serviceHostImage.CollectGarbage(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	freedSpaceMB := math.Round(float64(result.FreedBytes)/(1024*1024)*100) / 100
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.TN_("%d image removed, %s freed", "%d images removed, %s freed", len(result.PrunedImages)),
+				len(result.PrunedImages), helper.AutoSize(int(result.FreedBytes))) + ". " +
+				fmt.Sprintf(lib.TN_("%d history record removed", "%d history records removed", int(result.RemovedHistory)), result.RemovedHistory),
+			"prunedImages":   result.PrunedImages,
+			"freedSpaceMB":   freedSpaceMB,
+			"removedHistory": result.RemovedHistory,
+			"retention":      policy,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// GenerateSBOM формирует перечень установленных в образе пакетов в формате spdx-json или cyclonedx-json.
+// Если output задан, документ записывается в файл, иначе возвращается в поле sbom ответа.
+func (a *Actions) GenerateSBOM(ctx context.Context, format string, output string) (*reply.APIResponse, error) {
+	if format != service.SBOMFormatSPDX && format != service.SBOMFormatCycloneDX {
+		return nil, fmt.Errorf(lib.T_("Unknown SBOM format %s. Available formats: %s, %s"), format, service.SBOMFormatSPDX, service.SBOMFormatCycloneDX)
+	}
+
+	err := a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	packages, err := a.serviceAptDatabase.QueryHostImagePackages(ctx, map[string]interface{}{"installed": true}, "name", "ASC", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Без лицензий перечень остаётся полезным, поэтому ошибка rpm не прерывает формирование
+	licenses, err := a.serviceAptActions.GetPackageLicenses(ctx)
+	if err != nil {
+		lib.Log.Warning(err.Error())
+	}
+
+	distro := service.DistroID()
+	components := make([]service.SBOMComponent, 0, len(packages))
+	for _, pkg := range packages {
+		version := pkg.VersionInstalled
+		if version == "" {
+			version = pkg.Version
+		}
+		components = append(components, service.NewSBOMComponent(distro, pkg.Name, version, licenses[pkg.Name]))
+	}
+
+	imageName := distro
+	if lib.IsAtomic() {
+		if err = a.serviceHostConfig.LoadConfig(); err == nil && a.serviceHostConfig.Config.Image != "" {
+			imageName = a.serviceHostConfig.Config.Image
+		}
+	}
+
+	document, err := service.GenerateSBOM(format, imageName, components)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"message":    fmt.Sprintf(lib.TN_("SBOM generated for %d package", "SBOM generated for %d packages", len(components)), len(components)),
+		"components": len(components),
+	}
+
+	if output != "" {
+		if err = os.WriteFile(output, append(document, '\n'), 0644); err != nil {
+			return nil, fmt.Errorf(lib.T_("Error writing file %s: %v"), output, err)
+		}
+		data["message"] = fmt.Sprintf(lib.T_("SBOM saved to %s"), output)
+		data["output"] = output
+	} else {
+		data["sbom"] = json.RawMessage(document)
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// GenerateManifest формирует манифест системы: установленные вручную пакеты с точными версиями,
+// закреплённые пакеты и, на атомарной системе, базовый образ и удаляемые из образа пакеты.
+// Без outputPath манифест возвращается в ответе.
+func (a *Actions) GenerateManifest(ctx context.Context, outputPath string) (*reply.APIResponse, error) {
+	err := a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := map[string]interface{}{"installed": true, "install_reason": apt.InstallReasonManual}
+	packages, err := a.serviceAptDatabase.QueryHostImagePackages(ctx, filters, "name", "ASC", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := a.serviceAptActions.GetHeldPackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := service.Manifest{
+		Packages: make([]service.ManifestPackage, 0, len(packages)),
+		Held:     held,
+		Removed:  []string{},
+	}
+	for _, pkg := range packages {
+		version := pkg.VersionInstalled
+		if version == "" {
+			version = pkg.Version
+		}
+		manifest.Packages = append(manifest.Packages, service.ManifestPackage{Name: pkg.Name, Version: version})
+	}
+
+	if lib.IsAtomic() {
+		if err = a.serviceHostConfig.LoadConfig(); err != nil {
+			return nil, err
+		}
+		manifest.BaseImage = a.serviceHostConfig.Config.Image
+		manifest.Removed = append(manifest.Removed, a.serviceHostConfig.Config.Packages.Remove...)
+	}
+
+	data := map[string]interface{}{
+		"message": fmt.Sprintf(lib.TN_("Manifest generated for %d package", "Manifest generated for %d packages", len(manifest.Packages)), len(manifest.Packages)),
+	}
+
+	if outputPath != "" {
+		if err = manifest.Save(outputPath); err != nil {
+			return nil, err
+		}
+		data["message"] = fmt.Sprintf(lib.T_("Manifest saved to %s"), outputPath)
+		data["output"] = outputPath
+	} else {
+		data["manifest"] = manifest
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ManifestChanges изменения, необходимые для приведения системы к состоянию из манифеста.
+type ManifestChanges struct {
+	Install        []string `json:"install"`
+	Remove         []string `json:"remove"`
+	Hold           []string `json:"hold"`
+	VersionChanged []string `json:"versionChanged"`
+}
+
+// ApplyManifest приводит систему к состоянию из манифеста: устанавливает недостающие пакеты, удаляет установленные
+// вручную пакеты, которых нет в манифесте, и пакеты из раздела removed, закрепляет пакеты из раздела held.
+// Несовпадение версий установленных пакетов только сообщается. При apply изменения применяются и к образу.
+func (a *Actions) ApplyManifest(ctx context.Context, filePath string, apply bool) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(filePath) == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the manifest file, for example apply-manifest manifest.yml"))
+	}
+
+	err = a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := service.LoadManifest(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := a.manifestChanges(ctx, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"changes": changes,
+	}
+	if lib.IsAtomic() && manifest.BaseImage != "" {
+		if err = a.serviceHostConfig.LoadConfig(); err != nil {
+			return nil, err
+		}
+		if a.serviceHostConfig.Config.Image != manifest.BaseImage {
+			data["warning"] = fmt.Sprintf(lib.T_("The manifest was created for the base image %s, the system uses %s"),
+				manifest.BaseImage, a.serviceHostConfig.Config.Image)
+		}
+	}
+
+	if len(changes.Install) == 0 && len(changes.Remove) == 0 && len(changes.Hold) == 0 {
+		data["message"] = lib.T_("The system already matches the manifest")
+		return &reply.APIResponse{Data: data, Error: false}, nil
+	}
+
+	mode, err := reply.GetConfirmationMode()
+	if err != nil {
+		return nil, err
+	}
+	if mode == reply.ConfirmationDeferred {
+		data["message"] = lib.T_("Confirmation required. Rerun the command with --yes to apply the changes")
+		data["confirmationRequired"] = true
+		return &reply.APIResponse{Data: data, Error: false}, nil
+	}
+
+	if len(changes.Install) > 0 {
+		if _, err = a.Install(ctx, changes.Install, apply, false, RebootParams{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(changes.Remove) > 0 {
+		if _, err = a.Remove(ctx, changes.Remove, apply, false, RebootParams{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = a.serviceAptActions.HoldPackages(ctx, changes.Hold); err != nil {
+		return nil, err
+	}
+
+	if apply && lib.IsAtomic() && len(changes.Hold) > 0 {
+		if err = a.serviceHostConfig.LoadConfig(); err != nil {
+			return nil, err
+		}
+		for _, name := range changes.Hold {
+			if _, err = a.serviceHostConfig.PinPackage(name, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	data["message"] = fmt.Sprintf(lib.T_("The manifest has been applied: %d installed, %d removed, %d held"),
+		len(changes.Install), len(changes.Remove), len(changes.Hold))
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// manifestChanges сравнивает манифест с установленными пакетами и определяет необходимые изменения.
+func (a *Actions) manifestChanges(ctx context.Context, manifest service.Manifest) (ManifestChanges, error) {
+	changes := ManifestChanges{Install: []string{}, Remove: []string{}, Hold: []string{}, VersionChanged: []string{}}
+
+	installedPackages, err := a.serviceAptDatabase.QueryHostImagePackages(ctx, map[string]interface{}{"installed": true}, "name", "ASC", 0, 0)
+	if err != nil {
+		return changes, err
+	}
+
+	installed := make(map[string]apt.Package, len(installedPackages))
+	for _, pkg := range installedPackages {
+		installed[pkg.Name] = pkg
+	}
+
+	wanted := make(map[string]bool, len(manifest.Packages))
+	for _, pkg := range manifest.Packages {
+		wanted[pkg.Name] = true
+
+		current, ok := installed[pkg.Name]
+		if !ok {
+			changes.Install = append(changes.Install, pkg.Name)
+			continue
+		}
+		if pkg.Version != "" && current.VersionInstalled != "" && current.VersionInstalled != pkg.Version {
+			changes.VersionChanged = append(changes.VersionChanged, fmt.Sprintf("%s: %s -> %s", pkg.Name, current.VersionInstalled, pkg.Version))
+		}
+	}
+
+	removing := make(map[string]bool)
+	for _, pkg := range installedPackages {
+		if pkg.InstallReason == apt.InstallReasonManual && !wanted[pkg.Name] {
+			removing[pkg.Name] = true
+		}
+	}
+	for _, name := range manifest.Removed {
+		if _, ok := installed[name]; ok {
+			removing[name] = true
+		}
+	}
+	for name := range removing {
+		changes.Remove = append(changes.Remove, name)
+	}
+	sort.Strings(changes.Remove)
+
+	held, err := a.serviceAptActions.GetHeldPackages(ctx)
+	if err != nil {
+		return changes, err
+	}
+
+	alreadyHeld := make(map[string]bool, len(held))
+	for _, name := range held {
+		alreadyHeld[name] = true
+	}
+	for _, name := range manifest.Held {
+		if !alreadyHeld[name] {
+			changes.Hold = append(changes.Hold, name)
+		}
+	}
+
+	return changes, nil
+}
+
+// ImageHistory история изменений образа. since и until ограничивают период сборки, status - статус записи.
+func (a *Actions) ImageHistory(ctx context.Context, imageName string, limit int64, offset int64, since time.Time, until time.Time,
+	status string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case "", service.ImageStatusBuilt, service.ImageStatusDeployed, service.ImageStatusSuperseded:
+	default:
+		return nil, fmt.Errorf(lib.T_("Unknown image status %s, allowed: %s"), status,
+			strings.Join([]string{service.ImageStatusBuilt, service.ImageStatusDeployed, service.ImageStatusSuperseded}, ", "))
+	}
+
+	filter := service.ImageHistoryFilter{
+		ImageName: imageName,
+		Since:     since,
+		Until:     until,
+		Status:    status,
+	}
+
+	history, err := a.serviceHostDatabase.GetImageHistoriesFiltered(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	totalCount, err := a.serviceHostDatabase.CountImageHistoriesFiltered(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(history)), len(history))
+
+	resp := reply.APIResponse{
+		Data: ImageHistoryResponse{
+			Message:    msg,
+			History:    history,
+			TotalCount: totalCount,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// OperationsHistory история операций с пакетами
+func (a *Actions) OperationsHistory(ctx context.Context, operation string, limit int64, offset int64) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation {
+	case "", "install", "remove", "update", "upgrade":
+	default:
+		return nil, fmt.Errorf(lib.T_("Unknown operation %s, allowed: install, remove, update, upgrade"), operation)
+	}
+
+	operations, err := a.serviceHostDatabase.GetOperationsFiltered(ctx, operation, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	totalCount, err := a.serviceHostDatabase.CountOperationsFiltered(ctx, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(operations)), len(operations))
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":    msg,
+			"operations": operations,
+			"totalCount": totalCount,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImageHistoryShow подробная информация об одной записи истории образа
+func (a *Actions) ImageHistoryShow(ctx context.Context, id int64) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := a.serviceHostDatabase.GetImageHistoryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.T_("History entry %d"), history.ID),
+			"history": history,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// GetBuildLog возвращает журнал сборки образа из записи истории historyID.
+func (a *Actions) GetBuildLog(ctx context.Context, historyID int64) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := a.serviceHostDatabase.GetImageHistoryByID(ctx, historyID)
+	if err != nil {
+		return nil, err
+	}
+	if history.BuildLogPath == "" {
+		return nil, reply.Errorf(reply.ErrorCodeNotFound, lib.T_("History entry %d has no build log"), historyID)
+	}
+
+	lines, err := service.ReadBuildLog(history.BuildLogPath, service.BuildLogMaxLines)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":      fmt.Sprintf(lib.T_("Build log of history entry %d"), historyID),
+			"buildLogPath": history.BuildLogPath,
+			"buildLog":     lines,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// LogTail возвращает последние lines строк журнала apm.
+func (a *Actions) LogTail(ctx context.Context, lines int) (*reply.APIResponse, error) {
+	if lines <= 0 {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("The number of lines must be positive"))
+	}
+
+	logLines, err := lib.TailLog(lines)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, reply.Errorf(reply.ErrorCodeNotFound, lib.T_("Error reading file %s: %v"), lib.Env.PathLogFile, err)
+	case errors.Is(err, os.ErrPermission):
+		return nil, reply.Errorf(reply.ErrorCodePermissionDenied, lib.T_("Error reading file %s: %v"), lib.Env.PathLogFile, err)
+	case err != nil:
+		return nil, fmt.Errorf(lib.T_("Error reading file %s: %v"), lib.Env.PathLogFile, err)
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.TN_("Last %d line of the log", "Last %d lines of the log", len(logLines)), len(logLines)),
+			"logPath": lib.Env.PathLogFile,
+			"log":     logLines,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImageRollbackList перечисляет поколения образа, на которые можно откатиться,
+// с пометкой, можно ли активировать поколение без загрузки из сети.
+func (a *Actions) ImageRollbackList(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	generations, err := a.serviceHostImage.RollbackList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.TN_("%d generation found", "%d generations found", len(generations)),
+				len(generations)),
+			"generations": generations,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// AddAptSourceLayer добавляет пользовательский источник apt в конфигурацию образа
+func (a *Actions) AddAptSourceLayer(ctx context.Context, sourceLine string, keyURL string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := a.serviceHostConfig.AddAptSource(sourceLine, keyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"message": lib.T_("Source added to the image configuration. To apply changes, run image apply"),
+		"source":  source,
+	}
+	a.addAptSourceWarnings(data, true)
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// RemoveAptSourceLayer удаляет пользовательский источник apt из конфигурации образа
+func (a *Actions) RemoveAptSourceLayer(ctx context.Context, id int) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := a.serviceHostConfig.RemoveAptSource(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": lib.T_("Source removed from the image configuration. To apply changes, run image apply"),
+			"source":  source,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ListAptSourceLayers возвращает пользовательские источники apt из конфигурации образа
+func (a *Actions) ListAptSourceLayers(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	sources := a.serviceHostConfig.Config.AptSources
+	if sources == nil {
+		sources = []service.AptSourceConfig{}
+	}
+
+	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(sources)), len(sources))
+
+	data := map[string]interface{}{
+		"message": msg,
+		"sources": sources,
+	}
+	a.addAptSourceWarnings(data, false)
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ImportDockerfile переносит Dockerfile, написанный без apm, в конфигурацию образа. Конфигурация заменяется
+// целиком, конструкции, которые она не может выразить, пропускаются с предупреждением.
+func (a *Actions) ImportDockerfile(ctx context.Context, dockerfilePath string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	if strings.TrimSpace(dockerfilePath) == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the path to the Dockerfile"))
+	}
+
+	content, err := os.ReadFile(dockerfilePath)
+	if err != nil {
+		return nil, reply.WithErrorCode(reply.ErrorCodeNotFound, fmt.Errorf(lib.T_("Error reading file %s: %v"), dockerfilePath, err))
+	}
+
+	cfg, warnings := service.ParseDockerfile(string(content))
+	if cfg.Image == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Dockerfile %s has no FROM instruction"), dockerfilePath)
+	}
+
+	a.serviceHostConfig.Config = &cfg
+	if err = a.serviceHostConfig.SaveConfig(); err != nil {
+		return nil, err
+	}
+
+	if warning := a.unknownConfigPackagesWarning(ctx); warning != "" {
+		warnings = append(warnings, warning)
+	}
+
+	data := map[string]interface{}{
+		"message": fmt.Sprintf(lib.T_("Dockerfile %s imported into the image configuration. To apply changes, run image apply"), dockerfilePath),
+		"config":  cfg,
+	}
+	if len(warnings) > 0 {
+		for _, warning := range warnings {
+			reply.Logger(ctx).Warning(warning)
+		}
+		data["warning"] = strings.Join(warnings, "\n")
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// AddEnvLayer задаёт переменную окружения в конфигурации образа
+func (a *Actions) AddEnvLayer(ctx context.Context, key string, value string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := a.serviceHostConfig.SetEnvVar(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": lib.T_("Environment variable added to the image configuration. To apply changes, run image apply"),
+			"env":     env,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// RemoveEnvLayer удаляет переменную окружения из конфигурации образа
+func (a *Actions) RemoveEnvLayer(ctx context.Context, key string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := a.serviceHostConfig.RemoveEnvVar(key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": lib.T_("Environment variable removed from the image configuration. To apply changes, run image apply"),
+			"env":     env,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ListEnvLayers возвращает переменные окружения из конфигурации образа
+func (a *Actions) ListEnvLayers(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	envVars := a.serviceHostConfig.Config.EnvVars
+	if envVars == nil {
+		envVars = []service.EnvVar{}
+	}
+
+	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(envVars)), len(envVars))
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": msg,
+			"envVars": envVars,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// PinPackageInConfig закрепляет пакет в конфигурации образа, при указании версии она устанавливается перед закреплением
+func (a *Actions) PinPackageInConfig(ctx context.Context, packageName string, version string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	if strings.TrimSpace(packageName) == "" {
+		return nil, fmt.Errorf(lib.T_("You must specify the package name, for example pin-package vim"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := a.serviceHostConfig.PinPackage(packageName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"message":     lib.T_("Package pinned in the image configuration. To apply changes, run image apply"),
+		"heldPackage": held,
+	}
+	if warning := a.unknownConfigPackagesWarning(ctx); warning != "" {
+		reply.Logger(ctx).Warning(warning)
+		data["warning"] = warning
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// UnpinPackageInConfig снимает закрепление пакета в конфигурации образа
+func (a *Actions) UnpinPackageInConfig(ctx context.Context, packageName string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := a.serviceHostConfig.UnpinPackage(packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":     lib.T_("Package unpinned in the image configuration. To apply changes, run image apply"),
+			"heldPackage": held,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ListPinnedPackages возвращает пакеты, закреплённые в конфигурации образа
+func (a *Actions) ListPinnedPackages(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	heldPackages := a.serviceHostConfig.Config.HeldPackages
+	if heldPackages == nil {
+		heldPackages = []service.HeldPackage{}
+	}
+
+	msg := fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(heldPackages)), len(heldPackages))
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":      msg,
+			"heldPackages": heldPackages,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// PinVersion закрепляет версию пакета через apt preferences с приоритетом priority. Версия должна быть
+// доступна в кэше apt.
+func (a *Actions) PinVersion(ctx context.Context, packageName string, version string, priority int) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	packageName = strings.TrimSpace(packageName)
+	version = strings.TrimSpace(version)
+	if packageName == "" || version == "" {
+		return nil, reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the package name and version, for example apt-pin set vim 9.1.0-alt1"))
+	}
+
+	if err = apt.ValidatePin(packageName, version, priority); err != nil {
+		return nil, reply.WithErrorCode(reply.ErrorCodeInvalidArgument, err)
+	}
+
+	// Версию с маской apt сопоставляет только при закреплении, проверить её установкой нельзя
+	if !strings.Contains(version, "*") {
+		_, aptErrors := a.serviceAptActions.Check(ctx, packageName+"="+version, "install")
+		if criticalError := apt.FindCriticalError(aptErrors); criticalError != nil {
+			return nil, criticalError
+		}
+	}
+
+	pin, err := apt.WritePin(packageName, version, priority)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.T_("Package %s pinned to version %s"), packageName, version),
+			"pin":     pin,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// UnpinVersion снимает закрепление версии пакета, удаляя его файл apt preferences.
+func (a *Actions) UnpinVersion(ctx context.Context, packageName string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	pin, err := apt.RemovePin(strings.TrimSpace(packageName))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.T_("Version pin of package %s removed"), pin.Package),
+			"pin":     pin,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// ListPins возвращает закрепления версий, созданные apt-pin set.
+func (a *Actions) ListPins(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	pins, err := apt.ListPins()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": fmt.Sprintf(lib.TN_("%d record found", "%d records found", len(pins)), len(pins)),
+			"pins":    pins,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// CheckImageConflicts проверяет, не вызовут ли пакеты на установку и удаление из конфигурации образа
+// конфликтов apt, не запуская сборку.
+func (a *Actions) CheckImageConflicts(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts, err := a.serviceHostConfig.CheckConfigConflicts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := lib.T_("No package conflicts found in the image configuration")
+	if len(conflicts) > 0 {
+		msg = fmt.Sprintf(lib.TN_("%d package conflict found", "%d package conflicts found", len(conflicts)), len(conflicts))
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":   msg,
+			"conflicts": conflicts,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// parseLabels разбирает метки вида key=value и проверяет их.
+func parseLabels(labels []string) (map[string]string, error) {
+	parsed := make(map[string]string, len(labels))
+	for _, label := range labels {
+		key, value, found := strings.Cut(label, "=")
+		key = strings.TrimSpace(key)
+		if !found {
+			return nil, fmt.Errorf(lib.T_("Invalid label %s, expected key=value"), label)
+		}
+		if err := service.ValidateLabel(key, value); err != nil {
+			return nil, err
+		}
+		parsed[key] = value
+	}
+
+	return parsed, nil
+}
+
+// AddLabel задаёт пользовательскую метку в конфигурации образа
+func (a *Actions) AddLabel(ctx context.Context, key string, value string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	label, err := a.serviceHostConfig.SetLabel(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": lib.T_("Label added to the image configuration. To apply changes, run image apply"),
+			"label":   label,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// RemoveLabel удаляет пользовательскую метку из конфигурации образа
+func (a *Actions) RemoveLabel(ctx context.Context, key string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	label, err := a.serviceHostConfig.RemoveLabel(key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": lib.T_("Label removed from the image configuration. To apply changes, run image apply"),
+			"label":   label,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// AddRepository добавляет репозиторий в источники apt конфигурации образа
+func (a *Actions) AddRepository(ctx context.Context, repoURL string, component string, keyURL string, vendor string) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err = a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := a.serviceHostConfig.AddRepository(repoURL, component, keyURL, vendor)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"message": lib.T_("Repository added to the image configuration. To apply changes, run image apply"),
+		"source":  source,
+	}
+	a.addAptSourceWarnings(data, true)
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// addAptSourceWarnings добавляет в ответ предупреждения об источниках apt без ключа подписи, logWarnings
+// дополнительно выводит их в журнал.
+func (a *Actions) addAptSourceWarnings(data map[string]interface{}, logWarnings bool) {
+	warnings := a.serviceHostConfig.AptSourceWarnings()
+	if len(warnings) == 0 {
+		return
+	}
+
+	if logWarnings {
+		for _, warning := range warnings {
+			lib.Log.Warning(warning)
+		}
+	}
+	data["warning"] = strings.Join(warnings, "\n")
+}
+
+// buildResult возвращает результаты скриптов последней сборки образа вместе с предупреждением warning,
+// ошибка скрипта postBuild добавляется к предупреждениям.
+func (a *Actions) buildResult(warning string) BuildResult {
+	result := BuildResult{
+		BuildHooks: a.serviceHostImage.HookResults(),
+		Warning:    warning,
+	}
+
+	for _, hook := range result.BuildHooks {
+		if hook.Hook != service.HookPostBuild || hook.Error == "" {
+			continue
+		}
+
+		if result.Warning != "" {
+			result.Warning += "\n" + hook.Error
+		} else {
+			result.Warning = hook.Error
+		}
+	}
+
+	return result
+}
+
+// checkRoot проверяет, запущен ли установщик от имени root
+func (a *Actions) checkRoot() error {
+	if syscall.Geteuid() != 0 {
+		return reply.Errorf(reply.ErrorCodeNotRoot, lib.T_("Elevated rights are required to perform this action. Please use sudo or su"))
+	}
+
+	if lib.IsAtomic() {
+		err := a.serviceHostImage.EnableOverlay()
+		if err != nil {
 			return err
 		}
 	}
@@ -806,15 +3233,108 @@ func (a *Actions) checkRoot() error {
 
 // applyChange применяет изменения к образу системы
 func (a *Actions) applyChange(ctx context.Context, packages []string, isInstall bool) error {
-	if !lib.Env.IsAtomic {
-		return fmt.Errorf(lib.T_("This option is only available for an atomic system"))
+	if !lib.IsAtomic() {
+		return reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	err := a.writeConfigChanges(ctx, packages, isInstall, false)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err = a.checkBaseSignature(a.serviceHostConfig.Config.Image, false); err != nil {
+		return err
+	}
+
+	conflicts, err := a.serviceHostConfig.CheckConfigConflicts(ctx)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return &service.ConfigConflictsError{Conflicts: conflicts}
+	}
+
+	timings := reply.TimingsFromContext(ctx)
+	stopTiming := timings.Start(reply.TimingDockerfile)
+	err = a.serviceHostConfig.GenerateDockerfile("")
+	stopTiming()
+	if err != nil {
+		return err
+	}
+
+	stopTiming = timings.Start(reply.TimingImageBuild)
+	err = a.serviceHostImage.BuildAndSwitch(ctx, true, *a.serviceHostConfig.Config, false, service.BuildOptions{PullAlways: true})
+	stopTiming()
+	if err != nil {
+		return a.buildError(err, 0)
+	}
+
+	return nil
+}
+
+// writeConfigChanges записывает пакеты в списки установки и удаления конфигурации образа по правилам
+// resolveConfigChanges.
+func (a *Actions) writeConfigChanges(ctx context.Context, packages []string, isInstall bool, onlyApplied bool) error {
+	err := a.serviceHostConfig.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	for _, change := range a.resolveConfigChanges(ctx, packages, isInstall, onlyApplied) {
+		if change.install {
+			err = a.serviceHostConfig.AddInstallPackage(change.name)
+		} else {
+			err = a.serviceHostConfig.AddRemovePackage(change.name)
+		}
+		if err != nil {
+			return err
+		}
 	}
 
+	return nil
+}
+
+// validateConfigChanges проверяет пакеты конфигурации образа в том виде, который она примет после
+// writeConfigChanges. Вызывается до транзакции apt, поэтому конфигурация не изменяется.
+func (a *Actions) validateConfigChanges(ctx context.Context, packages []string, isInstall bool) error {
 	err := a.serviceHostConfig.LoadConfig()
 	if err != nil {
 		return err
 	}
 
+	config := *a.serviceHostConfig.Config
+	install := slices.Clone(config.Packages.Install)
+	remove := slices.Clone(config.Packages.Remove)
+	for _, change := range a.resolveConfigChanges(ctx, packages, isInstall, false) {
+		if change.install {
+			remove = slices.DeleteFunc(remove, func(name string) bool { return name == change.name })
+			if !slices.Contains(install, change.name) {
+				install = append(install, change.name)
+			}
+		} else {
+			install = slices.DeleteFunc(install, func(name string) bool { return name == change.name })
+			if !slices.Contains(remove, change.name) {
+				remove = append(remove, change.name)
+			}
+		}
+	}
+	config.Packages.Install = install
+	config.Packages.Remove = remove
+
+	return a.checkConfigPackages(ctx, config)
+}
+
+// configChange пакет, который попадёт в список установки или удаления конфигурации образа.
+type configChange struct {
+	name    string
+	install bool
+}
+
+// resolveConfigChanges определяет по аргументам install и remove, в какие списки конфигурации попадут пакеты.
+// Суффиксы + и - задают действие для отдельного пакета. С onlyApplied пропускаются пакеты, состояние
+// которых в системе не совпадает с запрошенным.
+func (a *Actions) resolveConfigChanges(ctx context.Context, packages []string, isInstall bool, onlyApplied bool) []configChange {
+	var changes []configChange
 	for _, pkg := range packages {
 		if len(pkg) == 0 {
 			continue
@@ -823,42 +3343,187 @@ func (a *Actions) applyChange(ctx context.Context, packages []string, isInstall
 		originalPkg := pkg
 		canonicalPkg := pkg
 
-		if _, errFull := a.serviceAptDatabase.GetPackageByName(ctx, canonicalPkg); errFull != nil {
+		packageInfo, errFull := a.serviceAptDatabase.GetPackageByName(ctx, canonicalPkg)
+		if errFull != nil {
 			for len(canonicalPkg) > 0 && (canonicalPkg[len(canonicalPkg)-1] == '+' || canonicalPkg[len(canonicalPkg)-1] == '-') {
 				canonicalPkg = canonicalPkg[:len(canonicalPkg)-1]
-				if _, errTmp := a.serviceAptDatabase.GetPackageByName(ctx, canonicalPkg); errTmp == nil {
+				var errTmp error
+				if packageInfo, errTmp = a.serviceAptDatabase.GetPackageByName(ctx, canonicalPkg); errTmp == nil {
+					errFull = nil
 					break
 				}
 			}
 		}
 
+		install := isInstall
 		if originalPkg[len(originalPkg)-1] == '+' {
-			err = a.serviceHostConfig.AddInstallPackage(canonicalPkg)
+			install = true
 		} else if originalPkg[len(originalPkg)-1] == '-' {
-			err = a.serviceHostConfig.AddRemovePackage(canonicalPkg)
-		} else {
-			if isInstall {
-				err = a.serviceHostConfig.AddInstallPackage(canonicalPkg)
-			} else {
-				err = a.serviceHostConfig.AddRemovePackage(canonicalPkg)
-			}
+			install = false
 		}
-		if err != nil {
-			return err
+
+		if onlyApplied && (errFull != nil || packageInfo.Installed != install) {
+			continue
+		}
+
+		changes = append(changes, configChange{name: canonicalPkg, install: install})
+	}
+
+	return changes
+}
+
+// buildError заменяет ошибку тайм-аута сборки понятным пользователю сообщением. buildTimeout - тайм-аут этой сборки,
+// нулевое значение означает тайм-аут из конфигурации.
+func (a *Actions) buildError(err error, buildTimeout time.Duration) error {
+	if errors.Is(err, service.ErrBuildTimeout) {
+		if buildTimeout <= 0 {
+			buildTimeout = a.serviceHostImage.BuildTimeout()
+		}
+		return fmt.Errorf(lib.T_("The image build did not finish within %s and was stopped. Increase imageBuildTimeout in the configuration or use --timeout"),
+			buildTimeout)
+	}
+
+	return err
+}
+
+// saveOperation записывает результат операции в историю. Ошибка записи не прерывает основную операцию.
+func (a *Actions) saveOperation(ctx context.Context, operation string, packages []string, resp *reply.APIResponse, opErr error) {
+	record := service.OperationRecord{
+		Operation: operation,
+		Packages:  packages,
+		Success:   opErr == nil,
+	}
+
+	if opErr != nil {
+		record.Message = opErr.Error()
+	} else if resp != nil {
+		data, ok := resp.Data.(map[string]interface{})
+		if !ok {
+			data = reply.StructData(resp.Data)
 		}
+		record.Message, _ = data["message"].(string)
 	}
 
-	err = a.serviceHostConfig.GenerateDockerfile()
+	if err := a.serviceHostDatabase.SaveOperation(ctx, record); err != nil {
+		lib.Log.Debug(err.Error())
+	}
+}
+
+// checkBaseSignature проверяет политику подписи базового образа baseImage.
+// При включённом requireSignedBase сборка из неподписанного образа запрещена, если не передан allowUnsigned,
+// в этом случае возвращается предупреждение для вывода пользователю.
+func (a *Actions) checkBaseSignature(baseImage string, allowUnsigned bool) (service.SignatureStatus, string, error) {
+	status, err := service.VerifyBaseImageSignature(baseImage)
 	if err != nil {
-		return err
+		return status, "", err
 	}
 
-	err = a.serviceHostImage.BuildAndSwitch(ctx, true, *a.serviceHostConfig.Config, false)
+	switch status.Status {
+	case service.SignatureRejected:
+		return status, "", &service.SignatureError{Code: service.ErrBaseImageRejected, Status: status}
+	case service.SignatureUnsigned:
+		if !lib.Env.RequireSignedBase {
+			return status, "", nil
+		}
+
+		if !allowUnsigned {
+			return status, "", &service.SignatureError{Code: service.ErrUnsignedBaseImage, Status: status}
+		}
+
+		warning := fmt.Sprintf(lib.T_("WARNING: the signature of the base image %s is NOT verified, requireSignedBase was overridden by --insecure-allow-unsigned"), status.Image)
+		lib.Log.Warning(warning)
+		return status, warning, nil
+	}
+
+	return status, "", nil
+}
+
+// validateConfigPackages проверяет, что все пакеты из конфигурации образа существуют в репозитории.
+// Для неизвестных пакетов предлагаются ближайшие по написанию варианты.
+func (a *Actions) validateConfigPackages(ctx context.Context) error {
+	return a.checkConfigPackages(ctx, *a.serviceHostConfig.Config)
+}
+
+// checkConfigPackages проверяет, что все пакеты конфигурации config существуют в репозитории.
+func (a *Actions) checkConfigPackages(ctx context.Context, config service.Config) error {
+	unknown, err := a.findUnknownConfigPackages(ctx, config)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	return reply.Errorf(reply.ErrorCodePackageNotFound, lib.T_("Packages not found in the repository: %s. If they are only available in repositories configured inside the image, use --skip-validation"),
+		strings.Join(unknown, "; "))
+}
+
+// unknownConfigPackagesWarning проверяет пакеты только что записанной конфигурации и возвращает предупреждение
+// о неизвестных. Запись не отменяется: пакеты могут быть доступны только в репозиториях внутри образа.
+func (a *Actions) unknownConfigPackagesWarning(ctx context.Context) string {
+	unknown, err := a.findUnknownConfigPackages(ctx, *a.serviceHostConfig.Config)
+	if err != nil {
+		lib.Log.Debug(err.Error())
+		return ""
+	}
+
+	if len(unknown) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(lib.T_("Packages not found in the repository: %s. The image build will fail unless they are available in repositories configured inside the image"),
+		strings.Join(unknown, "; "))
+}
+
+// findUnknownConfigPackages возвращает описания пакетов конфигурации, отсутствующих в базе, с вариантами замены.
+// Проверяются списки установки и удаления и закреплённые пакеты.
+func (a *Actions) findUnknownConfigPackages(ctx context.Context, config service.Config) ([]string, error) {
+	err := a.validateDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var allNames []string
+	var unknown []string
+	packages := append(append([]string{}, config.Packages.Install...), config.Packages.Remove...)
+	for _, held := range config.HeldPackages {
+		if !slices.Contains(packages, held.Name) {
+			packages = append(packages, held.Name)
+		}
+	}
+	for _, pkg := range packages {
+		if _, err = a.serviceAptDatabase.GetPackageByName(ctx, pkg); err == nil {
+			continue
+		}
+
+		filters := map[string]interface{}{
+			"provides": pkg,
+		}
+		alternativePackages, errFind := a.serviceAptDatabase.QueryHostImagePackages(ctx, filters, "", "", 1, 0)
+		if errFind != nil {
+			return nil, errFind
+		}
+		if len(alternativePackages) > 0 {
+			continue
+		}
+
+		if allNames == nil {
+			allNames, err = a.serviceAptDatabase.GetPackageNames(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		suggestions := helper.ClosestMatches(pkg, allNames, 3)
+		if len(suggestions) > 0 {
+			unknown = append(unknown, fmt.Sprintf(lib.T_("%s (maybe: %s)"), pkg, strings.Join(suggestions, ", ")))
+		} else {
+			unknown = append(unknown, pkg)
+		}
+	}
+
+	return unknown, nil
 }
 
 // validateDB проверяет, существует ли база данных
@@ -872,7 +3537,7 @@ func (a *Actions) validateDB(ctx context.Context) error {
 
 		_, err = a.serviceAptActions.Update(ctx)
 		if err != nil {
-			return err
+			return reply.WithErrorCode(reply.ErrorCodeDBEmpty, err)
 		}
 	}
 
@@ -889,16 +3554,22 @@ func (a *Actions) updateAllPackagesDB(ctx context.Context) error {
 		return err
 	}
 
-	err = a.serviceAptDatabase.SyncPackageInstallationInfo(ctx, installedPackages)
+	reasons, err := a.serviceAptActions.GetInstallReasons(ctx)
+	if err != nil {
+		lib.Log.Warning(err.Error())
+	}
+
+	err = a.serviceAptDatabase.SyncPackageInstallationInfo(ctx, installedPackages, reasons)
 	if err != nil {
 		return err
 	}
+	apt.RecordSyncTime()
 
 	return nil
 }
 
 func (a *Actions) getImageStatus(ctx context.Context) (ImageStatus, error) {
-	hostImage, err := a.serviceHostImage.GetHostImage()
+	hostImage, err := a.serviceHostImage.GetHostImage(ctx)
 	if err != nil {
 		return ImageStatus{}, err
 	}
@@ -908,16 +3579,36 @@ func (a *Actions) getImageStatus(ctx context.Context) (ImageStatus, error) {
 		return ImageStatus{}, err
 	}
 
-	if hostImage.Status.Booted.Image.Image.Transport == "containers-storage" {
+	stagedStatus := ""
+	if labels := hostImage.StagedLabels; labels != nil {
+		stagedStatus = fmt.Sprintf(lib.T_(". After reboot, the image built %s with configuration hash %s will be loaded"), labels.Built, labels.ConfigHash)
+	}
+
+	if strings.HasPrefix(hostImage.Status.Booted.Image.Image.Transport, "containers-storage") {
+		status := lib.T_("Modified image. Configuration file: ") + lib.Env.PathImageFile
+
+		// Для образов, собранных apm, показываем метаданные сборки и связанную запись истории
+		var history *service.ImageHistory
+		if labels := hostImage.Labels; labels != nil {
+			status += fmt.Sprintf(lib.T_(". Built %s by apm %s, configuration hash %s"), labels.Built, labels.Version, labels.ConfigHash)
+			if labels.ConfigHash != "" {
+				history, err = a.serviceHostDatabase.GetImageHistoryByConfigHash(ctx, labels.ConfigHash)
+				if err != nil {
+					lib.Log.Debug(err.Error())
+				}
+			}
+		}
+
 		return ImageStatus{
-			Status: lib.T_("Modified image. Configuration file: ") + lib.Env.PathImageFile,
-			Image:  hostImage,
-			Config: *a.serviceHostConfig.Config,
+			Status:  status + stagedStatus,
+			Image:   hostImage,
+			Config:  *a.serviceHostConfig.Config,
+			History: history,
 		}, nil
 	}
 
 	return ImageStatus{
-		Status: lib.T_("Cloud image without changes"),
+		Status: lib.T_("Cloud image without changes") + stagedStatus,
 		Image:  hostImage,
 		Config: *a.serviceHostConfig.Config,
 	}, nil
@@ -928,7 +3619,52 @@ type ShortPackageResponse struct {
 	Name        string `json:"name"`
 	Installed   bool   `json:"installed"`
 	Version     string `json:"version"`
+	Reason      string `json:"reason,omitempty"`
 	Description string `json:"description"`
+	// LastChangelog заполняется только при запросе списка с журналом изменений
+	LastChangelog *string `json:"lastChangelog,omitempty"`
+	// MatchedOn поле, совпавшее с поисковым запросом, заполняется только при поиске
+	MatchedOn string `json:"matchedOn,omitempty"`
+}
+
+// UpgradablePackageResponse представление пакета в списке доступных обновлений.
+type UpgradablePackageResponse struct {
+	Name             string `json:"name"`
+	Version          string `json:"version"`
+	VersionInstalled string `json:"versionInstalled"`
+	Description      string `json:"description"`
+}
+
+// SearchPackageResponse полное представление пакета в результатах поиска.
+type SearchPackageResponse struct {
+	apt.Package
+	MatchedOn string `json:"matchedOn"`
+}
+
+// withChangelog добавляет журнал изменений в сокращённый вывод списка пакетов. Для пакетов без журнала
+// в базе он загружается в фоне и появится в следующих ответах, текущий ответ при этом не ждёт загрузки.
+func (a *Actions) withChangelog(shortList []ShortPackageResponse, packages []apt.Package) []ShortPackageResponse {
+	a.fetchMissingChangelogs(packages)
+
+	for i := range shortList {
+		changelog := packages[i].Changelog
+		shortList[i].LastChangelog = &changelog
+	}
+
+	return shortList
+}
+
+// fetchMissingChangelogs запускает фоновую загрузку журналов изменений пакетов, которых ещё нет в базе.
+func (a *Actions) fetchMissingChangelogs(packages []apt.Package) {
+	var missing []string
+	for _, pkg := range packages {
+		if pkg.Changelog == "" {
+			missing = append(missing, pkg.Name)
+		}
+	}
+	if len(missing) > 0 {
+		go a.serviceAptActions.FetchMissingChangelogs(context.Background(), missing)
+	}
 }
 
 // FormatPackageOutput принимает данные (один пакет или срез пакетов) и флаг full.
@@ -940,28 +3676,35 @@ func (a *Actions) FormatPackageOutput(data interface{}, full bool) interface{} {
 		if full {
 			return v
 		}
-		return ShortPackageResponse{
-			Name:        v.Name,
-			Version:     v.Version,
-			Installed:   v.Installed,
-			Description: v.Description,
-		}
+		return shortPackage(v)
 	// Если передан срез пакетов
 	case []apt.Package:
 		if full {
 			return v
 		}
-		shortList := make([]ShortPackageResponse, 0, len(v))
-		for _, pkg := range v {
-			shortList = append(shortList, ShortPackageResponse{
-				Name:        pkg.Name,
-				Version:     pkg.Version,
-				Installed:   pkg.Installed,
-				Description: pkg.Description,
-			})
-		}
-		return shortList
+		return shortPackages(v)
 	default:
 		return nil
 	}
 }
+
+// shortPackage возвращает сокращённое представление пакета.
+func shortPackage(pkg apt.Package) ShortPackageResponse {
+	return ShortPackageResponse{
+		Name:        pkg.Name,
+		Version:     pkg.Version,
+		Installed:   pkg.Installed,
+		Reason:      pkg.InstallReason,
+		Description: pkg.Description,
+	}
+}
+
+// shortPackages возвращает сокращённые представления пакетов.
+func shortPackages(packages []apt.Package) []ShortPackageResponse {
+	shortList := make([]ShortPackageResponse, 0, len(packages))
+	for _, pkg := range packages {
+		shortList = append(shortList, shortPackage(pkg))
+	}
+
+	return shortList
+}
@@ -75,6 +75,20 @@ type Package struct {
 	Description      string   `json:"description"`
 	Changelog        string   `json:"lastChangelog"`
 	Installed        bool     `json:"installed"`
+	InstallReason    string   `json:"reason,omitempty"`
+}
+
+// Причины установки пакета по данным apt-mark.
+const (
+	InstallReasonManual = "manual"
+	InstallReasonAuto   = "auto"
+)
+
+// InstallProgress описывает прогресс установки отдельного пакета.
+type InstallProgress struct {
+	Package     string `json:"package"`
+	Stage       string `json:"stage"`
+	PercentDone int    `json:"percentDone"`
 }
 
 const (
@@ -95,7 +109,7 @@ func (a *Actions) Install(ctx context.Context, packageName string) []error {
 	}
 
 	command := fmt.Sprintf("%s apt-get -y install %s", lib.Env.CommandPrefix, packageName)
-	err := a.commandWithProgress(ctx, command, typeProcess)
+	err := a.commandWithProgress(ctx, command, typeProcess, nil)
 	if err != nil {
 		return err
 	}
@@ -103,14 +117,35 @@ func (a *Actions) Install(ctx context.Context, packageName string) []error {
 	return nil
 }
 
-func (a *Actions) Remove(ctx context.Context, packageName string) []error {
+// InstallWithProgress устанавливает пакеты, отправляя в progressCh прогресс по каждому пакету.
+// Канал закрывается по завершении установки.
+func (a *Actions) InstallWithProgress(ctx context.Context, packageNames string, progressCh chan<- InstallProgress) error {
+	defer close(progressCh)
+
 	syncAptMutex.Lock()
 	defer syncAptMutex.Unlock()
 	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("system.Working"))
 	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("system.Working"))
 
+	typeProcess := typeInstall
+	if hasChangePackage(packageNames) {
+		typeProcess = typeChanged
+	}
+
+	command := fmt.Sprintf("%s apt-get -y install %s", lib.Env.CommandPrefix, packageNames)
+	errList := a.commandWithProgress(ctx, command, typeProcess, progressCh)
+
+	return FindCriticalError(errList)
+}
+
+func (a *Actions) Remove(ctx context.Context, packageName string) []error {
+	syncAptMutex.Lock()
+	defer syncAptMutex.Unlock()
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("system.Working"), reply.WithPhase(reply.EventPhaseRemove))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("system.Working"), reply.WithPhase(reply.EventPhaseRemove))
+
 	command := fmt.Sprintf("%s apt-get -y remove %s", lib.Env.CommandPrefix, packageName)
-	err := a.commandWithProgress(ctx, command, typeRemove)
+	err := a.commandWithProgress(ctx, command, typeRemove, nil)
 	if err != nil {
 		return err
 	}
@@ -118,14 +153,20 @@ func (a *Actions) Remove(ctx context.Context, packageName string) []error {
 	return nil
 }
 
-// CommandWithProgress запускает команду с прогрессом
-func (a *Actions) commandWithProgress(ctx context.Context, command string, typeProcess int) []error {
+// CommandWithProgress запускает команду с прогрессом. Если progressCh задан, в него отправляется
+// прогресс по каждому пакету.
+func (a *Actions) commandWithProgress(ctx context.Context, command string, typeProcess int, progressCh chan<- InstallProgress) []error {
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Env = []string{"LC_ALL=C"}
 
 	// Запускаем команду через pty для захвата вывода в реальном времени.
+	// pty.Start запускает её в новой сессии, поэтому при отмене сигнал получает вся её группа процессов.
+	lib.KillGroupOnCancel(cmd)
+	defer lib.TrackCommand()()
+	finish := lib.LogCommand(cmd)
 	ptmx, err := pty.Start(cmd)
 	if err != nil {
+		finish(err)
 		return []error{err}
 	}
 	defer ptmx.Close()
@@ -147,14 +188,24 @@ func (a *Actions) commandWithProgress(ctx context.Context, command string, typeP
 	// Регулярное выражение для распознавания прогресса установки.
 	// Пример строки: "1: erlang-otp-1:26.2.5.3-alt2  ########## [ 25%]"
 	installRegex := regexp.MustCompile(`^(?P<step>\d+):\s+(?P<pkg>[\w\-\:\+]+).*?\[\s*(?P<percent>\d+)%\]`)
+	// Регулярное выражение для распознавания стадий установки пакета.
+	// Пример строки: "Unpacking vim (2:9.1-alt1) ..."
+	stageRegex := regexp.MustCompile(`^(?P<stage>Preparing to unpack|Unpacking|Setting up)\s+(?:\S*/)?(?P<pkg>[\w\-\.\+]+?)(?:[_:]\S+)?(?:\s|$)`)
+	stagePercent := map[string]int{
+		"Preparing to unpack": 25,
+		"Unpacking":           50,
+		"Setting up":          100,
+	}
 
 	// Мапы: ключ – уникальное имя события, значение – чистое имя пакета.
 	downloadEvents := make(map[string]string)
 	installEvents := make(map[string]string)
 
 	textStatus := lib.T_("Installation")
+	phase := reply.EventPhaseInstall
 	if typeProcess == typeRemove {
 		textStatus = lib.T_("Removal")
+		phase = reply.EventPhaseRemove
 	} else if typeProcess == typeChanged {
 		textStatus = lib.T_("Change")
 	}
@@ -172,32 +223,43 @@ func (a *Actions) commandWithProgress(ctx context.Context, command string, typeP
 				match := downloadRegex.FindStringSubmatch(line)
 				pkgName := match[downloadRegex.SubexpIndex("pkg")]
 				// Уникальное имя события
-				eventName := fmt.Sprintf("system.downloadProgress-%s", pkgName)
+				eventName := "system.downloadProgress-" + pkgName
 				downloadEvents[eventName] = pkgName
 
 				percentStr := match[downloadRegex.SubexpIndex("local")]
 				if percent, err := strconv.Atoi(percentStr); err == nil {
 					reply.CreateEventNotification(ctx, reply.StateBefore,
-						reply.WithEventName(eventName),
-						reply.WithProgress(true),
-						reply.WithProgressPercent(float64(percent)),
-						reply.WithEventView(fmt.Sprintf(lib.T_("Downloading: %s"), pkgName)),
+						reply.WithEventName("system.downloadProgress-"+pkgName),
+						reply.WithProgress(float64(percent)),
+						reply.WithMessage(fmt.Sprintf(lib.T_("Downloading: %s"), pkgName)),
 					)
 				}
 			} else if installRegex.MatchString(line) {
 				match := installRegex.FindStringSubmatch(line)
 				pkgName := match[installRegex.SubexpIndex("pkg")]
-				eventName := fmt.Sprintf("system.installProgress")
+				eventName := "system.installProgress"
 				installEvents[eventName] = pkgName
 
 				percentStr := match[installRegex.SubexpIndex("percent")]
 				if percent, err := strconv.Atoi(percentStr); err == nil {
 					reply.CreateEventNotification(ctx, reply.StateBefore,
-						reply.WithEventName(eventName),
-						reply.WithProgress(true),
-						reply.WithProgressPercent(float64(percent)),
-						reply.WithEventView(fmt.Sprintf("%s: %s", textStatus, pkgName)),
+						reply.WithEventName("system.installProgress"),
+						reply.WithPhase(phase),
+						reply.WithProgress(float64(percent)),
+						reply.WithMessage(fmt.Sprintf("%s: %s", textStatus, pkgName)),
 					)
+
+					if progressCh != nil {
+						progressCh <- InstallProgress{Package: pkgName, Stage: textStatus, PercentDone: percent}
+					}
+				}
+			} else if progressCh != nil && stageRegex.MatchString(line) {
+				match := stageRegex.FindStringSubmatch(line)
+				stage := match[stageRegex.SubexpIndex("stage")]
+				progressCh <- InstallProgress{
+					Package:     match[stageRegex.SubexpIndex("pkg")],
+					Stage:       stage,
+					PercentDone: stagePercent[stage],
 				}
 			}
 
@@ -206,17 +268,16 @@ func (a *Actions) commandWithProgress(ctx context.Context, command string, typeP
 				for event, pkg := range downloadEvents {
 					reply.CreateEventNotification(ctx, reply.StateAfter,
 						reply.WithEventName(event),
-						reply.WithProgress(true),
+						reply.WithProgress(100),
 						reply.WithProgressDoneText(pkg),
-						reply.WithProgressPercent(100),
 					)
 				}
 				for event, pkg := range installEvents {
 					reply.CreateEventNotification(ctx, reply.StateAfter,
 						reply.WithEventName(event),
-						reply.WithProgress(true),
+						reply.WithPhase(phase),
+						reply.WithProgress(100),
 						reply.WithProgressDoneText(pkg),
-						reply.WithProgressPercent(100),
 					)
 				}
 			}
@@ -224,7 +285,9 @@ func (a *Actions) commandWithProgress(ctx context.Context, command string, typeP
 	}()
 
 	// Ожидаем завершения выполнения команды.
-	if err = cmd.Wait(); err != nil {
+	err = cmd.Wait()
+	finish(err)
+	if err != nil {
 		wg.Wait()
 		aptErrors := ErrorLinesAnalyseAll(outputLines)
 		if len(aptErrors) > 0 {
@@ -259,7 +322,7 @@ func (a *Actions) Check(ctx context.Context, packageName string, aptCommand stri
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Env = []string{"LC_ALL=C"}
 
-	output, err := cmd.CombinedOutput()
+	output, err := lib.CommandCombinedOutput(cmd)
 	outputStr := string(output)
 	lines := strings.Split(outputStr, "\n")
 	aptErrors := ErrorLinesAnalyseAll(lines)
@@ -279,7 +342,7 @@ func (a *Actions) Check(ctx context.Context, packageName string, aptCommand stri
 	}
 
 	if err != nil {
-		lib.Log.Errorf(lib.T_("Package verification error: %s"), outputStr)
+		reply.Logger(ctx).WithField("package", packageName).Errorf(lib.T_("Package verification error: %s"), outputStr)
 		return PackageChanges{}, []error{fmt.Errorf(lib.T_("Package verification error: %v"), err)}
 	}
 
@@ -307,7 +370,8 @@ func (a *Actions) Update(ctx context.Context) ([]Package, error) {
 	if err != nil {
 		return nil, fmt.Errorf(lib.T_("Error opening stdout pipe: %w"), err)
 	}
-	if err := cmd.Start(); err != nil {
+	wait, err := lib.CommandStart(cmd)
+	if err != nil {
 		return nil, fmt.Errorf(lib.T_("Error executing command: %w"), err)
 	}
 
@@ -419,7 +483,8 @@ func (a *Actions) Update(ctx context.Context) ([]Package, error) {
 		}
 		return nil, fmt.Errorf(lib.T_("Scanner error: %w"), err)
 	}
-	if err = cmd.Wait(); err != nil {
+	err = wait()
+	if err != nil {
 		return nil, fmt.Errorf(lib.T_("Command execution error: %w"), err)
 	}
 	for i := range packages {
@@ -436,6 +501,7 @@ func (a *Actions) Update(ctx context.Context) ([]Package, error) {
 	if err != nil {
 		return nil, err
 	}
+	RecordSyncTime()
 
 	return packages, nil
 }
@@ -461,10 +527,17 @@ func (a *Actions) updateInstalledInfo(ctx context.Context, packages []Package) (
 		return nil, err
 	}
 
+	// Отсутствие apt-mark не должно мешать обновлению базы пакетов
+	reasons, err := a.GetInstallReasons(ctx)
+	if err != nil {
+		lib.Log.Warning(err.Error())
+	}
+
 	for i, pkg := range packages {
 		if version, found := installed[pkg.Name]; found {
 			packages[i].Installed = true
 			packages[i].VersionInstalled = version
+			packages[i].InstallReason = reasons[pkg.Name]
 		}
 	}
 
@@ -477,7 +550,7 @@ func (a *Actions) GetInstalledPackages(ctx context.Context) (map[string]string,
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Env = []string{"LC_ALL=C"}
 
-	output, err := cmd.CombinedOutput()
+	output, err := lib.CommandCombinedOutput(cmd)
 	if err != nil {
 		return nil, fmt.Errorf(lib.T_("Error executing the rpm -qia command: %w"), err)
 	}
@@ -531,6 +604,185 @@ func (a *Actions) GetInstalledPackages(ctx context.Context) (map[string]string,
 	return installed, nil
 }
 
+// GetPackageLicenses возвращает карту, где ключ – имя установленного пакета, а значение – его лицензия.
+// В apt-cache show поля лицензии нет, поэтому лицензии читаются одним запросом к базе rpm.
+func (a *Actions) GetPackageLicenses(ctx context.Context) (map[string]string, error) {
+	command := fmt.Sprintf("%s rpm -qa --queryformat '%%{NAME}\t%%{LICENSE}\n'", lib.Env.CommandPrefix)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = []string{"LC_ALL=C"}
+
+	output, err := lib.CommandOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Error executing the rpm -qa command: %w"), err)
+	}
+
+	licenses := make(map[string]string)
+	for _, line := range strings.Split(string(output), "\n") {
+		name, license, found := strings.Cut(line, "\t")
+		if !found || name == "" {
+			continue
+		}
+		if license = strings.TrimSpace(license); license != "(none)" {
+			licenses[name] = license
+		}
+	}
+
+	return licenses, nil
+}
+
+// GetSecurityUpgrades возвращает пакеты, обновления которых приходят из репозиториев безопасности.
+// Репозиторий определяется по строкам Inst вывода apt-get -s upgrade.
+func (a *Actions) GetSecurityUpgrades(ctx context.Context) ([]string, error) {
+	command := fmt.Sprintf("%s apt-get -s upgrade", lib.Env.CommandPrefix)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = []string{"LC_ALL=C"}
+
+	output, err := lib.CommandCombinedOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Package verification error: %v"), err)
+	}
+
+	return parseSecurityUpgrades(string(output)), nil
+}
+
+// parseSecurityUpgrades выбирает из вывода apt-get -s имена пакетов, источник которых содержит security.
+func parseSecurityUpgrades(output string) []string {
+	packages := []string{}
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "Inst" || !strings.Contains(strings.ToLower(line), "security") {
+			continue
+		}
+
+		if !seen[fields[1]] {
+			seen[fields[1]] = true
+			packages = append(packages, fields[1])
+		}
+	}
+
+	return packages
+}
+
+// GetMarkedPackages возвращает пакеты, отмеченные как установленные вручную (manual) или автоматически (auto).
+func (a *Actions) GetMarkedPackages(ctx context.Context, reason string) ([]string, error) {
+	command := fmt.Sprintf("%s apt-mark show%s", lib.Env.CommandPrefix, reason)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = []string{"LC_ALL=C"}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := lib.CommandOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Error executing the apt-mark show%s command: %v, stderr: %s"), reason, err, stderr.String())
+	}
+
+	packages := []string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			packages = append(packages, name)
+		}
+	}
+
+	return packages, nil
+}
+
+// Отметка apt-mark для закреплённых пакетов, используется с GetMarkedPackages.
+const markHold = "hold"
+
+// GetHeldPackages возвращает пакеты, закреплённые через apt-mark hold.
+func (a *Actions) GetHeldPackages(ctx context.Context) ([]string, error) {
+	return a.GetMarkedPackages(ctx, markHold)
+}
+
+// HoldPackages закрепляет пакеты через apt-mark hold.
+func (a *Actions) HoldPackages(ctx context.Context, packages []string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	command := fmt.Sprintf("%s apt-mark hold %s", lib.Env.CommandPrefix, strings.Join(packages, " "))
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = []string{"LC_ALL=C"}
+
+	output, err := lib.CommandCombinedOutput(cmd)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Error executing the apt-mark hold command: %v, output: %s"), err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// maxChangelogFetch сколько журналов изменений загружается за один вызов FetchMissingChangelogs.
+const maxChangelogFetch = 20
+
+// changelogFetching пакеты, журнал изменений которых загружается в данный момент.
+var changelogFetching sync.Map
+
+// GetChangelog возвращает последнюю запись журнала изменений пакета из apt-get changelog.
+func (a *Actions) GetChangelog(ctx context.Context, packageName string) (string, error) {
+	command := fmt.Sprintf("%s apt-get changelog %s", lib.Env.CommandPrefix, packageName)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = []string{"LC_ALL=C"}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := lib.CommandOutput(cmd)
+	if err != nil {
+		return "", fmt.Errorf(lib.T_("Error executing the apt-get changelog command: %v, stderr: %s"), err, stderr.String())
+	}
+
+	return extractLastMessage(string(output)), nil
+}
+
+// FetchMissingChangelogs загружает журналы изменений пакетов, для которых их нет в базе, и сохраняет в базу.
+// Предназначена для фонового запуска: ошибки только записываются в журнал.
+func (a *Actions) FetchMissingChangelogs(ctx context.Context, packageNames []string) {
+	if len(packageNames) > maxChangelogFetch {
+		packageNames = packageNames[:maxChangelogFetch]
+	}
+
+	for _, name := range packageNames {
+		if _, busy := changelogFetching.LoadOrStore(name, true); busy {
+			continue
+		}
+
+		changelog, err := a.GetChangelog(ctx, name)
+		if err == nil && changelog != "" {
+			err = a.serviceAptDatabase.UpdatePackageChangelog(ctx, name, changelog)
+		}
+		if err != nil {
+			lib.Log.Debug(err.Error())
+		}
+
+		changelogFetching.Delete(name)
+	}
+}
+
+// GetInstallReasons возвращает карту, где ключ – имя пакета, а значение – причина его установки.
+func (a *Actions) GetInstallReasons(ctx context.Context) (map[string]string, error) {
+	reasons := make(map[string]string)
+	for _, reason := range []string{InstallReasonAuto, InstallReasonManual} {
+		packages, err := a.GetMarkedPackages(ctx, reason)
+		if err != nil {
+			return reasons, err
+		}
+
+		for _, name := range packages {
+			reasons[name] = reason
+		}
+	}
+
+	return reasons, nil
+}
+
+// UpdateLists обновляет только списки пакетов apt-get update, не перечитывая их в базу пакетов.
+func (a *Actions) UpdateLists(ctx context.Context) error {
+	return aptUpdate(ctx)
+}
+
 func aptUpdate(ctx context.Context) error {
 	syncAptMutex.Lock()
 	defer syncAptMutex.Unlock()
@@ -538,10 +790,10 @@ func aptUpdate(ctx context.Context) error {
 	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("system.AptUpdate"))
 
 	command := fmt.Sprintf("%s apt-get update", lib.Env.CommandPrefix)
-	cmd := exec.Command("sh", "-c", command)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	cmd.Env = []string{"LC_ALL=C"}
 
-	output, err := cmd.CombinedOutput()
+	output, err := lib.CommandCombinedOutput(cmd)
 	outputStr := string(output)
 	lines := strings.Split(outputStr, "\n")
 	aptError := ErrorLinesAnalise(lines)
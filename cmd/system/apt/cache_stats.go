@@ -0,0 +1,95 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package apt
+
+import (
+	"apm/cmd/common/helper"
+	"apm/lib"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AptCacheStats сводка кэша метаданных apt по данным apt-cache stats.
+type AptCacheStats struct {
+	TotalPackageNames     int `json:"totalPackageNames"`
+	NormalPackages        int `json:"normalPackages"`
+	PureVirtualPackages   int `json:"pureVirtualPackages"`
+	SingleVirtualPackages int `json:"singleVirtualPackages"`
+	MixedVirtualPackages  int `json:"mixedVirtualPackages"`
+	MissingPackages       int `json:"missingPackages"`
+	TotalDistinctVersions int `json:"totalDistinctVersions"`
+	TotalDependencies     int `json:"totalDependencies"`
+	TotalProvides         int `json:"totalProvides"`
+}
+
+// GetCacheStats возвращает сводку кэша apt из вывода apt-cache stats.
+func (a *Actions) GetCacheStats(ctx context.Context) (AptCacheStats, error) {
+	command := fmt.Sprintf("%s env LC_ALL=C apt-cache stats", lib.Env.CommandPrefix)
+	stdout, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return AptCacheStats{}, fmt.Errorf(lib.T_("Error executing the apt-cache stats command: %v, stderr: %s"), err, strings.TrimSpace(stderr))
+	}
+
+	return parseCacheStats(stdout), nil
+}
+
+// parseCacheStats разбирает вывод apt-cache stats:
+//
+//	Total package names: 40213 (804k)
+//	  Normal packages: 36500
+//	  Pure virtual packages: 1200
+//	Total distinct versions: 36600 (1.9M)
+//
+// Размеры в скобках и неизвестные строки пропускаются.
+func parseCacheStats(output string) AptCacheStats {
+	var stats AptCacheStats
+	fields := map[string]*int{
+		"total package names":     &stats.TotalPackageNames,
+		"normal packages":         &stats.NormalPackages,
+		"pure virtual packages":   &stats.PureVirtualPackages,
+		"single virtual packages": &stats.SingleVirtualPackages,
+		"mixed virtual packages":  &stats.MixedVirtualPackages,
+		"missing":                 &stats.MissingPackages,
+		"total distinct versions": &stats.TotalDistinctVersions,
+		"total dependencies":      &stats.TotalDependencies,
+		"total provides mappings": &stats.TotalProvides,
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		target, ok := fields[strings.ToLower(strings.TrimSpace(key))]
+		if !ok {
+			continue
+		}
+
+		number := strings.Fields(value)
+		if len(number) == 0 {
+			continue
+		}
+		if parsed, err := strconv.Atoi(number[0]); err == nil {
+			*target = parsed
+		}
+	}
+
+	return stats
+}
@@ -44,6 +44,16 @@ func NewPackageDBService(db *sql.DB) *PackageDBService {
 // syncDBMutex защищает операции синхронизации базы пакетов.
 var syncDBMutex sync.Mutex
 
+// SortFields возвращает поля, по которым разрешена сортировка.
+func SortFields() []string {
+	return append([]string(nil), allowedSortFields...)
+}
+
+// FilterFields возвращает поля, по которым разрешена фильтрация.
+func FilterFields() []string {
+	return append([]string(nil), allowedFilterFields...)
+}
+
 // Списки разрешённых полей для сортировки
 var allowedSortFields = []string{
 	"name",
@@ -59,6 +69,7 @@ var allowedSortFields = []string{
 	"description",
 	"changelog",
 	"installed",
+	"install_reason",
 }
 
 // Списки разрешённых полей для фильтрации.
@@ -76,6 +87,7 @@ var allowedFilterFields = []string{
 	"description",
 	"changelog",
 	"installed",
+	"install_reason",
 }
 
 // SavePackagesToDB сохраняет список пакетов
@@ -100,12 +112,17 @@ func (s *PackageDBService) SavePackagesToDB(ctx context.Context, packages []Pack
 		filename TEXT,
 		description TEXT,
 		changelog TEXT,
-		installed INTEGER
+		installed INTEGER,
+		install_reason TEXT
 	)`, s.tableName)
 	if _, err := s.dbConn.Exec(createQuery); err != nil {
 		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
 	}
 
+	if err := s.migratePackagesTable(ctx); err != nil {
+		return err
+	}
+
 	// Очищаем таблицу.
 	deleteQuery := fmt.Sprintf("DELETE FROM %s", s.tableName)
 	if _, err := s.dbConn.Exec(deleteQuery); err != nil {
@@ -130,7 +147,7 @@ func (s *PackageDBService) SavePackagesToDB(ctx context.Context, packages []Pack
 		var placeholders []string
 		var args []interface{}
 		for _, pkg := range batch {
-			placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 			dependsStr := strings.Join(pkg.Depends, ",")
 			providersStr := strings.Join(pkg.Provides, ",")
 			var installed int
@@ -153,10 +170,11 @@ func (s *PackageDBService) SavePackagesToDB(ctx context.Context, packages []Pack
 				pkg.Description,
 				pkg.Changelog,
 				installed,
+				pkg.InstallReason,
 			)
 		}
 
-		query := fmt.Sprintf("INSERT INTO %s (name, section, installed_size, maintainer, version, versionInstalled, depends, provides, size, filename, description, changelog, installed) VALUES %s",
+		query := fmt.Sprintf("INSERT INTO %s (name, section, installed_size, maintainer, version, versionInstalled, depends, provides, size, filename, description, changelog, installed, install_reason) VALUES %s",
 			s.tableName, strings.Join(placeholders, ","))
 		if _, err = tx.Exec(query, args...); err != nil {
 			errRollback := tx.Rollback()
@@ -222,11 +240,16 @@ func (s *PackageDBService) GetPackageByName(ctx context.Context, packageName str
 	return pkg, nil
 }
 
-// SyncPackageInstallationInfo синхронизирует базу пакетов с результатом выполнения apt.GetInstalledPackages().
-func (s *PackageDBService) SyncPackageInstallationInfo(ctx context.Context, installedPackages map[string]string) error {
+// SyncPackageInstallationInfo синхронизирует базу пакетов с результатом выполнения apt.GetInstalledPackages()
+// и причинами установки из apt.GetInstallReasons().
+func (s *PackageDBService) SyncPackageInstallationInfo(ctx context.Context, installedPackages map[string]string, reasons map[string]string) error {
 	syncDBMutex.Lock()
 	defer syncDBMutex.Unlock()
 
+	if err := s.migratePackagesTable(ctx); err != nil {
+		return err
+	}
+
 	tx, err := s.dbConn.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf(lib.T_("Transaction start error: %w"), err)
@@ -240,7 +263,8 @@ func (s *PackageDBService) SyncPackageInstallationInfo(ctx context.Context, inst
 	createTempTableQuery := `
         CREATE TEMPORARY TABLE tmp_installed (
             name TEXT PRIMARY KEY,
-            version TEXT
+            version TEXT,
+            reason TEXT
         );
     `
 	if _, err = tx.ExecContext(ctx, createTempTableQuery); err != nil {
@@ -250,12 +274,12 @@ func (s *PackageDBService) SyncPackageInstallationInfo(ctx context.Context, inst
 	var placeholders []string
 	var args []interface{}
 	for name, version := range installedPackages {
-		placeholders = append(placeholders, "(?, ?)")
-		args = append(args, name, version)
+		placeholders = append(placeholders, "(?, ?, ?)")
+		args = append(args, name, version, reasons[name])
 	}
 
 	if len(placeholders) > 0 {
-		insertQuery := fmt.Sprintf("INSERT INTO tmp_installed (name, version) VALUES %s", strings.Join(placeholders, ", "))
+		insertQuery := fmt.Sprintf("INSERT INTO tmp_installed (name, version, reason) VALUES %s", strings.Join(placeholders, ", "))
 		if _, err = tx.ExecContext(ctx, insertQuery, args...); err != nil {
 			return fmt.Errorf(lib.T_("Batch insert into temporary table error: %w"), err)
 		}
@@ -271,8 +295,12 @@ func (s *PackageDBService) SyncPackageInstallationInfo(ctx context.Context, inst
             versionInstalled = COALESCE(
                 (SELECT t.version FROM tmp_installed t WHERE t.name = %s.name), 
                 ''
+            ),
+            install_reason = COALESCE(
+                (SELECT t.reason FROM tmp_installed t WHERE t.name = %s.name), 
+                ''
             )
-    `, s.tableName, s.tableName, s.tableName)
+    `, s.tableName, s.tableName, s.tableName, s.tableName)
 	if _, err = tx.ExecContext(ctx, updateQuery); err != nil {
 		return fmt.Errorf(lib.T_("Batch update error: %w"), err)
 	}
@@ -287,6 +315,49 @@ func (s *PackageDBService) SyncPackageInstallationInfo(ctx context.Context, inst
 // SearchPackagesByName ищет пакеты в таблице по части названия.
 // Параметр `installed` определяет, нужно ли показывать только установленные пакеты.
 func (s *PackageDBService) SearchPackagesByName(ctx context.Context, namePart string, installed bool) ([]Package, error) {
+	// Подготавливаем шаблон для поиска, например "%имя%"
+	searchPattern := "%" + namePart + "%"
+
+	return s.searchPackages(ctx, "name LIKE ?", installed, searchPattern)
+}
+
+// SearchByNameOrDescription ищет пакеты по части названия или описания.
+// Результаты упорядочены по релевантности: совпадения в названии выше совпадений только в описании.
+func (s *PackageDBService) SearchByNameOrDescription(ctx context.Context, query string, installed bool) ([]Package, error) {
+	searchPattern := "%" + query + "%"
+
+	packages, err := s.searchPackages(ctx, "(name LIKE ? OR description LIKE ?)", installed, searchPattern, searchPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	SortBySearchRelevance(packages, query)
+
+	return packages, nil
+}
+
+// GetPackagesByNames возвращает записи пакетов с указанными названиями одним запросом.
+// Названия, которых нет в базе, пропускаются.
+func (s *PackageDBService) GetPackagesByNames(ctx context.Context, names []string) ([]Package, error) {
+	if len(names) == 0 {
+		return []Package{}, nil
+	}
+
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")
+
+	return s.searchPackages(ctx, fmt.Sprintf("name IN (%s)", placeholders), false, args...)
+}
+
+// searchPackages выбирает пакеты по условию condition с аргументами args.
+func (s *PackageDBService) searchPackages(ctx context.Context, condition string, installed bool, args ...interface{}) ([]Package, error) {
+	if err := s.migratePackagesTable(ctx); err != nil {
+		return nil, err
+	}
+
 	baseQuery := fmt.Sprintf(`
 		SELECT 
 			name, 
@@ -301,20 +372,18 @@ func (s *PackageDBService) SearchPackagesByName(ctx context.Context, namePart st
 			filename, 
 			description, 
 			changelog, 
-			installed
+			installed,
+			COALESCE(install_reason, '')
 		FROM %s
-		WHERE name LIKE ?
-	`, s.tableName)
+		WHERE %s
+	`, s.tableName, condition)
 
 	// Если нужно искать только среди установленных
 	if installed {
 		baseQuery += " AND installed = 1"
 	}
 
-	// Подготавливаем шаблон для поиска, например "%имя%"
-	searchPattern := "%" + namePart + "%"
-
-	rows, err := s.dbConn.QueryContext(ctx, baseQuery, searchPattern)
+	rows, err := s.dbConn.QueryContext(ctx, baseQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf(lib.T_("Query execution error: %w"), err)
 	}
@@ -347,6 +416,7 @@ func (s *PackageDBService) SearchPackagesByName(ctx context.Context, namePart st
 			&pkg.Description,
 			&pkg.Changelog,
 			&installedInt,
+			&pkg.InstallReason,
 		); err != nil {
 			return nil, fmt.Errorf(lib.T_("Batch data read error: %w"), err)
 		}
@@ -382,6 +452,9 @@ func (s *PackageDBService) QueryHostImagePackages(
 	sortField, sortOrder string,
 	limit, offset int64,
 ) ([]Package, error) {
+	if err := s.migratePackagesTable(ctx); err != nil {
+		return nil, err
+	}
 
 	query := fmt.Sprintf(`
         SELECT 
@@ -397,7 +470,8 @@ func (s *PackageDBService) QueryHostImagePackages(
             filename,
             description,
             changelog,
-            installed
+            installed,
+            COALESCE(install_reason, '')
         FROM %s
     `, s.tableName)
 
@@ -504,6 +578,7 @@ func (s *PackageDBService) QueryHostImagePackages(
 			&pkg.Description,
 			&pkg.Changelog,
 			&installedInt,
+			&pkg.InstallReason,
 		); err != nil {
 			return nil, fmt.Errorf(lib.T_("Package data read error: %w"), err)
 		}
@@ -534,6 +609,10 @@ func (s *PackageDBService) QueryHostImagePackages(
 // CountHostImagePackages возвращает количество записей из таблицы host_image_packages
 // с учётом переданных фильтров (строки => LIKE '%...%', для остальных типов "=").
 func (s *PackageDBService) CountHostImagePackages(ctx context.Context, filters map[string]interface{}) (int64, error) {
+	if err := s.migratePackagesTable(ctx); err != nil {
+		return 0, err
+	}
+
 	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", s.tableName)
 
 	var args []interface{}
@@ -589,6 +668,16 @@ func (s *PackageDBService) CountHostImagePackages(ctx context.Context, filters m
 	return totalCount, nil
 }
 
+// UpdatePackageChangelog сохраняет последнюю запись журнала изменений пакета.
+func (s *PackageDBService) UpdatePackageChangelog(ctx context.Context, packageName string, changelog string) error {
+	query := fmt.Sprintf("UPDATE %s SET changelog = ? WHERE name = ?", s.tableName)
+	if _, err := s.dbConn.ExecContext(ctx, query, changelog, packageName); err != nil {
+		return fmt.Errorf(lib.T_("Error updating data: %v"), err)
+	}
+
+	return nil
+}
+
 // PackageDatabaseExist проверяет, существует ли таблица и содержит ли она хотя бы одну запись.
 func (s *PackageDBService) PackageDatabaseExist(ctx context.Context) error {
 	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", s.tableName)
@@ -601,6 +690,148 @@ func (s *PackageDBService) PackageDatabaseExist(ctx context.Context) error {
 	return nil
 }
 
+// GetPackageNames возвращает имена всех пакетов из базы.
+func (s *PackageDBService) GetPackageNames(ctx context.Context) ([]string, error) {
+	query := fmt.Sprintf("SELECT name FROM %s", s.tableName)
+	rows, err := s.dbConn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Query execution error: %w"), err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// PackageNamesByPrefix возвращает не более limit имён пакетов, начинающихся с prefix. Таблица не создаётся
+// и не обновляется, поэтому запрос подходит для дополнения в оболочке.
+func (s *PackageDBService) PackageNamesByPrefix(ctx context.Context, prefix string, limit int) ([]string, error) {
+	query := fmt.Sprintf("SELECT name FROM %s WHERE substr(name, 1, length(?)) = ? ORDER BY name LIMIT ?", s.tableName)
+	rows, err := s.dbConn.QueryContext(ctx, query, prefix, prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Query execution error: %w"), err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// CountUpgradablePackages возвращает число установленных пакетов, версия которых в репозитории
+// отличается от установленной. Таблица не создаётся и не обновляется.
+func (s *PackageDBService) CountUpgradablePackages(ctx context.Context) (int, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE installed = 1 AND versionInstalled != '' AND version != versionInstalled", s.tableName)
+
+	var count int
+	if err := s.dbConn.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf(lib.T_("Query execution error: %w"), err)
+	}
+
+	return count, nil
+}
+
+// GetInstalledPackageSizes возвращает размеры (в байтах) всех установленных пакетов.
+func (s *PackageDBService) GetInstalledPackageSizes(ctx context.Context) ([]int, error) {
+	query := fmt.Sprintf("SELECT installed_size FROM %s WHERE installed = 1", s.tableName)
+	rows, err := s.dbConn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Query execution error: %w"), err)
+	}
+	defer rows.Close()
+
+	var sizes []int
+	for rows.Next() {
+		var size int
+		if err = rows.Scan(&size); err != nil {
+			return nil, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		sizes = append(sizes, size)
+	}
+
+	return sizes, rows.Err()
+}
+
+// MaintainerCount число пакетов одного сопровождающего.
+type MaintainerCount struct {
+	Maintainer string `json:"maintainer"`
+	Count      int    `json:"count"`
+}
+
+// GroupByMaintainer возвращает сопровождающих пакетов с числом их пакетов, начиная с самых крупных.
+func (s *PackageDBService) GroupByMaintainer(ctx context.Context) ([]MaintainerCount, error) {
+	query := fmt.Sprintf("SELECT maintainer, COUNT(*) FROM %s GROUP BY maintainer ORDER BY COUNT(*) DESC, maintainer ASC", s.tableName)
+	rows, err := s.dbConn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Query execution error: %w"), err)
+	}
+	defer rows.Close()
+
+	var result []MaintainerCount
+	for rows.Next() {
+		var maintainer sql.NullString
+		var count int
+		if err = rows.Scan(&maintainer, &count); err != nil {
+			return nil, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		result = append(result, MaintainerCount{Maintainer: maintainer.String, Count: count})
+	}
+
+	return result, rows.Err()
+}
+
+// migratePackagesTable добавляет колонку install_reason в таблицу, созданную предыдущими версиями.
+func (s *PackageDBService) migratePackagesTable(ctx context.Context) error {
+	rows, err := s.dbConn.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", s.tableName))
+	if err != nil {
+		return fmt.Errorf(lib.T_("Query execution error: %w"), err)
+	}
+
+	tableExists := false
+	hasReason := false
+	for rows.Next() {
+		var cid int
+		var name, columnType string
+		var notNull, pk int
+		var defaultValue sql.NullString
+		if err = rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		tableExists = true
+		if name == "install_reason" {
+			hasReason = true
+		}
+	}
+	rows.Close()
+
+	if !tableExists || hasReason {
+		return nil
+	}
+
+	alterQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN install_reason TEXT", s.tableName)
+	if _, err = s.dbConn.ExecContext(ctx, alterQuery); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return nil
+}
+
 // Проверка, входит ли поле в список разрешённых.
 func (s *PackageDBService) isAllowedField(field string, allowed []string) bool {
 	for _, f := range allowed {
@@ -18,7 +18,6 @@ package apt
 
 import (
 	"apm/cmd/common/helper"
-	"apm/cmd/common/reply"
 	"apm/lib"
 	"fmt"
 	"os"
@@ -50,11 +49,8 @@ type model struct {
 }
 
 // NewDialog запускает диалог отображения информации о пакете с выбором действия.
+// Способ подтверждения выбирает вызывающая сторона через reply.GetConfirmationMode.
 func NewDialog(packageInfo []Package, packageChange PackageChanges, action DialogAction) (bool, error) {
-	if lib.Env.Format != "text" && reply.IsTTY() {
-		return true, nil
-	}
-
 	switch action {
 	case ActionMultiInstall:
 		choices = []string{lib.T_("Edit"), lib.T_("Abort")}
@@ -321,7 +317,7 @@ func (m model) buildContent() string {
 	packageRemovedCount := fmt.Sprintf(lib.TN_("%d package", "%d packages", m.pckChange.RemovedCount), m.pckChange.RemovedCount)
 	packageNotUpgradedCount := fmt.Sprintf(lib.TN_("%d package", "%d packages", m.pckChange.NotUpgradedCount), m.pckChange.NotUpgradedCount)
 
-	sb.WriteString(titleStyle.Render(fmt.Sprintf("\n\n", lib.T_("Total:"))))
+	sb.WriteString(titleStyle.Render(fmt.Sprintf("\n\n%s", lib.T_("Total:"))))
 	sb.WriteString("\n" + formatLine(lib.T_("Will be updated"), packageUpgradedCount, keyWidth, keyStyle, valueStyle))
 	sb.WriteString("\n" + formatLine(lib.T_("Will be installed"), packageNewInstalledCount, keyWidth, keyStyle, valueStyle))
 	sb.WriteString("\n" + formatLine(lib.T_("Will be removed"), packageRemovedCount, keyWidth, keyStyle, valueStyle))
@@ -17,6 +17,7 @@
 package apt
 
 import (
+	"apm/cmd/common/reply"
 	"apm/lib"
 	"errors"
 	"fmt"
@@ -163,6 +164,7 @@ var errorPatterns = []ErrorEntry{
 
 // ErrorLinesAnalyseAll проверяет все строки и возвращает срез найденных ошибок.
 func ErrorLinesAnalyseAll(lines []string) []*MatchedError {
+	lib.LogCommandOutput("apt", lines)
 	var errorsFound []*MatchedError
 	for _, line := range lines {
 		trimmed := strings.TrimSpace(line)
@@ -179,6 +181,7 @@ func ErrorLinesAnalyseAll(lines []string) []*MatchedError {
 
 // ErrorLinesAnalise возвращает любую ошибку
 func ErrorLinesAnalise(lines []string) *MatchedError {
+	lib.LogCommandOutput("apt", lines)
 	for _, line := range lines {
 		trimmed := strings.TrimSpace(line)
 		if trimmed == "" {
@@ -250,6 +253,38 @@ func (e *MatchedError) NeedUpdate() bool {
 	}
 }
 
+// ErrorCode возвращает код ошибки ответа для найденной ошибки apt.
+func (e *MatchedError) ErrorCode() string {
+	switch e.Entry.Code {
+	case ErrLockDownloadDir, ErrRpmDatabaseLock:
+		return reply.ErrorCodeAptLock
+	case ErrPermissionDenied:
+		return reply.ErrorCodePermissionDenied
+	case ErrBrokenPackages, ErrInternalBrokenPackages, ErrBuilddepBrokenPackages, ErrResolverBroken,
+		ErrDependencyUnsatisfied, ErrDependencyUnsatisfied2, ErrFailedDependencyTooNew, ErrFailedDependency,
+		ErrUnmetDependencies, ErrVirtualMultipleProviders, ErrVirtualMultipleProvidersShort:
+		return reply.ErrorCodeDependencyConflict
+	case ErrPackageNotFound, ErrNoPackagesFound, ErrNoInstallationCandidate, ErrPackageNotInstalled,
+		ErrSourcePackageNotFound, ErrReleaseNotFound, ErrVersionNotFound, ErrVirtualNoProviders,
+		ErrVirtualNoProvidersShort, ErrMissingChangelogPackage:
+		return reply.ErrorCodePackageNotFound
+	case ErrDownloadFailed, ErrFetchArchivesFailed, ErrFailedToFetchArchives, ErrFailedToFetch,
+		ErrFailedToFetchSomeIndex:
+		return reply.ErrorCodeDownloadFailed
+	default:
+		return reply.ErrorCodeAptFailed
+	}
+}
+
+// ErrorDetails возвращает параметры ошибки apt, например название пакета или путь.
+func (e *MatchedError) ErrorDetails() map[string]interface{} {
+	if len(e.Params) == 0 {
+		return nil
+	}
+
+	return map[string]interface{}{"params": e.Params}
+}
+
 func FindCriticalError(errorList []error) error {
 	for _, err := range errorList {
 		var matchedErr *MatchedError
@@ -0,0 +1,179 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package apt
+
+import (
+	"apm/lib"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PreferencesDir каталог, из которого apt читает приоритеты версий пакетов.
+const PreferencesDir = "/etc/apt/preferences.d"
+
+// DefaultPinPriority приоритет закрепления по умолчанию. Приоритет выше 1000 позволяет apt установить
+// закреплённую версию, даже если она старше установленной.
+const DefaultPinPriority = 1001
+
+// pinFilePrefix и pinFileSuffix задают имя файла закрепления apm-pin-<пакет>.pref.
+const (
+	pinFilePrefix = "apm-pin-"
+	pinFileSuffix = ".pref"
+)
+
+// pinNameRegex допустимое имя закрепляемого пакета, оно же входит в имя файла.
+var pinNameRegex = regexp.MustCompile(`^[A-Za-z0-9][\w.+-]*$`)
+
+// pinVersionRegex допустимая версия закрепления, например 1:2.4.1-alt1 или 2.4*.
+var pinVersionRegex = regexp.MustCompile(`^[\w.+~:*-]+$`)
+
+// AptPin закрепление версии пакета в настройках apt preferences.
+type AptPin struct {
+	Package  string `json:"package"`
+	Version  string `json:"version"`
+	Priority int    `json:"priority"`
+	Path     string `json:"path"`
+}
+
+// ValidatePin проверяет имя пакета, версию и приоритет закрепления.
+func ValidatePin(packageName string, version string, priority int) error {
+	if !pinNameRegex.MatchString(packageName) {
+		return fmt.Errorf(lib.T_("Invalid package name: %s"), packageName)
+	}
+
+	if !pinVersionRegex.MatchString(version) {
+		return fmt.Errorf(lib.T_("Invalid version %s for package %s"), version, packageName)
+	}
+
+	// Приоритет 0 apt не определяет
+	if priority == 0 {
+		return fmt.Errorf(lib.T_("The pin priority must not be zero"))
+	}
+
+	return nil
+}
+
+// PinFilePath возвращает путь к файлу закрепления пакета.
+func PinFilePath(packageName string) string {
+	return filepath.Join(PreferencesDir, pinFilePrefix+packageName+pinFileSuffix)
+}
+
+// WritePin записывает файл закрепления версии пакета, заменяя прежнее закрепление этого пакета.
+func WritePin(packageName string, version string, priority int) (AptPin, error) {
+	if err := ValidatePin(packageName, version, priority); err != nil {
+		return AptPin{}, err
+	}
+
+	pin := AptPin{Package: packageName, Version: version, Priority: priority, Path: PinFilePath(packageName)}
+	content := fmt.Sprintf("Package: %s\nPin: version %s\nPin-Priority: %d\n", pin.Package, pin.Version, pin.Priority)
+
+	if err := os.MkdirAll(PreferencesDir, 0755); err != nil {
+		return AptPin{}, err
+	}
+	if err := os.WriteFile(pin.Path, []byte(content), 0644); err != nil {
+		return AptPin{}, fmt.Errorf(lib.T_("Error writing file %s: %v"), pin.Path, err)
+	}
+
+	return pin, nil
+}
+
+// RemovePin удаляет файл закрепления пакета и возвращает снятое закрепление.
+func RemovePin(packageName string) (AptPin, error) {
+	if !pinNameRegex.MatchString(packageName) {
+		return AptPin{}, fmt.Errorf(lib.T_("Invalid package name: %s"), packageName)
+	}
+
+	path := PinFilePath(packageName)
+	pin, err := readPinFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return AptPin{}, fmt.Errorf(lib.T_("Package %s is not pinned in apt preferences"), packageName)
+	}
+	if err != nil {
+		return AptPin{}, err
+	}
+
+	if err = os.Remove(path); err != nil {
+		return AptPin{}, err
+	}
+
+	return pin, nil
+}
+
+// ListPins возвращает закрепления из всех файлов apm-pin-*.pref, отсортированные по имени пакета.
+func ListPins() ([]AptPin, error) {
+	paths, err := filepath.Glob(filepath.Join(PreferencesDir, pinFilePrefix+"*"+pinFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	pins := make([]AptPin, 0, len(paths))
+	for _, path := range paths {
+		pin, err := readPinFile(path)
+		if err != nil {
+			lib.Log.Warningf(lib.T_("Error reading file %s: %v"), path, err)
+			continue
+		}
+		pins = append(pins, pin)
+	}
+
+	sort.Slice(pins, func(i, j int) bool {
+		return pins[i].Package < pins[j].Package
+	})
+
+	return pins, nil
+}
+
+// readPinFile читает файл закрепления.
+func readPinFile(path string) (AptPin, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return AptPin{}, err
+	}
+
+	pin := ParsePin(string(content))
+	pin.Path = path
+	return pin, nil
+}
+
+// ParsePin разбирает запись apt preferences из полей Package, Pin: version и Pin-Priority.
+func ParsePin(content string) AptPin {
+	var pin AptPin
+	for _, line := range strings.Split(content, "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "package":
+			pin.Package = value
+		case "pin":
+			pin.Version = strings.TrimSpace(strings.TrimPrefix(value, "version"))
+		case "pin-priority":
+			pin.Priority, _ = strconv.Atoi(value)
+		}
+	}
+
+	return pin
+}
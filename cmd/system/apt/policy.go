@@ -0,0 +1,213 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package apt
+
+import (
+	"apm/cmd/common/helper"
+	"apm/lib"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// VersionEntry версия пакета из таблицы версий apt-cache policy и репозиторий, в котором она доступна.
+type VersionEntry struct {
+	Version    string `json:"version"`
+	Priority   int    `json:"priority"`
+	Repository string `json:"repository"`
+	Archive    string `json:"archive"`
+	Component  string `json:"component"`
+	Origin     string `json:"origin"`
+	Label      string `json:"label"`
+	Installed  bool   `json:"installed"`
+}
+
+// releaseInfo сведения о репозитории из строки release вывода apt-cache policy.
+type releaseInfo struct {
+	Origin    string
+	Label     string
+	Archive   string
+	Component string
+}
+
+// GetVersionTable возвращает все доступные версии пакета с репозиториями, из которых их можно установить.
+func (a *Actions) GetVersionTable(ctx context.Context, packageName string) ([]VersionEntry, error) {
+	command := fmt.Sprintf("%s env LC_ALL=C apt-cache policy %s", lib.Env.CommandPrefix, packageName)
+	stdout, stderr, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to retrieve information about the package %s"), packageName+": "+stderr)
+	}
+
+	// Сведения о происхождении репозиториев есть только в общем выводе без имени пакета
+	command = fmt.Sprintf("%s env LC_ALL=C apt-cache policy", lib.Env.CommandPrefix)
+	releaseOutput, _, err := helper.RunCommand(ctx, command)
+	if err != nil {
+		lib.Log.Debug(err.Error())
+	}
+
+	return ParseVersionTable(stdout, releaseOutput), nil
+}
+
+// ParseVersionTable разбирает вывод apt-cache policy <пакет>, дополняя версии сведениями о репозиториях
+// из общего вывода apt-cache policy releaseOutput.
+func ParseVersionTable(packageOutput string, releaseOutput string) []VersionEntry {
+	return parseVersionTable(packageOutput, parseReleaseInfo(releaseOutput))
+}
+
+// parseVersionTable разбирает таблицу версий apt-cache policy <пакет>:
+//
+//	*** 1.2-alt1 0
+//	       500 http://ftp.altlinux.org/pub/distributions/ALTLinux Sisyphus/x86_64/classic pkglist
+//	       100 RPM Database
+//
+// Строка версии отмечена *** для установленной версии, под ней перечислены источники с приоритетами.
+func parseVersionTable(output string, releases map[string]releaseInfo) []VersionEntry {
+	entries := []VersionEntry{}
+	inTable := false
+	var current *VersionEntry
+	hasSource := false
+
+	// Версия, доступная только из базы установленных пакетов, всё равно попадает в список
+	flush := func() {
+		if current != nil && !hasSource {
+			entries = append(entries, *current)
+		}
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.EqualFold(trimmed, "Version table:") {
+			inTable = true
+			continue
+		}
+		if !inTable || trimmed == "" {
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		if fields[0] == "***" || indent < 6 {
+			flush()
+			installed := fields[0] == "***"
+			if installed {
+				fields = fields[1:]
+			}
+			if len(fields) == 0 {
+				current = nil
+				continue
+			}
+			current = &VersionEntry{Version: fields[0], Installed: installed}
+			hasSource = false
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		priority, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		current.Priority = priority
+
+		// Источник без адреса - база установленных пакетов (RPM Database или /var/lib/dpkg/status)
+		if len(fields) < 3 || !strings.Contains(fields[1], "://") {
+			continue
+		}
+
+		entry := *current
+		entry.Repository = fields[1]
+
+		distParts := strings.Split(fields[2], "/")
+		entry.Archive = distParts[0]
+		if len(distParts) > 1 {
+			entry.Component = distParts[len(distParts)-1]
+		}
+
+		if release, ok := releases[fields[1]+" "+fields[2]]; ok {
+			entry.Origin = release.Origin
+			entry.Label = release.Label
+			if release.Archive != "" {
+				entry.Archive = release.Archive
+			}
+			if release.Component != "" {
+				entry.Component = release.Component
+			}
+		}
+
+		entries = append(entries, entry)
+		hasSource = true
+	}
+	flush()
+
+	return entries
+}
+
+// parseReleaseInfo разбирает раздел Package Files вывода apt-cache policy:
+//
+//	500 http://ftp.altlinux.org/pub/distributions/ALTLinux Sisyphus/x86_64/classic pkglist
+//	    release o=ALT Linux Team,a=Sisyphus,l=Sisyphus,c=classic
+//
+// Ключ результата - адрес репозитория и путь дистрибутива, как в таблице версий пакета.
+func parseReleaseInfo(output string) map[string]releaseInfo {
+	releases := make(map[string]releaseInfo)
+
+	var key string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if _, err := strconv.Atoi(fields[0]); err == nil {
+			key = ""
+			if len(fields) >= 3 && strings.Contains(fields[1], "://") {
+				key = fields[1] + " " + fields[2]
+			}
+			continue
+		}
+
+		if fields[0] != "release" || key == "" {
+			continue
+		}
+
+		var release releaseInfo
+		attributes := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "release"))
+		for _, attribute := range strings.Split(attributes, ",") {
+			name, value, found := strings.Cut(attribute, "=")
+			if !found {
+				continue
+			}
+			switch strings.TrimSpace(name) {
+			case "o":
+				release.Origin = value
+			case "l":
+				release.Label = value
+			case "a":
+				release.Archive = value
+			case "c":
+				release.Component = value
+			}
+		}
+		releases[key] = release
+	}
+
+	return releases
+}
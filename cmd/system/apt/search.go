@@ -0,0 +1,78 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package apt
+
+import (
+	"sort"
+	"strings"
+)
+
+// Поле пакета, совпавшее с поисковым запросом.
+const (
+	MatchedOnName        = "name"
+	MatchedOnDescription = "description"
+	MatchedOnBoth        = "both"
+)
+
+// SearchMatch возвращает, где пакет совпал с запросом: в названии, описании или в обоих полях.
+// Сравнение, как и LIKE в SQLite, не учитывает регистр.
+func SearchMatch(pkg Package, query string) string {
+	query = strings.ToLower(query)
+	inName := strings.Contains(strings.ToLower(pkg.Name), query)
+	inDescription := strings.Contains(strings.ToLower(pkg.Description), query)
+
+	switch {
+	case inName && inDescription:
+		return MatchedOnBoth
+	case inName:
+		return MatchedOnName
+	case inDescription:
+		return MatchedOnDescription
+	default:
+		return ""
+	}
+}
+
+// searchRank оценивает релевантность пакета: чем меньше значение, тем выше пакет в выдаче.
+// Точное совпадение названия выше совпадения с началом названия, затем любое вхождение в название
+// и в конце совпадения только в описании.
+func searchRank(pkg Package, query string) int {
+	name := strings.ToLower(pkg.Name)
+	query = strings.ToLower(query)
+
+	switch {
+	case name == query:
+		return 0
+	case strings.HasPrefix(name, query):
+		return 1
+	case strings.Contains(name, query):
+		return 2
+	default:
+		return 3
+	}
+}
+
+// SortBySearchRelevance упорядочивает результаты поиска по релевантности, а при равной — по названию.
+func SortBySearchRelevance(packages []Package, query string) {
+	sort.SliceStable(packages, func(i, j int) bool {
+		ri, rj := searchRank(packages[i], query), searchRank(packages[j], query)
+		if ri != rj {
+			return ri < rj
+		}
+		return packages[i].Name < packages[j].Name
+	})
+}
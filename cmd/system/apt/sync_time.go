@@ -0,0 +1,51 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package apt
+
+import (
+	"apm/lib"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// lastSyncTimeKey ключ в KV-хранилище со временем последней синхронизации базы пакетов.
+const lastSyncTimeKey = "system:lastSyncTime"
+
+// RecordSyncTime запоминает время синхронизации базы пакетов. Ошибка хранилища не прерывает операцию.
+func RecordSyncTime() {
+	err := lib.GetDBKv().Put([]byte(lastSyncTimeKey), []byte(time.Now().Format(time.RFC3339)))
+	if err != nil {
+		lib.Log.Warning(fmt.Sprintf(lib.T_("Error saving the package database sync time: %v"), err))
+	}
+}
+
+// LastSyncTime возвращает время последней синхронизации базы пакетов в формате RFC 3339 или пустую строку,
+// если синхронизация не записана. Без прав root хранилище, которое ещё не открыто, не открывается.
+func LastSyncTime() string {
+	if lib.CheckDBKv() == nil && syscall.Geteuid() != 0 {
+		return ""
+	}
+
+	data, err := lib.GetDBKv().Get([]byte(lastSyncTimeKey))
+	if err != nil {
+		lib.Log.Debug(err.Error())
+		return ""
+	}
+
+	return string(data)
+}
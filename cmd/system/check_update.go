@@ -0,0 +1,140 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package system
+
+import (
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// aptListsDir каталог, в котором apt хранит загруженные списки пакетов.
+var aptListsDir = "/var/lib/apt/lists"
+
+// CheckForUpdates проверяет без прав root, устарели ли списки пакетов apt. Списки считаются устаревшими,
+// если они обновлялись раньше, чем интервал updateCheckInterval (по умолчанию сутки). Если команда
+// запущена не от root и системная служба D-Bus доступна, списки обновляются через её метод Update.
+func (a *Actions) CheckForUpdates(ctx context.Context) (*reply.APIResponse, error) {
+	interval := maxUpdateCheckInterval
+	if lib.Env.UpdateCheckInterval > 0 {
+		interval = time.Duration(lib.Env.UpdateCheckInterval) * time.Minute
+	}
+
+	lastUpdated, err := aptListsUpdatedAt()
+	if err != nil {
+		return nil, err
+	}
+	stale := time.Since(lastUpdated) > interval
+
+	delegated := false
+	if stale && syscall.Geteuid() != 0 {
+		if err = updateViaSystemService(ctx); err != nil {
+			lib.Log.Debug(err.Error())
+		} else if lastUpdated, err = aptListsUpdatedAt(); err == nil {
+			delegated = true
+			stale = time.Since(lastUpdated) > interval
+		} else {
+			return nil, err
+		}
+	}
+
+	message := lib.T_("Package lists are up to date")
+	if stale {
+		message = lib.T_("Package lists are out of date, run the update with elevated rights")
+	}
+
+	data := map[string]interface{}{
+		"message":    message,
+		"listsStale": stale,
+		"delegated":  delegated,
+	}
+	if !lastUpdated.IsZero() {
+		data["lastUpdated"] = lastUpdated.Format(time.RFC3339)
+	}
+
+	// Без базы пакетов число обновлений неизвестно, но устаревание списков всё равно сообщается
+	if count, err := a.serviceAptDatabase.CountUpgradablePackages(ctx); err == nil {
+		data["upgradeAvailableCount"] = count
+	} else {
+		lib.Log.Debug(err.Error())
+	}
+
+	resp := reply.APIResponse{
+		Data:  data,
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// aptListsUpdatedAt возвращает время последнего изменения списков пакетов apt или нулевое время,
+// если списки ещё не загружались.
+func aptListsUpdatedAt() (time.Time, error) {
+	entries, err := os.ReadDir(aptListsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf(lib.T_("Failed to read the apt package lists: %v"), err)
+	}
+
+	var latest time.Time
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == "lock" {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// updateViaSystemService обновляет списки пакетов методом Update системной службы D-Bus, которая
+// работает с правами root. Возвращает ошибку, если служба не запущена.
+func updateViaSystemService(ctx context.Context) error {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var running bool
+	err = conn.BusObject().CallWithContext(ctx, "org.freedesktop.DBus.NameHasOwner", 0, "com.application.APM").Store(&running)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return fmt.Errorf(lib.T_("The system D-Bus service com.application.APM is not running"))
+	}
+
+	var result string
+	obj := conn.Object("com.application.APM", "/com/application/APM")
+	return obj.CallWithContext(ctx, "com.application.system.Update", 0, reply.TransactionFromContext(ctx)).Store(&result)
+}
@@ -17,27 +17,194 @@
 package system
 
 import (
+	"apm/cmd/common/helper"
 	"apm/cmd/common/reply"
+	"apm/cmd/system/apt"
+	"apm/cmd/system/service"
 	"apm/lib"
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/urfave/cli/v3"
 )
 
-// newErrorResponse создаёт ответ с ошибкой и указанным сообщением.
-func newErrorResponse(message string) reply.APIResponse {
-	lib.Log.Error(message)
+// newErrorResponse создаёт ответ с сообщением, кодом и подробностями ошибки.
+// Необязательный code заменяет код, определённый по самой ошибке.
+func newErrorResponse(err error, code ...string) reply.APIResponse {
+	lib.Log.Error(err.Error())
+
+	errorCode := reply.ErrorCode(err)
+	if len(code) > 0 && code[0] != "" {
+		errorCode = code[0]
+	}
 
 	return reply.APIResponse{
-		Data:  map[string]interface{}{"message": message},
-		Error: true,
+		Data:    map[string]interface{}{"message": err.Error()},
+		Error:   true,
+		Code:    errorCode,
+		Details: reply.ErrorDetails(err),
+	}
+}
+
+// newListCommand создаёт команду запроса списка пакетов. baseFilters добавляются к фильтрам, указанным пользователем.
+func newListCommand(name string, usage string, baseFilters []string) *cli.Command {
+	return &cli.Command{
+		Name:  name,
+		Usage: usage,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "sort",
+				Usage: lib.T_("Building query to fetch package list"),
+			},
+			&cli.StringFlag{
+				Name:  "order",
+				Usage: lib.T_("Sorting order: ASC or DESC"),
+				Value: "ASC",
+			},
+			&cli.IntFlag{
+				Name:  "limit",
+				Usage: lib.T_("Limit of the result"),
+				Value: 10,
+			},
+			&cli.IntFlag{
+				Name:  "offset",
+				Usage: lib.T_("Offset of the result"),
+				Value: 0,
+			},
+			&cli.StringSliceFlag{
+				Name:  "filter",
+				Usage: lib.T_("Filter in the format key=value. The flag can be specified multiple times, for example: --filter name=zip --filter installed=true"),
+			},
+			&cli.BoolFlag{
+				Name:  "force-update",
+				Usage: lib.T_("Force update all packages before query"),
+				Value: false,
+			},
+			&cli.BoolFlag{
+				Name:  "full",
+				Usage: lib.T_("Full information output"),
+				Value: false,
+			},
+			&cli.BoolFlag{
+				Name:  "with-changelog",
+				Usage: lib.T_("Include the last changelog entry of each package"),
+				Value: false,
+			},
+			&cli.BoolFlag{
+				Name:  "count-only",
+				Usage: lib.T_("Print only the number of packages matching the filters"),
+				Value: false,
+			},
+			&cli.StringFlag{
+				Name:  "maintainer",
+				Usage: lib.T_("Only packages whose maintainer contains the given text"),
+			},
+			&cli.BoolFlag{
+				Name:    "upgradable",
+				Usage:   lib.T_("List only packages with available updates, without a full database scan"),
+				Aliases: []string{"only-upgradable"},
+				Value:   false,
+			},
+			columnsFlag(),
+		},
+		Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.Bool("upgradable") {
+				resp, err := NewActions().ListUpgradablePackages(ctx)
+				if err != nil {
+					return reply.CliResponse(ctx, newErrorResponse(err))
+				}
+
+				return reply.CliResponse(ctx, *resp)
+			}
+
+			filters := append(append([]string(nil), baseFilters...), cmd.StringSlice("filter")...)
+			if maintainer := cmd.String("maintainer"); maintainer != "" {
+				filters = append(filters, "maintainer="+maintainer)
+			}
+
+			params := ListParams{
+				Sort:             cmd.String("sort"),
+				Order:            cmd.String("order"),
+				Offset:           cmd.Int("offset"),
+				Limit:            cmd.Int("limit"),
+				Filters:          filters,
+				ForceUpdate:      cmd.Bool("force-update"),
+				IncludeChangelog: cmd.Bool("with-changelog"),
+			}
+
+			if cmd.Bool("count-only") {
+				resp, err := NewActions().Count(ctx, params)
+				if err != nil {
+					return reply.CliResponse(ctx, newErrorResponse(err))
+				}
+
+				return reply.CliResponse(ctx, *resp)
+			}
+
+			resp, err := NewActions().List(ctx, params, cmd.Bool("full"))
+			if err != nil {
+				return reply.CliResponse(ctx, newErrorResponse(err))
+			}
+
+			return reply.CliResponse(ctx, *resp)
+		}),
+	}
+}
+
+// rebootFlags флаги перезагрузки после применения изменений к образу.
+func rebootFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:   "reboot",
+			Usage:  lib.T_("Schedule a reboot after the image has been built and applied"),
+			Value:  false,
+			Hidden: !lib.IsAtomic(),
+		},
+		&cli.DurationFlag{
+			Name:   "reboot-delay",
+			Usage:  lib.T_("Delay before the scheduled reboot"),
+			Value:  5 * time.Minute,
+			Hidden: !lib.IsAtomic(),
+		},
+	}
+}
+
+// columnsFlag флаг выбора колонок табличного формата вывода для команд, возвращающих списки.
+func columnsFlag(defaults ...string) *cli.StringSliceFlag {
+	return &cli.StringSliceFlag{
+		Name:    "columns",
+		Usage:   lib.T_("Columns for the table and csv formats, for example: --columns name,version,installed,size"),
+		Aliases: []string{"fields"},
+		Value:   defaults,
+	}
+}
+
+// rebootParams читает параметры перезагрузки из флагов команды.
+func rebootParams(cmd *cli.Command) RebootParams {
+	return RebootParams{
+		Enabled: cmd.Bool("reboot"),
+		Delay:   cmd.Duration("reboot-delay"),
 	}
 }
 
 func withGlobalWrapper(action cli.ActionFunc) cli.ActionFunc {
 	return func(ctx context.Context, cmd *cli.Command) error {
 		lib.Env.Format = cmd.String("format")
+		reply.SetOutputFile(cmd.String("output"))
 		ctx = context.WithValue(ctx, "transaction", cmd.String("transaction"))
+		ctx = context.WithValue(ctx, "columns", cmd.StringSlice("columns"))
+		ctx = context.WithValue(ctx, "command", cmd.FullName())
+		if err := reply.ValidateFormat(lib.Env.Format); err != nil {
+			lib.Env.Format = "text"
+			return reply.CliResponse(ctx, newErrorResponse(err))
+		}
+		if err := reply.SetColorMode(cmd.String("color")); err != nil {
+			return reply.CliResponse(ctx, newErrorResponse(err))
+		}
 
 		reply.CreateSpinner()
 		return action(ctx, cmd)
@@ -45,7 +212,7 @@ func withGlobalWrapper(action cli.ActionFunc) cli.ActionFunc {
 }
 
 func CommandList() *cli.Command {
-	return &cli.Command{
+	command := &cli.Command{
 		Name:    "system",
 		Aliases: []string{"s"},
 		Usage:   lib.T_("System package management"),
@@ -54,19 +221,25 @@ func CommandList() *cli.Command {
 				Name:      "install",
 				Usage:     lib.T_("Package list for installation. The format package- package+ is supported."),
 				ArgsUsage: "packages",
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&cli.BoolFlag{
 						Name:    "apply",
 						Usage:   lib.T_("Apply to image"),
 						Aliases: []string{"a"},
 						Value:   false,
-						Hidden:  !lib.Env.IsAtomic,
+						Hidden:  !lib.IsAtomic(),
 					},
-				},
+					&cli.BoolFlag{
+						Name:   "skip-validation",
+						Usage:  lib.T_("Skip checking configuration packages against the host repositories"),
+						Value:  false,
+						Hidden: !lib.IsAtomic(),
+					},
+				}, rebootFlags()...),
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
-					resp, err := NewActions().Install(ctx, cmd.Args().Slice(), cmd.Bool("apply"))
+					resp, err := NewActions().Install(ctx, cmd.Args().Slice(), cmd.Bool("apply"), cmd.Bool("skip-validation"), rebootParams(cmd))
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
@@ -77,19 +250,67 @@ func CommandList() *cli.Command {
 				Usage:     lib.T_("List of packages to remove"),
 				Aliases:   []string{"rm"},
 				ArgsUsage: "packages",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{
+						Name:    "apply",
+						Usage:   lib.T_("Apply to image"),
+						Aliases: []string{"a"},
+						Value:   false,
+						Hidden:  !lib.IsAtomic(),
+					},
+					&cli.BoolFlag{
+						Name:   "skip-validation",
+						Usage:  lib.T_("Skip checking configuration packages against the host repositories"),
+						Value:  false,
+						Hidden: !lib.IsAtomic(),
+					},
+				}, rebootFlags()...),
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().Remove(ctx, cmd.Args().Slice(), cmd.Bool("apply"), cmd.Bool("skip-validation"), rebootParams(cmd))
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:  "upgrade",
+				Usage: lib.T_("Upgrade installed packages"),
 				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "security-only",
+						Usage: lib.T_("Upgrade only packages from security repositories"),
+						Value: false,
+					},
 					&cli.BoolFlag{
 						Name:    "apply",
 						Usage:   lib.T_("Apply to image"),
 						Aliases: []string{"a"},
 						Value:   false,
-						Hidden:  !lib.Env.IsAtomic,
+						Hidden:  !lib.IsAtomic(),
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: lib.T_("Show the changes of the upgrade without installing anything"),
+						Value: false,
 					},
 				},
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
-					resp, err := NewActions().Remove(ctx, cmd.Args().Slice(), cmd.Bool("apply"))
+					var resp *reply.APIResponse
+					var err error
+					switch {
+					case cmd.Bool("dry-run") && cmd.Bool("security-only"):
+						err = reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("--dry-run cannot be combined with --security-only"))
+					case cmd.Bool("dry-run"):
+						resp, err = NewActions().UpgradeDryRun(ctx)
+					case cmd.Bool("security-only"):
+						resp, err = NewActions().SecurityUpgrade(ctx, cmd.Bool("apply"))
+					default:
+						resp, err = NewActions().Upgrade(ctx, cmd.Bool("apply"))
+					}
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
@@ -101,7 +322,19 @@ func CommandList() *cli.Command {
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
 					resp, err := NewActions().Update(ctx)
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:  "check-update",
+				Usage: lib.T_("Check whether the package lists are out of date, does not require root"),
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().CheckForUpdates(ctx)
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
@@ -117,11 +350,25 @@ func CommandList() *cli.Command {
 						Usage: lib.T_("Full output of information"),
 						Value: false,
 					},
+					&cli.BoolFlag{
+						Name:  "all-versions",
+						Usage: lib.T_("Show all versions of the package available in the repositories"),
+						Value: false,
+					},
 				},
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Bool("all-versions") {
+						resp, err := NewActions().AllVersions(ctx, cmd.Args().First())
+						if err != nil {
+							return reply.CliResponse(ctx, newErrorResponse(err))
+						}
+
+						return reply.CliResponse(ctx, *resp)
+					}
+
 					resp, err := NewActions().Info(ctx, cmd.Args().First(), cmd.Bool("full"))
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
@@ -143,144 +390,1054 @@ func CommandList() *cli.Command {
 						Usage: lib.T_("Full information output"),
 						Value: false,
 					},
+					&cli.BoolFlag{
+						Name:  "name-only",
+						Usage: lib.T_("Search only by package name, without descriptions"),
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:  "interactive",
+						Usage: lib.T_("Select packages from the results and install them"),
+						Value: false,
+					},
+					&cli.BoolFlag{
+						Name:    "apply",
+						Usage:   lib.T_("Apply to image"),
+						Aliases: []string{"a"},
+						Value:   false,
+						Hidden:  !lib.IsAtomic(),
+					},
+					columnsFlag(),
+				},
+				ShellComplete: func(ctx context.Context, cmd *cli.Command) {
+					if cmd.NArg() > 0 {
+						return
+					}
+
+					for _, query := range NewActions().RecentSearchQueries(ctx, 20) {
+						fmt.Fprintln(cmd.Root().Writer, query)
+					}
+				},
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Bool("interactive") {
+						resp, err := NewActions().SearchInteractive(ctx, cmd.Args().First(), cmd.Bool("installed"), cmd.Bool("name-only"), cmd.Bool("apply"))
+						if err != nil {
+							return reply.CliResponse(ctx, newErrorResponse(err))
+						}
+
+						return reply.CliResponse(ctx, *resp)
+					}
+
+					resp, err := NewActions().Search(ctx, cmd.Args().First(), cmd.Bool("installed"), cmd.Bool("full"), cmd.Bool("name-only"))
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:  "search-history",
+				Usage: lib.T_("Recent package search queries"),
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: lib.T_("Limit of the result"),
+						Value: 20,
+					},
+					&cli.BoolFlag{
+						Name:  "clear",
+						Usage: lib.T_("Clear the search history"),
+						Value: false,
+					},
 				},
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
-					resp, err := NewActions().Search(ctx, cmd.Args().First(), cmd.Bool("installed"), cmd.Bool("full"))
+					var resp *reply.APIResponse
+					var err error
+					if cmd.Bool("clear") {
+						resp, err = NewActions().ClearSearchHistory(ctx)
+					} else {
+						resp, err = NewActions().SearchHistory(ctx, int(cmd.Int("limit")))
+					}
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
 				}),
 			},
 			{
-				Name:  "list",
-				Usage: "Построение запроса для получения списка пакетов",
+				Name:  "size-histogram",
+				Usage: lib.T_("Size distribution of installed packages"),
 				Flags: []cli.Flag{
-					&cli.StringFlag{
-						Name:  "sort",
-						Usage: lib.T_("Building query to fetch package list"),
+					&cli.FloatFlag{
+						Name:  "bucket-size",
+						Usage: lib.T_("Width of one histogram interval in MB"),
+						Value: 5,
+					},
+				},
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().SizeHistogram(ctx, cmd.Float("bucket-size"))
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:      "maintainers",
+				Usage:     lib.T_("Package maintainers with the number of their packages, or the packages of one maintainer"),
+				ArgsUsage: "[maintainer]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "full",
+						Usage: lib.T_("Full information output"),
+						Value: false,
 					},
+				},
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					var resp *reply.APIResponse
+					var err error
+					if cmd.Args().Len() == 0 {
+						resp, err = NewActions().ListMaintainers(ctx)
+					} else {
+						resp, err = NewActions().PackagesByMaintainer(ctx, strings.Join(cmd.Args().Slice(), " "), cmd.Bool("full"))
+					}
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:  "history",
+				Usage: lib.T_("History of package operations"),
+				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:  "order",
-						Usage: lib.T_("Sorting order: ASC or DESC"),
-						Value: "ASC",
+						Name:  "operation",
+						Usage: lib.T_("Filter by operation: install, remove, update or upgrade"),
 					},
 					&cli.IntFlag{
 						Name:  "limit",
-						Usage: lib.T_("Limit of the result"),
-						Value: 10,
+						Usage: lib.T_("Limit of the selection"),
+						Value: 50,
 					},
 					&cli.IntFlag{
 						Name:  "offset",
-						Usage: lib.T_("Offset of the result"),
+						Usage: lib.T_("Offset of the selection"),
 						Value: 0,
 					},
-					&cli.StringSliceFlag{
-						Name:  "filter",
-						Usage: lib.T_("Filter in the format key=value. The flag can be specified multiple times, for example: --filter name=zip --filter installed=true"),
-					},
+				},
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().OperationsHistory(ctx, cmd.String("operation"), cmd.Int("limit"), cmd.Int("offset"))
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			newListCommand("list", lib.T_("List of packages"), nil),
+			newListCommand("list-manual", lib.T_("List of manually installed packages"), []string{"install_reason=" + apt.InstallReasonManual}),
+			newListCommand("list-auto", lib.T_("List of automatically installed packages"), []string{"install_reason=" + apt.InstallReasonAuto}),
+			{
+				Name:  "apt-mark",
+				Usage: lib.T_("Lists of manually and automatically installed packages according to apt-mark"),
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().ManuallyInstalledPackages(ctx)
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:    "apt-cache-stats",
+				Usage:   lib.T_("apt cache statistics, its difference from the package database and the interrupted operation awaiting recovery"),
+				Aliases: []string{"stats"},
+				Flags: []cli.Flag{
 					&cli.BoolFlag{
-						Name:  "force-update",
-						Usage: lib.T_("Force update all packages before query"),
-						Value: false,
+						Name:  "quick",
+						Usage: lib.T_("Only count packages in the database, same as packages-count"),
 					},
-					&cli.BoolFlag{
-						Name:  "full",
-						Usage: lib.T_("Full information output"),
-						Value: false,
+				},
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Bool("quick") {
+						resp, err := NewActions().QuickStats(ctx)
+						if err != nil {
+							return reply.CliResponse(ctx, newErrorResponse(err))
+						}
+
+						return reply.CliResponse(ctx, *resp)
+					}
+
+					resp, err := NewActions().AptCacheStats(ctx)
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:  "packages-count",
+				Usage: lib.T_("Number of packages in the database, installed packages and available updates without querying apt"),
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().QuickStats(ctx)
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:  "package-manifest",
+				Usage: lib.T_("Generate a manifest of manually installed and held packages"),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Usage:   lib.T_("File to write the manifest to. By default it is printed in the response"),
+						Aliases: []string{"o"},
 					},
 				},
 				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
-					params := ListParams{
-						Sort:        cmd.String("sort"),
-						Order:       cmd.String("order"),
-						Offset:      cmd.Int("offset"),
-						Limit:       cmd.Int("limit"),
-						Filters:     cmd.StringSlice("filter"),
-						ForceUpdate: cmd.Bool("force-update"),
+					resp, err := NewActions().GenerateManifest(ctx, cmd.String("output"))
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
-					resp, err := NewActions().List(ctx, params, cmd.Bool("full"))
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
+			{
+				Name:      "apply-manifest",
+				Usage:     lib.T_("Bring the installed packages to the state described in the manifest"),
+				ArgsUsage: "file",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "apply",
+						Usage:   lib.T_("Apply to image"),
+						Aliases: []string{"a"},
+						Value:   false,
+						Hidden:  !lib.IsAtomic(),
+					},
+				},
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().ApplyManifest(ctx, cmd.Args().First(), cmd.Bool("apply"))
 					if err != nil {
-						return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+						return reply.CliResponse(ctx, newErrorResponse(err))
 					}
 
 					return reply.CliResponse(ctx, *resp)
 				}),
 			},
 			{
-				Name:    "image",
-				Usage:   lib.T_("Module for working with the image"),
-				Aliases: []string{"i"},
-				Hidden:  !lib.Env.IsAtomic,
+				Name:  "apt-pin",
+				Usage: lib.T_("Pin package versions with apt preferences"),
 				Commands: []*cli.Command{
 					{
-						Name:  "apply",
-						Usage: lib.T_("Apply changes to the host"),
+						Name:      "set",
+						Usage:     lib.T_("Pin a package to a version"),
+						ArgsUsage: "package version",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "priority",
+								Usage: lib.T_("Pin priority. A priority above 1000 allows downgrading to the pinned version"),
+								Value: apt.DefaultPinPriority,
+							},
+						},
 						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
-							resp, err := NewActions().ImageApply(ctx)
+							resp, err := NewActions().PinVersion(ctx, cmd.Args().Get(0), cmd.Args().Get(1), int(cmd.Int("priority")))
 							if err != nil {
-								return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+								return reply.CliResponse(ctx, newErrorResponse(err))
 							}
 
 							return reply.CliResponse(ctx, *resp)
 						}),
 					},
 					{
-						Name:  "status",
-						Usage: lib.T_("Image status"),
+						Name:      "remove",
+						Usage:     lib.T_("Remove the version pin of a package"),
+						ArgsUsage: "package",
 						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
-							resp, err := NewActions().ImageStatus(ctx)
+							resp, err := NewActions().UnpinVersion(ctx, cmd.Args().First())
 							if err != nil {
-								return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+								return reply.CliResponse(ctx, newErrorResponse(err))
 							}
 
 							return reply.CliResponse(ctx, *resp)
 						}),
 					},
 					{
-						Name:  "update",
-						Usage: lib.T_("Image update"),
+						Name:  "list",
+						Usage: lib.T_("List of package version pins"),
 						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
-							resp, err := NewActions().ImageUpdate(ctx)
+							resp, err := NewActions().ListPins(ctx)
 							if err != nil {
-								return reply.CliResponse(ctx, newErrorResponse(err.Error()))
+								return reply.CliResponse(ctx, newErrorResponse(err))
 							}
 
 							return reply.CliResponse(ctx, *resp)
 						}),
 					},
+				},
+			},
+			{
+				Name:  "config",
+				Usage: lib.T_("Image configuration management"),
+				Commands: []*cli.Command{
 					{
-						Name:  "history",
-						Usage: lib.T_("Image changes history"),
-						Flags: []cli.Flag{
-							&cli.StringFlag{
-								Name:  "image",
-								Usage: lib.T_("Filter by image name"),
+						Name:  "repo",
+						Usage: lib.T_("Additional repositories of the image"),
+						Commands: []*cli.Command{
+							{
+								Name:      "add",
+								Usage:     lib.T_("Add a repository to the image"),
+								ArgsUsage: "url component",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:  "key-url",
+										Usage: lib.T_("Link to the repository signing key"),
+									},
+									&cli.StringFlag{
+										Name:  "vendor",
+										Usage: lib.T_("Vendor whose key checks the repository signature. With --key-url the key is registered under this vendor"),
+									},
+								},
+								Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+									if cmd.NArg() != 2 {
+										return reply.CliResponse(ctx, newErrorResponse(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the repository URL and component, for example repo add http://example.org/repo/x86_64 classic"))))
+									}
+
+									resp, err := NewActions().AddRepository(ctx, cmd.Args().Get(0), cmd.Args().Get(1), cmd.String("key-url"), cmd.String("vendor"))
+									if err != nil {
+										return reply.CliResponse(ctx, newErrorResponse(err))
+									}
+
+									return reply.CliResponse(ctx, *resp)
+								}),
 							},
-							&cli.IntFlag{
-								Name:  "limit",
-								Usage: lib.T_("Limit of the selection"),
-								Value: 10,
+							{
+								Name:      "remove",
+								Usage:     lib.T_("Remove a repository from the image"),
+								ArgsUsage: "id",
+								Aliases:   []string{"rm"},
+								Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+									id, err := strconv.Atoi(cmd.Args().First())
+									if err != nil {
+										return reply.CliResponse(ctx, newErrorResponse(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the repository id, for example repo remove 1"))))
+									}
+
+									resp, err := NewActions().RemoveAptSourceLayer(ctx, id)
+									if err != nil {
+										return reply.CliResponse(ctx, newErrorResponse(err))
+									}
+
+									return reply.CliResponse(ctx, *resp)
+								}),
 							},
-							&cli.IntFlag{
-								Name:  "offset",
-								Usage: lib.T_("Offset of the selection"),
-								Value: 0,
+							{
+								Name:  "list",
+								Usage: lib.T_("List of additional repositories of the image"),
+								Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+									resp, err := NewActions().ListAptSourceLayers(ctx)
+									if err != nil {
+										return reply.CliResponse(ctx, newErrorResponse(err))
+									}
+
+									return reply.CliResponse(ctx, *resp)
+								}),
 							},
 						},
-						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
-							resp, err := NewActions().ImageHistory(ctx, cmd.String("image"), cmd.Int("limit"), cmd.Int("offset"))
-							if err != nil {
-								return reply.CliResponse(ctx, newErrorResponse(err.Error()))
-							}
-
-							return reply.CliResponse(ctx, *resp)
-						}),
 					},
 				},
 			},
+			{
+				Name:    "image",
+				Usage:   lib.T_("Module for working with the image"),
+				Aliases: []string{"i"},
+				Hidden:  !lib.IsAtomic(),
+				Commands: []*cli.Command{
+					{
+						Name:  "apply",
+						Usage: lib.T_("Apply changes to the host"),
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:  "skip-validation",
+								Usage: lib.T_("Skip checking configuration packages against the host repositories"),
+								Value: false,
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: lib.T_("Rebuild the image even if the configuration has not changed"),
+								Value: false,
+							},
+							&cli.BoolFlag{
+								Name:  "insecure-allow-unsigned",
+								Usage: lib.T_("Allow an unsigned base image even if requireSignedBase is enabled"),
+								Value: false,
+							},
+							&cli.DurationFlag{
+								Name:  "timeout",
+								Usage: lib.T_("Maximum image build duration, for example 45m. Overrides imageBuildTimeout from the configuration"),
+							},
+							&cli.StringFlag{
+								Name:  "from",
+								Usage: lib.T_("Base image for this build only. The configuration is not changed"),
+							},
+							&cli.BoolFlag{
+								Name:  "no-cache",
+								Usage: lib.T_("Build the image without the podman layer cache"),
+								Value: false,
+							},
+							&cli.BoolFlag{
+								Name:  "pull-always",
+								Usage: lib.T_("Always pull the base image from the registry during the build"),
+								Value: false,
+							},
+							&cli.StringFlag{
+								Name:  "arch",
+								Usage: lib.T_("Target architecture of the image: amd64, arm64 or armv7"),
+							},
+							&cli.BoolFlag{
+								Name:  "packages-only",
+								Usage: lib.T_("Rebuild only the package changes on top of the current base image digest, without checking for base image updates. The default when pinned is set in the configuration"),
+								Value: false,
+							},
+						}, rebootFlags()...),
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ImageApply(ctx, cmd.Bool("skip-validation"), cmd.Bool("force"), cmd.Bool("insecure-allow-unsigned"),
+								cmd.Duration("timeout"), rebootParams(cmd), ApplyOptions{
+									BaseImageOverride: cmd.String("from"),
+									NoCache:           cmd.Bool("no-cache"),
+									PullAlways:        cmd.Bool("pull-always"),
+									Arch:              cmd.String("arch"),
+									PackagesOnly:      cmd.Bool("packages-only"),
+								})
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "prune",
+						Usage: lib.T_("Remove old images built by apm from local storage"),
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "keep-last",
+								Usage: lib.T_("Number of most recent images to keep"),
+								Value: 3,
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ImagePrune(ctx, int(cmd.Int("keep-last")))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "cache",
+						Usage: lib.T_("Manage the podman build cache"),
+						Commands: []*cli.Command{
+							{
+								Name:  "size",
+								Usage: lib.T_("Show the size of the build cache"),
+								Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+									resp, err := NewActions().ImageCacheSize(ctx)
+									if err != nil {
+										return reply.CliResponse(ctx, newErrorResponse(err))
+									}
+
+									return reply.CliResponse(ctx, *resp)
+								}),
+							},
+							{
+								Name:  "prune",
+								Usage: lib.T_("Remove the build cache"),
+								Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+									resp, err := NewActions().ImageCachePrune(ctx)
+									if err != nil {
+										return reply.CliResponse(ctx, newErrorResponse(err))
+									}
+
+									return reply.CliResponse(ctx, *resp)
+								}),
+							},
+						},
+					},
+					{
+						Name:  "gc",
+						Usage: lib.T_("Remove built images and history records according to keepImages and keepHistoryDays"),
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ImageGC(ctx)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "sbom",
+						Usage: lib.T_("Generate a software bill of materials for the installed packages"),
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "sbom-format",
+								Usage: lib.T_("SBOM format: spdx-json, cyclonedx-json"),
+								Value: service.SBOMFormatSPDX,
+							},
+							&cli.StringFlag{
+								Name:    "output",
+								Usage:   lib.T_("File to write the SBOM to. By default it is printed to stdout"),
+								Aliases: []string{"o"},
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().GenerateSBOM(ctx, cmd.String("sbom-format"), cmd.String("output"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							// Документ выводится как есть, чтобы его можно было передать другим инструментам
+							if document, ok := resp.Data.(map[string]interface{})["sbom"].(json.RawMessage); ok {
+								reply.StopSpinner()
+								fmt.Println(string(document))
+								return nil
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "build",
+						Usage: lib.T_("Build the image without switching the host to it"),
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "skip-validation",
+								Usage: lib.T_("Skip checking configuration packages against the host repositories"),
+								Value: false,
+							},
+							&cli.BoolFlag{
+								Name:  "insecure-allow-unsigned",
+								Usage: lib.T_("Allow an unsigned base image even if requireSignedBase is enabled"),
+								Value: false,
+							},
+							&cli.DurationFlag{
+								Name:  "timeout",
+								Usage: lib.T_("Maximum image build duration, for example 45m. Overrides imageBuildTimeout from the configuration"),
+							},
+							&cli.StringSliceFlag{
+								Name:  "label",
+								Usage: lib.T_("Label in the key=value format attached only to this build. Can be specified multiple times"),
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ImageBuild(ctx, cmd.Bool("skip-validation"), cmd.Bool("insecure-allow-unsigned"), cmd.Duration("timeout"),
+								cmd.StringSlice("label"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "switch",
+						Usage: lib.T_("Switch the host to the previously built image"),
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "to",
+								Usage: lib.T_("Tag, digest, image ID or history entry number of a locally available image"),
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ImageSwitch(ctx, cmd.String("to"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "status",
+						Usage: lib.T_("Image status"),
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ImageStatus(ctx)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "cancel-reboot",
+						Usage: lib.T_("Cancel the reboot scheduled after applying the image"),
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ImageCancelReboot(ctx)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "check",
+						Usage: lib.T_("Check whether a newer base image is available without downloading it"),
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ImageCheck(ctx)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "plan",
+						Usage: lib.T_("Show what the next image apply will do without building the image"),
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ImagePlan(ctx)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "update",
+						Usage: lib.T_("Image update"),
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "skip-validation",
+								Usage: lib.T_("Skip checking configuration packages against the host repositories"),
+								Value: false,
+							},
+							&cli.BoolFlag{
+								Name:  "insecure-allow-unsigned",
+								Usage: lib.T_("Allow an unsigned base image even if requireSignedBase is enabled"),
+								Value: false,
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: lib.T_("Rebuild the image even if the base image has not changed, for example after manual changes that did not touch the package lists"),
+								Value: false,
+							},
+							&cli.BoolFlag{
+								Name:  "no-switch",
+								Usage: lib.T_("Only build the image and leave it pending without switching the host to it"),
+								Value: false,
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ImageUpdate(ctx, cmd.Bool("skip-validation"), cmd.Bool("insecure-allow-unsigned"),
+								cmd.Bool("force"), cmd.Bool("no-switch"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "rollback",
+						Usage: lib.T_("Image generations available for rollback"),
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "list",
+								Usage: lib.T_("Show generations available for rollback"),
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							if !cmd.Bool("list") {
+								return reply.CliResponse(ctx, newErrorResponse(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Use --list to show generations available for rollback"))))
+							}
+
+							resp, err := NewActions().ImageRollbackList(ctx)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "history",
+						Usage: lib.T_("Image changes history"),
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "image",
+								Usage: lib.T_("Filter by image name"),
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: lib.T_("Limit of the selection"),
+								Value: 10,
+							},
+							&cli.IntFlag{
+								Name:  "offset",
+								Usage: lib.T_("Offset of the selection"),
+								Value: 0,
+							},
+							&cli.IntFlag{
+								Name:  "show",
+								Usage: lib.T_("Show one history entry in full detail by its id"),
+							},
+							&cli.StringFlag{
+								Name:  "since",
+								Usage: lib.T_("Show images built after the given time: a duration such as 30d, 2w, 12h or a date such as 2006-01-02"),
+							},
+							&cli.StringFlag{
+								Name:  "until",
+								Usage: lib.T_("Show images built before the given time: a duration such as 30d, 2w, 12h or a date such as 2006-01-02"),
+							},
+							&cli.StringFlag{
+								Name:  "status",
+								Usage: lib.T_("Filter by status: built, deployed or superseded"),
+							},
+							columnsFlag(),
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							if cmd.IsSet("show") {
+								resp, err := NewActions().ImageHistoryShow(ctx, cmd.Int("show"))
+								if err != nil {
+									return reply.CliResponse(ctx, newErrorResponse(err))
+								}
+
+								return reply.CliResponse(ctx, *resp)
+							}
+
+							now := time.Now()
+							since, err := helper.ParseTimeBound(cmd.String("since"), now)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							until, err := helper.ParseTimeBound(cmd.String("until"), now)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							resp, err := NewActions().ImageHistory(ctx, cmd.String("image"), cmd.Int("limit"), cmd.Int("offset"), since, until,
+								cmd.String("status"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "build-log",
+						Usage: lib.T_("Show the build log of an image history entry"),
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:     "history-id",
+								Usage:    lib.T_("Image history entry id"),
+								Required: true,
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().GetBuildLog(ctx, cmd.Int("history-id"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:      "add-source",
+						Usage:     lib.T_("Add a custom apt source to the image"),
+						ArgsUsage: "\"source-line\"",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "key-url",
+								Usage: lib.T_("Link to the repository signing key"),
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().AddAptSourceLayer(ctx, strings.Join(cmd.Args().Slice(), " "), cmd.String("key-url"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:      "remove-source",
+						Usage:     lib.T_("Remove a custom apt source from the image"),
+						ArgsUsage: "id",
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							id, err := strconv.Atoi(cmd.Args().First())
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the source id, for example remove-source 1"))))
+							}
+
+							resp, err := NewActions().RemoveAptSourceLayer(ctx, id)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "config",
+						Usage: lib.T_("Image configuration"),
+						Commands: []*cli.Command{
+							{
+								Name:      "import-dockerfile",
+								Usage:     lib.T_("Replace the image configuration with one converted from a Dockerfile"),
+								ArgsUsage: "path",
+								Aliases:   []string{"convert"},
+								Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+									resp, err := NewActions().ImportDockerfile(ctx, cmd.Args().First())
+									if err != nil {
+										return reply.CliResponse(ctx, newErrorResponse(err))
+									}
+
+									return reply.CliResponse(ctx, *resp)
+								}),
+							},
+						},
+					},
+					{
+						Name:      "add-env",
+						Usage:     lib.T_("Add an environment variable to the image"),
+						ArgsUsage: "KEY VALUE",
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							if cmd.NArg() != 2 {
+								return reply.CliResponse(ctx, newErrorResponse(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the variable name and value, for example add-env LANG ru_RU.UTF-8"))))
+							}
+
+							resp, err := NewActions().AddEnvLayer(ctx, cmd.Args().Get(0), cmd.Args().Get(1))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:      "remove-env",
+						Usage:     lib.T_("Remove an environment variable from the image"),
+						ArgsUsage: "KEY",
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().RemoveEnvLayer(ctx, cmd.Args().First())
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:      "add-label",
+						Usage:     lib.T_("Add a label to the image"),
+						ArgsUsage: "KEY VALUE",
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							if cmd.NArg() != 2 {
+								return reply.CliResponse(ctx, newErrorResponse(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("You must specify the label key and value, for example add-label org.example.branch main"))))
+							}
+
+							resp, err := NewActions().AddLabel(ctx, cmd.Args().Get(0), cmd.Args().Get(1))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:      "remove-label",
+						Usage:     lib.T_("Remove a label from the image"),
+						ArgsUsage: "KEY",
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().RemoveLabel(ctx, cmd.Args().First())
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "list-env",
+						Usage: lib.T_("List of environment variables of the image"),
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ListEnvLayers(ctx)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:      "pin-package",
+						Usage:     lib.T_("Pin a package in the image with apt-mark hold"),
+						ArgsUsage: "package",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "version",
+								Usage: lib.T_("Install this version of the package before pinning it"),
+							},
+						},
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().PinPackageInConfig(ctx, cmd.Args().First(), cmd.String("version"))
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:      "unpin-package",
+						Usage:     lib.T_("Remove a package pin from the image"),
+						ArgsUsage: "package",
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().UnpinPackageInConfig(ctx, cmd.Args().First())
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "list-pinned",
+						Usage: lib.T_("List of packages pinned in the image"),
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ListPinnedPackages(ctx)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "check-conflicts",
+						Usage: lib.T_("Check the packages of the image configuration for apt conflicts without building"),
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().CheckImageConflicts(ctx)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+					{
+						Name:  "sources",
+						Usage: lib.T_("List of custom apt sources of the image"),
+						Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+							resp, err := NewActions().ListAptSourceLayers(ctx)
+							if err != nil {
+								return reply.CliResponse(ctx, newErrorResponse(err))
+							}
+
+							return reply.CliResponse(ctx, *resp)
+						}),
+					},
+				},
+			},
+		},
+	}
+
+	setupCompletion(command)
+
+	return command
+}
+
+// LogsCommand создаёт команду apm logs, выводящую последние строки журнала apm для отчёта об ошибке.
+func LogsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "logs",
+		Usage: lib.T_("Show the last lines of the apm log"),
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "tail",
+				Usage: lib.T_("Number of lines to show"),
+				Value: 50,
+			},
+		},
+		Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+			resp, err := NewActions().LogTail(ctx, int(cmd.Int("tail")))
+			if err != nil {
+				return reply.CliResponse(ctx, newErrorResponse(err))
+			}
+
+			return reply.CliResponse(ctx, *resp)
+		}),
+	}
+}
+
+// RecoverCommand создаёт команду apm recover, завершающую операцию, прерванную при прошлом запуске apm.
+func RecoverCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "recover",
+		Usage: lib.T_("Complete an operation interrupted at the previous apm run"),
+		Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+			resp, err := NewActions().Recover(ctx)
+			if err != nil {
+				return reply.CliResponse(ctx, newErrorResponse(err))
+			}
+
+			return reply.CliResponse(ctx, *resp)
+		}),
+	}
+}
+
+// DoctorCommand создаёт команду apm doctor с диагностикой окружения apm.
+func DoctorCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "doctor",
+		Usage: lib.T_("Show how the system type was detected and other diagnostics"),
+		Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+			resp, err := NewActions().Doctor(ctx)
+			if err != nil {
+				return reply.CliResponse(ctx, newErrorResponse(err))
+			}
+
+			return reply.CliResponse(ctx, *resp)
+		}),
+	}
+}
+
+// DBCommand создаёт команду apm db со сведениями о базах данных apm.
+func DBCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "db",
+		Usage: lib.T_("apm databases"),
+		Commands: []*cli.Command{
+			{
+				Name:  "path",
+				Usage: lib.T_("Show which databases are used and why"),
+				Action: withGlobalWrapper(func(ctx context.Context, cmd *cli.Command) error {
+					resp, err := NewActions().DBPath(ctx)
+					if err != nil {
+						return reply.CliResponse(ctx, newErrorResponse(err))
+					}
+
+					return reply.CliResponse(ctx, *resp)
+				}),
+			},
 		},
 	}
 }
@@ -0,0 +1,65 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package system
+
+import (
+	"apm/cmd/common/helper"
+	"apm/cmd/system/apt"
+	"apm/lib"
+	"context"
+
+	"github.com/urfave/cli/v3"
+)
+
+// completePackageNames дополняет названия пакетов из базы пакетов образа. База не создаётся и не
+// обновляется: если она пуста или недоступна, вариантов нет.
+func completePackageNames(ctx context.Context, prefix string) []string {
+	names, err := apt.NewPackageDBService(lib.GetSystemDB()).PackageNamesByPrefix(ctx, prefix, helper.CompletionLimit)
+	if err != nil {
+		lib.Log.Debug(err.Error())
+		return nil
+	}
+
+	return names
+}
+
+// completeFilterFields дополняет поля фильтра в виде "поле=".
+func completeFilterFields(ctx context.Context, prefix string) []string {
+	var fields []string
+	for _, field := range apt.FilterFields() {
+		fields = append(fields, field+"=")
+	}
+
+	return fields
+}
+
+// setupCompletion назначает дополнение командам system.
+func setupCompletion(command *cli.Command) {
+	helper.SetupCompletion(command,
+		map[string]helper.Completer{
+			"sort":   helper.StaticCompleter(apt.SortFields()),
+			"filter": completeFilterFields,
+		},
+		map[string]helper.Completer{
+			"install":             completePackageNames,
+			"remove":              completePackageNames,
+			"info":                completePackageNames,
+			"image pin-package":   completePackageNames,
+			"image unpin-package": completePackageNames,
+		},
+	)
+}
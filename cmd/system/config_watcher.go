@@ -0,0 +1,178 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package system
+
+import (
+	"apm/cmd/system/service"
+	"apm/lib"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// configReloadDelay пауза после последнего события, чтобы редактор успел дописать файл.
+const configReloadDelay = 500 * time.Millisecond
+
+// configWatchMask события каталога, после которых файл конфигурации мог измениться.
+// Каталог отслеживается целиком, потому что редакторы сохраняют файл через переименование временного.
+const configWatchMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE | syscall.IN_DELETE
+
+// ConfigWatcher следит за файлом конфигурации образа и перечитывает его при изменении извне.
+type ConfigWatcher struct {
+	actions *Actions
+	path    string
+}
+
+// NewConfigWatcher создаёт наблюдатель за файлом конфигурации образа.
+func NewConfigWatcher(a *Actions) *ConfigWatcher {
+	return &ConfigWatcher{
+		actions: a,
+		path:    lib.Env.PathImageFile,
+	}
+}
+
+// Run отслеживает изменения файла до отмены контекста.
+func (w *ConfigWatcher) Run(ctx context.Context) {
+	if err := w.actions.serviceHostConfig.LoadConfig(); err != nil {
+		lib.Log.Error(err.Error())
+	}
+
+	events, err := w.watch(ctx)
+	if err != nil {
+		lib.Log.Error(err.Error())
+		return
+	}
+
+	timer := time.NewTimer(configReloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			timer.Reset(configReloadDelay)
+		case <-timer.C:
+			w.reload()
+		}
+	}
+}
+
+// watch подписывается через inotify на каталог файла конфигурации и возвращает канал событий для этого файла.
+func (w *ConfigWatcher) watch(ctx context.Context) (<-chan struct{}, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to watch the configuration file %s: %v"), w.path, err)
+	}
+
+	if _, err = syscall.InotifyAddWatch(fd, filepath.Dir(w.path), configWatchMask); err != nil {
+		_ = syscall.Close(fd)
+		return nil, fmt.Errorf(lib.T_("Failed to watch the configuration file %s: %v"), w.path, err)
+	}
+
+	// Неблокирующий дескриптор обслуживается планировщиком Go, поэтому Close прерывает ожидающий Read
+	file := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		<-ctx.Done()
+		_ = file.Close()
+	}()
+
+	events := make(chan struct{}, 1)
+	name := filepath.Base(w.path)
+	go func() {
+		defer close(events)
+
+		buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				if ctx.Err() == nil {
+					lib.Log.Error(fmt.Sprintf(lib.T_("Failed to watch the configuration file %s: %v"), w.path, err))
+				}
+				return
+			}
+
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				start := offset + syscall.SizeofInotifyEvent
+				offset = start + int(event.Len)
+
+				eventName := string(bytes.TrimRight(buf[start:offset], "\x00"))
+				if eventName != name {
+					continue
+				}
+
+				select {
+				case events <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// reload перечитывает конфигурацию и сообщает об изменениях. Ошибочный файл отклоняется.
+func (w *ConfigWatcher) reload() {
+	if _, err := os.Stat(w.path); os.IsNotExist(err) {
+		return
+	}
+
+	previous, err := w.actions.serviceHostConfig.ReloadConfig()
+	if err != nil {
+		lib.Log.Errorf(lib.T_("The configuration file was changed externally and rejected: %v"), err)
+		sendConfigChanged(false, err.Error())
+		return
+	}
+
+	changes := service.SummarizeConfigChanges(previous, w.actions.serviceHostConfig.Config)
+	if len(changes) == 0 {
+		return
+	}
+
+	summary := strings.Join(changes, "; ")
+	lib.Log.Infof(lib.T_("The configuration file was changed externally: %s"), summary)
+	sendConfigChanged(true, summary)
+}
+
+// sendConfigChanged отправляет сигнал ConfigChanged с признаком корректности файла и описанием изменений или ошибки.
+func sendConfigChanged(valid bool, summary string) {
+	if lib.DBUSConn == nil {
+		return
+	}
+
+	objPath := dbus.ObjectPath("/com/application/APM")
+	signalName := "com.application.APM.ConfigChanged"
+
+	err := lib.DBUSConn.Emit(objPath, signalName, valid, summary)
+	if err != nil {
+		lib.Log.Errorf(lib.T_("Error sending notification: %v"), err)
+	}
+}
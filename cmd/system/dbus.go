@@ -17,10 +17,11 @@
 package system
 
 import (
+	"apm/cmd/common/reply"
+	"apm/cmd/system/service"
 	"apm/lib"
-	"context"
 	"encoding/json"
-	"fmt"
+	"time"
 
 	"github.com/godbus/dbus/v5"
 )
@@ -37,173 +38,685 @@ func NewDBusWrapper(a *Actions) *DBusWrapper {
 
 // Install – обёртка над Actions.Install.
 func (w *DBusWrapper) Install(packages []string, applyAtomic bool, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
-	resp, err := w.actions.Install(ctx, packages, applyAtomic)
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.Install(ctx, packages, applyAtomic, false, RebootParams{})
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
-	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // Remove – обёртка над Actions.Remove.
 func (w *DBusWrapper) Remove(packages []string, applyAtomic bool, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
-	resp, err := w.actions.Remove(ctx, packages, applyAtomic)
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.Remove(ctx, packages, applyAtomic, false, RebootParams{})
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
-	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // Update – обёртка над Actions.Update.
 func (w *DBusWrapper) Update(transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	resp, err := w.actions.Update(ctx)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+	return reply.DBusResponse(ctx, resp)
+}
+
+// CheckForUpdates – обёртка над Actions.CheckForUpdates.
+func (w *DBusWrapper) CheckForUpdates(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.CheckForUpdates(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // List – обёртка над Actions.List.
 func (w *DBusWrapper) List(paramsJSON string, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	var params ListParams
 	if err := json.Unmarshal([]byte(paramsJSON), &params); err != nil {
-		return "", dbus.MakeFailedError(fmt.Errorf(lib.T_("Failed to parse JSON: %w"), err))
+		return "", reply.DBusError(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Failed to parse JSON: %w"), err))
 	}
 
 	resp, err := w.actions.List(ctx, params, true)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// CountPackages – обёртка над Actions.Count.
+func (w *DBusWrapper) CountPackages(paramsJSON string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	var params ListParams
+	if err := json.Unmarshal([]byte(paramsJSON), &params); err != nil {
+		return "", reply.DBusError(reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Failed to parse JSON: %w"), err))
+	}
+
+	resp, err := w.actions.Count(ctx, params)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ListUpgradablePackages – обёртка над Actions.ListUpgradablePackages.
+func (w *DBusWrapper) ListUpgradablePackages(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ListUpgradablePackages(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// AptCacheStats – обёртка над Actions.AptCacheStats.
+func (w *DBusWrapper) AptCacheStats(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.AptCacheStats(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+	return reply.DBusResponse(ctx, resp)
+}
+
+// Doctor – обёртка над Actions.Doctor.
+func (w *DBusWrapper) Doctor(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.Doctor(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// QuickStats – обёртка над Actions.QuickStats.
+func (w *DBusWrapper) QuickStats(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.QuickStats(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
+}
+
+// Recover – обёртка над Actions.Recover.
+func (w *DBusWrapper) Recover(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.Recover(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
 }
 
 // Info – обёртка над Actions.Info.
 func (w *DBusWrapper) Info(packageName string, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	resp, err := w.actions.Info(ctx, packageName, true)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+	return reply.DBusResponse(ctx, resp)
+}
+
+// AllVersions – обёртка над Actions.AllVersions.
+func (w *DBusWrapper) AllVersions(packageName string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.AllVersions(ctx, packageName)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // CheckInstall – обёртка над Actions.CheckInstall.
 func (w *DBusWrapper) CheckInstall(packages []string, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	resp, err := w.actions.CheckInstall(ctx, packages)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+	return reply.DBusResponse(ctx, resp)
+}
+
+// UpgradeDryRun – обёртка над Actions.UpgradeDryRun.
+func (w *DBusWrapper) UpgradeDryRun(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.UpgradeDryRun(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // CheckRemove – обёртка над Actions.CheckRemove.
 func (w *DBusWrapper) CheckRemove(packages []string, transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	resp, err := w.actions.CheckRemove(ctx, packages)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
-	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // Search – обёртка над Actions.Search.
 func (w *DBusWrapper) Search(packageName string, transaction string, installed bool) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
-	resp, err := w.actions.Search(ctx, packageName, installed, true)
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.Search(ctx, packageName, installed, true, false)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
-	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // ImageApply – обёртка над Actions.Apply.
 func (w *DBusWrapper) ImageApply(transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
-	resp, err := w.actions.ImageApply(ctx)
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageApply(ctx, false, false, false, 0, RebootParams{}, ApplyOptions{})
+	if err != nil {
+		return "", makeImageError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageApplySkipValidation – обёртка над Actions.ImageApply с отключаемой проверкой пакетов конфигурации
+// по репозиторию хоста, для пакетов из репозиториев, настроенных внутри образа.
+func (w *DBusWrapper) ImageApplySkipValidation(skipValidation bool, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageApply(ctx, skipValidation, false, false, 0, RebootParams{}, ApplyOptions{})
+	if err != nil {
+		return "", makeImageError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageApplyNoCached – обёртка над Actions.ImageApply с параметрами podman build --no-cache и --pull=always.
+func (w *DBusWrapper) ImageApplyNoCached(noCache bool, pullAlways bool, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageApply(ctx, false, false, false, 0, RebootParams{}, ApplyOptions{NoCache: noCache, PullAlways: pullAlways})
+	if err != nil {
+		return "", makeImageError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageApplyArch – обёртка над Actions.ImageApply со сборкой образа для архитектуры arch.
+func (w *DBusWrapper) ImageApplyArch(arch string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageApply(ctx, false, false, false, 0, RebootParams{}, ApplyOptions{Arch: arch})
+	if err != nil {
+		return "", makeImageError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageApplyPackagesOnly – обёртка над Actions.ImageApply со сборкой поверх текущего базового образа.
+func (w *DBusWrapper) ImageApplyPackagesOnly(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageApply(ctx, false, false, false, 0, RebootParams{}, ApplyOptions{PackagesOnly: true})
+	if err != nil {
+		return "", makeImageError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageHistory – обёртка над Actions.ImageHistory. since и until задаются в секундах Unix, нулевые значения не ограничивают выборку.
+func (w *DBusWrapper) ImageHistory(transaction string, imageName string, limit int64, offset int64, since int64, until int64,
+	status string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	var sinceTime, untilTime time.Time
+	if since > 0 {
+		sinceTime = time.Unix(since, 0)
+	}
+	if until > 0 {
+		untilTime = time.Unix(until, 0)
+	}
+
+	resp, err := w.actions.ImageHistory(ctx, imageName, limit, offset, sinceTime, untilTime, status)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// SizeHistogram – обёртка над Actions.SizeHistogram.
+func (w *DBusWrapper) SizeHistogram(bucketSizeMB float64, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.SizeHistogram(ctx, bucketSizeMB)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// PinVersion – обёртка над Actions.PinVersion.
+func (w *DBusWrapper) PinVersion(packageName string, version string, priority int, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.PinVersion(ctx, packageName, version, priority)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+	return reply.DBusResponse(ctx, resp)
+}
+
+// UnpinVersion – обёртка над Actions.UnpinVersion.
+func (w *DBusWrapper) UnpinVersion(packageName string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.UnpinVersion(ctx, packageName)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ListPins – обёртка над Actions.ListPins.
+func (w *DBusWrapper) ListPins(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ListPins(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
-// ImageHistory – обёртка над Actions.ImageHistory.
-func (w *DBusWrapper) ImageHistory(transaction string, imageName string, limit int64, offset int64) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
-	resp, err := w.actions.ImageHistory(ctx, imageName, limit, offset)
+// ListMaintainers – обёртка над Actions.ListMaintainers.
+func (w *DBusWrapper) ListMaintainers(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ListMaintainers(ctx)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+	return reply.DBusResponse(ctx, resp)
+}
+
+// PackagesByMaintainer – обёртка над Actions.PackagesByMaintainer.
+func (w *DBusWrapper) PackagesByMaintainer(maintainer string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.PackagesByMaintainer(ctx, maintainer, true)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ManuallyInstalledPackages – обёртка над Actions.ManuallyInstalledPackages.
+func (w *DBusWrapper) ManuallyInstalledPackages(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ManuallyInstalledPackages(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// OperationsHistory – обёртка над Actions.OperationsHistory.
+func (w *DBusWrapper) OperationsHistory(op string, limit int64, offset int64, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.OperationsHistory(ctx, op, limit, offset)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImagePrune – обёртка над Actions.ImagePrune.
+func (w *DBusWrapper) ImagePrune(keepLast int64, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImagePrune(ctx, int(keepLast))
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageBuild – обёртка над Actions.ImageBuild.
+func (w *DBusWrapper) ImageBuild(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageBuild(ctx, false, false, 0, nil)
+	if err != nil {
+		return "", makeImageError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageSwitch – обёртка над Actions.ImageSwitch.
+func (w *DBusWrapper) ImageSwitch(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageSwitch(ctx, "")
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageSwitchTo – обёртка над Actions.ImageSwitch с указанием образа.
+func (w *DBusWrapper) ImageSwitchTo(reference string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageSwitch(ctx, reference)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
 }
 
 // ImageUpdate – обёртка над Actions.ImageUpdate.
 func (w *DBusWrapper) ImageUpdate(transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
-	resp, err := w.actions.ImageUpdate(ctx)
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageUpdate(ctx, false, false, false, false)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", makeImageError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageUpdateForce – обёртка над Actions.ImageUpdate, пересобирающая образ, даже если базовый образ не изменился.
+func (w *DBusWrapper) ImageUpdateForce(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageUpdate(ctx, false, false, true, false)
+	if err != nil {
+		return "", makeImageError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
 }
 
 // ImageStatus – обёртка над Actions.ImageStatus.
 func (w *DBusWrapper) ImageStatus(transaction string) (string, *dbus.Error) {
-	ctx := context.WithValue(context.Background(), "transaction", transaction)
+	ctx := reply.DBusContext(transaction)
 	resp, err := w.actions.ImageStatus(ctx)
 	if err != nil {
-		return "", dbus.MakeFailedError(err)
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageCancelReboot – обёртка над Actions.ImageCancelReboot.
+func (w *DBusWrapper) ImageCancelReboot(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageCancelReboot(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageCheck – обёртка над Actions.ImageCheck.
+func (w *DBusWrapper) ImageCheck(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageCheck(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImagePlan – обёртка над Actions.ImagePlan.
+func (w *DBusWrapper) ImagePlan(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImagePlan(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// GetAvailableUpdates – обёртка над Actions.GetAvailableUpdates.
+func (w *DBusWrapper) GetAvailableUpdates(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.GetAvailableUpdates(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// AddAptSourceLayer – обёртка над Actions.AddAptSourceLayer.
+func (w *DBusWrapper) AddAptSourceLayer(sourceLine string, keyURL string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.AddAptSourceLayer(ctx, sourceLine, keyURL)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// RemoveAptSourceLayer – обёртка над Actions.RemoveAptSourceLayer.
+func (w *DBusWrapper) RemoveAptSourceLayer(id int64, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.RemoveAptSourceLayer(ctx, int(id))
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ListAptSourceLayers – обёртка над Actions.ListAptSourceLayers.
+func (w *DBusWrapper) ListAptSourceLayers(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ListAptSourceLayers(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImportDockerfile – обёртка над Actions.ImportDockerfile.
+func (w *DBusWrapper) ImportDockerfile(dockerfilePath string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImportDockerfile(ctx, dockerfilePath)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// AddEnvLayer – обёртка над Actions.AddEnvLayer.
+func (w *DBusWrapper) AddEnvLayer(key string, value string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.AddEnvLayer(ctx, key, value)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// AddLabel – обёртка над Actions.AddLabel.
+func (w *DBusWrapper) AddLabel(key string, value string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.AddLabel(ctx, key, value)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// RemoveLabel – обёртка над Actions.RemoveLabel.
+func (w *DBusWrapper) RemoveLabel(key string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.RemoveLabel(ctx, key)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// RemoveEnvLayer – обёртка над Actions.RemoveEnvLayer.
+func (w *DBusWrapper) RemoveEnvLayer(key string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.RemoveEnvLayer(ctx, key)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ListEnvLayers – обёртка над Actions.ListEnvLayers.
+func (w *DBusWrapper) ListEnvLayers(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ListEnvLayers(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// PinPackageInConfig – обёртка над Actions.PinPackageInConfig.
+func (w *DBusWrapper) PinPackageInConfig(packageName string, version string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.PinPackageInConfig(ctx, packageName, version)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// UnpinPackageInConfig – обёртка над Actions.UnpinPackageInConfig.
+func (w *DBusWrapper) UnpinPackageInConfig(packageName string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.UnpinPackageInConfig(ctx, packageName)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ListPinnedPackages – обёртка над Actions.ListPinnedPackages.
+func (w *DBusWrapper) ListPinnedPackages(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ListPinnedPackages(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageCheckConflicts – обёртка над Actions.CheckImageConflicts.
+func (w *DBusWrapper) ImageCheckConflicts(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.CheckImageConflicts(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// AddRepository – обёртка над Actions.AddRepository.
+func (w *DBusWrapper) AddRepository(repoURL string, component string, keyURL string, vendor string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.AddRepository(ctx, repoURL, component, keyURL, vendor)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageCacheSize – обёртка над Actions.ImageCacheSize.
+func (w *DBusWrapper) ImageCacheSize(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageCacheSize(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageCachePrune – обёртка над Actions.ImageCachePrune.
+func (w *DBusWrapper) ImageCachePrune(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageCachePrune(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	data, jerr := json.Marshal(resp)
-	if jerr != nil {
-		return "", dbus.MakeFailedError(jerr)
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageGC – обёртка над Actions.ImageGC.
+func (w *DBusWrapper) ImageGC(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageGC(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
 	}
-	return string(data), nil
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageRollbackList – обёртка над Actions.ImageRollbackList.
+func (w *DBusWrapper) ImageRollbackList(transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageRollbackList(ctx)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// GenerateSBOM – обёртка над Actions.GenerateSBOM.
+func (w *DBusWrapper) GenerateSBOM(format string, output string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.GenerateSBOM(ctx, format, output)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// GenerateManifest – обёртка над Actions.GenerateManifest.
+func (w *DBusWrapper) GenerateManifest(output string, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.GenerateManifest(ctx, output)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ApplyManifest – обёртка над Actions.ApplyManifest.
+func (w *DBusWrapper) ApplyManifest(filePath string, applyAtomic bool, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ApplyManifest(ctx, filePath, applyAtomic)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// ImageHistoryShow – обёртка над Actions.ImageHistoryShow.
+func (w *DBusWrapper) ImageHistoryShow(id int64, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.ImageHistoryShow(ctx, id)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// GetBuildLog – обёртка над Actions.GetBuildLog.
+func (w *DBusWrapper) GetBuildLog(historyID int64, transaction string) (string, *dbus.Error) {
+	ctx := reply.DBusContext(transaction)
+	resp, err := w.actions.GetBuildLog(ctx, historyID)
+	if err != nil {
+		return "", reply.DBusError(err)
+	}
+	return reply.DBusResponse(ctx, resp)
+}
+
+// makeImageError преобразует ошибку сборки образа в ошибку D-Bus.
+// Ошибки проверки подписи базового образа получают отдельные имена, чтобы клиенты могли их различать.
+func makeImageError(err error) *dbus.Error {
+	if signatureErr, ok := service.IsSignatureError(err); ok {
+		name := "com.application.system.Error.UnsignedBaseImage"
+		if signatureErr.Code == service.ErrBaseImageRejected {
+			name = "com.application.system.Error.BaseImageRejected"
+		}
+
+		return dbus.NewError(name, []interface{}{err.Error()})
+	}
+
+	return reply.DBusError(err)
 }
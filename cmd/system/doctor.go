@@ -0,0 +1,54 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package system
+
+import (
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"fmt"
+)
+
+// Doctor выводит сведения для диагностики: как определён тип системы и по каким признакам, а также
+// операцию, прерванную при прошлом запуске apm.
+func (a *Actions) Doctor(ctx context.Context) (*reply.APIResponse, error) {
+	detection := lib.GetAtomicDetection()
+
+	message := lib.T_("Classic system")
+	if detection.IsAtomic {
+		message = lib.T_("Atomic system")
+	}
+	if detection.Source == "config" {
+		message += lib.T_(", set by the atomic parameter of the configuration")
+	}
+
+	pending := pendingRecovery()
+	if pending != nil {
+		message += fmt.Sprintf(lib.T_(". The operation %s was interrupted, run apm recover"), pending.Operation)
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":         message,
+			"atomic":          detection,
+			"pendingRecovery": pending,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
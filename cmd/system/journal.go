@@ -0,0 +1,227 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package system
+
+import (
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// pendingOperationKey ключ в KV-хранилище с журналом незавершённой операции.
+const pendingOperationKey = "system:pendingOperation"
+
+// Шаги операции, которые записываются в журнал после выполнения.
+const (
+	// OperationStepApt транзакция apt завершена
+	OperationStepApt = "apt"
+	// OperationStepPackagesDB база пакетов синхронизирована с системой
+	OperationStepPackagesDB = "packagesDB"
+	// OperationStepConfig изменения записаны в конфигурацию образа
+	OperationStepConfig = "config"
+)
+
+// PendingOperation запись журнала многошаговой операции. Запись создаётся перед транзакцией apt и удаляется
+// после успешного завершения, поэтому оставшаяся запись означает, что apm был прерван и база пакетов или
+// конфигурация образа могут расходиться с системой.
+type PendingOperation struct {
+	Operation string   `json:"operation"`
+	Packages  []string `json:"packages"`
+	Apply     bool     `json:"apply"`
+	Steps     []string `json:"steps"`
+	StartedAt string   `json:"startedAt"`
+}
+
+// Done сообщает, что шаг step операции уже выполнен.
+func (p *PendingOperation) Done(step string) bool {
+	return slices.Contains(p.Steps, step)
+}
+
+// ReadPendingOperation возвращает незавершённую операцию из журнала или nil, если её нет.
+func ReadPendingOperation() (*PendingOperation, error) {
+	data, err := lib.GetDBKv().Get([]byte(pendingOperationKey))
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Error reading the operation journal: %v"), err)
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var operation PendingOperation
+	if err = json.Unmarshal(data, &operation); err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to parse JSON: %v"), err)
+	}
+
+	return &operation, nil
+}
+
+// pendingRecovery возвращает незавершённую операцию для сводки состояния. Без прав root хранилище, которое
+// ещё не открыто, не открывается, и операция не выводится.
+func pendingRecovery() *PendingOperation {
+	if lib.CheckDBKv() == nil && syscall.Geteuid() != 0 {
+		return nil
+	}
+
+	pending, err := ReadPendingOperation()
+	if err != nil {
+		lib.Log.Warning(err.Error())
+		return nil
+	}
+
+	return pending
+}
+
+// writePendingOperation сохраняет запись журнала.
+func writePendingOperation(operation *PendingOperation) error {
+	data, err := json.Marshal(operation)
+	if err != nil {
+		return err
+	}
+
+	if err = lib.GetDBKv().Put([]byte(pendingOperationKey), data); err != nil {
+		return fmt.Errorf(lib.T_("Error writing the operation journal: %v"), err)
+	}
+
+	return nil
+}
+
+// beginOperation записывает в журнал намерение выполнить операцию. Ошибка журнала не прерывает операцию.
+func beginOperation(operation string, packages []string, apply bool) *PendingOperation {
+	pending := &PendingOperation{
+		Operation: operation,
+		Packages:  packages,
+		Apply:     apply,
+		Steps:     []string{},
+		StartedAt: time.Now().Format(time.RFC3339),
+	}
+
+	if err := writePendingOperation(pending); err != nil {
+		lib.Log.Warning(err.Error())
+	}
+
+	return pending
+}
+
+// completeStep отмечает в журнале выполненный шаг операции.
+func completeStep(pending *PendingOperation, step string) {
+	if pending.Done(step) {
+		return
+	}
+
+	pending.Steps = append(pending.Steps, step)
+	if err := writePendingOperation(pending); err != nil {
+		lib.Log.Warning(err.Error())
+	}
+}
+
+// finishOperation удаляет запись журнала после успешного завершения операции.
+func finishOperation() {
+	if err := lib.GetDBKv().Delete([]byte(pendingOperationKey)); err != nil {
+		lib.Log.Warning(fmt.Sprintf(lib.T_("Error writing the operation journal: %v"), err))
+	}
+}
+
+// abandonOperation удаляет запись журнала операции, которая завершилась ошибкой до окончания транзакции apt:
+// система не изменена, и восстанавливать нечего. Запись об операции, прерванной после шага apt, остаётся
+// для apm recover. Вызывается через defer сразу после beginOperation.
+func abandonOperation(pending *PendingOperation) {
+	if !pending.Done(OperationStepApt) {
+		finishOperation()
+	}
+}
+
+// resumePendingOperation завершает операцию, прерванную при прошлом запуске: синхронизирует базу пакетов
+// и записывает в конфигурацию образа пакеты, изменение которых фактически применилось. Оба шага
+// идемпотентны. Образ не пересобирается, это делает apm system image apply. Возвращает nil, если журнал пуст.
+func (a *Actions) resumePendingOperation(ctx context.Context) (*PendingOperation, error) {
+	pending, err := ReadPendingOperation()
+	if err != nil || pending == nil {
+		return nil, err
+	}
+
+	lib.Log.Warningf(lib.T_("Completing the interrupted operation %s started at %s"), pending.Operation, pending.StartedAt)
+
+	if !pending.Done(OperationStepPackagesDB) {
+		if err = a.updateAllPackagesDB(ctx); err != nil {
+			return pending, err
+		}
+		completeStep(pending, OperationStepPackagesDB)
+	}
+
+	if pending.Apply && lib.IsAtomic() && !pending.Done(OperationStepConfig) {
+		// Без отметки шага apt транзакция могла прерваться, поэтому в конфигурацию попадают только
+		// пакеты, состояние которых в системе совпадает с запрошенным
+		if err = a.writeConfigChanges(ctx, pending.Packages, pending.Operation != "remove", !pending.Done(OperationStepApt)); err != nil {
+			return pending, err
+		}
+		completeStep(pending, OperationStepConfig)
+	}
+
+	finishOperation()
+	return pending, nil
+}
+
+// recoverBeforeOperation автоматически завершает прерванную операцию перед новой.
+func (a *Actions) recoverBeforeOperation(ctx context.Context) error {
+	pending, err := a.resumePendingOperation(ctx)
+	if err != nil {
+		return reply.Errorf(reply.ErrorCodeRecoveryRequired, lib.T_("Failed to complete the interrupted operation: %v. Run apm recover"), err)
+	}
+
+	if pending != nil && pending.Apply && lib.IsAtomic() {
+		lib.Log.Warning(lib.T_("The interrupted operation changed the image configuration, run apm system image apply to rebuild the image"))
+	}
+
+	return nil
+}
+
+// Recover явно завершает операцию, прерванную при прошлом запуске apm.
+func (a *Actions) Recover(ctx context.Context) (*reply.APIResponse, error) {
+	err := a.checkRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := a.resumePendingOperation(ctx)
+	if err != nil {
+		return nil, reply.WithErrorCode(reply.ErrorCodeRecoveryRequired, err)
+	}
+
+	message := lib.T_("No interrupted operations found")
+	if pending != nil {
+		message = fmt.Sprintf(lib.T_("The interrupted operation %s has been completed"), pending.Operation)
+		if pending.Apply && lib.IsAtomic() {
+			message += lib.T_(". The image configuration has been updated, run apm system image apply to rebuild the image")
+		}
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":   message,
+			"operation": pending,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
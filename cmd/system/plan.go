@@ -0,0 +1,123 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package system
+
+import (
+	"apm/cmd/common/reply"
+	"apm/cmd/system/service"
+	"apm/lib"
+	"context"
+	"fmt"
+	"math"
+)
+
+// Грубая оценка длительности сборки образа в минутах.
+const (
+	// planBaseMinutes загрузка базового образа, apt-get update и фиксация образа
+	planBaseMinutes = 2.0
+	// planBaseUpdateMinutes загрузка новых слоёв обновлённого базового образа
+	planBaseUpdateMinutes = 3.0
+	// planInstallMinutes загрузка и установка одного пакета
+	planInstallMinutes = 0.25
+	// planRemoveMinutes удаление одного пакета
+	planRemoveMinutes = 0.05
+	// planCommandMinutes выполнение одной пользовательской команды
+	planCommandMinutes = 0.5
+)
+
+// BuildPlan описывает, что сделает следующая сборка образа.
+type BuildPlan struct {
+	BaseImage         string                   `json:"baseImage"`
+	BaseImageUpdate   service.ImageUpdateCheck `json:"baseImageUpdate"`
+	PackagesToInstall []string                 `json:"packagesToInstall"`
+	AlreadyInstalled  []string                 `json:"alreadyInstalled"`
+	PackagesToRemove  []string                 `json:"packagesToRemove"`
+	CustomLayers      []string                 `json:"customLayers"`
+	EstimatedMinutes  int                      `json:"estimatedMinutes"`
+}
+
+// ImagePlan показывает, что сделает image apply: Dockerfile, устанавливаемые и удаляемые пакеты,
+// состояние базового образа и примерное время сборки. Образ не собирается, файлы не изменяются.
+func (a *Actions) ImagePlan(ctx context.Context) (*reply.APIResponse, error) {
+	if !lib.IsAtomic() {
+		return nil, reply.Errorf(reply.ErrorCodeNotAtomic, lib.T_("This option is only available for an atomic system"))
+	}
+
+	// В отличие от LoadConfig не создаёт файл конфигурации, если его нет
+	if _, err := a.serviceHostConfig.ReloadConfig(); err != nil {
+		return nil, err
+	}
+	config := a.serviceHostConfig.Config
+
+	dockerfile, err := a.serviceHostConfig.DockerfileContent("")
+	if err != nil {
+		return nil, err
+	}
+
+	plan := BuildPlan{
+		BaseImage:         config.Image,
+		PackagesToInstall: []string{},
+		AlreadyInstalled:  []string{},
+		PackagesToRemove:  []string{},
+		CustomLayers:      []string{},
+	}
+
+	for _, pkg := range config.Packages.Install {
+		info, errPkg := a.serviceAptDatabase.GetPackageByName(ctx, pkg)
+		if errPkg == nil && info.Installed {
+			plan.AlreadyInstalled = append(plan.AlreadyInstalled, pkg)
+			continue
+		}
+		plan.PackagesToInstall = append(plan.PackagesToInstall, pkg)
+	}
+	plan.PackagesToRemove = append(plan.PackagesToRemove, config.Packages.Remove...)
+	plan.CustomLayers = append(plan.CustomLayers, config.Commands...)
+
+	plan.BaseImageUpdate, err = a.serviceHostImage.CheckImageUpdate(ctx, config.Image)
+	if err != nil {
+		plan.BaseImageUpdate.Status = service.ImageUpdateUnknown
+		plan.BaseImageUpdate.Error = err.Error()
+	}
+
+	plan.EstimatedMinutes = estimateBuildMinutes(plan)
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message":    fmt.Sprintf(lib.T_("Estimated build time: %d min"), plan.EstimatedMinutes),
+			"plan":       plan,
+			"dockerfile": dockerfile,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// estimateBuildMinutes грубо оценивает время сборки по числу пакетов и команд.
+func estimateBuildMinutes(plan BuildPlan) int {
+	minutes := planBaseMinutes
+	if plan.BaseImageUpdate.Status == service.ImageUpdateAvailable {
+		minutes += planBaseUpdateMinutes
+	}
+
+	// Уже установленные пакеты переустанавливаются в новом образе, поэтому тоже учитываются
+	minutes += float64(len(plan.PackagesToInstall)+len(plan.AlreadyInstalled)) * planInstallMinutes
+	minutes += float64(len(plan.PackagesToRemove)) * planRemoveMinutes
+	minutes += float64(len(plan.CustomLayers)) * planCommandMinutes
+
+	return int(math.Ceil(minutes))
+}
@@ -0,0 +1,65 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package system
+
+import (
+	"apm/cmd/common/reply"
+	"apm/cmd/system/apt"
+	"apm/lib"
+	"context"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// QuickStats возвращает число пакетов в базе, установленных пакетов и доступных обновлений для виджетов
+// панелей. Подсчёты выполняются параллельно по базе пакетов без обращения к apt, поэтому база не
+// синхронизируется: время последней синхронизации возвращается в lastSyncTime.
+func (a *Actions) QuickStats(ctx context.Context) (*reply.APIResponse, error) {
+	var stats QuickStatsResponse
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		var err error
+		stats.Total, err = a.serviceAptDatabase.CountHostImagePackages(groupCtx, nil)
+		return err
+	})
+	group.Go(func() error {
+		var err error
+		stats.Installed, err = a.serviceAptDatabase.CountHostImagePackages(groupCtx, map[string]interface{}{"installed": true})
+		return err
+	})
+	group.Go(func() error {
+		var err error
+		stats.Upgradable, err = a.serviceAptDatabase.CountUpgradablePackages(groupCtx)
+		return err
+	})
+
+	if err := group.Wait(); err != nil {
+		return nil, reply.WithErrorCode(reply.ErrorCodeDatabase, err)
+	}
+
+	stats.LastSyncTime = apt.LastSyncTime()
+	stats.Message = fmt.Sprintf(lib.TN_("%d package in the database", "%d packages in the database", int(stats.Total)), stats.Total)
+
+	resp := reply.APIResponse{
+		Data:  stats,
+		Error: false,
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,173 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package system
+
+import (
+	"apm/cmd/system/apt"
+	"apm/cmd/system/service"
+	"apm/lib"
+)
+
+// Типизированные ответы основных команд. Имена полей входят в контракт версии reply.APIVersion:
+// их нельзя переименовывать или удалять без повышения версии. Поле message в формате json не выводится.
+
+// PackageListResponse ответ команды list в сокращённом формате. Ответ только с подсчётом пакетов
+// содержит лишь message и totalCount.
+type PackageListResponse struct {
+	Message    string                 `json:"message"`
+	Packages   []ShortPackageResponse `json:"packages"`
+	TotalCount int                    `json:"totalCount"`
+}
+
+// PackageListFullResponse ответ команды list в полном формате.
+type PackageListFullResponse struct {
+	Message    string        `json:"message"`
+	Packages   []apt.Package `json:"packages"`
+	TotalCount int           `json:"totalCount"`
+}
+
+// UpgradableListResponse ответ команды upgradable.
+type UpgradableListResponse struct {
+	Message    string                      `json:"message"`
+	Packages   []UpgradablePackageResponse `json:"packages"`
+	TotalCount int                         `json:"totalCount"`
+}
+
+// PackageSearchResponse ответ команды search в сокращённом формате.
+type PackageSearchResponse struct {
+	Message  string                 `json:"message"`
+	Packages []ShortPackageResponse `json:"packages"`
+}
+
+// PackageSearchFullResponse ответ команды search в полном формате.
+type PackageSearchFullResponse struct {
+	Message  string                  `json:"message"`
+	Packages []SearchPackageResponse `json:"packages"`
+}
+
+// PackageInfoResponse ответ команды info в сокращённом формате.
+type PackageInfoResponse struct {
+	Message     string               `json:"message"`
+	PackageInfo ShortPackageResponse `json:"packageInfo"`
+}
+
+// PackageInfoFullResponse ответ команды info в полном формате.
+type PackageInfoFullResponse struct {
+	Message     string      `json:"message"`
+	PackageInfo apt.Package `json:"packageInfo"`
+}
+
+// MaintainerListResponse ответ команды maintainers без указания сопровождающего.
+type MaintainerListResponse struct {
+	Message     string                `json:"message"`
+	Maintainers []apt.MaintainerCount `json:"maintainers"`
+	TotalCount  int                   `json:"totalCount"`
+}
+
+// QuickStatsResponse ответ команды packages-count.
+type QuickStatsResponse struct {
+	Message      string `json:"message"`
+	Total        int64  `json:"total"`
+	Installed    int64  `json:"installed"`
+	Upgradable   int    `json:"upgradable"`
+	LastSyncTime string `json:"lastSyncTime"`
+}
+
+// ImageStatusResponse ответ команды image status.
+type ImageStatusResponse struct {
+	Message         string                   `json:"message"`
+	BootedImage     ImageStatus              `json:"bootedImage"`
+	BaseSignature   service.SignatureStatus  `json:"baseSignature"`
+	PendingImage    *service.ImageHistory    `json:"pendingImage,omitempty"`
+	ScheduledReboot *service.ScheduledReboot `json:"scheduledReboot,omitempty"`
+	Atomic          lib.AtomicDetection      `json:"atomic"`
+}
+
+// ImageHistoryResponse ответ команды image history.
+type ImageHistoryResponse struct {
+	Message    string                 `json:"message"`
+	History    []service.ImageHistory `json:"history"`
+	TotalCount int                    `json:"totalCount"`
+}
+
+// PackageChangesResponse ответ команд install, remove и upgrade и проверок перед установкой и удалением.
+type PackageChangesResponse struct {
+	Message string             `json:"message"`
+	Info    apt.PackageChanges `json:"info"`
+	// ConfirmationRequired изменения только запланированы и ждут повторного запуска с --yes
+	ConfirmationRequired bool `json:"confirmationRequired,omitempty"`
+	// Packages пакеты, обновлённые командой upgrade
+	Packages []string `json:"packages,omitempty"`
+	// Selected пакеты, выбранные в интерактивном поиске
+	Selected        []string                 `json:"selected,omitempty"`
+	ScheduledReboot *service.ScheduledReboot `json:"scheduledReboot,omitempty"`
+	// Simulated изменения только рассчитаны командой upgrade --dry-run и не применялись
+	Simulated bool `json:"simulated,omitempty"`
+	// DiskSpaceDeltaMB изменение занятого места в мегабайтах, рассчитывается только для upgrade --dry-run
+	DiskSpaceDeltaMB *float64 `json:"diskSpaceDeltaMB,omitempty"`
+	// Timings длительность этапов операции в миллисекундах
+	Timings map[string]int64 `json:"timings,omitempty"`
+	BuildResult
+}
+
+// ImageApplyResponse ответ команды image apply.
+type ImageApplyResponse struct {
+	Message       string                  `json:"message"`
+	BootedImage   ImageStatus             `json:"bootedImage"`
+	BaseSignature service.SignatureStatus `json:"baseSignature"`
+	ImageName     string                  `json:"imageName"`
+	Arch          string                  `json:"arch"`
+	// BaseImageDigest дайджест базового образа, из которого собран образ
+	BaseImageDigest string `json:"baseImageDigest,omitempty"`
+	// PackagesOnly образ собран поверх текущего базового образа без проверки его обновлений
+	PackagesOnly bool `json:"packagesOnly,omitempty"`
+	// BaseImageOverride базовый образ только для этой сборки, ConfigImage - образ из конфигурации
+	BaseImageOverride string                   `json:"baseImageOverride,omitempty"`
+	ConfigImage       string                   `json:"configImage,omitempty"`
+	ScheduledReboot   *service.ScheduledReboot `json:"scheduledReboot,omitempty"`
+	// Timings длительность этапов сборки в миллисекундах
+	Timings map[string]int64 `json:"timings,omitempty"`
+	// NoChanges хеш конфигурации ConfigHash совпал с хешем загруженного образа, образ не собирался
+	NoChanges  bool   `json:"noChanges,omitempty"`
+	ConfigHash string `json:"configHash,omitempty"`
+	BuildResult
+}
+
+// ImageUpdateResponse ответ команды image update. PendingImage заполняется для сборки с --no-switch.
+type ImageUpdateResponse struct {
+	Message       string                  `json:"message"`
+	Updated       bool                    `json:"updated"`
+	Forced        bool                    `json:"forced"`
+	BootedImage   ImageStatus             `json:"bootedImage"`
+	BaseSignature service.SignatureStatus `json:"baseSignature"`
+	PendingImage  *service.ImageHistory   `json:"pendingImage,omitempty"`
+	BuildResult
+}
+
+// ImageBuildResponse ответ команды image build.
+type ImageBuildResponse struct {
+	Message       string                  `json:"message"`
+	PendingImage  service.ImageHistory    `json:"pendingImage"`
+	BaseSignature service.SignatureStatus `json:"baseSignature"`
+	BuildResult
+}
+
+// BuildResult результаты скриптов сборки образа и предупреждения, общие для ответов команд, собирающих образ.
+type BuildResult struct {
+	BuildHooks []service.BuildHookResult `json:"buildHooks,omitempty"`
+	Warning    string                    `json:"warning,omitempty"`
+}
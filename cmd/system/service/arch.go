@@ -0,0 +1,85 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// targetPlatforms поддерживаемые целевые архитектуры образа и соответствующие им платформы podman.
+var targetPlatforms = map[string]string{
+	"amd64": "linux/amd64",
+	"arm64": "linux/arm64",
+	"armv7": "linux/arm/v7",
+}
+
+// TargetArchitectures возвращает поддерживаемые целевые архитектуры образа.
+func TargetArchitectures() []string {
+	return []string{"amd64", "arm64", "armv7"}
+}
+
+// ValidateTargetArch проверяет, что архитектура пустая (архитектура хоста) или поддерживается сборкой.
+func ValidateTargetArch(arch string) error {
+	if _, ok := targetPlatforms[arch]; ok || arch == "" {
+		return nil
+	}
+
+	return reply.Errorf(reply.ErrorCodeInvalidArgument, lib.T_("Unknown architecture %s, allowed: %s"), arch,
+		strings.Join(TargetArchitectures(), ", "))
+}
+
+// TargetPlatform возвращает платформу podman для архитектуры или пустую строку для архитектуры хоста.
+func TargetPlatform(arch string) string {
+	return targetPlatforms[arch]
+}
+
+// HostArch возвращает архитектуру хоста в обозначениях TargetArchitectures.
+func HostArch() string {
+	if runtime.GOARCH == "arm" {
+		return "armv7"
+	}
+
+	return runtime.GOARCH
+}
+
+// CheckBaseImageArch проверяет через skopeo, что базовый образ опубликован для архитектуры arch.
+func (h *HostImageService) CheckBaseImageArch(ctx context.Context, image string, arch string) error {
+	platform := strings.Split(TargetPlatform(arch), "/")
+	if len(platform) < 2 {
+		return ValidateTargetArch(arch)
+	}
+
+	overrides := "--override-arch " + platform[1]
+	if len(platform) > 2 {
+		overrides += " --override-variant " + platform[2]
+	}
+
+	command := fmt.Sprintf("%s skopeo inspect --no-tags %s docker://%s", lib.Env.CommandPrefix, overrides, image)
+	output, err := lib.CommandCombinedOutput(exec.CommandContext(ctx, "sh", "-c", command))
+	if err != nil {
+		return reply.Errorf(reply.ErrorCodeImageBuildFailed, lib.T_("Base image %s is not available for architecture %s: %s"),
+			image, arch, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// podmanDiskUsageEntry строка вывода podman system df --format json.
+type podmanDiskUsageEntry struct {
+	Type           string `json:"Type"`
+	RawSize        int64  `json:"RawSize"`
+	RawReclaimable int64  `json:"RawReclaimable"`
+}
+
+// BuildCacheSize возвращает размер кеша сборки podman в байтах.
+func (h *HostImageService) BuildCacheSize(ctx context.Context) (int64, error) {
+	command := fmt.Sprintf("%s podman system df --format json", lib.Env.CommandPrefix)
+	output, err := lib.CommandOutput(exec.CommandContext(ctx, "sh", "-c", command))
+	if err != nil {
+		return 0, fmt.Errorf(lib.T_("Failed to get the size of the build cache: %v"), err)
+	}
+
+	return ParseBuildCacheSize(output)
+}
+
+// ParseBuildCacheSize извлекает размер кеша сборки из вывода podman system df --format json. Если podman
+// выводит кеш сборки отдельной строкой, берётся её размер, иначе кешем считаются промежуточные слои,
+// которые не используются ни одним образом и контейнером: это освобождаемое место строки Images.
+func ParseBuildCacheSize(output []byte) (int64, error) {
+	var entries []podmanDiskUsageEntry
+	if err := json.Unmarshal(output, &entries); err != nil {
+		return 0, fmt.Errorf(lib.T_("Failed to parse JSON: %v"), err)
+	}
+
+	var imagesReclaimable int64
+	for _, entry := range entries {
+		switch entry.Type {
+		case "Build Cache":
+			return entry.RawSize, nil
+		case "Images":
+			imagesReclaimable = entry.RawReclaimable
+		}
+	}
+
+	return imagesReclaimable, nil
+}
+
+// PruneBuildCache удаляет кеш сборки: постоянные каталоги RUN --mount=type=cache и неиспользуемые
+// промежуточные слои. Возвращает освобождённое место в байтах.
+func (h *HostImageService) PruneBuildCache(ctx context.Context) (int64, error) {
+	before, err := h.BuildCacheSize(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	command := fmt.Sprintf("%s podman image prune --build-cache --force", lib.Env.CommandPrefix)
+	if output, err := lib.CommandCombinedOutput(exec.CommandContext(ctx, "sh", "-c", command)); err != nil {
+		return 0, fmt.Errorf(lib.T_("Failed to prune the build cache: %v, %s"), err, string(output))
+	}
+
+	after, err := h.BuildCacheSize(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if before < after {
+		return 0, nil
+	}
+
+	return before - after, nil
+}
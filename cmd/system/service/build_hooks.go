@@ -0,0 +1,94 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Точки запуска пользовательских скриптов сборки образа.
+const (
+	HookPreBuild  = "preBuild"
+	HookPostBuild = "postBuild"
+)
+
+// buildImageTag тег, под которым podman build сохраняет собранный образ.
+const buildImageTag = "os"
+
+// BuildHookResult результат выполнения скрипта сборки.
+type BuildHookResult struct {
+	Hook     string `json:"hook"`
+	Path     string `json:"path"`
+	ExitCode int    `json:"exitCode"`
+	Output   string `json:"output"`
+	Error    string `json:"error,omitempty"`
+}
+
+// buildHookEnv формирует переменные окружения, описывающие сборку.
+func buildHookEnv(ctx context.Context, configHash string) []string {
+	transaction, _ := ctx.Value("transaction").(string)
+
+	return []string{
+		"APM_CONFIG_HASH=" + configHash,
+		"APM_IMAGE_TAG=" + buildImageTag,
+		"APM_TRANSACTION=" + transaction,
+		"APM_VERSION=" + lib.Env.Version,
+	}
+}
+
+// runBuildHook выполняет скрипт hook по пути path. Вывод скрипта записывается в журнал сборки.
+// Пустой путь означает, что скрипт не настроен, тогда возвращается nil.
+func runBuildHook(ctx context.Context, hook string, path string, env []string) (*BuildHookResult, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, nil
+	}
+
+	result := &BuildHookResult{Hook: hook, Path: path}
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = append(os.Environ(), append(env, "APM_HOOK="+hook)...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := lib.CommandRun(cmd)
+	result.Output = strings.TrimSpace(output.String())
+	if result.Output != "" {
+		lib.Log.Infof(lib.T_("Output of the %s hook %s:\n%s"), hook, path, result.Output)
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+		}
+		result.Error = fmt.Sprintf(lib.T_("Hook %s (%s) failed with code %d: %v"), hook, path, result.ExitCode, err)
+		return result, errors.New(result.Error)
+	}
+
+	return result, nil
+}
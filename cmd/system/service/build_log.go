@@ -0,0 +1,108 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BuildLogDir каталог журналов сборки образа.
+var BuildLogDir = "/var/log/apm/builds"
+
+// BuildLogMaxLines число последних строк, которые остаются в журнале сборки.
+const BuildLogMaxLines = 10000
+
+// NewBuildLogPath возвращает путь к журналу новой сборки.
+func NewBuildLogPath() string {
+	return filepath.Join(BuildLogDir, fmt.Sprintf("build-%s.log", time.Now().Format("20060102-150405")))
+}
+
+// openBuildLog открывает журнал сборки на запись. Журнал не обязателен для сборки, поэтому при ошибке
+// возвращается nil, а сама ошибка записывается в журнал apm.
+func openBuildLog(logFile string) *os.File {
+	if logFile == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logFile), 0o755); err != nil {
+		lib.Log.Warningf(lib.T_("Failed to create the build log %s: %v"), logFile, err)
+		return nil
+	}
+
+	file, err := os.Create(logFile)
+	if err != nil {
+		lib.Log.Warningf(lib.T_("Failed to create the build log %s: %v"), logFile, err)
+		return nil
+	}
+
+	return file
+}
+
+// ReadBuildLog возвращает последние maxLines строк журнала сборки. Псевдотерминал завершает строки
+// символами \r\n, поэтому \r отбрасывается.
+func ReadBuildLog(logFile string, maxLines int) ([]string, error) {
+	file, err := os.Open(logFile)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to read the build log %s: %v"), logFile, err)
+	}
+	defer file.Close()
+
+	lines, err := tailLines(file, maxLines)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to read the build log %s: %v"), logFile, err)
+	}
+
+	return lines, nil
+}
+
+// truncateBuildLog оставляет в журнале сборки последние maxLines строк.
+func truncateBuildLog(logFile string, maxLines int) error {
+	lines, err := ReadBuildLog(logFile, maxLines)
+	if err != nil {
+		return err
+	}
+
+	content := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		content += "\n"
+	}
+
+	return os.WriteFile(logFile, []byte(content), 0o644)
+}
+
+// tailLines читает строки из reader и возвращает последние maxLines из них.
+func tailLines(reader io.Reader, maxLines int) ([]string, error) {
+	lines := []string{}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+		if len(lines) > maxLines {
+			lines = lines[1:]
+		}
+	}
+
+	return lines, scanner.Err()
+}
@@ -18,9 +18,14 @@ package service
 
 import (
 	"apm/lib"
+	"bytes"
 	"context"
 	"fmt"
+	"net/url"
 	"os"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -35,14 +40,103 @@ type Config struct {
 		Install []string `yaml:"install" json:"install"`
 		Remove  []string `yaml:"remove" json:"remove"`
 	} `yaml:"packages" json:"packages"`
-	Commands []string `yaml:"commands" json:"commands"`
+	Commands     []string          `yaml:"commands" json:"commands"`
+	AptSources   []AptSourceConfig `yaml:"aptSources,omitempty" json:"aptSources"`
+	EnvVars      []EnvVar          `yaml:"envVars,omitempty" json:"envVars"`
+	Labels       map[string]string `yaml:"labels,omitempty" json:"labels"`
+	HeldPackages []HeldPackage     `yaml:"heldPackages,omitempty" json:"heldPackages"`
+	// TargetArch архитектура, для которой собирается образ. Пустое значение - архитектура хоста
+	TargetArch string `yaml:"targetArch,omitempty" json:"targetArch,omitempty"`
+	// Pinned закрепляет базовый образ: image apply по умолчанию собирает образ только с изменёнными
+	// пакетами поверх текущего базового образа, не загружая его обновления
+	Pinned bool `yaml:"pinned,omitempty" json:"pinned,omitempty"`
 }
 
+// HeldPackage описывает пакет, закреплённый в образе через apt-mark hold.
+// Непустая версия устанавливается перед закреплением.
+type HeldPackage struct {
+	Name    string `yaml:"name" json:"name"`
+	Version string `yaml:"version,omitempty" json:"version"`
+}
+
+// EnvVar описывает переменную окружения, задаваемую в образе инструкцией ENV.
+type EnvVar struct {
+	Key   string `yaml:"key" json:"key"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// AptSourceConfig описывает дополнительный источник apt, добавляемый в образ.
+type AptSourceConfig struct {
+	ID         int    `yaml:"id" json:"id"`
+	SourceLine string `yaml:"source" json:"source"`
+	KeyURL     string `yaml:"keyUrl,omitempty" json:"keyUrl"`
+}
+
+// Vendor возвращает поставщика из строки источника, например alt для rpm [alt] ...
+// По ключу поставщика из vendors.list apt-rpm проверяет подпись репозитория.
+func (a AptSourceConfig) Vendor() string {
+	match := aptSourceRegex.FindStringSubmatch(a.SourceLine)
+	if match == nil {
+		return ""
+	}
+
+	return strings.Trim(strings.TrimSpace(match[2]), "[]")
+}
+
+// repositorySourceLine формирует строку источника apt. Последний сегмент пути URL считается каталогом архитектуры,
+// например http://ftp.altlinux.org/pub/distributions/ALTLinux/Sisyphus/x86_64 и компонент classic.
+func repositorySourceLine(repoURL string, component string, vendor string) string {
+	base, arch := repoURL, ""
+	if i := strings.LastIndex(repoURL, "/"); i >= 0 {
+		base, arch = repoURL[:i], repoURL[i+1:]
+	}
+
+	parts := []string{"rpm"}
+	if vendor != "" {
+		parts = append(parts, fmt.Sprintf("[%s]", vendor))
+	}
+	parts = append(parts, base, arch, component)
+
+	return strings.Join(parts, " ")
+}
+
+// aptArchivesDir каталог архивов apt, кешируемый между сборками образа.
+const aptArchivesDir = "/var/cache/apt/archives"
+
+// CacheDateArg аргумент сборки, от значения которого зависит актуальность слоя со списками пакетов.
+const CacheDateArg = "APM_CACHE_DATE"
+
+// aptSourcesFile файл образа, в который записываются дополнительные источники apt.
+const aptSourcesFile = "/etc/apt/sources.list.d/custom.list"
+
+// vendorKeyringDir каталог gpg, в котором apt-rpm ищет ключи поставщиков из vendors.list.
+const vendorKeyringDir = "/usr/lib/alt-gpgkeys"
+
+// vendorsListDir каталог дополнительных описаний поставщиков apt-rpm.
+const vendorsListDir = "/etc/apt/vendors.list.d"
+
+// repositoryNameRegex допустимые значения компонента и ключа поставщика репозитория.
+var repositoryNameRegex = regexp.MustCompile(`^[\w.+-]+$`)
+
+// envKeyRegex допустимое имя переменной окружения.
+var envKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// heldPackageNameRegex допустимое имя закрепляемого пакета.
+var heldPackageNameRegex = regexp.MustCompile(`^[A-Za-z0-9][\w.+-]*$`)
+
+// heldPackageVersionRegex допустимая версия закрепляемого пакета, например 1:2.4.1-alt1.
+var heldPackageVersionRegex = regexp.MustCompile(`^[\w.+~:-]+$`)
+
+// aptSourceRegex формат строки источника apt, например: rpm [alt] http://ftp.altlinux.org/pub/distributions/ALTLinux Sisyphus/x86_64 classic
+var aptSourceRegex = regexp.MustCompile(`^rpm(-src)?\s+(\[[\w-]+\]\s+)?(https?|ftp|file|rsync)://\S+\s+\S+(\s+\S+)+$`)
+
 // HostConfigService — сервис для работы с конфигурацией хоста.
 type HostConfigService struct {
 	Config              *Config
 	configPath          string
 	serviceHostDatabase *HostDBService
+	// savedData содержимое файла, которое сервис последним прочитал или записал сам
+	savedData []byte
 }
 
 func NewHostConfigService(configPath string, hostDBService *HostDBService) *HostConfigService {
@@ -52,11 +146,14 @@ func NewHostConfigService(configPath string, hostDBService *HostDBService) *Host
 	}
 }
 
-// syncYamlMutex защищает операции работы с файлом.
+// syncYamlMutex защищает операции работы с файлом и замену поля Config.
 var syncYamlMutex sync.Mutex
 
 // LoadConfig загружает конфигурацию из файла и сохраняет в поле config.
 func (s *HostConfigService) LoadConfig() error {
+	syncYamlMutex.Lock()
+	defer syncYamlMutex.Unlock()
+
 	data, err := os.ReadFile(s.configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -65,7 +162,7 @@ func (s *HostConfigService) LoadConfig() error {
 				return err
 			}
 			s.Config = &cfg
-			return s.SaveConfig()
+			return s.saveConfig()
 		}
 		return err
 	}
@@ -79,10 +176,132 @@ func (s *HostConfigService) LoadConfig() error {
 		return fmt.Errorf(lib.T_("Image must be specified in the configuration file"))
 	}
 	s.Config = &cfg
+	s.savedData = data
+
+	return nil
+}
+
+// ReloadConfig перечитывает изменённый извне файл конфигурации и возвращает предыдущую конфигурацию.
+// Файл, который не удалось разобрать или проверить, отклоняется, и сервис сохраняет прежнюю конфигурацию.
+// Если файл совпадает с последней записью самого сервиса, конфигурация не заменяется и возвращается текущая.
+func (s *HostConfigService) ReloadConfig() (*Config, error) {
+	syncYamlMutex.Lock()
+	defer syncYamlMutex.Unlock()
+
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to open file %s: %w"), s.configPath, err)
+	}
+
+	if s.Config != nil && bytes.Equal(data, s.savedData) {
+		return s.Config, nil
+	}
+
+	var cfg Config
+	if err = yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf(lib.T_("Error parsing configuration file %s: %v"), s.configPath, err)
+	}
+
+	if err = validateConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	previous := s.Config
+	s.Config = &cfg
+	s.savedData = data
+
+	return previous, nil
+}
+
+// validateConfig проверяет значения, которые команды apm проверяют при добавлении в конфигурацию.
+func validateConfig(cfg Config) error {
+	if cfg.Image == "" {
+		return fmt.Errorf(lib.T_("Image must be specified in the configuration file"))
+	}
+
+	for _, env := range cfg.EnvVars {
+		if !envKeyRegex.MatchString(env.Key) {
+			return fmt.Errorf(lib.T_("Invalid environment variable name: %s. Use letters, digits and underscores, not starting with a digit"), env.Key)
+		}
+	}
+
+	for key, value := range cfg.Labels {
+		if err := ValidateLabel(key, value); err != nil {
+			return err
+		}
+	}
+
+	for _, held := range cfg.HeldPackages {
+		if err := validateHeldPackage(held.Name, held.Version); err != nil {
+			return err
+		}
+	}
+
+	for _, source := range cfg.AptSources {
+		if strings.ContainsAny(source.SourceLine, "\"`$\\") || !aptSourceRegex.MatchString(source.SourceLine) {
+			return fmt.Errorf(lib.T_("Invalid source line format: %s"), source.SourceLine)
+		}
+	}
 
 	return nil
 }
 
+// SummarizeConfigChanges описывает отличия новой конфигурации от предыдущей. Пустой результат означает отсутствие изменений.
+func SummarizeConfigChanges(previous *Config, current *Config) []string {
+	if previous == nil {
+		previous = &Config{}
+	}
+
+	var changes []string
+	if previous.Image != current.Image {
+		changes = append(changes, fmt.Sprintf("image: %s -> %s", previous.Image, current.Image))
+	}
+
+	lists := []struct {
+		name     string
+		previous []string
+		current  []string
+	}{
+		{"install", previous.Packages.Install, current.Packages.Install},
+		{"remove", previous.Packages.Remove, current.Packages.Remove},
+		{"commands", previous.Commands, current.Commands},
+	}
+	for _, list := range lists {
+		var parts []string
+		for _, item := range list.current {
+			if !contains(list.previous, item) {
+				parts = append(parts, "+"+item)
+			}
+		}
+		for _, item := range list.previous {
+			if !contains(list.current, item) {
+				parts = append(parts, "-"+item)
+			}
+		}
+		if len(parts) > 0 {
+			changes = append(changes, fmt.Sprintf("%s: %s", list.name, strings.Join(parts, " ")))
+		}
+	}
+
+	sections := []struct {
+		name     string
+		previous interface{}
+		current  interface{}
+	}{
+		{"aptSources", previous.AptSources, current.AptSources},
+		{"envVars", previous.EnvVars, current.EnvVars},
+		{"labels", previous.Labels, current.Labels},
+		{"heldPackages", previous.HeldPackages, current.HeldPackages},
+	}
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.previous, section.current) {
+			changes = append(changes, fmt.Sprintf(lib.T_("%s changed"), section.name))
+		}
+	}
+
+	return changes
+}
+
 // SaveConfig сохраняет текущую конфигурацию сервиса в файл.
 func (s *HostConfigService) SaveConfig() error {
 	if s.Config == nil {
@@ -92,18 +311,28 @@ func (s *HostConfigService) SaveConfig() error {
 	syncYamlMutex.Lock()
 	defer syncYamlMutex.Unlock()
 
+	return s.saveConfig()
+}
+
+// saveConfig записывает конфигурацию в файл и запоминает записанное содержимое. Вызывается под syncYamlMutex.
+func (s *HostConfigService) saveConfig() error {
 	data, err := yaml.Marshal(s.Config)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.configPath, data, 0644)
+	if err = os.WriteFile(s.configPath, data, 0644); err != nil {
+		return err
+	}
+
+	s.savedData = data
+	return nil
 }
 
 // generateDefaultConfig генерирует конфигурацию по умолчанию, если файл не существует.
 func (s *HostConfigService) generateDefaultConfig() (Config, error) {
 	var cfg Config
 	hostImageService := NewHostImageService(s)
-	imageName, err := hostImageService.GetImageFromDocker()
+	imageName, err := hostImageService.GetImageFromDocker(context.Background())
 	if err != nil {
 		return cfg, err
 	}
@@ -125,12 +354,100 @@ func (s *HostConfigService) generateDefaultConfig() (Config, error) {
 	return cfg, nil
 }
 
-// GenerateDockerfile генерирует содержимое Dockerfile, формируя apt-get команды с модификаторами для пакетов.
-func (s *HostConfigService) GenerateDockerfile() error {
-	if err := s.CheckCommands(); err != nil {
+// GenerateDockerfile генерирует Dockerfile, формируя apt-get команды с модификаторами для пакетов, и записывает его в ContainerFile.
+// Непустой overrideBaseImage заменяет базовый образ в строке FROM без изменения конфигурации.
+func (s *HostConfigService) GenerateDockerfile(overrideBaseImage string) error {
+	return s.GenerateDockerfileForArch(overrideBaseImage, s.Config.TargetArch)
+}
+
+// GenerateDockerfileForArch генерирует Dockerfile как GenerateDockerfile, но для архитектуры arch.
+// Архитектура действует только на этот Dockerfile и не записывается в конфигурацию.
+func (s *HostConfigService) GenerateDockerfileForArch(overrideBaseImage string, arch string) error {
+	dockerStr, err := s.dockerfileContent(overrideBaseImage, arch)
+	if err != nil {
 		return err
 	}
 
+	return os.WriteFile(ContainerFile, []byte(dockerStr), 0644)
+}
+
+// DockerfileContent возвращает содержимое Dockerfile для текущей конфигурации, ничего не записывая.
+func (s *HostConfigService) DockerfileContent(overrideBaseImage string) (string, error) {
+	return s.dockerfileContent(overrideBaseImage, s.Config.TargetArch)
+}
+
+// dockerfileContent возвращает содержимое Dockerfile для сборки под архитектуру arch.
+func (s *HostConfigService) dockerfileContent(overrideBaseImage string, arch string) (string, error) {
+	if err := s.CheckCommands(); err != nil {
+		return "", err
+	}
+
+	baseImage := s.Config.Image
+	if overrideBaseImage != "" {
+		baseImage = overrideBaseImage
+	}
+
+	if SupportsBuildCache() {
+		return s.generateCachedDockerfile(baseImage, arch), nil
+	}
+
+	return s.generateLegacyDockerfile(baseImage, arch), nil
+}
+
+// fromLine возвращает инструкцию FROM. Для сборки под другую архитектуру в ней указывается платформа.
+func (s *HostConfigService) fromLine(baseImage string, arch string) string {
+	if platform := TargetPlatform(arch); platform != "" {
+		return fmt.Sprintf("FROM --platform=%s \"%s\"", platform, baseImage)
+	}
+
+	return fmt.Sprintf("FROM \"%s\"", baseImage)
+}
+
+// generateCachedDockerfile формирует Dockerfile с отдельными слоями для обновления списков, удаления
+// и установки пакетов (от редко к часто меняющимся) и кешем архивов apt между сборками.
+// Слой apt-get update пересобирается при смене аргумента CacheDateArg.
+func (s *HostConfigService) generateCachedDockerfile(baseImage string, arch string) string {
+	runPrefix := fmt.Sprintf("RUN --mount=type=cache,target=%s,sharing=locked ", aptArchivesDir)
+
+	var dockerfileLines []string
+	dockerfileLines = append(dockerfileLines, s.fromLine(baseImage, arch))
+	dockerfileLines = append(dockerfileLines, s.envLines()...)
+	dockerfileLines = append(dockerfileLines, s.aptSourceLines()...)
+	dockerfileLines = append(dockerfileLines, fmt.Sprintf("ARG %s", CacheDateArg))
+	dockerfileLines = append(dockerfileLines, strings.Join(splitCommand(runPrefix, "apt-get update"), "\n"))
+
+	var removePkgs []string
+	for _, pkg := range uniqueStrings(s.Config.Packages.Remove) {
+		removePkgs = append(removePkgs, pkg+"-")
+	}
+	if len(removePkgs) > 0 {
+		removeLines := splitCommand(runPrefix, "apt-get -y install "+strings.Join(removePkgs, " "))
+		dockerfileLines = append(dockerfileLines, strings.Join(removeLines, "\n"))
+	}
+
+	var installPkgs []string
+	for _, pkg := range uniqueStrings(s.Config.Packages.Install) {
+		installPkgs = append(installPkgs, pkg+"+")
+	}
+	if len(installPkgs) > 0 {
+		installLines := splitCommand(runPrefix, "apt-get -y install "+strings.Join(installPkgs, " "))
+		dockerfileLines = append(dockerfileLines, strings.Join(installLines, "\n"))
+	}
+	dockerfileLines = append(dockerfileLines, s.holdLines(runPrefix)...)
+
+	if len(s.Config.Commands) > 0 {
+		cmdLines := splitCommand("RUN ", strings.Join(s.Config.Commands, " && "))
+		dockerfileLines = append(dockerfileLines, strings.Join(cmdLines, "\n"))
+	}
+
+	// Метки не влияют на содержимое образа, поэтому идут последними и не сбрасывают кеш слоёв
+	dockerfileLines = append(dockerfileLines, s.labelLines()...)
+
+	return strings.Join(dockerfileLines, "\n") + "\n"
+}
+
+// generateLegacyDockerfile формирует Dockerfile с единым RUN блоком для podman без поддержки кеширующих монтирований.
+func (s *HostConfigService) generateLegacyDockerfile(baseImage string, arch string) string {
 	// Формирование базовой apt-get команды.
 	aptCmd := "apt-get update"
 
@@ -151,11 +468,19 @@ func (s *HostConfigService) GenerateDockerfile() error {
 
 	// Формирование Dockerfile.
 	var dockerfileLines []string
-	dockerfileLines = append(dockerfileLines, fmt.Sprintf("FROM \"%s\"", s.Config.Image))
+	dockerfileLines = append(dockerfileLines, s.fromLine(baseImage, arch))
+
+	// Переменные окружения задаются до установки пакетов, чтобы действовать и при установке, и в работающей системе.
+	dockerfileLines = append(dockerfileLines, s.envLines()...)
+	dockerfileLines = append(dockerfileLines, s.aptSourceLines()...)
+
 	// Разбиваем apt-get команду по строкам.
 	aptLines := splitCommand("RUN ", aptCmd)
 	dockerfileLines = append(dockerfileLines, strings.Join(aptLines, "\n"))
 
+	// Закрепление пакетов идёт до пользовательских команд, чтобы они не обновили закреплённые пакеты.
+	dockerfileLines = append(dockerfileLines, s.holdLines("RUN ")...)
+
 	// Формирование RUN блока для пользовательских команд, если они заданы.
 	if len(s.Config.Commands) > 0 {
 		cmdCombined := strings.Join(s.Config.Commands, " && ")
@@ -163,17 +488,101 @@ func (s *HostConfigService) GenerateDockerfile() error {
 		dockerfileLines = append(dockerfileLines, strings.Join(cmdLines, "\n"))
 	}
 
-	dockerStr := strings.Join(dockerfileLines, "\n") + "\n"
-	err := os.WriteFile(ContainerFile, []byte(dockerStr), 0644)
-	if err != nil {
-		return err
+	dockerfileLines = append(dockerfileLines, s.labelLines()...)
+
+	return strings.Join(dockerfileLines, "\n") + "\n"
+}
+
+// aptSourceLines возвращает инструкции подключения дополнительных источников apt и их ключей.
+// Они должны выполняться до apt-get update.
+func (s *HostConfigService) aptSourceLines() []string {
+	var lines []string
+	for _, source := range s.Config.AptSources {
+		lines = append(lines, fmt.Sprintf("RUN echo \"%s\" >> %s", source.SourceLine, aptSourcesFile))
+		if source.KeyURL != "" {
+			lines = append(lines, aptKeyLine(source))
+		}
 	}
 
-	return nil
+	return lines
+}
+
+// aptKeyLine возвращает инструкцию установки ключа источника. apt-rpm не поддерживает apt-key: ключ импортируется
+// в vendorKeyringDir, а для поставщика из строки источника в vendorsListDir записывается описание с отпечатком ключа.
+func aptKeyLine(source AptSourceConfig) string {
+	const keyFile = "/tmp/apm-source-key.asc"
+
+	download := fmt.Sprintf("curl -fsSL %s -o %s", source.KeyURL, keyFile)
+	vendor := source.Vendor()
+	if vendor == "" {
+		return fmt.Sprintf("RUN %s && gpg --homedir %s --batch --import %s && rm -f %s", download, vendorKeyringDir, keyFile, keyFile)
+	}
+
+	// Отпечаток берётся из строки IMPORT_OK вывода gpg --status-fd
+	importKey := fmt.Sprintf(`fingerprint=$(gpg --homedir %s --batch --status-fd 1 --import %s | awk '$2 == "IMPORT_OK" {print $4; exit}')`,
+		vendorKeyringDir, keyFile)
+	vendorEntry := fmt.Sprintf(`printf 'simple-key "%[1]s" {\n\tFingerprint "%%s";\n\tName "%[1]s";\n}\n' "$fingerprint" > %[2]s/apm-%[1]s.list`,
+		vendor, vendorsListDir)
+
+	return fmt.Sprintf("RUN %s && %s && %s && rm -f %s", download, importKey, vendorEntry, keyFile)
+}
+
+// holdLines возвращает инструкции установки закреплённых версий пакетов и их закрепления через apt-mark hold.
+func (s *HostConfigService) holdLines(runPrefix string) []string {
+	var versioned []string
+	for _, held := range s.Config.HeldPackages {
+		if held.Version != "" {
+			versioned = append(versioned, held.Name+"="+held.Version)
+		}
+	}
+
+	var lines []string
+	if len(versioned) > 0 {
+		installLines := splitCommand(runPrefix, "apt-get -y install "+strings.Join(versioned, " "))
+		lines = append(lines, strings.Join(installLines, "\n"))
+	}
+	for _, held := range s.Config.HeldPackages {
+		lines = append(lines, fmt.Sprintf("RUN apt-mark hold %s", held.Name))
+	}
+
+	return lines
+}
+
+// envLines возвращает инструкции ENV для переменных окружения из конфигурации.
+func (s *HostConfigService) envLines() []string {
+	var lines []string
+	for _, env := range s.Config.EnvVars {
+		lines = append(lines, fmt.Sprintf("ENV %s=\"%s\"", env.Key, escapeDockerfileValue(env.Value)))
+	}
+
+	return lines
+}
+
+// labelLines возвращает инструкции LABEL для пользовательских меток из конфигурации в порядке ключей.
+func (s *HostConfigService) labelLines() []string {
+	keys := make([]string, 0, len(s.Config.Labels))
+	for key := range s.Config.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("LABEL %s=\"%s\"", key, escapeDockerfileValue(s.Config.Labels[key])))
+	}
+
+	return lines
+}
+
+// escapeDockerfileValue экранирует значение для подстановки в двойные кавычки инструкций ENV и LABEL.
+func escapeDockerfileValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(value)
 }
 
 func (s *HostConfigService) CheckCommands() error {
-	if len(s.Config.Packages.Install) == 0 && len(s.Config.Packages.Remove) == 0 && len(s.Config.Commands) == 0 {
+	if len(s.Config.Packages.Install) == 0 && len(s.Config.Packages.Remove) == 0 && len(s.Config.Commands) == 0 &&
+		len(s.Config.AptSources) == 0 && len(s.Config.EnvVars) == 0 &&
+		len(s.Config.Labels) == 0 && len(s.Config.HeldPackages) == 0 {
 		return fmt.Errorf(lib.T_("Local image configuration file has no changes"))
 	}
 	return nil
@@ -190,20 +599,59 @@ func (s *HostConfigService) ConfigIsChanged(ctx context.Context) (bool, error) {
 	return !statusSame, nil
 }
 
-// SaveConfigToDB сохраняет историю конфигурации в базу, если конфиг изменился.
-func (s *HostConfigService) SaveConfigToDB(ctx context.Context) error {
+// SaveConfigToDB сохраняет историю конфигурации в базу, если конфиг или хеш конфигурации изменились.
+func (s *HostConfigService) SaveConfigToDB(ctx context.Context, configHash string, buildLogPath string, forced bool) error {
+	// Принудительная пересборка записывается всегда, даже если конфигурация не менялась
+	if forced {
+		return s.saveHistory(ctx, ImageStatusDeployed, "", configHash, buildLogPath, ImageOriginForced)
+	}
+
 	changed, err := s.ConfigIsChanged(ctx)
 	if err != nil {
 		return err
 	}
+
 	if !changed {
-		return nil
+		deployedHash, err := s.serviceHostDatabase.GetDeployedConfigHash(ctx)
+		if err != nil {
+			return err
+		}
+		if deployedHash == configHash {
+			return nil
+		}
+	}
+
+	return s.saveHistory(ctx, ImageStatusDeployed, "", configHash, buildLogPath, "")
+}
+
+// SaveBuiltConfigToDB сохраняет в историю собранный, но ещё не установленный образ.
+func (s *HostConfigService) SaveBuiltConfigToDB(ctx context.Context, imageID string, configHash string, buildLogPath string, forced bool) error {
+	origin := ""
+	if forced {
+		origin = ImageOriginForced
+	}
+
+	return s.saveHistory(ctx, ImageStatusBuilt, imageID, configHash, buildLogPath, origin)
+}
+
+// saveHistory добавляет запись истории с разницей пакетов относительно предыдущей сборки.
+func (s *HostConfigService) saveHistory(ctx context.Context, status string, imageID string, configHash string, buildLogPath string,
+	origin string) error {
+	previousConfig, err := s.serviceHostDatabase.GetLatestConfig(ctx)
+	if err != nil {
+		return err
 	}
 
 	history := ImageHistory{
-		ImageName: s.Config.Image,
-		Config:    s.Config,
-		ImageDate: time.Now().Format(time.RFC3339),
+		ImageName:    s.Config.Image,
+		ImageID:      imageID,
+		ConfigHash:   configHash,
+		Status:       status,
+		Config:       s.Config,
+		PackageDiff:  NewPackageDiff(previousConfig, s.Config),
+		ImageDate:    time.Now().Format(time.RFC3339),
+		BuildLogPath: buildLogPath,
+		Origin:       origin,
 	}
 	return s.serviceHostDatabase.SaveImageToDB(ctx, history)
 }
@@ -251,6 +699,233 @@ func (s *HostConfigService) AddRemovePackage(pkg string) error {
 	return s.SaveConfig()
 }
 
+// AddAptSource проверяет и добавляет источник apt в конфигурацию, возвращает добавленную запись.
+func (s *HostConfigService) AddAptSource(sourceLine string, keyURL string) (AptSourceConfig, error) {
+	sourceLine = strings.Join(strings.Fields(sourceLine), " ")
+	if strings.ContainsAny(sourceLine, "\"`$\\") || !aptSourceRegex.MatchString(sourceLine) {
+		return AptSourceConfig{}, fmt.Errorf(lib.T_("Invalid source line format: %s"), sourceLine)
+	}
+
+	keyURL = strings.TrimSpace(keyURL)
+	if err := validateKeyURL(keyURL); err != nil {
+		return AptSourceConfig{}, err
+	}
+
+	maxID := 0
+	for _, source := range s.Config.AptSources {
+		if source.SourceLine == sourceLine {
+			return AptSourceConfig{}, fmt.Errorf(lib.T_("Source already added: %s"), sourceLine)
+		}
+		if source.ID > maxID {
+			maxID = source.ID
+		}
+	}
+
+	source := AptSourceConfig{
+		ID:         maxID + 1,
+		SourceLine: sourceLine,
+		KeyURL:     keyURL,
+	}
+	s.Config.AptSources = append(s.Config.AptSources, source)
+
+	return source, s.SaveConfig()
+}
+
+// RemoveAptSource удаляет источник apt из конфигурации по идентификатору.
+func (s *HostConfigService) RemoveAptSource(id int) (AptSourceConfig, error) {
+	for i, source := range s.Config.AptSources {
+		if source.ID == id {
+			s.Config.AptSources = append(s.Config.AptSources[:i], s.Config.AptSources[i+1:]...)
+			return source, s.SaveConfig()
+		}
+	}
+
+	return AptSourceConfig{}, fmt.Errorf(lib.T_("Source with id %d not found"), id)
+}
+
+// SetEnvVar проверяет и задаёт переменную окружения образа. Значение существующей переменной заменяется.
+func (s *HostConfigService) SetEnvVar(key string, value string) (EnvVar, error) {
+	key = strings.TrimSpace(key)
+	if !envKeyRegex.MatchString(key) {
+		return EnvVar{}, fmt.Errorf(lib.T_("Invalid environment variable name: %s. Use letters, digits and underscores, not starting with a digit"), key)
+	}
+
+	if strings.ContainsAny(value, "\r\n") {
+		return EnvVar{}, fmt.Errorf(lib.T_("The value of environment variable %s must be a single line"), key)
+	}
+
+	env := EnvVar{Key: key, Value: value}
+	for i, existing := range s.Config.EnvVars {
+		if existing.Key == key {
+			s.Config.EnvVars[i] = env
+			return env, s.SaveConfig()
+		}
+	}
+
+	s.Config.EnvVars = append(s.Config.EnvVars, env)
+	return env, s.SaveConfig()
+}
+
+// RemoveEnvVar удаляет переменную окружения образа по имени.
+func (s *HostConfigService) RemoveEnvVar(key string) (EnvVar, error) {
+	key = strings.TrimSpace(key)
+	for i, env := range s.Config.EnvVars {
+		if env.Key == key {
+			s.Config.EnvVars = append(s.Config.EnvVars[:i], s.Config.EnvVars[i+1:]...)
+			return env, s.SaveConfig()
+		}
+	}
+
+	return EnvVar{}, fmt.Errorf(lib.T_("Environment variable %s not found"), key)
+}
+
+// PinPackage закрепляет пакет в образе. Версия существующего закрепления заменяется.
+func (s *HostConfigService) PinPackage(name string, version string) (HeldPackage, error) {
+	name = strings.TrimSpace(name)
+	version = strings.TrimSpace(version)
+	if err := validateHeldPackage(name, version); err != nil {
+		return HeldPackage{}, err
+	}
+
+	held := HeldPackage{Name: name, Version: version}
+	for i, existing := range s.Config.HeldPackages {
+		if existing.Name == name {
+			s.Config.HeldPackages[i] = held
+			return held, s.SaveConfig()
+		}
+	}
+
+	s.Config.HeldPackages = append(s.Config.HeldPackages, held)
+	return held, s.SaveConfig()
+}
+
+// UnpinPackage снимает закрепление пакета в образе.
+func (s *HostConfigService) UnpinPackage(name string) (HeldPackage, error) {
+	name = strings.TrimSpace(name)
+	for i, held := range s.Config.HeldPackages {
+		if held.Name == name {
+			s.Config.HeldPackages = append(s.Config.HeldPackages[:i], s.Config.HeldPackages[i+1:]...)
+			return held, s.SaveConfig()
+		}
+	}
+
+	return HeldPackage{}, fmt.Errorf(lib.T_("Package %s is not pinned in the image configuration"), name)
+}
+
+// validateHeldPackage проверяет имя и версию закрепляемого пакета.
+func validateHeldPackage(name string, version string) error {
+	if !heldPackageNameRegex.MatchString(name) {
+		return fmt.Errorf(lib.T_("Invalid package name: %s"), name)
+	}
+
+	if version != "" && !heldPackageVersionRegex.MatchString(version) {
+		return fmt.Errorf(lib.T_("Invalid version %s for package %s"), version, name)
+	}
+
+	return nil
+}
+
+// SetLabel проверяет и задаёт пользовательскую метку образа. Значение существующей метки заменяется.
+func (s *HostConfigService) SetLabel(key string, value string) (map[string]string, error) {
+	key = strings.TrimSpace(key)
+	if err := ValidateLabel(key, value); err != nil {
+		return nil, err
+	}
+
+	if s.Config.Labels == nil {
+		s.Config.Labels = map[string]string{}
+	}
+	s.Config.Labels[key] = value
+
+	return map[string]string{key: value}, s.SaveConfig()
+}
+
+// RemoveLabel удаляет пользовательскую метку образа по ключу.
+func (s *HostConfigService) RemoveLabel(key string) (map[string]string, error) {
+	key = strings.TrimSpace(key)
+	value, ok := s.Config.Labels[key]
+	if !ok {
+		return nil, fmt.Errorf(lib.T_("Label %s not found"), key)
+	}
+
+	delete(s.Config.Labels, key)
+	return map[string]string{key: value}, s.SaveConfig()
+}
+
+// AddRepository проверяет адрес, компонент и поставщика репозитория и добавляет его в источники apt конфигурации.
+func (s *HostConfigService) AddRepository(repoURL string, component string, keyURL string, vendor string) (AptSourceConfig, error) {
+	repoURL = strings.TrimSuffix(strings.TrimSpace(repoURL), "/")
+	component = strings.TrimSpace(component)
+	vendor = strings.TrimSpace(vendor)
+
+	if err := validateRepositoryURL(repoURL); err != nil {
+		return AptSourceConfig{}, err
+	}
+	if !repositoryNameRegex.MatchString(component) {
+		return AptSourceConfig{}, fmt.Errorf(lib.T_("Invalid repository component: %s"), component)
+	}
+	if vendor != "" && !repositoryNameRegex.MatchString(vendor) {
+		return AptSourceConfig{}, fmt.Errorf(lib.T_("Invalid repository vendor: %s"), vendor)
+	}
+
+	return s.AddAptSource(repositorySourceLine(repoURL, component, vendor), keyURL)
+}
+
+// AptSourceWarnings возвращает предупреждения об источниках с проверкой подписи, для которых не задан ключ.
+// Без ключа сборка завершится успешно, только если он уже есть в базовом образе.
+func (s *HostConfigService) AptSourceWarnings() []string {
+	var warnings []string
+	for _, source := range s.Config.AptSources {
+		if vendor := source.Vendor(); vendor != "" && source.KeyURL == "" {
+			warnings = append(warnings, fmt.Sprintf(
+				lib.T_("Source %s is checked with the %s vendor key, but no key URL is set. The key must already be present in the base image"),
+				source.SourceLine, vendor))
+		}
+	}
+
+	return warnings
+}
+
+// validateRepositoryURL проверяет адрес репозитория. Путь должен заканчиваться каталогом архитектуры.
+func validateRepositoryURL(repoURL string) error {
+	parsed, err := url.Parse(repoURL)
+	if err != nil || strings.ContainsAny(repoURL, " \"'`$\\|;&") {
+		return fmt.Errorf(lib.T_("Invalid repository URL: %s"), repoURL)
+	}
+
+	switch parsed.Scheme {
+	case "http", "https", "ftp", "rsync":
+		if parsed.Host == "" {
+			return fmt.Errorf(lib.T_("Invalid repository URL: %s"), repoURL)
+		}
+	case "file":
+	default:
+		return fmt.Errorf(lib.T_("Invalid repository URL: %s"), repoURL)
+	}
+
+	if strings.Trim(parsed.Path, "/") == "" {
+		return fmt.Errorf(lib.T_("Repository URL must include the architecture directory, for example %s"),
+			"http://ftp.altlinux.org/pub/distributions/ALTLinux/Sisyphus/x86_64")
+	}
+
+	return nil
+}
+
+// validateKeyURL проверяет ссылку на ключ подписи. Пустая ссылка допустима.
+func validateKeyURL(keyURL string) error {
+	if keyURL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(keyURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" ||
+		strings.ContainsAny(keyURL, " \"'`$\\|;&") {
+		return fmt.Errorf(lib.T_("Invalid key URL: %s"), keyURL)
+	}
+
+	return nil
+}
+
 // removeElement удаляет элемент из среза строк.
 func removeElement(slice []string, element string) []string {
 	var newSlice []string
@@ -0,0 +1,133 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// ConflictReport конфликт или нарушенная зависимость пакета из конфигурации образа.
+type ConflictReport struct {
+	Package       string `json:"package"`
+	ConflictsWith string `json:"conflictsWith"`
+	Reason        string `json:"reason"`
+}
+
+// ConfigConflictsError ошибка сборки образа, конфигурация которого приводит к конфликтам apt.
+type ConfigConflictsError struct {
+	Conflicts []ConflictReport
+}
+
+func (e *ConfigConflictsError) Error() string {
+	return fmt.Sprintf(lib.TN_("The image configuration has %d package conflict, the build has been cancelled",
+		"The image configuration has %d package conflicts, the build has been cancelled", len(e.Conflicts)), len(e.Conflicts))
+}
+
+// ErrorCode возвращает код ошибки из реестра.
+func (e *ConfigConflictsError) ErrorCode() string {
+	return reply.ErrorCodeDependencyConflict
+}
+
+// ErrorDetails возвращает найденные конфликты, чтобы клиент мог показать их пользователю.
+func (e *ConfigConflictsError) ErrorDetails() map[string]interface{} {
+	return map[string]interface{}{"conflicts": e.Conflicts}
+}
+
+// conflictLineRegex строка вывода apt о нарушенной зависимости, например
+// "  foo: Depends: bar (>= 1.0) but it is not going to be installed" или "       Conflicts: baz".
+var conflictLineRegex = regexp.MustCompile(`^\s*(?:(\S+):\s+)?(Depends|PreDepends|Conflicts|Breaks|Obsoletes):\s+(\S+)(.*)$`)
+
+// CheckConfigConflicts проверяет симуляцией apt-get, что пакеты на установку и удаление из конфигурации
+// не вызывают конфликтов и нарушенных зависимостей, которые иначе обнаружатся только при сборке образа.
+func (s *HostConfigService) CheckConfigConflicts(ctx context.Context) ([]ConflictReport, error) {
+	conflicts := []ConflictReport{}
+
+	for _, step := range []struct {
+		command  string
+		packages []string
+	}{
+		{"install", s.Config.Packages.Install},
+		{"remove", s.Config.Packages.Remove},
+	} {
+		if len(step.packages) == 0 {
+			continue
+		}
+
+		command := fmt.Sprintf("%s apt-get -s %s %s", lib.Env.CommandPrefix, step.command, strings.Join(step.packages, " "))
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Env = []string{"LC_ALL=C"}
+
+		output, err := lib.CommandCombinedOutput(cmd)
+		lines := strings.Split(string(output), "\n")
+		lib.LogCommandOutput("apt", lines)
+
+		reports := parseConflictLines(lines)
+		conflicts = append(conflicts, reports...)
+
+		if err != nil && len(reports) == 0 {
+			return nil, fmt.Errorf(lib.T_("Failed to check the image configuration for conflicts: %s"), aptErrorLine(lines, err))
+		}
+	}
+
+	return conflicts, nil
+}
+
+// parseConflictLines разбирает блок "The following packages have unmet dependencies" вывода apt.
+// Строки без названия пакета относятся к последнему упомянутому пакету.
+func parseConflictLines(lines []string) []ConflictReport {
+	var reports []ConflictReport
+	currentPackage := ""
+
+	for _, line := range lines {
+		matches := conflictLineRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		if matches[1] != "" {
+			currentPackage = matches[1]
+		}
+		if currentPackage == "" {
+			continue
+		}
+
+		reports = append(reports, ConflictReport{
+			Package:       currentPackage,
+			ConflictsWith: matches[3],
+			Reason:        strings.TrimSpace(matches[2] + ": " + matches[3] + matches[4]),
+		})
+	}
+
+	return reports
+}
+
+// aptErrorLine возвращает первую строку ошибки apt вида "E: ...", иначе текст ошибки запуска.
+func aptErrorLine(lines []string, err error) string {
+	for _, line := range lines {
+		if strings.HasPrefix(line, "E: ") {
+			return strings.TrimPrefix(line, "E: ")
+		}
+	}
+
+	return err.Error()
+}
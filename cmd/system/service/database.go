@@ -22,6 +22,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -42,12 +43,88 @@ func NewHostDBService(db *sql.DB) *HostDBService {
 	}
 }
 
+// Статусы записей истории образов.
+const (
+	// ImageStatusDeployed образ собран и установлен на хост.
+	ImageStatusDeployed = "deployed"
+	// ImageStatusBuilt образ собран, но хост на него ещё не переключён.
+	ImageStatusBuilt = "built"
+	// ImageStatusSuperseded собранный образ так и не был установлен и заменён более новой сборкой.
+	ImageStatusSuperseded = "superseded"
+)
+
+// ImageOriginSwitch запись истории создана ручным переключением на ранее собранный образ.
+const ImageOriginSwitch = "switch"
+
+// ImageOriginForced запись истории о принудительной пересборке образа без изменений базового образа
+const ImageOriginForced = "forced"
+
 // ImageHistory описывает сведения об образе.
 // Здесь поле Config хранится в виде ссылки на структуру Config.
 type ImageHistory struct {
-	ImageName string  `json:"image"`
-	Config    *Config `json:"config"`
-	ImageDate string  `json:"date"`
+	ID          int64        `json:"id"`
+	ImageName   string       `json:"image"`
+	ImageID     string       `json:"imageId"`
+	ConfigHash  string       `json:"configHash"`
+	Status      string       `json:"status"`
+	Config      *Config      `json:"config"`
+	PackageDiff *PackageDiff `json:"packageDiff"`
+	ImageDate   string       `json:"date"`
+	// ImageRemoved время удаления собранного образа из локального хранилища
+	ImageRemoved string `json:"imageRemoved,omitempty"`
+	// Origin способ установки: пусто для сборки, ImageOriginSwitch для ручного переключения на готовый образ,
+	// ImageOriginForced для принудительной пересборки
+	Origin string `json:"origin,omitempty"`
+	// BuildLogPath путь к журналу сборки образа
+	BuildLogPath string `json:"buildLogPath,omitempty"`
+}
+
+// PackageDiff описывает изменения списков пакетов относительно предыдущей сборки.
+type PackageDiff struct {
+	Installed []string `json:"installed"`
+	Removed   []string `json:"removed"`
+	Reverted  []string `json:"reverted"`
+}
+
+// NewPackageDiff вычисляет разницу в пакетах между предыдущей и новой конфигурацией.
+// Reverted содержит пакеты, которые больше не упоминаются в конфигурации.
+func NewPackageDiff(previous *Config, current *Config) *PackageDiff {
+	diff := &PackageDiff{
+		Installed: []string{},
+		Removed:   []string{},
+		Reverted:  []string{},
+	}
+
+	var prevInstall, prevRemove []string
+	if previous != nil {
+		prevInstall = previous.Packages.Install
+		prevRemove = previous.Packages.Remove
+	}
+
+	for _, pkg := range uniqueStrings(current.Packages.Install) {
+		if !contains(prevInstall, pkg) {
+			diff.Installed = append(diff.Installed, pkg)
+		}
+	}
+
+	for _, pkg := range uniqueStrings(current.Packages.Remove) {
+		if !contains(prevRemove, pkg) {
+			diff.Removed = append(diff.Removed, pkg)
+		}
+	}
+
+	for _, pkg := range uniqueStrings(append(append([]string{}, prevInstall...), prevRemove...)) {
+		if !contains(current.Packages.Install, pkg) && !contains(current.Packages.Remove, pkg) {
+			diff.Reverted = append(diff.Reverted, pkg)
+		}
+	}
+
+	return diff
+}
+
+// Summary возвращает краткое описание изменений, например: +3/-1/~0.
+func (d *PackageDiff) Summary() string {
+	return fmt.Sprintf("+%d/-%d/~%d", len(d.Installed), len(d.Removed), len(d.Reverted))
 }
 
 // SaveImageToDB сохраняет историю образов в БД.
@@ -61,25 +138,41 @@ func (h *HostDBService) SaveImageToDB(ctx context.Context, imageHistory ImageHis
 	createQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
 		imagename TEXT,
 		config TEXT,
-		imagedate TIMESTAMP
+		imagedate TIMESTAMP,
+		packagediff TEXT,
+		status TEXT,
+		imageid TEXT,
+		confighash TEXT,
+		imageremoved TEXT,
+		origin TEXT,
+		build_log_path TEXT
 	)`, h.historyTableName)
 
 	if _, err := h.dbConn.Exec(createQuery); err != nil {
 		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
 	}
 
+	if err := h.migrateHistoryTable(ctx); err != nil {
+		return err
+	}
+
 	// Сериализуем конфиг в JSON-строку.
 	configJSON, err := json.Marshal(imageHistory.Config)
 	if err != nil {
 		return fmt.Errorf(lib.T_("Error serializing config: %v"), err)
 	}
 
+	diffJSON, err := json.Marshal(imageHistory.PackageDiff)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Error serializing config: %v"), err)
+	}
+
 	tx, err := h.dbConn.Begin()
 	if err != nil {
 		return fmt.Errorf(lib.T_("Error starting transaction: %v"), err)
 	}
 
-	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s (imagename, config, imagedate) VALUES (?, ?, ?)`, tableName))
+	stmt, err := tx.Prepare(fmt.Sprintf(`INSERT INTO %s (imagename, config, imagedate, packagediff, status, imageid, confighash, origin, build_log_path) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, tableName))
 	if err != nil {
 		tx.Rollback()
 		return fmt.Errorf(lib.T_("Error preparing the query: %v"), err)
@@ -92,7 +185,12 @@ func (h *HostDBService) SaveImageToDB(ctx context.Context, imageHistory ImageHis
 		return fmt.Errorf(lib.T_("Error parsing date %s: %v"), imageHistory.ImageDate, err)
 	}
 
-	if _, err = stmt.Exec(imageHistory.ImageName, string(configJSON), parsedDate); err != nil {
+	status := imageHistory.Status
+	if status == "" {
+		status = ImageStatusDeployed
+	}
+
+	if _, err = stmt.Exec(imageHistory.ImageName, string(configJSON), parsedDate, string(diffJSON), status, imageHistory.ImageID, imageHistory.ConfigHash, imageHistory.Origin, imageHistory.BuildLogPath); err != nil {
 		tx.Rollback()
 		return fmt.Errorf(lib.T_("Error inserting data: %v"), err)
 	}
@@ -104,18 +202,61 @@ func (h *HostDBService) SaveImageToDB(ctx context.Context, imageHistory ImageHis
 	return nil
 }
 
-// GetImageHistoriesFiltered возвращает все записи из таблицы host_image_history,
-// сортируя их по дате (новые записи первыми), фильтруя по названию образа,
-// а также применяя limit и offset для пагинации.
-func (h *HostDBService) GetImageHistoriesFiltered(ctx context.Context, imageNameFilter string, limit int64, offset int64) ([]ImageHistory, error) {
-	query := fmt.Sprintf("SELECT imagename, config, imagedate FROM %s", h.historyTableName)
+// ImageHistoryFilter условия выборки истории образов. Пустые значения не ограничивают выборку.
+type ImageHistoryFilter struct {
+	ImageName string
+	Since     time.Time
+	Until     time.Time
+	Status    string
+}
+
+// where формирует условие WHERE и его аргументы.
+func (f ImageHistoryFilter) where() (string, []interface{}) {
+	var conditions []string
 	var args []interface{}
 
-	if imageNameFilter != "" {
-		query += " WHERE imagename LIKE ?"
-		args = append(args, "%"+imageNameFilter+"%")
+	if f.ImageName != "" {
+		conditions = append(conditions, "imagename LIKE ?")
+		args = append(args, "%"+f.ImageName+"%")
 	}
 
+	if !f.Since.IsZero() {
+		conditions = append(conditions, "imagedate >= ?")
+		args = append(args, f.Since)
+	}
+
+	if !f.Until.IsZero() {
+		conditions = append(conditions, "imagedate <= ?")
+		args = append(args, f.Until)
+	}
+
+	// Записи без статуса созданы до его появления и всегда были установлены на хост
+	if f.Status == ImageStatusDeployed {
+		conditions = append(conditions, "(status = ? OR status IS NULL OR status = '')")
+		args = append(args, f.Status)
+	} else if f.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, f.Status)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetImageHistoriesFiltered возвращает все записи из таблицы host_image_history,
+// сортируя их по дате (новые записи первыми), фильтруя по названию образа, периоду и статусу,
+// а также применяя limit и offset для пагинации.
+func (h *HostDBService) GetImageHistoriesFiltered(ctx context.Context, filter ImageHistoryFilter, limit int64, offset int64) ([]ImageHistory, error) {
+	if err := h.migrateHistoryTable(ctx); err != nil {
+		return nil, err
+	}
+
+	where, args := filter.where()
+	query := fmt.Sprintf("SELECT %s FROM %s", historyColumns, h.historyTableName) + where
+
 	query += " ORDER BY imagedate DESC"
 	query += " LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
@@ -132,45 +273,355 @@ func (h *HostDBService) GetImageHistoriesFiltered(ctx context.Context, imageName
 	var histories []ImageHistory
 
 	for rows.Next() {
-		var imageName string
-		var configJSON string
-		var imageDate time.Time
+		history, err := scanImageHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		histories = append(histories, history)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(lib.T_("String processing error: %v"), err)
+	}
 
-		if err = rows.Scan(&imageName, &configJSON, &imageDate); err != nil {
-			return nil, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+	return histories, nil
+}
+
+// GetImageHistoryByID возвращает запись истории образа по её идентификатору.
+func (h *HostDBService) GetImageHistoryByID(ctx context.Context, id int64) (ImageHistory, error) {
+	if err := h.migrateHistoryTable(ctx); err != nil {
+		return ImageHistory{}, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE rowid = ?", historyColumns, h.historyTableName)
+	rows, err := h.dbConn.QueryContext(ctx, query, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") || strings.Contains(err.Error(), "doesn't exist") {
+			return ImageHistory{}, fmt.Errorf(lib.T_("History not found"))
 		}
+		return ImageHistory{}, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return ImageHistory{}, fmt.Errorf(lib.T_("History entry %d not found"), id)
+	}
+
+	return scanImageHistory(rows)
+}
+
+// GetLatestConfig возвращает конфигурацию последней сохранённой сборки или nil, если истории нет.
+func (h *HostDBService) GetLatestConfig(ctx context.Context) (*Config, error) {
+	query := fmt.Sprintf("SELECT config FROM %s ORDER BY imagedate DESC LIMIT 1", h.historyTableName)
 
-		var cfg Config
-		if err = json.Unmarshal([]byte(configJSON), &cfg); err != nil {
-			return nil, fmt.Errorf(lib.T_("Config conversion error: %v"), err)
+	var configJSON string
+	err := h.dbConn.QueryRowContext(ctx, query).Scan(&configJSON)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "no such table") ||
+			strings.Contains(err.Error(), "doesn't exist") {
+			return nil, nil
 		}
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+
+	var latestConfig Config
+	if err = json.Unmarshal([]byte(configJSON), &latestConfig); err != nil {
+		return nil, fmt.Errorf(lib.T_("History config conversion error: %v"), err)
+	}
+
+	return &latestConfig, nil
+}
+
+// scanImageHistory читает одну запись истории из результата запроса.
+func scanImageHistory(rows *sql.Rows) (ImageHistory, error) {
+	var id int64
+	var imageName string
+	var configJSON string
+	var imageDate time.Time
+	var diffJSON sql.NullString
+	var status sql.NullString
+	var imageID sql.NullString
+	var configHash sql.NullString
+	var imageRemoved sql.NullString
+	var origin sql.NullString
+	var buildLogPath sql.NullString
+
+	if err := rows.Scan(&id, &imageName, &configJSON, &imageDate, &diffJSON, &status, &imageID, &configHash, &imageRemoved, &origin,
+		&buildLogPath); err != nil {
+		return ImageHistory{}, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return ImageHistory{}, fmt.Errorf(lib.T_("Config conversion error: %v"), err)
+	}
 
-		history := ImageHistory{
-			ImageName: imageName,
-			Config:    &cfg,
-			ImageDate: imageDate.Format(time.RFC3339),
+	// У записей, созданных до появления колонки packagediff, разницы нет
+	var diff *PackageDiff
+	if diffJSON.Valid && diffJSON.String != "" {
+		if err := json.Unmarshal([]byte(diffJSON.String), &diff); err != nil {
+			return ImageHistory{}, fmt.Errorf(lib.T_("Config conversion error: %v"), err)
 		}
-		histories = append(histories, history)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf(lib.T_("String processing error: %v"), err)
+	// Записи, созданные до появления колонки status, всегда были установлены на хост
+	imageStatus := status.String
+	if imageStatus == "" {
+		imageStatus = ImageStatusDeployed
 	}
 
-	return histories, nil
+	return ImageHistory{
+		ID:           id,
+		ImageName:    imageName,
+		ImageID:      imageID.String,
+		ConfigHash:   configHash.String,
+		Status:       imageStatus,
+		Config:       &cfg,
+		PackageDiff:  diff,
+		ImageDate:    imageDate.Format(time.RFC3339),
+		ImageRemoved: imageRemoved.String,
+		Origin:       origin.String,
+		BuildLogPath: buildLogPath.String,
+	}, nil
 }
 
-// CountImageHistoriesFiltered возвращает количество записей
-// фильтруя по названию образа.
-func (h *HostDBService) CountImageHistoriesFiltered(ctx context.Context, imageNameFilter string) (int, error) {
-	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", h.historyTableName)
-	var args []interface{}
+// historyMigrationColumns колонки, отсутствующие в таблицах истории предыдущих версий.
+var historyMigrationColumns = []string{"packagediff", "status", "imageid", "confighash", "imageremoved", "origin", "build_log_path"}
+
+// historyColumns колонки, читаемые scanImageHistory.
+const historyColumns = "rowid, imagename, config, imagedate, packagediff, status, imageid, confighash, imageremoved, origin, build_log_path"
+
+// migrateHistoryTable добавляет недостающие колонки в таблицу истории, созданную предыдущими версиями.
+func (h *HostDBService) migrateHistoryTable(ctx context.Context) error {
+	rows, err := h.dbConn.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", h.historyTableName))
+	if err != nil {
+		return fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+
+	tableExists := false
+	existingColumns := make(map[string]bool)
+	for rows.Next() {
+		var cid int
+		var name, columnType string
+		var notNull, pk int
+		var defaultValue sql.NullString
+		if err = rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		tableExists = true
+		existingColumns[name] = true
+	}
+	rows.Close()
+
+	if !tableExists {
+		return nil
+	}
+
+	for _, column := range historyMigrationColumns {
+		if existingColumns[column] {
+			continue
+		}
+
+		alterQuery := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", h.historyTableName, column)
+		if _, err = h.dbConn.ExecContext(ctx, alterQuery); err != nil {
+			return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+		}
+	}
+
+	// Все выборки истории сортируются и фильтруются по дате сборки
+	indexQuery := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_imagedate ON %s (imagedate)", h.historyTableName, h.historyTableName)
+	if _, err = h.dbConn.ExecContext(ctx, indexQuery); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return nil
+}
+
+// GetPendingImage возвращает последнюю собранную, но не установленную запись истории, либо nil.
+func (h *HostDBService) GetPendingImage(ctx context.Context) (*ImageHistory, error) {
+	if err := h.migrateHistoryTable(ctx); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE status = ? ORDER BY imagedate DESC LIMIT 1", historyColumns, h.historyTableName)
+	rows, err := h.dbConn.QueryContext(ctx, query, ImageStatusBuilt)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") || strings.Contains(err.Error(), "doesn't exist") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, nil
+	}
+
+	history, err := scanImageHistory(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &history, nil
+}
+
+// GetDeployedConfigHash возвращает хеш конфигурации последнего установленного образа или пустую строку.
+func (h *HostDBService) GetDeployedConfigHash(ctx context.Context) (string, error) {
+	if err := h.migrateHistoryTable(ctx); err != nil {
+		return "", err
+	}
+
+	query := fmt.Sprintf("SELECT confighash FROM %s WHERE status = ? OR status IS NULL OR status = '' ORDER BY imagedate DESC LIMIT 1", h.historyTableName)
+
+	var configHash sql.NullString
+	err := h.dbConn.QueryRowContext(ctx, query, ImageStatusDeployed).Scan(&configHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "no such table") ||
+			strings.Contains(err.Error(), "doesn't exist") {
+			return "", nil
+		}
+		return "", fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+
+	return configHash.String, nil
+}
+
+// GetImageHistoryByConfigHash возвращает последнюю запись истории с указанным хешем конфигурации либо nil.
+func (h *HostDBService) GetImageHistoryByConfigHash(ctx context.Context, configHash string) (*ImageHistory, error) {
+	if err := h.migrateHistoryTable(ctx); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE confighash = ? ORDER BY imagedate DESC LIMIT 1", historyColumns, h.historyTableName)
+	rows, err := h.dbConn.QueryContext(ctx, query, configHash)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") || strings.Contains(err.Error(), "doesn't exist") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+	defer rows.Close()
 
-	if imageNameFilter != "" {
-		query += " WHERE imagename LIKE ?"
-		args = append(args, "%"+imageNameFilter+"%")
+	if !rows.Next() {
+		return nil, nil
 	}
 
+	history, err := scanImageHistory(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &history, nil
+}
+
+// SetImageStatus меняет статус записи истории образа.
+func (h *HostDBService) SetImageStatus(ctx context.Context, id int64, status string) error {
+	query := fmt.Sprintf("UPDATE %s SET status = ? WHERE rowid = ?", h.historyTableName)
+	if _, err := h.dbConn.ExecContext(ctx, query, status, id); err != nil {
+		return fmt.Errorf(lib.T_("Error updating data: %v"), err)
+	}
+
+	return nil
+}
+
+// SupersedePendingImages помечает все собранные, но не установленные образы как заменённые.
+func (h *HostDBService) SupersedePendingImages(ctx context.Context) error {
+	if err := h.migrateHistoryTable(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET status = ? WHERE status = ?", h.historyTableName)
+	if _, err := h.dbConn.ExecContext(ctx, query, ImageStatusSuperseded, ImageStatusBuilt); err != nil {
+		if strings.Contains(err.Error(), "no such table") || strings.Contains(err.Error(), "doesn't exist") {
+			return nil
+		}
+		return fmt.Errorf(lib.T_("Error updating data: %v"), err)
+	}
+
+	return nil
+}
+
+// ApplyHistoryRetention в одной транзакции помечает записи истории, чьи образы будут удалены,
+// и удаляет записи старше cutoff. Последняя установленная и ожидающая установки записи не удаляются.
+// Пометка ставится до удаления образов, поэтому после сбоя история не ссылается на удалённый образ без отметки.
+func (h *HostDBService) ApplyHistoryRetention(ctx context.Context, removedImageIDs []string, cutoff time.Time) (int64, error) {
+	if err := h.migrateHistoryTable(ctx); err != nil {
+		return 0, err
+	}
+
+	tx, err := h.dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf(lib.T_("Error starting transaction: %v"), err)
+	}
+
+	removedAt := time.Now().Format(time.RFC3339)
+	markQuery := fmt.Sprintf("UPDATE %s SET imageremoved = ? WHERE imageid = ? AND COALESCE(imageremoved, '') = ''", h.historyTableName)
+	for _, imageID := range removedImageIDs {
+		if _, err = tx.ExecContext(ctx, markQuery, removedAt, imageID); err != nil {
+			tx.Rollback()
+			if strings.Contains(err.Error(), "no such table") || strings.Contains(err.Error(), "doesn't exist") {
+				return 0, nil
+			}
+			return 0, fmt.Errorf(lib.T_("Error updating data: %v"), err)
+		}
+	}
+
+	var removedRows int64
+	if !cutoff.IsZero() {
+		deleteQuery := fmt.Sprintf(`DELETE FROM %[1]s WHERE imagedate < ? AND COALESCE(status, '') != ?
+			AND rowid NOT IN (SELECT rowid FROM %[1]s WHERE COALESCE(status, '') IN ('', ?) ORDER BY imagedate DESC LIMIT 1)`,
+			h.historyTableName)
+		result, err := tx.ExecContext(ctx, deleteQuery, cutoff, ImageStatusBuilt, ImageStatusDeployed)
+		if err != nil {
+			tx.Rollback()
+			if strings.Contains(err.Error(), "no such table") || strings.Contains(err.Error(), "doesn't exist") {
+				return 0, nil
+			}
+			return 0, fmt.Errorf(lib.T_("Table cleanup error: %w"), err)
+		}
+
+		removedRows, _ = result.RowsAffected()
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf(lib.T_("Transaction commit error: %v"), err)
+	}
+
+	return removedRows, nil
+}
+
+// UnmarkImagesRemoved снимает отметку об удалении с записей истории образов imageIDs, которые остались
+// в локальном хранилище.
+func (h *HostDBService) UnmarkImagesRemoved(ctx context.Context, imageIDs []string) error {
+	if len(imageIDs) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET imageremoved = NULL WHERE imageid IN (?%s)", h.historyTableName,
+		strings.Repeat(", ?", len(imageIDs)-1))
+	args := make([]interface{}, 0, len(imageIDs))
+	for _, imageID := range imageIDs {
+		args = append(args, imageID)
+	}
+
+	if _, err := h.dbConn.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf(lib.T_("Error updating data: %v"), err)
+	}
+
+	return nil
+}
+
+// CountImageHistoriesFiltered возвращает количество записей
+// фильтруя по названию образа, периоду и статусу.
+func (h *HostDBService) CountImageHistoriesFiltered(ctx context.Context, filter ImageHistoryFilter) (int, error) {
+	if err := h.migrateHistoryTable(ctx); err != nil {
+		return 0, err
+	}
+
+	where, args := filter.where()
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", h.historyTableName) + where
+
 	var count int
 	err := h.dbConn.QueryRowContext(ctx, query, args...).Scan(&count)
 	if err != nil {
@@ -0,0 +1,258 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// dockerfileInstruction инструкция Dockerfile с продолжениями строк, собранными в одну строку.
+type dockerfileInstruction struct {
+	line     int
+	keyword  string
+	argument string
+}
+
+// ParseDockerfile переносит Dockerfile, написанный без apm, в конфигурацию образа: FROM задаёт базовый образ,
+// apt-get install и apt-get remove в RUN - пакеты, остальные команды RUN сохраняются как пользовательские
+// команды, ENV и LABEL - как переменные окружения и метки. О конструкциях, которые конфигурация не может
+// выразить, возвращаются предупреждения.
+func ParseDockerfile(content string) (Config, []string) {
+	cfg := emptyImportedConfig()
+	var warnings []string
+
+	stages := 0
+	for _, instruction := range scanDockerfile(content) {
+		switch instruction.keyword {
+		case "FROM":
+			stages++
+			if stages > 1 {
+				// Образ даёт последняя стадия, артефакты предыдущих стадий конфигурация не хранит
+				warnings = append(warnings, fmt.Sprintf(lib.T_("Line %d: multi-stage builds are not supported, only the last stage is imported"), instruction.line))
+				cfg = emptyImportedConfig()
+			}
+			cfg.Image = parseFromImage(instruction.argument)
+		case "RUN":
+			if strings.HasPrefix(instruction.argument, "[") {
+				warnings = append(warnings, fmt.Sprintf(lib.T_("Line %d: RUN in exec form is not supported and was skipped"), instruction.line))
+				continue
+			}
+			importRunCommand(&cfg, stripRunFlags(instruction.argument))
+		case "ENV":
+			for _, pair := range parseKeyValues(instruction.argument) {
+				cfg.EnvVars = append(cfg.EnvVars, EnvVar{Key: pair[0], Value: pair[1]})
+			}
+		case "LABEL":
+			for _, pair := range parseKeyValues(instruction.argument) {
+				if cfg.Labels == nil {
+					cfg.Labels = map[string]string{}
+				}
+				cfg.Labels[pair[0]] = pair[1]
+			}
+		case "ARG":
+			// Аргумент кеша добавляет сам apm
+			if !strings.HasPrefix(instruction.argument, CacheDateArg) {
+				warnings = append(warnings, fmt.Sprintf(lib.T_("Line %d: instruction %s cannot be represented in the apm configuration and was skipped"),
+					instruction.line, instruction.keyword))
+			}
+		default:
+			warnings = append(warnings, fmt.Sprintf(lib.T_("Line %d: instruction %s cannot be represented in the apm configuration and was skipped"),
+				instruction.line, instruction.keyword))
+		}
+	}
+
+	cfg.Packages.Install = append([]string{}, uniqueStrings(cfg.Packages.Install)...)
+	cfg.Packages.Remove = append([]string{}, uniqueStrings(cfg.Packages.Remove)...)
+
+	return cfg, warnings
+}
+
+// emptyImportedConfig возвращает конфигурацию с пустыми списками, как у конфигурации по умолчанию.
+func emptyImportedConfig() Config {
+	var cfg Config
+	cfg.Packages.Install = []string{}
+	cfg.Packages.Remove = []string{}
+	cfg.Commands = []string{}
+
+	return cfg
+}
+
+// scanDockerfile разбивает Dockerfile на инструкции, пропуская комментарии и собирая строки, продолженные
+// обратной косой чертой.
+func scanDockerfile(content string) []dockerfileInstruction {
+	var instructions []dockerfileInstruction
+	var current strings.Builder
+	startLine := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") || (line == "" && current.Len() == 0) {
+			continue
+		}
+
+		if current.Len() == 0 {
+			startLine = lineNumber
+		}
+
+		continued := strings.HasSuffix(line, `\`)
+		current.WriteString(strings.TrimSuffix(line, `\`))
+		current.WriteString(" ")
+		if continued {
+			continue
+		}
+
+		keyword, argument, _ := strings.Cut(strings.TrimSpace(current.String()), " ")
+		instructions = append(instructions, dockerfileInstruction{
+			line:     startLine,
+			keyword:  strings.ToUpper(keyword),
+			argument: strings.TrimSpace(argument),
+		})
+		current.Reset()
+	}
+
+	return instructions
+}
+
+// parseFromImage возвращает образ из аргумента FROM без флагов и имени стадии.
+func parseFromImage(argument string) string {
+	for _, field := range strings.Fields(argument) {
+		if strings.HasPrefix(field, "--") {
+			continue
+		}
+
+		return strings.Trim(field, `"'`)
+	}
+
+	return ""
+}
+
+// stripRunFlags убирает флаги инструкции RUN, например --mount=type=cache.
+func stripRunFlags(argument string) string {
+	for strings.HasPrefix(argument, "--") {
+		_, rest, found := strings.Cut(argument, " ")
+		if !found {
+			return ""
+		}
+		argument = strings.TrimSpace(rest)
+	}
+
+	return argument
+}
+
+// importRunCommand разбирает команды RUN, разделённые &&: установка и удаление пакетов через apt-get
+// переносятся в списки пакетов, обновление списков пропускается, остальные команды сохраняются как есть.
+func importRunCommand(cfg *Config, command string) {
+	for _, part := range strings.Split(command, "&&") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		fields := strings.Fields(part)
+		// Переменные окружения перед командой, например DEBIAN_FRONTEND=noninteractive apt-get install
+		for len(fields) > 0 && strings.Contains(fields[0], "=") && !strings.HasPrefix(fields[0], "-") {
+			fields = fields[1:]
+		}
+
+		if len(fields) < 2 || (fields[0] != "apt-get" && fields[0] != "apt") {
+			cfg.Commands = append(cfg.Commands, part)
+			continue
+		}
+
+		action := ""
+		var packages []string
+		for _, field := range fields[1:] {
+			switch {
+			case strings.HasPrefix(field, "-"):
+			case action == "":
+				action = field
+			default:
+				packages = append(packages, field)
+			}
+		}
+
+		switch action {
+		case "update", "clean":
+		case "install":
+			for _, pkg := range packages {
+				if strings.HasSuffix(pkg, "-") {
+					cfg.Packages.Remove = append(cfg.Packages.Remove, strings.TrimSuffix(pkg, "-"))
+				} else {
+					cfg.Packages.Install = append(cfg.Packages.Install, strings.TrimSuffix(pkg, "+"))
+				}
+			}
+		case "remove", "purge":
+			for _, pkg := range packages {
+				cfg.Packages.Remove = append(cfg.Packages.Remove, strings.TrimSuffix(pkg, "-"))
+			}
+		default:
+			cfg.Commands = append(cfg.Commands, part)
+		}
+	}
+}
+
+// parseKeyValues разбирает аргумент ENV или LABEL: пары key=value или устаревшую форму "key value".
+func parseKeyValues(argument string) [][2]string {
+	if !strings.Contains(strings.SplitN(argument, " ", 2)[0], "=") {
+		key, value, _ := strings.Cut(argument, " ")
+		return [][2]string{{strings.Trim(key, `"`), strings.Trim(strings.TrimSpace(value), `"`)}}
+	}
+
+	var pairs [][2]string
+	for _, field := range splitQuotedFields(argument) {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		pairs = append(pairs, [2]string{strings.Trim(key, `"`), strings.Trim(value, `"`)})
+	}
+
+	return pairs
+}
+
+// splitQuotedFields разбивает строку по пробелам вне двойных кавычек.
+func splitQuotedFields(value string) []string {
+	var fields []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case r == ' ' && !quoted:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+
+	return fields
+}
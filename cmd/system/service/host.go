@@ -21,23 +21,39 @@ import (
 	"apm/lib"
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
+	"time"
 )
 
 var ContainerFile = "/var/Containerfile"
 
+// PendingImageTag тег, под которым хранится собранный, но не установленный образ.
+var PendingImageTag = "localhost/apm-pending:latest"
+
 type HostImage struct {
 	Spec struct {
 		Image ImageInfo `json:"image"`
 	} `json:"spec"`
 	Status struct {
-		Staged *ImageStatus `json:"staged"`
-		Booted ImageStatus  `json:"booted"`
+		Staged   *ImageStatus `json:"staged"`
+		Booted   ImageStatus  `json:"booted"`
+		Rollback *ImageStatus `json:"rollback"`
+		// OtherDeployments закреплённые развёртывания, кроме загруженного, подготовленного и отката
+		OtherDeployments []ImageStatus `json:"otherDeployments,omitempty"`
 	} `json:"status"`
+	// Labels метаданные apm загруженного образа, если он собран apm
+	Labels *ImageLabels `json:"labels,omitempty"`
+	// StagedLabels метаданные apm образа, который будет загружен после перезагрузки
+	StagedLabels *ImageLabels `json:"stagedLabels,omitempty"`
 }
 
 type ImageInfo struct {
@@ -58,28 +74,80 @@ type Image struct {
 	ImageDigest string    `json:"imageDigest"`
 }
 
+// DefaultBuildTimeout тайм-аут сборки образа, если imageBuildTimeout не задан в конфигурации.
+const DefaultBuildTimeout = 30 * time.Minute
+
+// ErrBuildTimeout сборка образа прервана по тайм-ауту.
+var ErrBuildTimeout = errors.New("image build timed out")
+
+// BuildOptions параметры podman build для одной сборки образа.
+type BuildOptions struct {
+	// NoCacheFlag собирает образ без кеша слоёв (--no-cache)
+	NoCacheFlag bool
+	// PullAlways всегда заново загружает базовый образ из реестра (--pull=always)
+	PullAlways bool
+	// Arch целевая архитектура образа (--platform). Пустое значение - архитектура хоста
+	Arch string
+	// Forced пересборка без изменений базового образа, в истории отмечается как ImageOriginForced
+	Forced bool
+	// PackagesOnly сборка только с изменёнными пакетами: базовый образ не загружается, а в Dockerfile
+	// он указан по дайджесту из CurrentBaseImageRef
+	PackagesOnly bool
+	// BaseDigest дайджест базового образа сборки PackagesOnly, он входит в хеш конфигурации
+	BaseDigest string
+	// Timeout тайм-аут этой сборки. Нулевое значение - тайм-аут из конфигурации
+	Timeout time.Duration
+}
+
+// BuiltImageName полное имя, под которым podman сохраняет собранный образ.
+const BuiltImageName = "localhost/" + buildImageTag + ":latest"
+
 // HostImageService — единый сервис для операций с образом (build, switch и т.д.).
 type HostImageService struct {
 	commandPrefix     string
 	containerPath     string
+	buildTimeout      time.Duration
+	hookResults       []BuildHookResult
+	buildLogPath      string
+	extraLabels       map[string]string
 	serviceHostConfig *HostConfigService
 }
 
 // NewHostImageService — конструктор сервиса
 func NewHostImageService(hostConfigService *HostConfigService) *HostImageService {
+	buildTimeout := DefaultBuildTimeout
+	if lib.Env.ImageBuildTimeout > 0 {
+		buildTimeout = time.Duration(lib.Env.ImageBuildTimeout) * time.Minute
+	}
+
 	return &HostImageService{
 		commandPrefix:     lib.Env.CommandPrefix,
 		containerPath:     ContainerFile,
+		buildTimeout:      buildTimeout,
 		serviceHostConfig: hostConfigService,
 	}
 }
 
-func (h *HostImageService) GetHostImage() (HostImage, error) {
+// SetExtraLabels задаёт метки, добавляемые только к следующим сборкам этого сервиса, без записи в конфигурацию.
+func (h *HostImageService) SetExtraLabels(labels map[string]string) {
+	h.extraLabels = labels
+}
+
+// BuildTimeout возвращает текущий тайм-аут сборки образа.
+func (h *HostImageService) BuildTimeout() time.Duration {
+	if h.buildTimeout <= 0 {
+		return DefaultBuildTimeout
+	}
+
+	return h.buildTimeout
+}
+
+func (h *HostImageService) GetHostImage(ctx context.Context) (HostImage, error) {
 	var host HostImage
 
 	command := fmt.Sprintf("%s bootc status --format json", lib.Env.CommandPrefix)
-	cmd := exec.Command("sh", "-c", command)
-	output, err := cmd.CombinedOutput()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	output, err := lib.CommandCombinedOutput(cmd)
 	if err != nil {
 		return host, fmt.Errorf(lib.T_("Failed to execute bootc command: %v"), string(output))
 	}
@@ -88,12 +156,29 @@ func (h *HostImageService) GetHostImage() (HostImage, error) {
 		return host, fmt.Errorf(lib.T_("Failed to parse JSON: %v"), err)
 	}
 
+	if strings.HasPrefix(host.Status.Booted.Image.Image.Transport, "containers-storage") {
+		labels, err := ReadImageLabels(ctx, host.Status.Booted.Image.Image.Image)
+		if err != nil {
+			lib.Log.Debug(err.Error())
+		}
+		host.Labels = labels
+	}
+
+	// После switch на локальный образ метки загруженной версии ещё не отражают установленный образ
+	if staged := host.Status.Staged; staged != nil && strings.HasPrefix(staged.Image.Image.Transport, "containers-storage") {
+		labels, err := ReadImageLabels(ctx, staged.Image.Image.Image)
+		if err != nil {
+			lib.Log.Debug(err.Error())
+		}
+		host.StagedLabels = labels
+	}
+
 	return host, nil
 }
 
 // GetImageFromDocker ищет название образа в docker-файле.
-func (h *HostImageService) GetImageFromDocker() (string, error) {
-	host, err := h.GetHostImage()
+func (h *HostImageService) GetImageFromDocker(ctx context.Context) (string, error) {
+	host, err := h.GetHostImage(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -155,8 +240,8 @@ func (h *HostImageService) EnableOverlay() error {
 
 	if runOverlay {
 		command := fmt.Sprintf("%s bootc usr-overlay", lib.Env.CommandPrefix)
-		cmd := exec.Command("sh", "-c", command)
-		if output, err := cmd.CombinedOutput(); err != nil {
+		cmd := exec.CommandContext(context.Background(), "sh", "-c", command)
+		if output, err := lib.CommandCombinedOutput(cmd); err != nil {
 			return fmt.Errorf(lib.T_("Error activating usr-overlay: %s"), string(output))
 		}
 	}
@@ -164,22 +249,206 @@ func (h *HostImageService) EnableOverlay() error {
 	return nil
 }
 
-// BuildImage сборка образа
-func (h *HostImageService) BuildImage(ctx context.Context, pullImage bool) (string, error) {
+// ConfigHash вычисляет хеш действующей конфигурации: дайджест базового образа, списки пакетов, команды и источники apt.
+// Для дайджеста используется локальная копия базового образа, без неё возвращается ошибка.
+func (h *HostImageService) ConfigHash(ctx context.Context, config Config) (string, error) {
+	baseDigest, err := LocalImageDigest(ctx, config.Image)
+	if err != nil {
+		return "", err
+	}
+
+	return HashConfig(config, baseDigest)
+}
+
+// ExpectedConfigHash возвращает хеш, который получит сборка с загрузкой базового образа. Берётся дайджест
+// образа в реестре, а не устаревшей локальной копии: иначе обновление только базового образа не
+// считалось бы изменением.
+func (h *HostImageService) ExpectedConfigHash(ctx context.Context, config Config) (string, error) {
+	baseDigest, err := RemoteImageDigest(ctx, config.Image)
+	if err != nil {
+		return "", err
+	}
+
+	return HashConfig(config, baseDigest)
+}
+
+// HashConfig вычисляет хеш конфигурации с дайджестом базового образа baseDigest. В хеш входит всё,
+// что меняет содержимое собранного образа, в том числе закреплённые через apt-mark hold пакеты.
+func HashConfig(config Config, baseDigest string) (string, error) {
+	content := struct {
+		BaseDigest   string            `json:"baseDigest"`
+		Image        string            `json:"image"`
+		Install      []string          `json:"install"`
+		Remove       []string          `json:"remove"`
+		Commands     []string          `json:"commands"`
+		AptSources   []AptSourceConfig `json:"aptSources"`
+		EnvVars      []EnvVar          `json:"envVars,omitempty"`
+		Labels       map[string]string `json:"labels,omitempty"`
+		HeldPackages []HeldPackage     `json:"heldPackages,omitempty"`
+		TargetArch   string            `json:"targetArch,omitempty"`
+		Pinned       bool              `json:"pinned,omitempty"`
+	}{
+		BaseDigest:   baseDigest,
+		Image:        config.Image,
+		Install:      config.Packages.Install,
+		Remove:       config.Packages.Remove,
+		Commands:     config.Commands,
+		AptSources:   config.AptSources,
+		EnvVars:      config.EnvVars,
+		Labels:       config.Labels,
+		HeldPackages: config.HeldPackages,
+		TargetArch:   config.TargetArch,
+		Pinned:       config.Pinned,
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf(lib.T_("Error serializing config: %v"), err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// pullBaseImage заранее загружает базовый образ, чтобы хеш конфигурации учитывал его актуальный дайджест.
+func (h *HostImageService) pullBaseImage(ctx context.Context, config Config) error {
+	command := fmt.Sprintf("%s podman pull %s", lib.Env.CommandPrefix, config.Image)
+	if platform := TargetPlatform(config.TargetArch); platform != "" {
+		command = fmt.Sprintf("%s podman pull --platform %s %s", lib.Env.CommandPrefix, platform, config.Image)
+	}
+	if stdout, err := PullAndProgress(ctx, command); err != nil {
+		return fmt.Errorf(lib.T_("Error building image: %s status: %d"), stdout, err)
+	}
+
+	return nil
+}
+
+// HookResults возвращает результаты скриптов hooks.preBuild и hooks.postBuild последней сборки.
+func (h *HostImageService) HookResults() []BuildHookResult {
+	return h.hookResults
+}
+
+// BuildLogPath возвращает путь к журналу последней сборки или пустую строку, если журнал не записывался.
+func (h *HostImageService) BuildLogPath() string {
+	return h.buildLogPath
+}
+
+// BuildImage сборка образа с запуском скриптов hooks.preBuild и hooks.postBuild.
+// Ошибка скрипта preBuild прерывает сборку, ошибка postBuild только записывается в журнал.
+func (h *HostImageService) BuildImage(ctx context.Context, pullImage bool, configHash string) (string, error) {
+	return h.BuildWithLog(ctx, configHash, "", BuildOptions{PullAlways: pullImage})
+}
+
+// BuildWithLog собирает образ как BuildImage и записывает весь вывод podman build в logFile.
+// В журнале остаются последние BuildLogMaxLines строк. Если журнал создать не удалось, сборка
+// продолжается без него, а BuildLogPath возвращает пустую строку.
+func (h *HostImageService) BuildWithLog(ctx context.Context, configHash string, logFile string, options BuildOptions) (string, error) {
+	h.hookResults = nil
+	h.buildLogPath = ""
+
+	var logWriter io.Writer
+	if file := openBuildLog(logFile); file != nil {
+		h.buildLogPath = logFile
+		logWriter = file
+		defer func() {
+			_ = file.Close()
+			if err := truncateBuildLog(logFile, BuildLogMaxLines); err != nil {
+				lib.Log.Warning(err.Error())
+			}
+		}()
+	}
+
+	env := buildHookEnv(ctx, configHash)
+
+	result, err := runBuildHook(ctx, HookPreBuild, lib.Env.Hooks.PreBuild, env)
+	if result != nil {
+		h.hookResults = append(h.hookResults, *result)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	podmanImageID, buildErr := h.buildImage(ctx, configHash, logWriter, options)
+
+	buildResult := "success"
+	if buildErr != nil {
+		buildResult = "failure"
+	}
+	env = append(env, "APM_BUILD_RESULT="+buildResult, "APM_IMAGE_ID="+podmanImageID)
+
+	result, err = runBuildHook(ctx, HookPostBuild, lib.Env.Hooks.PostBuild, env)
+	if result != nil {
+		h.hookResults = append(h.hookResults, *result)
+	}
+	if err != nil {
+		lib.Log.Warning(err.Error())
+	}
+
+	return podmanImageID, reply.WithErrorCode(reply.ErrorCodeImageBuildFailed, buildErr)
+}
+
+// buildImage сборка образа с параметрами options. Образ помечается метками apm, включая configHash, вывод сборки
+// дублируется в logWriter
+func (h *HostImageService) buildImage(ctx context.Context, configHash string, logWriter io.Writer, options BuildOptions) (string, error) {
 	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("system.BuildImage"))
 	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("system.BuildImage"))
-	command := fmt.Sprintf("%s podman build --squash -t os /var", lib.Env.CommandPrefix)
-	if pullImage {
-		command = fmt.Sprintf("%s podman build --pull=always --squash -t os /var", lib.Env.CommandPrefix)
+
+	var diff *PackageDiff
+	if h.serviceHostConfig.Config != nil {
+		previousConfig, err := h.serviceHostConfig.serviceHostDatabase.GetLatestConfig(ctx)
+		if err != nil {
+			return "", err
+		}
+		diff = NewPackageDiff(previousConfig, h.serviceHostConfig.Config)
+	}
+	labels := buildLabelArgs(newImageLabels(ctx, configHash, diff))
+	if len(h.extraLabels) > 0 {
+		labels += " " + extraLabelArgs(h.extraLabels)
+	}
+
+	// С кешем слоёв списки пакетов обновляются раз в сутки, архивы apt переиспользуются между сборками
+	buildMode := "--squash"
+	if SupportsBuildCache() {
+		buildMode = fmt.Sprintf("--layers --build-arg %s=%s", CacheDateArg, time.Now().Format("2006-01-02"))
+	}
+
+	if options.NoCacheFlag {
+		lib.Log.Warning(lib.T_("The image is built without the layer cache, the build will take significantly longer"))
+		buildMode += " --no-cache"
 	}
+	if options.PullAlways {
+		buildMode = "--pull=always " + buildMode
+	}
+	if platform := TargetPlatform(options.Arch); platform != "" {
+		buildMode = "--platform " + platform + " " + buildMode
+	}
+
+	command := fmt.Sprintf("%s podman build %s %s -t %s /var", lib.Env.CommandPrefix, buildMode, labels, buildImageTag)
 
-	stdout, err := PullAndProgress(ctx, command)
+	startTime := time.Now()
+	defer func() {
+		lib.Log.Infof(lib.T_("Image build took %s"), time.Since(startTime).Round(time.Second))
+	}()
+
+	// По истечении тайм-аута процесс podman завершается через контекст
+	timeout := h.BuildTimeout()
+	if options.Timeout > 0 {
+		timeout = options.Timeout
+	}
+	buildCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout, err := PullAndProgressWithLog(buildCtx, command, logWriter)
 	if err != nil {
+		if errors.Is(buildCtx.Err(), context.DeadlineExceeded) {
+			cleanupPartialBuild(ctx)
+			return "", ErrBuildTimeout
+		}
 		return "", fmt.Errorf(lib.T_("Error building image: %s status: %d"), stdout, err)
 	}
 
-	cmd := exec.Command("sh", "-c", fmt.Sprintf("%s podman images -q os", lib.Env.CommandPrefix))
-	output, err := cmd.Output()
+	cmd := exec.CommandContext(ctx, "sh", "-c", fmt.Sprintf("%s podman images -q %s", lib.Env.CommandPrefix, buildImageTag))
+	output, err := lib.CommandOutput(cmd)
 	if err != nil {
 		return "", fmt.Errorf(lib.T_("Error podman image: %v"), err)
 	}
@@ -192,6 +461,15 @@ func (h *HostImageService) BuildImage(ctx context.Context, pullImage bool) (stri
 	return podmanImageID, nil
 }
 
+// cleanupPartialBuild удаляет безымянные промежуточные образы прерванной сборки. Удаляются только образы с меткой apm,
+// чтобы не затронуть висящие образы других сборок на хосте.
+func cleanupPartialBuild(ctx context.Context) {
+	command := fmt.Sprintf("%s podman image prune --force --filter label=%s=true", lib.Env.CommandPrefix, LabelManaged)
+	if output, err := lib.CommandCombinedOutput(exec.CommandContext(ctx, "sh", "-c", command)); err != nil {
+		lib.Log.Warningf(lib.T_("Failed to clean up the interrupted build: %s"), string(output))
+	}
+}
+
 // SwitchImage переключение образа
 func (h *HostImageService) SwitchImage(ctx context.Context, podmanImageID string) error {
 	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("system.SwitchImage"))
@@ -199,41 +477,239 @@ func (h *HostImageService) SwitchImage(ctx context.Context, podmanImageID string
 
 	command := fmt.Sprintf("%s bootc switch --transport containers-storage %s", lib.Env.CommandPrefix, podmanImageID)
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := lib.CommandCombinedOutput(cmd); err != nil {
 		return fmt.Errorf(lib.T_("Error switching to the new image: %s"), string(output))
 	}
 
 	return nil
 }
 
-// CheckAndUpdateBaseImage проверяет обновление базового образа.
-func (h *HostImageService) CheckAndUpdateBaseImage(ctx context.Context, pullImage bool, config Config) error {
+// CheckAndUpdateBaseImage проверяет обновление базового образа и применяет его. Если базовый образ не изменился,
+// обновление пропускается и возвращается false. force пересобирает образ без проверки, а noSwitch только
+// собирает его и оставляет ожидающим установки, что доступно лишь для локально собранного образа.
+func (h *HostImageService) CheckAndUpdateBaseImage(ctx context.Context, pullImage bool, config Config, force bool, noSwitch bool) (bool, error) {
 	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("system.CheckAndUpdateBaseImage"))
 	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("system.CheckAndUpdateBaseImage"))
-	image, err := h.GetHostImage()
+	image, err := h.GetHostImage(ctx)
+	if err != nil {
+		return false, fmt.Errorf(lib.T_("Error retrieving information: %v"), err)
+	}
+
+	if image.Status.Booted.Image.Image.Transport != "containers-storage" {
+		if noSwitch {
+			return false, reply.Errorf(reply.ErrorCodeNotSupported,
+				lib.T_("Building without switching is only available for a locally built image"))
+		}
+
+		if !force {
+			command := fmt.Sprintf("%s bootc upgrade --check", lib.Env.CommandPrefix)
+			cmd := exec.CommandContext(ctx, "sh", "-c", command)
+			output, err := lib.CommandCombinedOutput(cmd)
+			if err != nil {
+				return false, fmt.Errorf(lib.T_("bootc upgrade --check failed: %s"), string(output))
+			}
+
+			if strings.Contains(string(output), "No changes in:") {
+				return false, nil
+			}
+		}
+
+		return true, h.bootcUpgrade(ctx)
+	}
+
+	if _, err = os.Stat(h.containerPath); err != nil {
+		return false, fmt.Errorf(lib.T_("Error, file %s not found"), h.containerPath)
+	}
+
+	if !force {
+		available, err := h.CheckBaseImageUpdate(ctx)
+		if err != nil {
+			return false, err
+		}
+		if !available {
+			return false, nil
+		}
+	}
+
+	if noSwitch {
+		_, err = h.BuildOnly(ctx, pullImage, config, BuildOptions{PullAlways: pullImage, Arch: config.TargetArch, Forced: force})
+		return true, err
+	}
+
+	return true, h.BuildAndSwitch(ctx, pullImage, config, false, BuildOptions{PullAlways: pullImage, Arch: config.TargetArch, Forced: force})
+}
+
+// CheckBaseImageUpdate только проверяет наличие обновления базового образа, ничего не применяя.
+func (h *HostImageService) CheckBaseImageUpdate(ctx context.Context) (bool, error) {
+	image, err := h.GetHostImage(ctx)
 	if err != nil {
-		return fmt.Errorf(lib.T_("Error retrieving information: %v"), err)
+		return false, fmt.Errorf(lib.T_("Error retrieving information: %v"), err)
 	}
 
 	if image.Status.Booted.Image.Image.Transport != "containers-storage" {
 		command := fmt.Sprintf("%s bootc upgrade --check", lib.Env.CommandPrefix)
-		cmd := exec.Command("sh", "-c", command)
-		output, err := cmd.CombinedOutput()
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		output, err := lib.CommandCombinedOutput(cmd)
 		if err != nil {
-			return fmt.Errorf("bootc upgrade --check failed: %s", string(output))
+			return false, fmt.Errorf(lib.T_("bootc upgrade --check failed: %s"), string(output))
 		}
 
-		if !strings.Contains(string(output), "No changes in:") {
-			return h.bootcUpgrade(ctx)
+		return !strings.Contains(string(output), "No changes in:"), nil
+	}
+
+	// Для локального образа сравниваем дайджест базового образа в хранилище с дайджестом в реестре
+	baseImage, err := h.GetImageFromDocker(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	command := fmt.Sprintf("%s podman image inspect --format '{{.Digest}}' %s", lib.Env.CommandPrefix, baseImage)
+	localDigest, err := lib.CommandOutput(exec.CommandContext(ctx, "sh", "-c", command))
+	if err != nil {
+		// Базового образа нет локально, значит следующая сборка его скачает
+		return true, nil
+	}
+
+	command = fmt.Sprintf("%s skopeo inspect --format '{{.Digest}}' docker://%s", lib.Env.CommandPrefix, baseImage)
+	remoteDigest, err := lib.CommandCombinedOutput(exec.CommandContext(ctx, "sh", "-c", command))
+	if err != nil {
+		return false, fmt.Errorf(lib.T_("Failed to get the digest of image %s: %s"), baseImage, string(remoteDigest))
+	}
+
+	return strings.TrimSpace(string(localDigest)) != strings.TrimSpace(string(remoteDigest)), nil
+}
+
+// Результаты проверки наличия нового базового образа.
+const (
+	ImageUpdateAvailable = "available"
+	ImageUpToDate        = "upToDate"
+	// ImageUpdateUnknown реестр недоступен, наличие обновления определить не удалось
+	ImageUpdateUnknown = "unknown"
+)
+
+// ImageUpdateCheck результат сравнения базового образа в реестре с образом хоста.
+type ImageUpdateCheck struct {
+	Image           string `json:"image"`
+	Status          string `json:"status"`
+	UpdateAvailable *bool  `json:"updateAvailable"`
+	LocalDigest     string `json:"localDigest"`
+	RemoteDigest    string `json:"remoteDigest"`
+	RemoteCreated   string `json:"remoteCreated"`
+	Error           string `json:"error,omitempty"`
+}
+
+// CheckImageUpdate сравнивает дайджест образа baseImage в реестре с дайджестом образа хоста.
+// Из реестра читаются только манифест и конфигурация, слои не загружаются.
+func (h *HostImageService) CheckImageUpdate(ctx context.Context, baseImage string) (ImageUpdateCheck, error) {
+	check := ImageUpdateCheck{Image: baseImage}
+
+	host, err := h.GetHostImage(ctx)
+	if err != nil {
+		return check, fmt.Errorf(lib.T_("Error retrieving information: %v"), err)
+	}
+
+	if strings.HasPrefix(host.Status.Booted.Image.Image.Transport, "containers-storage") {
+		// Локальная сборка: сравниваем с базовым образом, из которого она собрана
+		command := fmt.Sprintf("%s podman image inspect --format '{{.Digest}}' %s", lib.Env.CommandPrefix, baseImage)
+		if output, err := lib.CommandOutput(exec.CommandContext(ctx, "sh", "-c", command)); err == nil {
+			check.LocalDigest = strings.TrimSpace(string(output))
 		}
-		return nil
+	} else if host.Status.Staged != nil {
+		check.LocalDigest = host.Status.Staged.Image.ImageDigest
+	} else {
+		check.LocalDigest = host.Status.Booted.Image.ImageDigest
 	}
 
-	if _, err = os.Stat(h.containerPath); err != nil {
-		return fmt.Errorf(lib.T_("Error, file %s not found"), h.containerPath)
+	command := fmt.Sprintf("%s skopeo inspect --no-tags docker://%s", lib.Env.CommandPrefix, baseImage)
+	output, err := lib.CommandCombinedOutput(exec.CommandContext(ctx, "sh", "-c", command))
+	if err != nil {
+		check.Status = ImageUpdateUnknown
+		check.Error = fmt.Sprintf(lib.T_("Failed to get the digest of image %s: %s"), baseImage, strings.TrimSpace(string(output)))
+		return check, nil
 	}
 
-	return h.BuildAndSwitch(ctx, pullImage, config, false)
+	return CompareImageDigests(check, output)
+}
+
+// CompareImageDigests дополняет check дайджестом и датой создания образа из вывода skopeo inspect и
+// сравнивает дайджест с локальным. Неизвестный локальный дайджест считается устаревшим.
+func CompareImageDigests(check ImageUpdateCheck, inspectOutput []byte) (ImageUpdateCheck, error) {
+	var remote struct {
+		Digest  string `json:"Digest"`
+		Created string `json:"Created"`
+	}
+	if err := json.Unmarshal(inspectOutput, &remote); err != nil {
+		return check, fmt.Errorf(lib.T_("Failed to parse JSON: %v"), err)
+	}
+
+	check.RemoteDigest = remote.Digest
+	check.RemoteCreated = remote.Created
+
+	updateAvailable := check.LocalDigest != check.RemoteDigest
+	check.UpdateAvailable = &updateAvailable
+	check.Status = ImageUpToDate
+	if updateAvailable {
+		check.Status = ImageUpdateAvailable
+	}
+
+	return check, nil
+}
+
+// CurrentBaseImageRef возвращает ссылку на базовый образ baseImage по дайджесту, из которого собран текущий
+// образ хоста, и сам дайджест. Для локальной сборки дайджест берётся из локального хранилища podman,
+// для образа из реестра - из загруженного образа.
+func (h *HostImageService) CurrentBaseImageRef(ctx context.Context, baseImage string) (string, string, error) {
+	host, err := h.GetHostImage(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf(lib.T_("Error retrieving information: %v"), err)
+	}
+
+	digest := host.Status.Booted.Image.ImageDigest
+	if strings.HasPrefix(host.Status.Booted.Image.Image.Transport, "containers-storage") {
+		digest, err = LocalImageDigest(ctx, baseImage)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if digest == "" {
+		return "", "", fmt.Errorf(lib.T_("Failed to determine the digest of the current base image %s"), baseImage)
+	}
+
+	return imageRepository(baseImage) + "@" + digest, digest, nil
+}
+
+// LocalImageDigest возвращает дайджест образа image в локальном хранилище podman.
+func LocalImageDigest(ctx context.Context, image string) (string, error) {
+	command := fmt.Sprintf("%s podman image inspect --format '{{.Digest}}' %s", lib.Env.CommandPrefix, image)
+	output, err := lib.CommandOutput(exec.CommandContext(ctx, "sh", "-c", command))
+	if err != nil {
+		return "", fmt.Errorf(lib.T_("Failed to get the digest of image %s: %v"), image, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RemoteImageDigest возвращает дайджест образа image в реестре. Слои образа не загружаются.
+func RemoteImageDigest(ctx context.Context, image string) (string, error) {
+	command := fmt.Sprintf("%s skopeo inspect --format '{{.Digest}}' docker://%s", lib.Env.CommandPrefix, image)
+	output, err := lib.CommandCombinedOutput(exec.CommandContext(ctx, "sh", "-c", command))
+	if err != nil {
+		return "", fmt.Errorf(lib.T_("Failed to get the digest of image %s: %s"), image, strings.TrimSpace(string(output)))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// imageRepository возвращает имя образа без тега и дайджеста.
+func imageRepository(image string) string {
+	if at := strings.Index(image, "@"); at >= 0 {
+		image = image[:at]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		image = image[:colon]
+	}
+
+	return image
 }
 
 func (h *HostImageService) bootcUpgrade(ctx context.Context) error {
@@ -241,21 +717,37 @@ func (h *HostImageService) bootcUpgrade(ctx context.Context) error {
 	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("system.bootcUpgrade"))
 
 	cmd := exec.CommandContext(ctx, "sh", "-c", fmt.Sprintf("%s bootc upgrade", lib.Env.CommandPrefix))
-	if output, err := cmd.CombinedOutput(); err != nil {
+	if output, err := lib.CommandCombinedOutput(cmd); err != nil {
 		return fmt.Errorf(lib.T_("bootc upgrade failed: %s"), string(output))
 	}
 
 	return nil
 }
 
-// BuildAndSwitch перестраивает и переключает систему на новый образ. checkSame - включена ли проверка на изменение конфигурации
-func (h *HostImageService) BuildAndSwitch(ctx context.Context, pullImage bool, config Config, checkSame bool) error {
+// BuildAndSwitch перестраивает и переключает систему на новый образ. checkSame - включена ли проверка на изменение конфигурации,
+// options - параметры podman build
+func (h *HostImageService) BuildAndSwitch(ctx context.Context, pullImage bool, config Config, checkSame bool, options BuildOptions) error {
+	if options.PackagesOnly {
+		pullImage = false
+		options.PullAlways = false
+	}
+
 	statusSame, err := h.serviceHostConfig.ConfigIsChanged(ctx)
 	if !statusSame && checkSame {
 		return fmt.Errorf(lib.T_("The image has not changed, build paused"))
 	}
 
-	idImage, err := h.BuildImage(ctx, pullImage)
+	var configHash string
+	if options.PackagesOnly && options.BaseDigest != "" {
+		configHash, err = HashConfig(config, options.BaseDigest)
+	} else {
+		configHash, err = h.prepareBuild(ctx, pullImage, config)
+	}
+	if err != nil {
+		return err
+	}
+
+	idImage, err := h.BuildWithLog(ctx, configHash, NewBuildLogPath(), options)
 	if err != nil {
 		return err
 	}
@@ -265,10 +757,232 @@ func (h *HostImageService) BuildAndSwitch(ctx context.Context, pullImage bool, c
 		return err
 	}
 
-	err = h.serviceHostConfig.SaveConfigToDB(ctx)
+	err = h.serviceHostConfig.SaveConfigToDB(ctx, configHash, h.buildLogPath, options.Forced)
+	if err != nil {
+		return err
+	}
+
+	// Ранее собранные образы устарели, так как хост переключён на свежую сборку
+	err = h.serviceHostConfig.serviceHostDatabase.SupersedePendingImages(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = pruneOldImages(ctx)
 	if err != nil {
 		return err
 	}
 
-	return pruneOldImages(ctx)
+	// Ошибка очистки по правилам хранения не отменяет успешно установленный образ
+	if _, err = h.CollectGarbage(ctx, DefaultRetentionPolicy()); err != nil {
+		lib.Log.Warningf(lib.T_("Failed to apply the retention policy: %v"), err)
+	}
+
+	return nil
+}
+
+// prepareBuild при необходимости загружает базовый образ и возвращает хеш конфигурации для сборки.
+func (h *HostImageService) prepareBuild(ctx context.Context, pullImage bool, config Config) (string, error) {
+	if pullImage {
+		if err := h.pullBaseImage(ctx, config); err != nil {
+			return "", err
+		}
+	}
+
+	return h.ConfigHash(ctx, config)
+}
+
+// BuildOnly собирает образ и сохраняет его под тегом PendingImageTag, не переключая хост.
+// Сборка записывается в историю со статусом «собран, не установлен», options.Forced отмечает её как принудительную.
+func (h *HostImageService) BuildOnly(ctx context.Context, pullImage bool, config Config, options BuildOptions) (ImageHistory, error) {
+	configHash, err := h.prepareBuild(ctx, pullImage, config)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+
+	idImage, err := h.BuildWithLog(ctx, configHash, NewBuildLogPath(), options)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+
+	command := fmt.Sprintf("%s podman tag %s %s", lib.Env.CommandPrefix, idImage, PendingImageTag)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if output, err := lib.CommandCombinedOutput(cmd); err != nil {
+		return ImageHistory{}, fmt.Errorf(lib.T_("Error tagging image: %s"), string(output))
+	}
+
+	err = h.serviceHostConfig.serviceHostDatabase.SupersedePendingImages(ctx)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+
+	err = h.serviceHostConfig.SaveBuiltConfigToDB(ctx, idImage, configHash, h.buildLogPath, options.Forced)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+
+	pending, err := h.serviceHostConfig.serviceHostDatabase.GetPendingImage(ctx)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+	if pending == nil {
+		return ImageHistory{}, fmt.Errorf(lib.T_("History not found"))
+	}
+
+	return *pending, nil
+}
+
+// SwitchToBuilt переключает хост на ранее собранный образ без повторной сборки.
+func (h *HostImageService) SwitchToBuilt(ctx context.Context) (ImageHistory, error) {
+	pending, err := h.serviceHostConfig.serviceHostDatabase.GetPendingImage(ctx)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+	if pending == nil || pending.ImageID == "" {
+		return ImageHistory{}, fmt.Errorf(lib.T_("There is no built image waiting to be deployed. Run the image build command first"))
+	}
+
+	command := fmt.Sprintf("%s podman image exists %s", lib.Env.CommandPrefix, pending.ImageID)
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if err = lib.CommandRun(cmd); err != nil {
+		return ImageHistory{}, fmt.Errorf(lib.T_("Built image %s no longer exists, please build it again"), pending.ImageID)
+	}
+
+	err = h.SwitchImage(ctx, pending.ImageID)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+
+	err = h.serviceHostConfig.serviceHostDatabase.SetImageStatus(ctx, pending.ID, ImageStatusDeployed)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+	pending.Status = ImageStatusDeployed
+
+	command = fmt.Sprintf("%s podman untag %s %s", lib.Env.CommandPrefix, pending.ImageID, PendingImageTag)
+	cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	if output, err := lib.CommandCombinedOutput(cmd); err != nil {
+		lib.Log.Debugf("podman untag: %v, output: %s", err, string(output))
+	}
+
+	return *pending, pruneOldImages(ctx)
+}
+
+// podmanImage запись из вывода podman images --format json.
+type podmanImage struct {
+	ID      string   `json:"Id"`
+	Names   []string `json:"Names"`
+	Digest  string   `json:"Digest"`
+	Created int64    `json:"Created"`
+	Size    int64    `json:"Size"`
+	// Labels метки образа, в том числе метки apm
+	Labels map[string]string `json:"Labels"`
+}
+
+// PruneImages удаляет собранные apm образы, оставляя keepLast самых новых.
+// Образы, чей идентификатор или дайджест входит в protected, не удаляются.
+// Возвращает идентификаторы удалённых образов и объём освобождённого места в байтах.
+func (h *HostImageService) PruneImages(ctx context.Context, keepLast int, protected []string) ([]string, int64, error) {
+	images, err := prunableImages(ctx, keepLast, protected)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if _, err = h.serviceHostConfig.serviceHostDatabase.ApplyHistoryRetention(ctx, podmanImageIDs(images), time.Time{}); err != nil {
+		return nil, 0, err
+	}
+
+	return h.removeMarkedImages(ctx, images)
+}
+
+// listManagedImages возвращает локальные образы с меткой apm, начиная с самых новых.
+func listManagedImages(ctx context.Context) ([]podmanImage, error) {
+	command := fmt.Sprintf("%s podman images --format json --filter label=%s=true", lib.Env.CommandPrefix, LabelManaged)
+	output, err := lib.CommandOutput(exec.CommandContext(ctx, "sh", "-c", command))
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Error retrieving podman image: %v"), err)
+	}
+
+	var images []podmanImage
+	if err = json.Unmarshal(output, &images); err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to parse JSON: %v"), err)
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Created > images[j].Created
+	})
+
+	return images, nil
+}
+
+// prunableImages возвращает собранные apm образы, кроме keepLast самых новых и защищённых protected.
+func prunableImages(ctx context.Context, keepLast int, protected []string) ([]podmanImage, error) {
+	images, err := listManagedImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	isProtected := func(image podmanImage) bool {
+		for _, value := range protected {
+			value = strings.TrimPrefix(value, "sha256:")
+			if value == "" {
+				continue
+			}
+			if strings.HasPrefix(image.ID, value) || strings.TrimPrefix(image.Digest, "sha256:") == value {
+				return true
+			}
+		}
+		return false
+	}
+
+	var prunable []podmanImage
+	for i, image := range images {
+		if i < keepLast || isProtected(image) {
+			continue
+		}
+		prunable = append(prunable, image)
+	}
+
+	return prunable, nil
+}
+
+// removeImages удаляет образы и возвращает идентификаторы удалённых и объём освобождённого места в байтах.
+func removeImages(ctx context.Context, images []podmanImage) ([]string, int64, error) {
+	pruned := []string{}
+	var freed int64
+	for _, image := range images {
+		command := fmt.Sprintf("%s podman rmi %s", lib.Env.CommandPrefix, image.ID)
+		if out, err := lib.CommandCombinedOutput(exec.CommandContext(ctx, "sh", "-c", command)); err != nil {
+			return pruned, freed, fmt.Errorf(lib.T_("Error deleting image %s: %v, output: %s\n"), image.ID, err, string(out))
+		}
+
+		pruned = append(pruned, image.ID)
+		freed += image.Size
+	}
+
+	return pruned, freed, nil
+}
+
+// removeMarkedImages удаляет образы, уже отмеченные в истории как удалённые, и снимает отметку с тех,
+// до которых удаление не дошло из-за ошибки.
+func (h *HostImageService) removeMarkedImages(ctx context.Context, images []podmanImage) ([]string, int64, error) {
+	pruned, freed, err := removeImages(ctx, images)
+	if err != nil {
+		remaining := podmanImageIDs(images[len(pruned):])
+		if errUnmark := h.serviceHostConfig.serviceHostDatabase.UnmarkImagesRemoved(ctx, remaining); errUnmark != nil {
+			lib.Log.Warning(errUnmark.Error())
+		}
+	}
+
+	return pruned, freed, err
+}
+
+// podmanImageIDs возвращает идентификаторы образов.
+func podmanImageIDs(images []podmanImage) []string {
+	ids := make([]string, 0, len(images))
+	for _, image := range images {
+		ids = append(ids, image.ID)
+	}
+
+	return ids
 }
@@ -0,0 +1,163 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OCI-метки, которыми apm помечает собранные образы. По ним работают prune, история и статус образа.
+const (
+	LabelManaged     = "com.application.apm"
+	LabelVersion     = "com.application.apm.version"
+	LabelBuilt       = "com.application.apm.built"
+	LabelConfigHash  = "com.application.apm.config-hash"
+	LabelPackageDiff = "com.application.apm.package-diff"
+	LabelTransaction = "com.application.apm.transaction"
+)
+
+// labelKeyRegex допустимый ключ пользовательской метки, например org.example.branch.
+var labelKeyRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// ImageLabels метаданные apm, прочитанные из меток образа.
+type ImageLabels struct {
+	Version     string `json:"version"`
+	Built       string `json:"built"`
+	ConfigHash  string `json:"configHash"`
+	PackageDiff string `json:"packageDiff"`
+	Transaction string `json:"transaction"`
+}
+
+// buildLabelArgs формирует аргументы --label для podman build.
+// Команда сборки разбивается на аргументы без участия shell, поэтому пробелы в значениях заменяются.
+func buildLabelArgs(labels ImageLabels) string {
+	values := [][2]string{
+		{LabelManaged, "true"},
+		{LabelVersion, labels.Version},
+		{LabelBuilt, labels.Built},
+		{LabelConfigHash, labels.ConfigHash},
+		{LabelPackageDiff, labels.PackageDiff},
+		{LabelTransaction, labels.Transaction},
+	}
+
+	args := make([]string, 0, len(values))
+	for _, value := range values {
+		if value[1] == "" {
+			continue
+		}
+		args = append(args, labelArg(value[0], value[1]))
+	}
+
+	return strings.Join(args, " ")
+}
+
+// extraLabelArgs формирует аргументы --label для меток, заданных только для одной сборки.
+func extraLabelArgs(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, labelArg(key, labels[key]))
+	}
+
+	return strings.Join(args, " ")
+}
+
+// labelArg формирует один аргумент --label, заменяя пробелы в значении.
+func labelArg(key string, value string) string {
+	return fmt.Sprintf("--label %s=%s", key, strings.Join(strings.Fields(value), "_"))
+}
+
+// ValidateLabel проверяет пользовательскую метку образа. Пространство имён com.application.apm
+// зарезервировано за метками, которые apm ставит сам.
+func ValidateLabel(key string, value string) error {
+	if !labelKeyRegex.MatchString(key) {
+		return fmt.Errorf(lib.T_("Invalid label key: %s. Use letters, digits, dots, dashes, underscores and slashes"), key)
+	}
+
+	if key == LabelManaged || strings.HasPrefix(key, LabelManaged+".") {
+		return fmt.Errorf(lib.T_("Label %s is reserved for apm"), key)
+	}
+
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf(lib.T_("The value of label %s must be a single line"), key)
+	}
+
+	return nil
+}
+
+// newImageLabels собирает метаданные для новой сборки образа.
+func newImageLabels(ctx context.Context, configHash string, diff *PackageDiff) ImageLabels {
+	labels := ImageLabels{
+		Version:    lib.Env.Version,
+		Built:      time.Now().Format(time.RFC3339),
+		ConfigHash: configHash,
+	}
+
+	if diff != nil {
+		labels.PackageDiff = diff.Summary()
+	}
+
+	if transaction, ok := ctx.Value("transaction").(string); ok {
+		labels.Transaction = transaction
+	}
+
+	return labels
+}
+
+// ReadImageLabels читает метки apm из локального образа. Для образов, собранных не apm, возвращает nil.
+func ReadImageLabels(ctx context.Context, image string) (*ImageLabels, error) {
+	command := fmt.Sprintf("%s podman image inspect --format '{{json .Labels}}' %s", lib.Env.CommandPrefix, image)
+	output, err := lib.CommandOutput(exec.CommandContext(ctx, "sh", "-c", command))
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Error podman image: %v"), err)
+	}
+
+	var labels map[string]string
+	if err = json.Unmarshal(output, &labels); err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to parse JSON: %v"), err)
+	}
+
+	return imageLabelsFromMap(labels), nil
+}
+
+// imageLabelsFromMap извлекает метаданные apm из меток образа. Для образов, собранных не apm, возвращает nil.
+func imageLabelsFromMap(labels map[string]string) *ImageLabels {
+	if labels[LabelManaged] != "true" {
+		return nil
+	}
+
+	return &ImageLabels{
+		Version:     labels[LabelVersion],
+		Built:       labels[LabelBuilt],
+		ConfigHash:  labels[LabelConfigHash],
+		PackageDiff: labels[LabelPackageDiff],
+		Transaction: labels[LabelTransaction],
+	}
+}
@@ -0,0 +1,105 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest описывает желаемое состояние пакетов работающей системы. В отличие от Config,
+// которая описывает сборку образа, манифест фиксирует точные версии установленных вручную пакетов.
+type Manifest struct {
+	BaseImage string            `yaml:"base-image" json:"baseImage"`
+	Packages  []ManifestPackage `yaml:"packages" json:"packages"`
+	Held      []string          `yaml:"held" json:"held"`
+	Removed   []string          `yaml:"removed" json:"removed"`
+}
+
+// ManifestPackage пакет манифеста с версией, установленной при его создании.
+type ManifestPackage struct {
+	Name    string `yaml:"name" json:"name"`
+	Version string `yaml:"version,omitempty" json:"version"`
+}
+
+// LoadManifest читает и проверяет манифест из файла.
+func LoadManifest(path string) (Manifest, error) {
+	var manifest Manifest
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, fmt.Errorf(lib.T_("Error reading file %s: %v"), path, err)
+	}
+
+	if err = yaml.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf(lib.T_("Error parsing manifest %s: %v"), path, err)
+	}
+
+	if err = manifest.Validate(); err != nil {
+		return manifest, err
+	}
+
+	return manifest, nil
+}
+
+// Validate проверяет, что в манифесте нет пустых имён и пакет не указан одновременно для установки и удаления.
+func (m Manifest) Validate() error {
+	installed := make(map[string]bool, len(m.Packages))
+	for _, pkg := range m.Packages {
+		if strings.TrimSpace(pkg.Name) == "" {
+			return fmt.Errorf(lib.T_("The manifest contains a package without a name"))
+		}
+		installed[pkg.Name] = true
+	}
+
+	for _, name := range m.Removed {
+		if installed[name] {
+			return fmt.Errorf(lib.T_("Package %s is listed in the manifest both for installation and removal"), name)
+		}
+	}
+
+	for _, name := range m.Held {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf(lib.T_("The manifest contains a held package without a name"))
+		}
+	}
+
+	return nil
+}
+
+// Save записывает манифест в файл, пакеты упорядочиваются по имени для воспроизводимости.
+func (m Manifest) Save(path string) error {
+	sort.Slice(m.Packages, func(i, j int) bool { return m.Packages[i].Name < m.Packages[j].Name })
+	sort.Strings(m.Held)
+	sort.Strings(m.Removed)
+
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf(lib.T_("Error writing file %s: %v"), path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const operationsTableName = "operations_history"
+
+// OperationRecord описывает одну запись истории пакетных операций.
+type OperationRecord struct {
+	ID        int64    `json:"id"`
+	Timestamp string   `json:"date"`
+	Operation string   `json:"operation"`
+	Packages  []string `json:"packages"`
+	Success   bool     `json:"success"`
+	Message   string   `json:"message"`
+}
+
+// createOperationsTable создаёт таблицу истории операций, если её ещё нет.
+func (h *HostDBService) createOperationsTable(ctx context.Context) error {
+	createQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp TIMESTAMP,
+		operation TEXT,
+		packages TEXT,
+		success INTEGER,
+		message TEXT
+	)`, operationsTableName)
+
+	if _, err := h.dbConn.ExecContext(ctx, createQuery); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return nil
+}
+
+// SaveOperation сохраняет запись о выполненной операции с пакетами.
+func (h *HostDBService) SaveOperation(ctx context.Context, record OperationRecord) error {
+	if err := h.createOperationsTable(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (timestamp, operation, packages, success, message) VALUES (?, ?, ?, ?, ?)`, operationsTableName)
+	_, err := h.dbConn.ExecContext(ctx, query, time.Now(), record.Operation, strings.Join(record.Packages, ","), record.Success, record.Message)
+	if err != nil {
+		return fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	return nil
+}
+
+// GetOperationsFiltered возвращает записи истории операций (новые первыми),
+// фильтруя по типу операции и применяя limit и offset для пагинации.
+func (h *HostDBService) GetOperationsFiltered(ctx context.Context, operation string, limit int64, offset int64) ([]OperationRecord, error) {
+	if err := h.createOperationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT id, timestamp, operation, packages, success, message FROM %s", operationsTableName)
+	var args []interface{}
+
+	if operation != "" {
+		query += " WHERE operation = ?"
+		args = append(args, operation)
+	}
+
+	query += " ORDER BY timestamp DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := h.dbConn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+	defer rows.Close()
+
+	records := []OperationRecord{}
+	for rows.Next() {
+		var record OperationRecord
+		var timestamp time.Time
+		var packages string
+
+		if err = rows.Scan(&record.ID, &timestamp, &record.Operation, &packages, &record.Success, &record.Message); err != nil {
+			return nil, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+
+		record.Timestamp = timestamp.Format(time.RFC3339)
+		record.Packages = []string{}
+		if packages != "" {
+			record.Packages = strings.Split(packages, ",")
+		}
+
+		records = append(records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(lib.T_("String processing error: %v"), err)
+	}
+
+	return records, nil
+}
+
+// CountOperationsFiltered возвращает общее количество записей истории операций с учётом фильтра.
+func (h *HostDBService) CountOperationsFiltered(ctx context.Context, operation string) (int, error) {
+	if err := h.createOperationsTable(ctx); err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", operationsTableName)
+	var args []interface{}
+
+	if operation != "" {
+		query += " WHERE operation = ?"
+		args = append(args, operation)
+	}
+
+	var count int
+	if err := h.dbConn.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+
+	return count, nil
+}
@@ -35,12 +35,23 @@ import (
 )
 
 func PullAndProgress(ctx context.Context, cmdLine string) (string, error) {
+	return PullAndProgressWithLog(ctx, cmdLine, nil)
+}
+
+// PullAndProgressWithLog выполняет команду как PullAndProgress и дополнительно записывает весь её вывод
+// в logWriter. Команда запускается в псевдотерминале, поэтому stdout и stderr попадают в один поток.
+func PullAndProgressWithLog(ctx context.Context, cmdLine string, logWriter io.Writer) (string, error) {
 	allBlobs := make(map[string]bool)
 
 	parts := strings.Fields(cmdLine)
 	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	// pty.Start запускает команду в новой сессии, поэтому при отмене сигнал получает вся её группа процессов
+	lib.KillGroupOnCancel(cmd)
+	defer lib.TrackCommand()()
+	finish := lib.LogCommand(cmd)
 	ptmx, err := pty.Start(cmd)
 	if err != nil {
+		finish(err)
 		return "", err
 	}
 	defer func() { _ = ptmx.Close() }()
@@ -59,13 +70,17 @@ func PullAndProgress(ctx context.Context, cmdLine string) (string, error) {
 	cmd.Env = env
 
 	var outputBuffer bytes.Buffer
+	var output io.Writer = &outputBuffer
+	if logWriter != nil {
+		output = io.MultiWriter(&outputBuffer, logWriter)
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		// Используем TeeReader для одновременного сканирования и записи в буфер
-		scanner := bufio.NewScanner(io.TeeReader(ptmx, &outputBuffer))
+		scanner := bufio.NewScanner(io.TeeReader(ptmx, output))
 		for scanner.Scan() {
 			line := scanner.Text()
 			parseProgressLine(ctx, line, allBlobs)
@@ -77,6 +92,7 @@ func PullAndProgress(ctx context.Context, cmdLine string) (string, error) {
 
 	// Ждем завершения команды
 	err = cmd.Wait()
+	finish(err)
 	wg.Wait()
 
 	if err != nil {
@@ -87,8 +103,7 @@ func PullAndProgress(ctx context.Context, cmdLine string) (string, error) {
 	for blobKey := range allBlobs {
 		reply.CreateEventNotification(ctx, reply.StateAfter,
 			reply.WithEventName("service.pullImage-"+blobKey),
-			reply.WithProgress(true),
-			reply.WithProgressPercent(100),
+			reply.WithProgress(100),
 		)
 	}
 
@@ -101,9 +116,8 @@ func printProgress(ctx context.Context, keyBlob string, progressPercent float64,
 
 	reply.CreateEventNotification(ctx, reply.StateBefore,
 		reply.WithEventName("service.pullImage-"+keyBlob),
-		reply.WithEventView(speed),
-		reply.WithProgress(true),
-		reply.WithProgressPercent(progressPercent),
+		reply.WithMessage(speed),
+		reply.WithProgress(progressPercent),
 	)
 }
 
@@ -184,14 +198,14 @@ func pruneOldImages(ctx context.Context) error {
 	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("system.pruneOldImages"))
 
 	command := fmt.Sprintf("%s podman image prune -f", lib.Env.CommandPrefix)
-	cmd := exec.Command("sh", "-c", command)
-	if output, err := cmd.CombinedOutput(); err != nil {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if output, err := lib.CommandCombinedOutput(cmd); err != nil {
 		return fmt.Errorf(lib.T_("Error deleting old images: %v, output: %s"), err, string(output))
 	}
 
 	command = fmt.Sprintf("%s podman images --noheading", lib.Env.CommandPrefix)
-	cmd = exec.Command("sh", "-c", command)
-	output, err := cmd.Output()
+	cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	output, err := lib.CommandOutput(cmd)
 	if err != nil {
 		return fmt.Errorf(lib.T_("Error retrieving podman image: %v"), err)
 	}
@@ -206,8 +220,8 @@ func pruneOldImages(ctx context.Context) error {
 		if fields[0] == "<none>" {
 			imageID := fields[2]
 			command = fmt.Sprintf("%s podman rmi -f %s", lib.Env.CommandPrefix, imageID)
-			cmd = exec.Command("sh", "-c", command)
-			if out, err := cmd.CombinedOutput(); err != nil {
+			cmd = exec.CommandContext(ctx, "sh", "-c", command)
+			if out, err := lib.CommandCombinedOutput(cmd); err != nil {
 				return fmt.Errorf(lib.T_("Error deleting image %s: %v, output: %s\n"), imageID, err, string(out))
 			}
 		}
@@ -215,3 +229,40 @@ func pruneOldImages(ctx context.Context) error {
 
 	return nil
 }
+
+// minBuildCachePodmanVersion минимальная версия podman с поддержкой RUN --mount=type=cache.
+var minBuildCachePodmanVersion = [2]int{4, 0}
+
+var (
+	buildCacheOnce      sync.Once
+	buildCacheSupported bool
+)
+
+// SupportsBuildCache проверяет, поддерживает ли установленный podman кеширующие монтирования при сборке.
+// Результат вычисляется один раз за время работы процесса.
+func SupportsBuildCache() bool {
+	buildCacheOnce.Do(func() {
+		command := fmt.Sprintf("%s podman version --format {{.Client.Version}}", lib.Env.CommandPrefix)
+		output, err := lib.CommandOutput(exec.CommandContext(context.Background(), "sh", "-c", command))
+		if err != nil {
+			lib.Log.Debugf("podman version: %v", err)
+			return
+		}
+
+		parts := strings.SplitN(strings.TrimSpace(string(output)), ".", 3)
+		if len(parts) < 2 {
+			return
+		}
+
+		major, errMajor := strconv.Atoi(parts[0])
+		minor, errMinor := strconv.Atoi(parts[1])
+		if errMajor != nil || errMinor != nil {
+			return
+		}
+
+		buildCacheSupported = major > minBuildCachePodmanVersion[0] ||
+			(major == minBuildCachePodmanVersion[0] && minor >= minBuildCachePodmanVersion[1])
+	})
+
+	return buildCacheSupported
+}
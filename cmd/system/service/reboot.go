@@ -0,0 +1,112 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Адрес менеджера systemd-logind на системной шине.
+const (
+	login1Destination = "org.freedesktop.login1"
+	login1Path        = "/org/freedesktop/login1"
+	login1Manager     = "org.freedesktop.login1.Manager"
+)
+
+// ScheduledReboot запланированное через systemd-logind выключение или перезагрузка.
+type ScheduledReboot struct {
+	Type string `json:"type"`
+	At   string `json:"at"`
+}
+
+// login1Object возвращает объект менеджера systemd-logind.
+func login1Object() (dbus.BusObject, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to connect to the system bus: %v"), err)
+	}
+
+	return conn.Object(login1Destination, login1Path), nil
+}
+
+// ScheduleReboot планирует перезагрузку через delay и рассылает пользователям предупреждение wallMessage.
+func ScheduleReboot(delay time.Duration, wallMessage string) (time.Time, error) {
+	obj, err := login1Object()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err = obj.Call(login1Manager+".SetWallMessage", 0, wallMessage, true).Err; err != nil {
+		return time.Time{}, fmt.Errorf(lib.T_("Failed to schedule a reboot: %v"), err)
+	}
+
+	at := time.Now().Add(delay)
+	if err = obj.Call(login1Manager+".ScheduleShutdown", 0, "reboot", uint64(at.UnixMicro())).Err; err != nil {
+		return time.Time{}, fmt.Errorf(lib.T_("Failed to schedule a reboot: %v"), err)
+	}
+
+	return at, nil
+}
+
+// CancelScheduledReboot отменяет запланированную перезагрузку. Возвращает false, если отменять было нечего.
+func CancelScheduledReboot() (bool, error) {
+	obj, err := login1Object()
+	if err != nil {
+		return false, err
+	}
+
+	var cancelled bool
+	if err = obj.Call(login1Manager+".CancelScheduledShutdown", 0).Store(&cancelled); err != nil {
+		return false, fmt.Errorf(lib.T_("Failed to cancel the scheduled reboot: %v"), err)
+	}
+
+	return cancelled, nil
+}
+
+// GetScheduledReboot возвращает запланированное выключение или перезагрузку либо nil, если ничего не запланировано.
+func GetScheduledReboot() (*ScheduledReboot, error) {
+	obj, err := login1Object()
+	if err != nil {
+		return nil, err
+	}
+
+	variant, err := obj.GetProperty(login1Manager + ".ScheduledShutdown")
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to get the scheduled reboot: %v"), err)
+	}
+
+	// Свойство имеет сигнатуру (st): тип выключения и время в микросекундах
+	fields, ok := variant.Value().([]interface{})
+	if !ok || len(fields) != 2 {
+		return nil, nil
+	}
+
+	shutdownType, _ := fields[0].(string)
+	usec, _ := fields[1].(uint64)
+	if shutdownType == "" || usec == 0 {
+		return nil, nil
+	}
+
+	return &ScheduledReboot{
+		Type: shutdownType,
+		At:   time.UnixMicro(int64(usec)).Format(time.RFC3339),
+	}, nil
+}
@@ -0,0 +1,111 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"context"
+	"time"
+)
+
+// RetentionPolicy правила хранения собранных образов и истории образов.
+// Нулевое значение отключает соответствующее правило.
+type RetentionPolicy struct {
+	KeepImages      int `json:"keepImages"`
+	KeepHistoryDays int `json:"keepHistoryDays"`
+}
+
+// GarbageCollectResult результат очистки по правилам хранения.
+type GarbageCollectResult struct {
+	PrunedImages   []string `json:"prunedImages"`
+	FreedBytes     int64    `json:"freedBytes"`
+	RemovedHistory int64    `json:"removedHistory"`
+}
+
+// DefaultRetentionPolicy возвращает правила хранения из конфигурации apm.
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		KeepImages:      lib.Env.KeepImages,
+		KeepHistoryDays: lib.Env.KeepHistoryDays,
+	}
+}
+
+// ProtectedImages возвращает образы, которые нельзя удалять: загруженный, подготовленный к загрузке,
+// закреплённые развёртывания и собранный, но ещё не установленный образ.
+func (h *HostImageService) ProtectedImages(ctx context.Context) ([]string, error) {
+	hostImage, err := h.GetHostImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	protected := []string{hostImage.Status.Booted.Image.ImageDigest}
+	if hostImage.Status.Staged != nil {
+		protected = append(protected, hostImage.Status.Staged.Image.ImageDigest)
+	}
+	if hostImage.Status.Rollback != nil && hostImage.Status.Rollback.Pinned {
+		protected = append(protected, hostImage.Status.Rollback.Image.ImageDigest)
+	}
+	for _, deployment := range hostImage.Status.OtherDeployments {
+		if deployment.Pinned {
+			protected = append(protected, deployment.Image.ImageDigest)
+		}
+	}
+
+	pendingImage, err := h.serviceHostConfig.serviceHostDatabase.GetPendingImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pendingImage != nil {
+		protected = append(protected, pendingImage.ImageID)
+	}
+
+	return protected, nil
+}
+
+// CollectGarbage удаляет образы сверх policy.KeepImages и записи истории старше policy.KeepHistoryDays.
+// Записи истории помечаются и очищаются в одной транзакции до удаления самих образов, отметка снимается
+// с образов, которые удалить не удалось.
+func (h *HostImageService) CollectGarbage(ctx context.Context, policy RetentionPolicy) (GarbageCollectResult, error) {
+	result := GarbageCollectResult{PrunedImages: []string{}}
+
+	var images []podmanImage
+	if policy.KeepImages > 0 {
+		protected, err := h.ProtectedImages(ctx)
+		if err != nil {
+			return result, err
+		}
+
+		images, err = prunableImages(ctx, policy.KeepImages, protected)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	var cutoff time.Time
+	if policy.KeepHistoryDays > 0 {
+		cutoff = time.Now().AddDate(0, 0, -policy.KeepHistoryDays)
+	}
+
+	removedHistory, err := h.serviceHostConfig.serviceHostDatabase.ApplyHistoryRetention(ctx, podmanImageIDs(images), cutoff)
+	if err != nil {
+		return result, err
+	}
+	result.RemovedHistory = removedHistory
+
+	result.PrunedImages, result.FreedBytes, err = h.removeMarkedImages(ctx, images)
+	return result, err
+}
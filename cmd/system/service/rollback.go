@@ -0,0 +1,194 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Развёртывания bootc, на которых находится поколение образа.
+const (
+	DeploymentStaged   = "staged"
+	DeploymentBooted   = "booted"
+	DeploymentRollback = "rollback"
+)
+
+// Способ появления поколения образа.
+const (
+	RollbackTriggerBuild  = "build"
+	RollbackTriggerSwitch = "switch"
+)
+
+// RollbackEntry поколение образа, на которое можно откатиться.
+type RollbackEntry struct {
+	Generation  int    `json:"generation"`
+	Image       string `json:"image"`
+	ImageDigest string `json:"imageDigest"`
+	Timestamp   string `json:"timestamp"`
+	// Deployment развёртывание bootc, пусто для поколений, известных только из истории
+	Deployment string `json:"deployment,omitempty"`
+	// HistoryID номер записи истории, по которому поколение можно передать в image switch --to
+	HistoryID int64  `json:"historyId,omitempty"`
+	Trigger   string `json:"trigger,omitempty"`
+	Summary   string `json:"summary"`
+	IsBooted  bool   `json:"isBooted"`
+	// IsAvailableLocally поколение можно активировать без загрузки образа из сети
+	IsAvailableLocally bool `json:"isAvailableLocally"`
+}
+
+// RollbackList перечисляет поколения образа: развёртывания bootc и установленные образы из истории.
+// Развёртывания идут первыми (подготовленное, загруженное, откат), затем записи истории от новых к старым.
+func (h *HostImageService) RollbackList(ctx context.Context) ([]RollbackEntry, error) {
+	hostImage, err := h.GetHostImage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := listManagedImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	histories, err := h.serviceHostConfig.serviceHostDatabase.GetImageHistoriesFiltered(ctx,
+		ImageHistoryFilter{Status: ImageStatusDeployed}, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []RollbackEntry{}
+	used := make(map[int64]bool)
+
+	deployments := []struct {
+		name   string
+		status *ImageStatus
+	}{
+		{DeploymentStaged, hostImage.Status.Staged},
+		{DeploymentBooted, &hostImage.Status.Booted},
+		{DeploymentRollback, hostImage.Status.Rollback},
+	}
+
+	for _, deployment := range deployments {
+		if deployment.status == nil || deployment.status.Image.ImageDigest == "" {
+			continue
+		}
+
+		// Развёртывание уже лежит на диске, поэтому активируется без сети
+		entry := RollbackEntry{
+			Image:              deployment.status.Image.Image.Image,
+			ImageDigest:        deployment.status.Image.ImageDigest,
+			Timestamp:          deployment.status.Image.Timestamp,
+			Deployment:         deployment.name,
+			IsBooted:           deployment.name == DeploymentBooted,
+			IsAvailableLocally: true,
+		}
+
+		var match func(ImageHistory) bool
+		for _, image := range images {
+			if image.Digest == entry.ImageDigest {
+				match = func(history ImageHistory) bool { return historyMatchesImage(history, image) }
+				break
+			}
+		}
+		if match == nil && deployment.name == DeploymentBooted && hostImage.Labels != nil && hostImage.Labels.ConfigHash != "" {
+			match = func(history ImageHistory) bool { return history.ConfigHash == hostImage.Labels.ConfigHash }
+		}
+
+		if match != nil {
+			for _, history := range histories {
+				if !used[history.ID] && match(history) {
+					used[history.ID] = true
+					applyRollbackHistory(&entry, history)
+					break
+				}
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	for _, history := range histories {
+		if used[history.ID] {
+			continue
+		}
+
+		entry := RollbackEntry{
+			Image:     history.ImageName,
+			Timestamp: history.ImageDate,
+		}
+
+		if history.ImageRemoved == "" {
+			for _, image := range images {
+				if historyMatchesImage(history, image) {
+					entry.ImageDigest = image.Digest
+					entry.IsAvailableLocally = true
+					break
+				}
+			}
+		}
+
+		applyRollbackHistory(&entry, history)
+		entries = append(entries, entry)
+	}
+
+	for i := range entries {
+		entries[i].Generation = i + 1
+	}
+
+	return entries, nil
+}
+
+// historyMatchesImage проверяет, что запись истории относится к локальному образу:
+// по идентификатору образа или по хешу конфигурации из меток apm.
+func historyMatchesImage(history ImageHistory, image podmanImage) bool {
+	if history.ImageID != "" && strings.HasPrefix(image.ID, strings.TrimPrefix(history.ImageID, "sha256:")) {
+		return true
+	}
+
+	labels := imageLabelsFromMap(image.Labels)
+	return history.ConfigHash != "" && labels != nil && labels.ConfigHash == history.ConfigHash
+}
+
+// applyRollbackHistory дополняет поколение сведениями из записи истории.
+func applyRollbackHistory(entry *RollbackEntry, history ImageHistory) {
+	entry.HistoryID = history.ID
+	if entry.Timestamp == "" {
+		entry.Timestamp = history.ImageDate
+	}
+	if entry.Image == "" {
+		entry.Image = history.ImageName
+	}
+
+	entry.Trigger = RollbackTriggerBuild
+	if history.Origin == ImageOriginSwitch {
+		entry.Trigger = RollbackTriggerSwitch
+	}
+
+	entry.Summary = packageDiffSummary(history.PackageDiff)
+}
+
+// packageDiffSummary кратко описывает изменения пакетов поколения.
+func packageDiffSummary(diff *PackageDiff) string {
+	if diff == nil || (len(diff.Installed) == 0 && len(diff.Removed) == 0 && len(diff.Reverted) == 0) {
+		return lib.T_("No package changes")
+	}
+
+	return fmt.Sprintf(lib.T_("Packages installed: %d, removed: %d, reverted: %d"),
+		len(diff.Installed), len(diff.Removed), len(diff.Reverted))
+}
@@ -0,0 +1,198 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"bufio"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые форматы SBOM.
+const (
+	SBOMFormatSPDX      = "spdx-json"
+	SBOMFormatCycloneDX = "cyclonedx-json"
+)
+
+// osReleaseFile файл с описанием дистрибутива, из которого берётся пространство имён PURL.
+const osReleaseFile = "/etc/os-release"
+
+// spdxNoAssertion значение SPDX для неизвестных сведений.
+const spdxNoAssertion = "NOASSERTION"
+
+// spdxIDRegex символы, недопустимые в идентификаторе SPDX.
+var spdxIDRegex = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+
+// SBOMComponent пакет образа в перечне компонентов.
+type SBOMComponent struct {
+	Name    string
+	Version string
+	License string
+	PURL    string
+}
+
+// NewSBOMComponent создаёт компонент с PURL вида pkg:rpm/<distro>/<name>@<version>.
+func NewSBOMComponent(distro string, name string, version string, license string) SBOMComponent {
+	purl := fmt.Sprintf("pkg:rpm/%s/%s", url.PathEscape(distro), url.PathEscape(name))
+	if version != "" {
+		purl += "@" + url.PathEscape(version)
+	}
+
+	return SBOMComponent{
+		Name:    name,
+		Version: version,
+		License: license,
+		PURL:    purl,
+	}
+}
+
+// DistroID возвращает идентификатор дистрибутива из /etc/os-release.
+func DistroID() string {
+	file, err := os.Open(osReleaseFile)
+	if err != nil {
+		lib.Log.Debug(err.Error())
+		return "linux"
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, found := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "ID="); found {
+			if id := strings.Trim(value, `"'`); id != "" {
+				return id
+			}
+		}
+	}
+
+	return "linux"
+}
+
+// GenerateSBOM сериализует компоненты образа imageName в формате SPDX 2.3 или CycloneDX 1.4.
+func GenerateSBOM(format string, imageName string, components []SBOMComponent) ([]byte, error) {
+	var document interface{}
+	switch format {
+	case SBOMFormatSPDX:
+		document = spdxDocument(imageName, components)
+	case SBOMFormatCycloneDX:
+		document = cycloneDXDocument(imageName, components)
+	default:
+		return nil, fmt.Errorf(lib.T_("Unknown SBOM format %s. Available formats: %s, %s"), format, SBOMFormatSPDX, SBOMFormatCycloneDX)
+	}
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Error serializing SBOM: %v"), err)
+	}
+
+	return data, nil
+}
+
+// spdxDocument формирует документ SPDX 2.3.
+func spdxDocument(imageName string, components []SBOMComponent) map[string]interface{} {
+	packages := make([]map[string]interface{}, 0, len(components))
+	relationships := make([]map[string]string, 0, len(components))
+	for _, component := range components {
+		license := component.License
+		if license == "" {
+			license = spdxNoAssertion
+		}
+
+		id := "SPDXRef-Package-" + spdxIDRegex.ReplaceAllString(component.Name, "-")
+		packages = append(packages, map[string]interface{}{
+			"SPDXID":           id,
+			"name":             component.Name,
+			"versionInfo":      component.Version,
+			"downloadLocation": spdxNoAssertion,
+			"licenseConcluded": spdxNoAssertion,
+			"licenseDeclared":  license,
+			"copyrightText":    spdxNoAssertion,
+			"externalRefs": []map[string]string{{
+				"referenceCategory": "PACKAGE-MANAGER",
+				"referenceType":     "purl",
+				"referenceLocator":  component.PURL,
+			}},
+		})
+		relationships = append(relationships, map[string]string{
+			"spdxElementId":      "SPDXRef-DOCUMENT",
+			"relationshipType":   "DESCRIBES",
+			"relatedSpdxElement": id,
+		})
+	}
+
+	return map[string]interface{}{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              imageName,
+		"documentNamespace": fmt.Sprintf("https://spdx.org/spdxdocs/apm-%s", newUUID()),
+		"creationInfo": map[string]interface{}{
+			"created":  time.Now().UTC().Format(time.RFC3339),
+			"creators": []string{"Tool: apm-" + lib.Env.Version},
+		},
+		"packages":      packages,
+		"relationships": relationships,
+	}
+}
+
+// cycloneDXDocument формирует документ CycloneDX 1.4.
+func cycloneDXDocument(imageName string, components []SBOMComponent) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(components))
+	for _, component := range components {
+		item := map[string]interface{}{
+			"type":    "library",
+			"bom-ref": component.PURL,
+			"name":    component.Name,
+			"version": component.Version,
+			"purl":    component.PURL,
+		}
+		// Лицензии rpm не всегда являются выражениями SPDX, поэтому передаются как название
+		if component.License != "" {
+			item["licenses"] = []map[string]interface{}{{"license": map[string]string{"name": component.License}}}
+		}
+		items = append(items, item)
+	}
+
+	return map[string]interface{}{
+		"bomFormat":    "CycloneDX",
+		"specVersion":  "1.4",
+		"serialNumber": "urn:uuid:" + newUUID(),
+		"version":      1,
+		"metadata": map[string]interface{}{
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"tools":     []map[string]string{{"name": "apm", "version": lib.Env.Version}},
+			"component": map[string]string{"type": "container", "name": imageName},
+		},
+		"components": items,
+	}
+}
+
+// newUUID возвращает случайный UUID версии 4.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
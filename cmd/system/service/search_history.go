@@ -0,0 +1,123 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const searchHistoryTableName = "search_history"
+
+// SearchHistoryEntry описывает один поисковый запрос из истории.
+type SearchHistoryEntry struct {
+	Query       string `json:"query"`
+	Timestamp   string `json:"date"`
+	ResultCount int    `json:"resultCount"`
+}
+
+// SearchHistoryService хранит историю поисковых запросов пользователя.
+type SearchHistoryService struct {
+	dbConn *sql.DB
+}
+
+// NewSearchHistoryService — конструктор сервиса истории поиска.
+func NewSearchHistoryService(db *sql.DB) *SearchHistoryService {
+	return &SearchHistoryService{
+		dbConn: db,
+	}
+}
+
+// createSearchHistoryTable создаёт таблицу истории поиска, если её ещё нет.
+func (s *SearchHistoryService) createSearchHistoryTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		query TEXT,
+		timestamp INTEGER,
+		result_count INTEGER
+	)`, searchHistoryTableName)
+
+	if _, err := s.dbConn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf(lib.T_("Error creating table: %w"), err)
+	}
+
+	return nil
+}
+
+// SaveSearch сохраняет поисковый запрос и количество найденных пакетов.
+func (s *SearchHistoryService) SaveSearch(ctx context.Context, searchQuery string, resultCount int) error {
+	if err := s.createSearchHistoryTable(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (query, timestamp, result_count) VALUES (?, ?, ?)", searchHistoryTableName)
+	if _, err := s.dbConn.ExecContext(ctx, query, searchQuery, time.Now().Unix(), resultCount); err != nil {
+		return fmt.Errorf(lib.T_("Error inserting data: %v"), err)
+	}
+
+	return nil
+}
+
+// GetSearchHistory возвращает последние уникальные запросы, новые первыми.
+// Для повторявшихся запросов берётся самая свежая запись.
+func (s *SearchHistoryService) GetSearchHistory(ctx context.Context, limit int) ([]SearchHistoryEntry, error) {
+	if err := s.createSearchHistoryTable(ctx); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT query, MAX(timestamp) AS last_used, result_count FROM %s
+		GROUP BY query ORDER BY last_used DESC LIMIT ?`, searchHistoryTableName)
+
+	rows, err := s.dbConn.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+	defer rows.Close()
+
+	entries := make([]SearchHistoryEntry, 0)
+	for rows.Next() {
+		var entry SearchHistoryEntry
+		var timestamp int64
+		if err = rows.Scan(&entry.Query, &timestamp, &entry.ResultCount); err != nil {
+			return nil, fmt.Errorf(lib.T_("Data reading error: %v"), err)
+		}
+		entry.Timestamp = time.Unix(timestamp, 0).Format(time.RFC3339)
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf(lib.T_("Query execution error: %v"), err)
+	}
+
+	return entries, nil
+}
+
+// ClearSearchHistory удаляет всю историю поиска.
+func (s *SearchHistoryService) ClearSearchHistory(ctx context.Context) error {
+	if err := s.createSearchHistoryTable(ctx); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s", searchHistoryTableName)
+	if _, err := s.dbConn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf(lib.T_("Table cleanup error: %w"), err)
+	}
+
+	return nil
+}
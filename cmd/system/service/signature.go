@@ -0,0 +1,198 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SignaturePolicyFile путь к политике проверки подписей контейнерных образов.
+var SignaturePolicyFile = "/etc/containers/policy.json"
+
+// Результаты проверки подписи базового образа.
+const (
+	// SignatureSigned политика требует подпись, podman проверяет её при загрузке образа.
+	SignatureSigned = "signed"
+	// SignatureUnsigned политика принимает образ без подписи.
+	SignatureUnsigned = "unsigned"
+	// SignatureRejected политика запрещает использование образа.
+	SignatureRejected = "rejected"
+)
+
+// Коды ошибок проверки подписи.
+const (
+	ErrUnsignedBaseImage = iota + 1
+	ErrBaseImageRejected
+)
+
+// SignatureStatus описывает результат проверки подписи базового образа.
+type SignatureStatus struct {
+	Image    string   `json:"image"`
+	Status   string   `json:"status"`
+	SignedBy []string `json:"signedBy"`
+	Scope    string   `json:"scope"`
+}
+
+// SignatureError ошибка сборки из-за непройденной проверки подписи базового образа.
+type SignatureError struct {
+	Code   int
+	Status SignatureStatus
+}
+
+func (e *SignatureError) Error() string {
+	if e.Code == ErrBaseImageRejected {
+		return fmt.Sprintf(lib.T_("Base image %s is rejected by the signature policy %s"), e.Status.Image, SignaturePolicyFile)
+	}
+
+	return fmt.Sprintf(lib.T_("Base image %s is not signed, but requireSignedBase is enabled. Configure a signature requirement in %s or use --insecure-allow-unsigned"),
+		e.Status.Image, SignaturePolicyFile)
+}
+
+// IsSignatureError проверяет, является ли ошибка ошибкой проверки подписи, и возвращает её.
+func IsSignatureError(err error) (*SignatureError, bool) {
+	var signatureErr *SignatureError
+	ok := errors.As(err, &signatureErr)
+	return signatureErr, ok
+}
+
+// policyRequirement требование из политики подписей.
+type policyRequirement struct {
+	Type     string   `json:"type"`
+	KeyType  string   `json:"keyType"`
+	KeyPath  string   `json:"keyPath"`
+	KeyPaths []string `json:"keyPaths"`
+	KeyData  string   `json:"keyData"`
+	Fulcio   *struct {
+		SubjectEmail string `json:"subjectEmail"`
+	} `json:"fulcio"`
+}
+
+// signaturePolicy структура файла policy.json.
+type signaturePolicy struct {
+	Default    []policyRequirement                       `json:"default"`
+	Transports map[string]map[string][]policyRequirement `json:"transports"`
+}
+
+// VerifyBaseImageSignature определяет по политике подписей, какая проверка применяется к базовому образу.
+// Сама проверка подписи выполняется podman при загрузке образа согласно этой политике.
+func VerifyBaseImageSignature(image string) (SignatureStatus, error) {
+	status := SignatureStatus{Image: image, SignedBy: []string{}}
+
+	data, err := os.ReadFile(SignaturePolicyFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			status.Status = SignatureUnsigned
+			return status, nil
+		}
+		return status, fmt.Errorf(lib.T_("Failed to open file %s: %w"), SignaturePolicyFile, err)
+	}
+
+	var policy signaturePolicy
+	if err = json.Unmarshal(data, &policy); err != nil {
+		return status, fmt.Errorf(lib.T_("Failed to parse JSON: %v"), err)
+	}
+
+	requirements, scope := policy.requirementsFor(image)
+	status.Scope = scope
+
+	status.Status = SignatureUnsigned
+	for _, requirement := range requirements {
+		switch requirement.Type {
+		case "reject":
+			status.Status = SignatureRejected
+			status.SignedBy = []string{}
+			return status, nil
+		case "signedBy", "sigstoreSigned":
+			status.Status = SignatureSigned
+			status.SignedBy = append(status.SignedBy, requirement.identity())
+		}
+	}
+
+	return status, nil
+}
+
+// requirementsFor ищет наиболее точную область политики для образа из реестра.
+func (p signaturePolicy) requirementsFor(image string) ([]policyRequirement, string) {
+	reference := strings.TrimPrefix(image, "docker://")
+	scopes := p.Transports["docker"]
+
+	for _, scope := range dockerScopes(reference) {
+		if requirements, ok := scopes[scope]; ok {
+			return requirements, scope
+		}
+	}
+
+	if requirements, ok := scopes[""]; ok {
+		return requirements, "docker"
+	}
+
+	return p.Default, "default"
+}
+
+// dockerScopes возвращает области политики от самой точной к самой общей:
+// полная ссылка, репозиторий, родительские пространства имён, реестр и шаблоны поддоменов.
+func dockerScopes(reference string) []string {
+	scopes := []string{reference}
+
+	repository := reference
+	if i := strings.Index(repository, "@"); i >= 0 {
+		repository = repository[:i]
+	}
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+
+	for path := repository; path != ""; {
+		if path != reference {
+			scopes = append(scopes, path)
+		}
+		i := strings.LastIndex(path, "/")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+
+	host := strings.SplitN(repository, "/", 2)[0]
+	labels := strings.Split(host, ".")
+	for i := 1; i < len(labels); i++ {
+		scopes = append(scopes, "*."+strings.Join(labels[i:], "."))
+	}
+
+	return scopes
+}
+
+// identity возвращает описание ключа или личности подписанта.
+func (r policyRequirement) identity() string {
+	switch {
+	case r.KeyPath != "":
+		return r.KeyPath
+	case len(r.KeyPaths) > 0:
+		return strings.Join(r.KeyPaths, ", ")
+	case r.Fulcio != nil && r.Fulcio.SubjectEmail != "":
+		return r.Fulcio.SubjectEmail
+	case r.KeyData != "":
+		return lib.T_("embedded key")
+	}
+
+	return r.Type
+}
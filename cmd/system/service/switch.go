@@ -0,0 +1,207 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package service
+
+import (
+	"apm/lib"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SwitchTo переключает хост на ранее собранный apm образ без повторной сборки.
+// reference — тег, дайджест, идентификатор образа или номер записи истории.
+// Переключение записывается в историю как ручное.
+func (h *HostImageService) SwitchTo(ctx context.Context, reference string) (ImageHistory, error) {
+	reference = strings.TrimSpace(reference)
+	if reference == "" {
+		return ImageHistory{}, fmt.Errorf(lib.T_("Image reference must be specified"))
+	}
+
+	images, err := listManagedImages(ctx)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+
+	image, err := h.resolveImageReference(ctx, images, reference)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+
+	if err = h.SwitchImage(ctx, image.ID); err != nil {
+		return ImageHistory{}, err
+	}
+
+	return h.saveSwitchHistory(ctx, image)
+}
+
+// resolveImageReference находит среди локальных образов apm образ по тегу, дайджесту,
+// идентификатору или номеру записи истории. Если образа больше нет, в ошибке предлагается ближайший доступный.
+func (h *HostImageService) resolveImageReference(ctx context.Context, images []podmanImage, reference string) (podmanImage, error) {
+	var created time.Time
+
+	// Число считается номером записи истории, только если такая запись существует
+	if id, err := strconv.ParseInt(reference, 10, 64); err == nil {
+		history, err := h.serviceHostConfig.serviceHostDatabase.GetImageHistoryByID(ctx, id)
+		if err == nil {
+			if history.ImageID == "" && history.ConfigHash == "" {
+				return podmanImage{}, fmt.Errorf(lib.T_("History entry %d does not reference a built image"), id)
+			}
+
+			for _, image := range images {
+				if historyMatchesImage(history, image) {
+					return image, nil
+				}
+			}
+
+			created, _ = time.Parse(time.RFC3339, history.ImageDate)
+			return podmanImage{}, imageNotFoundError(images, reference, created)
+		}
+	}
+
+	var matches []podmanImage
+	for _, image := range images {
+		if imageMatchesReference(image, reference) {
+			matches = append(matches, image)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return podmanImage{}, imageNotFoundError(images, reference, created)
+	case 1:
+		return matches[0], nil
+	default:
+		return podmanImage{}, fmt.Errorf(lib.T_("Image reference %s is ambiguous, specify a longer identifier"), reference)
+	}
+}
+
+// imageMatchesReference проверяет, соответствует ли образ тегу, дайджесту или префиксу идентификатора.
+func imageMatchesReference(image podmanImage, reference string) bool {
+	if image.Digest != "" && (image.Digest == reference || strings.TrimPrefix(image.Digest, "sha256:") == reference) {
+		return true
+	}
+
+	id := strings.TrimPrefix(reference, "sha256:")
+	if len(id) >= 4 && strings.HasPrefix(image.ID, id) {
+		return true
+	}
+
+	candidates := []string{reference, "localhost/" + reference}
+	if !strings.Contains(reference[strings.LastIndex(reference, "/")+1:], ":") {
+		candidates = append(candidates, reference+":latest", "localhost/"+reference+":latest")
+	}
+
+	for _, name := range image.Names {
+		for _, candidate := range candidates {
+			if name == candidate {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// imageNotFoundError формирует ошибку об отсутствующем образе с подсказкой ближайшего доступного.
+// Ближайшим считается образ, созданный ближе всего ко времени created, а без него — самый новый.
+func imageNotFoundError(images []podmanImage, reference string, created time.Time) error {
+	if len(images) == 0 {
+		return fmt.Errorf(lib.T_("Image %s is not available locally and there are no other images built by apm"), reference)
+	}
+
+	closest := images[0]
+	if !created.IsZero() {
+		distance := func(image podmanImage) int64 {
+			delta := image.Created - created.Unix()
+			if delta < 0 {
+				return -delta
+			}
+			return delta
+		}
+
+		for _, image := range images[1:] {
+			if distance(image) < distance(closest) {
+				closest = image
+			}
+		}
+	}
+
+	return fmt.Errorf(lib.T_("Image %s is not available locally. Closest available image: %s"), reference, podmanImageName(closest))
+}
+
+// podmanImageName возвращает имя образа для вывода, а для безымянного — короткий идентификатор.
+func podmanImageName(image podmanImage) string {
+	if len(image.Names) > 0 {
+		return image.Names[0]
+	}
+
+	if len(image.ID) > 12 {
+		return image.ID[:12]
+	}
+
+	return image.ID
+}
+
+// saveSwitchHistory добавляет в историю запись о ручном переключении на образ.
+// Конфигурация берётся из записи истории сборки с тем же хешем конфигурации из меток образа.
+func (h *HostImageService) saveSwitchHistory(ctx context.Context, image podmanImage) (ImageHistory, error) {
+	database := h.serviceHostConfig.serviceHostDatabase
+
+	history := ImageHistory{
+		ImageID:   image.ID,
+		Status:    ImageStatusDeployed,
+		Origin:    ImageOriginSwitch,
+		Config:    &Config{},
+		ImageDate: time.Now().Format(time.RFC3339),
+	}
+
+	if labels := imageLabelsFromMap(image.Labels); labels != nil && labels.ConfigHash != "" {
+		history.ConfigHash = labels.ConfigHash
+
+		source, err := database.GetImageHistoryByConfigHash(ctx, labels.ConfigHash)
+		if err != nil {
+			return ImageHistory{}, err
+		}
+		if source != nil && source.Config != nil {
+			history.Config = source.Config
+		}
+	}
+	history.ImageName = history.Config.Image
+
+	previousConfig, err := database.GetLatestConfig(ctx)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+	history.PackageDiff = NewPackageDiff(previousConfig, history.Config)
+
+	if err = database.SaveImageToDB(ctx, history); err != nil {
+		return ImageHistory{}, err
+	}
+
+	saved, err := database.GetImageHistoriesFiltered(ctx, ImageHistoryFilter{}, 1, 0)
+	if err != nil {
+		return ImageHistory{}, err
+	}
+	if len(saved) == 0 {
+		return ImageHistory{}, fmt.Errorf(lib.T_("History not found"))
+	}
+
+	return saved[0], nil
+}
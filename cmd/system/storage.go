@@ -0,0 +1,44 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package system
+
+import (
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"fmt"
+)
+
+// DBPath сообщает, какие базы данных использует apm и почему выбраны именно они.
+func (a *Actions) DBPath(ctx context.Context) (*reply.APIResponse, error) {
+	storage := lib.GetStorageInfo()
+
+	message := fmt.Sprintf(lib.T_("System-wide databases are used: %s"), storage.PathDBSQL)
+	if storage.Scope == lib.StorageScopeUser {
+		message = fmt.Sprintf(lib.T_("User databases are used: %s"), storage.PathDBSQL)
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": message,
+			"storage": storage,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
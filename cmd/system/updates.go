@@ -0,0 +1,287 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package system
+
+import (
+	"apm/cmd/common/reply"
+	"apm/cmd/system/apt"
+	"apm/lib"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	// availableUpdatesKey ключ в KV-хранилище с результатом последней проверки обновлений
+	availableUpdatesKey = "system:availableUpdates"
+
+	defaultUpdateCheckInterval = 6 * time.Hour
+	maxUpdateCheckInterval     = 24 * time.Hour
+
+	// Первая проверка выполняется вскоре после запуска, случайная задержка разносит проверки машин,
+	// запущенных одновременно
+	initialUpdateCheckDelay  = time.Minute
+	initialUpdateCheckJitter = 4 * time.Minute
+)
+
+// AvailableUpdates результат проверки наличия обновлений.
+type AvailableUpdates struct {
+	ImageUpdate   bool     `json:"imageUpdate"`
+	PackagesCount int      `json:"packagesCount"`
+	Packages      []string `json:"packages"`
+	CheckedAt     string   `json:"checkedAt"`
+}
+
+// UpdateChecker периодически проверяет наличие обновлений образа и пакетов. Ничего не применяет.
+type UpdateChecker struct {
+	actions  *Actions
+	interval time.Duration
+	failures int
+}
+
+// NewUpdateChecker создаёт планировщик проверки обновлений с интервалом из конфигурации.
+func NewUpdateChecker(a *Actions) *UpdateChecker {
+	interval := defaultUpdateCheckInterval
+	if lib.Env.UpdateCheckInterval > 0 {
+		interval = time.Duration(lib.Env.UpdateCheckInterval) * time.Minute
+	}
+
+	return &UpdateChecker{
+		actions:  a,
+		interval: interval,
+	}
+}
+
+// Run запускает цикл проверки до отмены контекста.
+func (u *UpdateChecker) Run(ctx context.Context) {
+	if !lib.IsAtomic() && !hasConfiguredRepositories() {
+		lib.Log.Info(lib.T_("No repositories configured, periodic update check is disabled"))
+		return
+	}
+
+	timer := time.NewTimer(initialUpdateCheckDelay + rand.N(initialUpdateCheckJitter))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if isNetworkMetered() {
+			lib.Log.Info(lib.T_("Metered or limited network connection, update check postponed"))
+			u.failures++
+		} else if _, err := u.actions.CheckAvailableUpdates(ctx); err != nil {
+			lib.Log.Error(err.Error())
+			u.failures++
+		} else {
+			u.failures = 0
+		}
+
+		timer.Reset(u.nextInterval())
+	}
+}
+
+// nextInterval возвращает интервал до следующей проверки.
+func (u *UpdateChecker) nextInterval() time.Duration {
+	return UpdateCheckBackoff(u.interval, u.failures)
+}
+
+// UpdateCheckBackoff возвращает интервал до следующей проверки, удваивая interval после каждой из failures
+// неудач подряд. Интервал не превышает суток.
+func UpdateCheckBackoff(interval time.Duration, failures int) time.Duration {
+	for i := 0; i < failures && interval < maxUpdateCheckInterval; i++ {
+		interval *= 2
+	}
+
+	if interval > maxUpdateCheckInterval {
+		return maxUpdateCheckInterval
+	}
+
+	return interval
+}
+
+// CheckAvailableUpdates проверяет обновления базового образа и пакетов, сохраняет результат и отправляет сигнал.
+func (a *Actions) CheckAvailableUpdates(ctx context.Context) (AvailableUpdates, error) {
+	reply.CreateEventNotification(ctx, reply.StateBefore, reply.WithEventName("system.CheckAvailableUpdates"))
+	defer reply.CreateEventNotification(ctx, reply.StateAfter, reply.WithEventName("system.CheckAvailableUpdates"))
+
+	updates := AvailableUpdates{}
+
+	if lib.IsAtomic() {
+		imageUpdate, err := a.serviceHostImage.CheckBaseImageUpdate(ctx)
+		if err != nil {
+			return updates, err
+		}
+		updates.ImageUpdate = imageUpdate
+	}
+
+	// Для симуляции достаточно свежих списков пакетов, база пакетов apm не перестраивается
+	err := a.serviceAptActions.UpdateLists(ctx)
+	if err != nil {
+		return updates, err
+	}
+
+	packageParse, aptErrors := a.serviceAptActions.Check(ctx, "", "dist-upgrade")
+	criticalError := apt.FindCriticalError(aptErrors)
+	if criticalError != nil {
+		return updates, criticalError
+	}
+
+	updates.Packages = packageParse.UpgradedPackages
+	updates.PackagesCount = packageParse.UpgradedCount
+	updates.CheckedAt = time.Now().Format(time.RFC3339)
+
+	data, err := json.Marshal(updates)
+	if err != nil {
+		return updates, err
+	}
+
+	if err = lib.GetDBKv().Put([]byte(availableUpdatesKey), data); err != nil {
+		return updates, fmt.Errorf(lib.T_("Error saving update check result: %v"), err)
+	}
+
+	sendUpdatesAvailable(ctx, updates)
+
+	return updates, nil
+}
+
+// GetAvailableUpdates возвращает результат последней проверки обновлений. До первой проверки возвращается
+// пустой результат без checkedAt.
+func (a *Actions) GetAvailableUpdates(ctx context.Context) (*reply.APIResponse, error) {
+	data, err := lib.GetDBKv().Get([]byte(availableUpdatesKey))
+	if err != nil {
+		return nil, fmt.Errorf(lib.T_("Error reading update check result: %v"), err)
+	}
+
+	if len(data) == 0 {
+		resp := reply.APIResponse{
+			Data: map[string]interface{}{
+				"message": lib.T_("The update check has not been performed yet"),
+				"updates": AvailableUpdates{Packages: []string{}},
+			},
+			Error: false,
+		}
+
+		return &resp, nil
+	}
+
+	var updates AvailableUpdates
+	if err = json.Unmarshal(data, &updates); err != nil {
+		return nil, fmt.Errorf(lib.T_("Failed to parse JSON: %v"), err)
+	}
+
+	resp := reply.APIResponse{
+		Data: map[string]interface{}{
+			"message": lib.T_("Available updates"),
+			"updates": updates,
+		},
+		Error: false,
+	}
+
+	return &resp, nil
+}
+
+// sendUpdatesAvailable отправляет сигнал UpdatesAvailable, если обновления найдены. Сигнал содержит
+// транзакцию вызова, выполнившего проверку, у периодической проверки она пустая.
+func sendUpdatesAvailable(ctx context.Context, updates AvailableUpdates) {
+	if lib.DBUSConn == nil || (!updates.ImageUpdate && updates.PackagesCount == 0) {
+		return
+	}
+
+	objPath := dbus.ObjectPath("/com/application/APM")
+	signalName := "com.application.APM.UpdatesAvailable"
+
+	err := lib.DBUSConn.Emit(objPath, signalName, updates.ImageUpdate, int64(updates.PackagesCount), reply.TransactionFromContext(ctx))
+	if err != nil {
+		lib.Log.Errorf(lib.T_("Error sending notification: %v"), err)
+	}
+}
+
+// isNetworkMetered проверяет через NetworkManager, является ли соединение лимитным или неполным.
+func isNetworkMetered() bool {
+	if lib.DBUSConn == nil {
+		return false
+	}
+
+	nm := lib.DBUSConn.Object("org.freedesktop.NetworkManager", "/org/freedesktop/NetworkManager")
+
+	// Недоступное свойство считается неизвестным значением, равным нулю
+	var metered, connectivity uint32
+	if property, err := nm.GetProperty("org.freedesktop.NetworkManager.Metered"); err == nil {
+		metered, _ = property.Value().(uint32)
+	}
+	if property, err := nm.GetProperty("org.freedesktop.NetworkManager.Connectivity"); err == nil {
+		connectivity, _ = property.Value().(uint32)
+	}
+
+	return NetworkMetered(metered, connectivity)
+}
+
+// NetworkMetered проверяет по значениям свойств NetworkManager Metered и Connectivity, является ли
+// соединение лимитным или неполным.
+func NetworkMetered(metered uint32, connectivity uint32) bool {
+	// NM_METERED_YES = 1, NM_METERED_GUESS_YES = 3
+	if metered == 1 || metered == 3 {
+		return true
+	}
+
+	// NM_CONNECTIVITY_FULL = 4, NM_CONNECTIVITY_UNKNOWN = 0
+	return connectivity != 0 && connectivity != 4
+}
+
+// hasConfiguredRepositories проверяет, подключён ли хотя бы один репозиторий apt.
+func hasConfiguredRepositories() bool {
+	files := []string{"/etc/apt/sources.list"}
+	if listFiles, err := filepath.Glob("/etc/apt/sources.list.d/*.list"); err == nil {
+		files = append(files, listFiles...)
+	}
+
+	return RepositoriesConfigured(files)
+}
+
+// RepositoriesConfigured проверяет, есть ли в файлах источников apt хотя бы одна строка rpm.
+// Отсутствующие файлы пропускаются.
+func RepositoriesConfigured(files []string) bool {
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "rpm") {
+				_ = file.Close()
+				return true
+			}
+		}
+		_ = file.Close()
+	}
+
+	return false
+}
@@ -0,0 +1,148 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Значения параметра atomic конфигурации.
+const (
+	// AtomicAuto тип системы определяется при запуске
+	AtomicAuto = "auto"
+	// AtomicForceOn система считается атомарной независимо от проверок
+	AtomicForceOn = "true"
+	// AtomicForceOff система считается обычной независимо от проверок
+	AtomicForceOff = "false"
+)
+
+// Пути, по которым определяется атомарная система. Переменные, чтобы тесты могли подменить их.
+var (
+	atomicBootcPath    = "/usr/bin/bootc"
+	atomicOstreeMarker = "/run/ostree-booted"
+	atomicUsrPath      = "/usr"
+)
+
+// AtomicDetection результат определения типа системы и признаки, по которым он получен.
+type AtomicDetection struct {
+	IsAtomic bool     `json:"isAtomic"`
+	Source   string   `json:"source"`
+	Evidence []string `json:"evidence"`
+}
+
+var atomicDetection AtomicDetection
+
+// IsAtomic сообщает, что apm работает на атомарной системе bootc. Все проверки типа системы должны
+// использовать этот метод, чтобы значение параметра atomic конфигурации учитывалось одинаково.
+func IsAtomic() bool {
+	return atomicDetection.IsAtomic
+}
+
+// GetAtomicDetection возвращает результат определения типа системы для диагностики. Если тип задан
+// параметром atomic, проверки при запуске не выполнялись, и признаки собираются при вызове.
+func GetAtomicDetection() AtomicDetection {
+	detection := atomicDetection
+	if detection.Source == "config" && detection.Evidence == nil {
+		detection.Evidence = DetectAtomic().Evidence
+	}
+
+	return detection
+}
+
+// SetAtomicDetection заменяет результат определения типа системы.
+func SetAtomicDetection(detection AtomicDetection) {
+	atomicDetection = detection
+}
+
+// ResolveAtomic определяет тип системы с учётом параметра atomic конфигурации: true и false задают тип
+// явно без проверок, иначе он определяется проверками DetectAtomic.
+func ResolveAtomic(override string) AtomicDetection {
+	switch override {
+	case AtomicForceOn, AtomicForceOff:
+		return AtomicDetection{IsAtomic: override == AtomicForceOn, Source: "config"}
+	}
+
+	return DetectAtomic()
+}
+
+// DetectAtomic определяет атомарную систему по признакам загрузки из развёртывания ostree. Наличие
+// /usr/bin/bootc не считается достаточным: bootc можно установить и на обычную систему. Если bootc
+// установлен, а маркера загрузки ostree нет, решение принимается по выводу bootc status.
+func DetectAtomic() AtomicDetection {
+	detection := AtomicDetection{Source: "detected", Evidence: []string{}}
+
+	_, err := os.Stat(atomicBootcPath)
+	hasBootc := err == nil
+	if hasBootc {
+		detection.Evidence = append(detection.Evidence, T_("bootc is installed"))
+	}
+
+	_, err = os.Stat(atomicOstreeMarker)
+	ostreeBooted := err == nil
+	if ostreeBooted {
+		detection.Evidence = append(detection.Evidence, T_("The system is booted from an ostree deployment"))
+	}
+
+	var stat unix.Statfs_t
+	if err = unix.Statfs(atomicUsrPath, &stat); err == nil && stat.Flags&unix.ST_RDONLY != 0 {
+		detection.Evidence = append(detection.Evidence, T_("/usr is mounted read-only"))
+	}
+
+	switch {
+	case ostreeBooted:
+		detection.IsAtomic = true
+	case hasBootc:
+		detection.IsAtomic = bootcHasBootedImage()
+		if detection.IsAtomic {
+			detection.Evidence = append(detection.Evidence, T_("bootc status reports a booted image"))
+		} else {
+			detection.Evidence = append(detection.Evidence, T_("bootc status reports no booted image"))
+		}
+	}
+
+	return detection
+}
+
+// bootcHasBootedImage сообщает, что bootc status выводит загруженный образ. Команда выполняется при
+// запуске apm, до настройки журнала, поэтому вызывается без записи в журнал и с ограничением времени.
+func bootcHasBootedImage() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, atomicBootcPath, "status", "--format", "json").Output()
+	if err != nil {
+		return false
+	}
+
+	var status struct {
+		Status struct {
+			Booted json.RawMessage `json:"booted"`
+		} `json:"status"`
+	}
+	if err = json.Unmarshal(output, &status); err != nil {
+		return false
+	}
+
+	booted := string(status.Status.Booted)
+	return booted != "" && booted != "null"
+}
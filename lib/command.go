@@ -0,0 +1,169 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lib
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// CommandWaitDelay время, которое команде даётся на завершение после SIGTERM при отмене контекста.
+// По его истечении её группа процессов получает SIGKILL.
+const CommandWaitDelay = 5 * time.Second
+
+// runningCommands внешние команды, которые ещё выполняются. При остановке apm ожидает их завершения.
+var runningCommands sync.WaitGroup
+
+// prepareCommand запускает команду в собственной группе процессов, чтобы при отмене контекста сигнал получили
+// и дочерние процессы обёрток sudo и sh. Команды без контекста и с вводом из терминала остаются в группе apm:
+// отменять их нечем, а из фоновой группы они не смогли бы читать терминал.
+func prepareCommand(cmd *exec.Cmd) {
+	// exec.CommandContext задаёт Cancel, у exec.Command он пуст
+	if cmd.Cancel == nil || cmd.Stdin != nil {
+		return
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	if !cmd.SysProcAttr.Setsid {
+		cmd.SysProcAttr.Setpgid = true
+	}
+	KillGroupOnCancel(cmd)
+}
+
+// KillGroupOnCancel при отмене контекста команды отправляет SIGTERM всей её группе процессов, а если группа
+// не завершилась за CommandWaitDelay - SIGKILL. Команда должна быть лидером группы: запускаться с Setpgid
+// или в новой сессии, как в pty.Start.
+func KillGroupOnCancel(cmd *exec.Cmd) {
+	cmd.WaitDelay = CommandWaitDelay
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		time.AfterFunc(CommandWaitDelay, func() {
+			_ = syscall.Kill(-pgid, syscall.SIGKILL)
+		})
+
+		return syscall.Kill(-pgid, syscall.SIGTERM)
+	}
+}
+
+// TrackCommand отмечает запущенную внешнюю команду, которую apm дождётся при остановке. Возвращённую функцию
+// вызывают после завершения команды.
+func TrackCommand() func() {
+	runningCommands.Add(1)
+	return runningCommands.Done
+}
+
+// WaitCommands ожидает завершения выполняющихся внешних команд, но не дольше timeout.
+// Возвращает false, если команды не завершились за это время.
+func WaitCommands(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		runningCommands.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// LogCommand пишет в журнал полную строку запуска внешней команды и возвращает функцию,
+// которую вызывают после завершения команды, чтобы записать её длительность и результат.
+func LogCommand(cmd *exec.Cmd) func(err error) {
+	commandLine := strings.Join(cmd.Args, " ")
+	Log.Debugf("run command: %s", commandLine)
+
+	start := time.Now()
+	return func(err error) {
+		if err != nil {
+			Log.Debugf("command failed in %s: %s: %v", time.Since(start).Round(time.Millisecond), commandLine, err)
+			return
+		}
+		Log.Debugf("command finished in %s: %s", time.Since(start).Round(time.Millisecond), commandLine)
+	}
+}
+
+// CommandRun выполняет cmd.Run с записью команды и её длительности в журнал.
+func CommandRun(cmd *exec.Cmd) error {
+	prepareCommand(cmd)
+	defer TrackCommand()()
+
+	finish := LogCommand(cmd)
+	err := cmd.Run()
+	finish(err)
+	return err
+}
+
+// CommandOutput выполняет cmd.Output с записью команды и её длительности в журнал.
+func CommandOutput(cmd *exec.Cmd) ([]byte, error) {
+	prepareCommand(cmd)
+	defer TrackCommand()()
+
+	finish := LogCommand(cmd)
+	output, err := cmd.Output()
+	finish(err)
+	return output, err
+}
+
+// CommandCombinedOutput выполняет cmd.CombinedOutput с записью команды и её длительности в журнал.
+func CommandCombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	prepareCommand(cmd)
+	defer TrackCommand()()
+
+	finish := LogCommand(cmd)
+	output, err := cmd.CombinedOutput()
+	finish(err)
+	return output, err
+}
+
+// CommandStart запускает команду, вывод которой читается через канал, и возвращает функцию ожидания её
+// завершения. Функцию ожидания нужно вызвать и при ошибке чтения вывода.
+func CommandStart(cmd *exec.Cmd) (func() error, error) {
+	prepareCommand(cmd)
+	finish := LogCommand(cmd)
+	if err := cmd.Start(); err != nil {
+		finish(err)
+		return nil, err
+	}
+
+	done := TrackCommand()
+	return func() error {
+		defer done()
+
+		err := cmd.Wait()
+		finish(err)
+		return err
+	}, nil
+}
+
+// LogCommandOutput пишет в журнал сырой вывод команды, только при включённом флаге --debug.
+func LogCommandOutput(source string, lines []string) {
+	if !DebugOutput {
+		return
+	}
+
+	for _, line := range lines {
+		Log.Debugf("%s: %s", source, line)
+	}
+}
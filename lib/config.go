@@ -31,9 +31,48 @@ type Environment struct {
 	PathLogFile   string `yaml:"pathLogFile"`
 	PathDBSQL     string `yaml:"pathDBSQL"`
 	PathDBKV      string `yaml:"pathDBKV"`
+	PathUserDBSQL string `yaml:"pathUserDBSQL"`
+	PathUserDBKV  string `yaml:"pathUserDBKV"`
 	PathImageFile string `yaml:"pathImageFile"`
-	IsAtomic      bool   // Внутреннее свойство
 	Format        string // Внутреннее свойство
+	Version       string // Внутреннее свойство
+
+	// Тип системы: auto определяет его при запуске, true и false задают явно
+	Atomic string `yaml:"atomic"`
+
+	// Периодическая проверка обновлений в системном DBus-сервисе
+	UpdateCheckEnabled  bool `yaml:"updateCheckEnabled"`
+	UpdateCheckInterval int  `yaml:"updateCheckInterval"` // Интервал в минутах
+
+	// Запрет сборки образа из базового образа без проверки подписи
+	RequireSignedBase bool `yaml:"requireSignedBase"`
+
+	// Максимальная длительность сборки образа в минутах
+	ImageBuildTimeout int `yaml:"imageBuildTimeout"`
+
+	// Хранение собранных образов и истории образов, 0 отключает очистку
+	KeepImages      int `yaml:"keepImages"`
+	KeepHistoryDays int `yaml:"keepHistoryDays"`
+
+	// Время жизни кэша списка контейнеров distrobox в секундах
+	ContainerListCacheTTL int `yaml:"containerListCacheTTL"`
+
+	// Отключение постраничного вывода длинных ответов через $PAGER
+	DisablePager bool `yaml:"disablePager"`
+
+	// Куда пишется журнал: auto, file или journal
+	LogBackend string `yaml:"logBackend"`
+
+	// Ротация файла журнала: размер в мегабайтах, число хранимых копий и их сжатие, 0 отключает ротацию
+	LogMaxSizeMB int  `yaml:"logMaxSizeMB"`
+	LogMaxFiles  int  `yaml:"logMaxFiles"`
+	LogCompress  bool `yaml:"logCompress"`
+
+	// Пользовательские скрипты, выполняемые до и после сборки образа
+	Hooks struct {
+		PreBuild  string `yaml:"preBuild"`
+		PostBuild string `yaml:"postBuild"`
+	} `yaml:"hooks"`
 }
 
 var Env Environment
@@ -48,6 +87,7 @@ var BuildPathLogFile string
 var BuildPathDBSQL string
 var BuildPathDBKV string
 var BuildPathImageFile string
+var BuildVersion string
 
 func InitConfig() {
 	var configPath string
@@ -75,6 +115,11 @@ func InitConfig() {
 		Env.PathImageFile = BuildPathImageFile
 	}
 
+	Env.Version = "dev"
+	if BuildVersion != "" {
+		Env.Version = BuildVersion
+	}
+
 	// Ищем конфигурационный файл в текущей директории
 	if _, err := os.Stat("config.yml"); err == nil {
 		configPath = "config.yml"
@@ -97,21 +142,13 @@ func InitConfig() {
 		log.Fatal(err)
 	}
 
-	// Проверяем и создаём путь для db-файла sql
-	if err := EnsurePath(Env.PathDBSQL); err != nil {
+	// Выбираем базы по пользователю и создаём их каталоги. Файлы баз создаются при открытии,
+	// чтобы базы из прежнего расположения можно было перенести
+	if err := SetStorage(ResolveStorage(os.Geteuid())); err != nil {
 		log.Fatal(err)
 	}
 
-	// Проверяем и создаём путь для db-директории key-value
-	if err := EnsureDir(Env.PathDBKV); err != nil {
-		log.Fatal(err)
-	}
-
-	if _, errAtomic := os.Stat("/usr/bin/bootc"); os.IsNotExist(errAtomic) {
-		Env.IsAtomic = false
-	} else {
-		Env.IsAtomic = true
-	}
+	SetAtomicDetection(ResolveAtomic(Env.Atomic))
 }
 
 // EnsurePath проверяет, существует ли файл и создает его при необходимости.
@@ -18,6 +18,7 @@ package lib
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"sync"
 
@@ -27,18 +28,29 @@ import (
 var (
 	dbInstance *sql.DB
 	once       sync.Once
+
+	systemDBInstance *sql.DB
+	systemOnce       sync.Once
 )
 
-// InitDatabase инициализирует базу данных один раз
+// InitDatabase инициализирует базу данных один раз. Путь к базе выбирается при запуске по пользователю,
+// см. ResolveStorage. Перед первым открытием базы пользователя в неё переносится база из прежнего расположения.
 func InitDatabase() {
 	once.Do(func() {
+		migratedFrom, err := MigrateUserStorage(storage)
+		if err != nil {
+			Log.Warning(err.Error())
+		} else if migratedFrom != "" {
+			storage.MigratedFrom = migratedFrom
+			Log.Infof(T_("The database has been moved from %s to %s"), migratedFrom, storage.PathDBSQL)
+		}
+
 		dbFile := Env.PathDBSQL
 
-		if _, err := os.Stat(dbFile); os.IsNotExist(err) {
+		if _, err = os.Stat(dbFile); os.IsNotExist(err) {
 			Log.Warning(T_("Database file not found. It will be created automatically."))
 		}
 
-		var err error
 		dbInstance, err = sql.Open("sqlite3", dbFile)
 		if err != nil {
 			Log.Fatal(T_("Error opening database: %v"), err)
@@ -61,3 +73,21 @@ func GetDB() *sql.DB {
 	}
 	return dbInstance
 }
+
+// GetSystemDB возвращает общую базу с пакетами и образами системы. Обычный пользователь открывает её
+// только для чтения: база пакетов системы обновляется с правами root, а читать её можно без них.
+func GetSystemDB() *sql.DB {
+	if storage.Scope != StorageScopeUser {
+		return GetDB()
+	}
+
+	systemOnce.Do(func() {
+		var err error
+		systemDBInstance, err = sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", storage.SystemPathDBSQL))
+		if err != nil {
+			Log.Fatal(T_("Error opening database: %v"), err)
+		}
+	})
+
+	return systemDBInstance
+}
@@ -26,6 +26,9 @@ import (
 	"github.com/leonelquinteros/gotext"
 )
 
+// SupportedLanguages языки интерфейса, доступные во флаге --lang и переменной APM_LANG.
+var SupportedLanguages = []string{"en", "ru"}
+
 // InitLocales инициализирует локаль с доменом "apm".
 func InitLocales() {
 	if _, err := os.Stat(Env.PathLocales); os.IsNotExist(err) {
@@ -36,11 +39,29 @@ func InitLocales() {
 	gotext.Configure(Env.PathLocales, GetSystemLocale().String(), "apm")
 }
 
+// SetLanguage переключает язык интерфейса на lang до конца работы приложения.
+func SetLanguage(lang string) error {
+	lang = strings.TrimSpace(lang)
+	for _, supported := range SupportedLanguages {
+		if lang == supported {
+			gotext.Configure(Env.PathLocales, lang, "apm")
+			return nil
+		}
+	}
+
+	return fmt.Errorf(T_("Unknown language %s. Supported languages: %s"), lang, strings.Join(SupportedLanguages, ", "))
+}
+
 // T_ T возвращает переведенную строку для заданного messageID.
 func T_(messageID string) string {
 	return gotext.Get(messageID)
 }
 
+// N_ помечает строку для извлечения xgettext, не переводя её. Перевод выполняется позже через T_.
+func N_(messageID string) string {
+	return messageID
+}
+
 func TN_(messageID string, pluralMessageID string, count int) string {
 	return gotext.GetN(messageID, pluralMessageID, count)
 }
@@ -54,9 +75,12 @@ func TD_(domain string, messageID string) string {
 }
 
 // GetSystemLocale возвращает базовый язык системы в виде language.Tag.
+// Переменная APM_LANG имеет приоритет над системными переменными локали.
 func GetSystemLocale() language.Tag {
 	var localeStr string
-	if v := os.Getenv("LC_ALL"); v != "" {
+	if v := os.Getenv("APM_LANG"); v != "" {
+		localeStr = stripAfterDot(v)
+	} else if v := os.Getenv("LC_ALL"); v != "" {
 		localeStr = stripAfterDot(v)
 	} else if v := os.Getenv("LC_MESSAGES"); v != "" {
 		localeStr = stripAfterDot(v)
@@ -0,0 +1,124 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Значения параметра logBackend.
+const (
+	// LogBackendAuto журнал systemd для службы D-Bus, запущенной systemd, иначе файл pathLogFile
+	LogBackendAuto = "auto"
+	// LogBackendFile всегда файл pathLogFile
+	LogBackendFile = "file"
+	// LogBackendJournal всегда журнал systemd, в том числе для команд в терминале
+	LogBackendJournal = "journal"
+)
+
+// journalSocket сокет, через который journald принимает записи в собственном формате.
+const journalSocket = "/run/systemd/journal/socket"
+
+// journalPriorities соответствие уровней logrus приоритетам syslog.
+var journalPriorities = map[logrus.Level]int{
+	logrus.PanicLevel: 2,
+	logrus.FatalLevel: 2,
+	logrus.ErrorLevel: 3,
+	logrus.WarnLevel:  4,
+	logrus.InfoLevel:  6,
+	logrus.DebugLevel: 7,
+	logrus.TraceLevel: 7,
+}
+
+// journalHook отправляет записи журнала в journald. Поля записи (transaction, container, package и другие)
+// передаются отдельными полями журнала, их можно отбирать через journalctl TRANSACTION=...
+type journalHook struct {
+	conn *net.UnixConn
+}
+
+// newJournalHook подключается к сокету journald. Ошибка означает, что journald недоступен.
+func newJournalHook() (*journalHook, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &journalHook{conn: conn}, nil
+}
+
+// Levels возвращает все уровни: отбор по уровню выполняет сам logrus.
+func (h *journalHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire отправляет запись в journald.
+func (h *journalHook) Fire(entry *logrus.Entry) error {
+	var message bytes.Buffer
+	writeJournalField(&message, "MESSAGE", entry.Message)
+	writeJournalField(&message, "PRIORITY", fmt.Sprint(journalPriorities[entry.Level]))
+	writeJournalField(&message, "SYSLOG_IDENTIFIER", "apm")
+	for key, value := range entry.Data {
+		if name := journalFieldName(key); name != "" {
+			writeJournalField(&message, name, fmt.Sprint(value))
+		}
+	}
+
+	_, err := h.conn.Write(message.Bytes())
+	return err
+}
+
+// writeJournalField записывает поле в формате journald. Многострочное значение передаётся с длиной,
+// иначе перевод строки в нём завершил бы поле.
+func writeJournalField(buf *bytes.Buffer, name string, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", name, value)
+		return
+	}
+
+	buf.WriteString(name + "\n")
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
+
+// journalFieldName приводит имя поля logrus к правилам journald: заглавные латинские буквы, цифры и
+// подчёркивание, без подчёркивания в начале, которое зарезервировано за journald.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	return strings.TrimLeft(name, "_0123456789")
+}
+
+// startedBySystemd сообщает, что процесс запущен systemd как служба.
+func startedBySystemd() bool {
+	return os.Getenv("INVOCATION_ID") != "" || os.Getenv("JOURNAL_STREAM") != ""
+}
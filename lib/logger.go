@@ -17,6 +17,7 @@
 package lib
 
 import (
+	"io"
 	"os"
 
 	"github.com/sirupsen/logrus"
@@ -24,6 +25,18 @@ import (
 
 var Log = logrus.New()
 
+// DebugOutput включается флагом --debug, в журнал дополнительно пишется сырой вывод внешних команд.
+var DebugOutput bool
+
+// VerboseOutput включается флагом -v, текстовый вывод дополняется подробностями, например длительностью этапов.
+var VerboseOutput bool
+
+// logFile открытый файл журнала с ротацией, nil если открыть его не удалось.
+var logFile *RotatingFile
+
+// journalActive записи отправляются в журнал systemd вместо файла.
+var journalActive bool
+
 func InitLogger() {
 	Log.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
@@ -32,10 +45,12 @@ func InitLogger() {
 
 	pathLogFile := Env.PathLogFile
 
-	file, err := os.OpenFile(pathLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	maxSize := int64(Env.LogMaxSizeMB) * 1024 * 1024
+	file, err := OpenRotatingFile(pathLogFile, maxSize, Env.LogMaxFiles, Env.LogCompress)
 	if err != nil {
-		Log.SetOutput(os.Stdout)
+		Log.SetOutput(os.Stderr)
 	} else {
+		logFile = file
 		Log.SetOutput(file)
 	}
 
@@ -44,4 +59,70 @@ func InitLogger() {
 	} else {
 		Log.SetLevel(logrus.InfoLevel)
 	}
+
+	if Env.LogBackend == LogBackendJournal {
+		useJournal()
+	}
+}
+
+// InitServiceLogger выбирает журнал для службы D-Bus. При logBackend auto служба, запущенная systemd,
+// пишет в журнал systemd, чтобы записи были видны в journalctl -u. Команды в терминале это не затрагивает.
+func InitServiceLogger() {
+	backend := Env.LogBackend
+	if backend == "" {
+		backend = LogBackendAuto
+	}
+
+	if backend == LogBackendAuto && startedBySystemd() {
+		useJournal()
+	}
+}
+
+// useJournal переключает журнал на journald. Если journald недоступен, журнал остаётся прежним.
+func useJournal() {
+	if journalActive {
+		return
+	}
+
+	hook, err := newJournalHook()
+	if err != nil {
+		Log.Warningf(T_("The systemd journal is unavailable, logging to %s: %v"), Env.PathLogFile, err)
+		return
+	}
+
+	Log.AddHook(hook)
+	Log.SetOutput(io.Discard)
+	journalActive = true
+}
+
+// SetLogVerbosity повышает уровень журнала на время текущего запуска: -v включает debug, -vv — trace,
+// --debug включает debug и сырой вывод команд. Повышенный журнал дублируется в stderr, чтобы
+// не портить json в stdout. Понизить уровень из конфигурации флаги не могут.
+func SetLogVerbosity(verbose int, debug bool) {
+	VerboseOutput = verbose > 0
+	level := Log.GetLevel()
+	switch {
+	case verbose >= 2:
+		level = logrus.TraceLevel
+	case verbose == 1 || debug:
+		level = logrus.DebugLevel
+	default:
+		return
+	}
+
+	DebugOutput = debug
+	if level > Log.GetLevel() {
+		Log.SetLevel(level)
+	}
+
+	// Записи уже попадают в журнал systemd, дублировать их в stderr службы не нужно
+	if journalActive {
+		return
+	}
+
+	if logFile != nil {
+		Log.SetOutput(io.MultiWriter(logFile, os.Stderr))
+	} else {
+		Log.SetOutput(os.Stderr)
+	}
 }
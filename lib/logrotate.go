@@ -0,0 +1,197 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lib
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// RotatingFile файл журнала, который по достижении MaxSize переименовывается в <путь>.1, а прежние
+// копии сдвигаются на номер вперёд. Хранится не больше MaxFiles копий, с Compress копии сжимаются gzip.
+// Запись и ротация защищены мьютексом, поэтому файл можно писать из нескольких горутин.
+type RotatingFile struct {
+	Path     string
+	MaxSize  int64
+	MaxFiles int
+	Compress bool
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenRotatingFile открывает файл журнала для дозаписи. Нулевой maxSize отключает ротацию.
+func OpenRotatingFile(path string, maxSize int64, maxFiles int, compress bool) (*RotatingFile, error) {
+	r := &RotatingFile{Path: path, MaxSize: maxSize, MaxFiles: maxFiles, Compress: compress}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Write дописывает p в файл, перед этим выполняя ротацию, если запись превысит MaxSize.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxSize {
+		// Ошибка ротации не должна терять запись, она дописывается в текущий файл
+		if err := r.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, T_("Failed to rotate the log %s: %v")+"\n", r.Path, err)
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close закрывает файл журнала.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.file.Close()
+}
+
+// open открывает файл журнала и запоминает его текущий размер.
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate сдвигает копии журнала, удаляя самую старую, и начинает новый файл.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	if r.MaxFiles > 0 {
+		_ = os.Remove(r.backupPath(r.MaxFiles, false))
+		_ = os.Remove(r.backupPath(r.MaxFiles, true))
+		for i := r.MaxFiles - 1; i >= 1; i-- {
+			for _, compressed := range []bool{false, true} {
+				if _, err := os.Stat(r.backupPath(i, compressed)); err == nil {
+					_ = os.Rename(r.backupPath(i, compressed), r.backupPath(i+1, compressed))
+				}
+			}
+		}
+
+		if err := os.Rename(r.Path, r.backupPath(1, false)); err != nil {
+			return r.reopen(err)
+		}
+		if r.Compress {
+			if err := compressFile(r.backupPath(1, false), r.backupPath(1, true)); err != nil {
+				return r.reopen(err)
+			}
+		}
+	} else if err := os.Truncate(r.Path, 0); err != nil {
+		return r.reopen(err)
+	}
+
+	return r.open()
+}
+
+// reopen открывает файл журнала после неудачной ротации и возвращает её ошибку.
+func (r *RotatingFile) reopen(rotateErr error) error {
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	return rotateErr
+}
+
+// backupPath возвращает путь к копии журнала с номером index.
+func (r *RotatingFile) backupPath(index int, compressed bool) string {
+	path := fmt.Sprintf("%s.%d", r.Path, index)
+	if compressed {
+		path += ".gz"
+	}
+
+	return path
+}
+
+// compressFile сжимает source в destination и удаляет source.
+func compressFile(source string, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(out)
+	if _, err = io.Copy(writer, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(destination)
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(destination)
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(source)
+}
+
+// TailLog возвращает последние lines строк текущего файла журнала.
+func TailLog(lines int) ([]string, error) {
+	file, err := os.Open(Env.PathLogFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	result := []string{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		result = append(result, strings.TrimRight(scanner.Text(), "\r"))
+		if len(result) > lines {
+			result = result[1:]
+		}
+	}
+
+	return result, scanner.Err()
+}
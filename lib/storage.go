@@ -0,0 +1,240 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Области хранения баз данных apm.
+const (
+	// StorageScopeSystem общие базы по путям pathDBSQL и pathDBKV, их использует root и системная служба
+	StorageScopeSystem = "system"
+	// StorageScopeUser базы обычного пользователя в $XDG_STATE_HOME/apm
+	StorageScopeUser = "user"
+)
+
+// StorageInfo расположение баз данных, которыми пользуется apm, и причина выбора.
+type StorageInfo struct {
+	Scope           string `json:"scope"`
+	PathDBSQL       string `json:"pathDBSQL"`
+	PathDBKV        string `json:"pathDBKV"`
+	SystemPathDBSQL string `json:"systemPathDBSQL"`
+	SystemPathDBKV  string `json:"systemPathDBKV"`
+	Reason          string `json:"reason"`
+	MigratedFrom    string `json:"migratedFrom,omitempty"`
+}
+
+var storage StorageInfo
+
+// GetStorageInfo возвращает расположение баз данных, выбранное при запуске.
+func GetStorageInfo() StorageInfo {
+	return storage
+}
+
+// ResolveStorage выбирает базы данных для пользователя euid. Root и системная служба пользуются общими
+// базами из конфигурации, обычный пользователь - своими в $XDG_STATE_HOME/apm (по умолчанию
+// ~/.local/state/apm), потому что общие базы ему обычно недоступны для записи. Параметры pathUserDBSQL и
+// pathUserDBKV конфигурации задают пути баз пользователя явно.
+func ResolveStorage(euid int) StorageInfo {
+	info := StorageInfo{
+		Scope:           StorageScopeSystem,
+		PathDBSQL:       Env.PathDBSQL,
+		PathDBKV:        Env.PathDBKV,
+		SystemPathDBSQL: Env.PathDBSQL,
+		SystemPathDBKV:  Env.PathDBKV,
+		Reason:          T_("apm runs as root, the system-wide databases are used"),
+	}
+
+	if euid == 0 {
+		return info
+	}
+
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			info.Reason = T_("The home directory of the user is unknown, the system-wide databases are used")
+			return info
+		}
+		stateDir = filepath.Join(home, ".local", "state")
+	}
+
+	info.Scope = StorageScopeUser
+	info.PathDBSQL = filepath.Join(stateDir, "apm", "apm.db")
+	info.PathDBKV = filepath.Join(stateDir, "apm", "pogreb")
+	info.Reason = T_("apm runs as a regular user, the databases in $XDG_STATE_HOME/apm are used")
+
+	if Env.PathUserDBSQL != "" || Env.PathUserDBKV != "" {
+		info.Reason = T_("apm runs as a regular user, the paths are set by the pathUserDBSQL and pathUserDBKV parameters of the configuration")
+	}
+	if Env.PathUserDBSQL != "" {
+		info.PathDBSQL = Env.PathUserDBSQL
+	}
+	if Env.PathUserDBKV != "" {
+		info.PathDBKV = Env.PathUserDBKV
+	}
+
+	return info
+}
+
+// SetStorage применяет выбранное расположение баз: пути записываются в Env и создаются их каталоги.
+func SetStorage(info StorageInfo) error {
+	storage = info
+	Env.PathDBSQL = info.PathDBSQL
+	Env.PathDBKV = info.PathDBKV
+
+	if err := EnsureDir(filepath.Dir(info.PathDBSQL)); err != nil {
+		return err
+	}
+
+	return EnsureDir(filepath.Dir(info.PathDBKV))
+}
+
+// legacyUserDBPath путь, в котором прежние версии хранили базу истории поиска пользователя.
+func legacyUserDBPath() string {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+
+	return filepath.Join(dataDir, "apm", "apm.db")
+}
+
+// MigrateUserStorage переносит базы пользователя из прежнего расположения при первом запуске, если в новом
+// их ещё нет. Переносятся только файлы, принадлежащие пользователю: общая база, созданная им при запуске
+// без root, или база истории поиска из $XDG_DATA_HOME/apm. Возвращает путь, из которого перенесена база SQL.
+func MigrateUserStorage(info StorageInfo) (string, error) {
+	if info.Scope != StorageScopeUser {
+		return "", nil
+	}
+
+	migratedFrom := ""
+	if !pathExists(info.PathDBSQL) {
+		for _, candidate := range []string{info.SystemPathDBSQL, legacyUserDBPath()} {
+			if candidate == "" || candidate == info.PathDBSQL || !ownedByCurrentUser(candidate) {
+				continue
+			}
+
+			if err := movePath(candidate, info.PathDBSQL); err != nil {
+				return "", fmt.Errorf(T_("Failed to move the database %s to %s: %v"), candidate, info.PathDBSQL, err)
+			}
+			migratedFrom = candidate
+			break
+		}
+	}
+
+	if !pathExists(info.PathDBKV) && info.SystemPathDBKV != info.PathDBKV && ownedByCurrentUser(info.SystemPathDBKV) {
+		if err := movePath(info.SystemPathDBKV, info.PathDBKV); err != nil {
+			return migratedFrom, fmt.Errorf(T_("Failed to move the database %s to %s: %v"), info.SystemPathDBKV, info.PathDBKV, err)
+		}
+	}
+
+	return migratedFrom, nil
+}
+
+// pathExists сообщает, что файл или каталог существует.
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ownedByCurrentUser сообщает, что файл или каталог существует и принадлежит текущему пользователю.
+func ownedByCurrentUser(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Geteuid()
+}
+
+// movePath переносит файл или каталог без вложенных каталогов. Если переименование невозможно, потому что
+// пути на разных файловых системах, содержимое копируется, а источник удаляется.
+func movePath(source string, destination string) error {
+	if err := EnsureDir(filepath.Dir(destination)); err != nil {
+		return err
+	}
+
+	err := os.Rename(source, destination)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		if err = copyFile(source, destination, info.Mode()); err != nil {
+			return err
+		}
+		return os.Remove(source)
+	}
+
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(destination, info.Mode().Perm()); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		entryInfo, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if err = copyFile(filepath.Join(source, entry.Name()), filepath.Join(destination, entry.Name()), entryInfo.Mode()); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(source)
+}
+
+// copyFile копирует файл source в destination с правами mode.
+func copyFile(source string, destination string, mode os.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(destination, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(destination)
+		return err
+	}
+
+	return out.Close()
+}
@@ -27,7 +27,9 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/godbus/dbus/v5/introspect"
 	"github.com/urfave/cli/v3"
@@ -44,6 +46,11 @@ func main() {
 	lib.InitConfig()
 	lib.InitLogger()
 	lib.InitLocales()
+
+	// Язык применяется до построения команд, чтобы справка по флагам тоже была на выбранном языке
+	if lang := languageFromArgs(os.Args[1:]); lang != "" {
+		_ = lib.SetLanguage(lang)
+	}
 	lib.InitDatabase()
 
 	sigs := make(chan os.Signal, 1)
@@ -51,6 +58,11 @@ func main() {
 
 	go func() {
 		sig := <-sigs
+		// Ctrl-C внутри постраничного просмотра закрывает только просмотрщик
+		for sig == syscall.SIGINT && reply.PagerActive() {
+			sig = <-sigs
+		}
+
 		switch sig {
 		case syscall.SIGINT, syscall.SIGTERM:
 			infoText := fmt.Sprintf(lib.T_("Recieved correct signal %s. Stopping application…"), sig)
@@ -72,32 +84,105 @@ func main() {
 			})
 		}
 
+		// Отмена контекста останавливает запущенные внешние команды вместе с их дочерними процессами.
+		// Выход откладывается до их завершения, иначе apt мог бы остаться работать с захваченной блокировкой
+		globalCancel()
+		if !lib.WaitCommands(lib.CommandWaitDelay + time.Second) {
+			lib.Log.Warning(lib.T_("External commands did not stop in time"))
+		}
+
 		cleanup()
 		os.Exit(0)
 	}()
 
 	rootCommand := &cli.Command{
-		Name:  "apm",
-		Usage: "Atomic Package Manager",
-		//EnableShellCompletion: true,
+		Name:                   "apm",
+		Usage:                  "Atomic Package Manager",
+		EnableShellCompletion:  true,
+		UseShortOptionHandling: true,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:    "format",
-				Usage:   lib.T_("Output format: json, text"),
+				Usage:   lib.T_("Output format: json, jsonstream, text, table, yaml, csv"),
 				Aliases: []string{"f"},
 				Value:   "text",
 			},
+			&cli.BoolFlag{
+				Name:  "pretty",
+				Usage: lib.T_("Indent the json output for reading, by default json is printed on a single line"),
+			},
+			&cli.StringFlag{
+				Name:  "color",
+				Usage: lib.T_("Colorize text output: auto, always, never"),
+				Value: "auto",
+			},
+			&cli.StringFlag{
+				Name:  "lang",
+				Usage: lib.T_("Interface language: en, ru. Overrides the system locale and the APM_LANG variable"),
+			},
+			&cli.BoolFlag{
+				Name:  "no-pager",
+				Usage: lib.T_("Do not pass long text output to $PAGER"),
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Usage:   lib.T_("Apply changes without the confirmation dialog"),
+				Aliases: []string{"y"},
+			},
+			&cli.BoolFlag{
+				Name:  "no-spinner",
+				Usage: lib.T_("Do not show the progress indicator"),
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Usage:   lib.T_("Print only essential output, repeat (-qq) to print only errors"),
+				Aliases: []string{"q"},
+				// Before корневой команды выполняется до разбора флагов подкоманды, поэтому уровень вывода задаётся
+				// действием флага: оно срабатывает и для -q, указанного после подкоманды
+				Action: func(ctx context.Context, cmd *cli.Command, _ bool) error {
+					reply.SetVerbosity(cmd.Count("quiet"))
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Usage:   lib.T_("Raise the log level for this run and duplicate the log to stderr, repeat (-vv) for trace"),
+				Aliases: []string{"v"},
+			},
+			&cli.BoolFlag{
+				Name:  "debug",
+				Usage: lib.T_("Enable debug logging to stderr, including external commands and their raw output"),
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Usage: lib.T_("Also write the response to a file: yaml for the yaml format, json otherwise. The terminal still gets the text output"),
+			},
 			&cli.StringFlag{
 				Name:    "transaction",
 				Usage:   lib.T_("Internal property, adds the transaction to the output"),
 				Aliases: []string{"t"},
 			},
 		},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			lib.SetLogVerbosity(cmd.Count("verbose"), cmd.Bool("debug"))
+			reply.SetPagerEnabled(!cmd.Bool("no-pager") && !lib.Env.DisablePager)
+			reply.SetSpinnerEnabled(!cmd.Bool("no-spinner"))
+			reply.SetAssumeYes(cmd.Bool("yes"))
+			reply.SetPrettyJSON(cmd.Bool("pretty"))
+			if cmd.IsSet("lang") {
+				if err := lib.SetLanguage(cmd.String("lang")); err != nil {
+					return ctx, err
+				}
+			}
+			return ctx, nil
+		},
 		Commands: []*cli.Command{
 			{
 				Name:  "dbus-session",
 				Usage: lib.T_("Start session D-Bus service com.application.APM"),
 				Action: func(ctx context.Context, cmd *cli.Command) error {
+					lib.InitServiceLogger()
+
 					err := lib.InitDBus(false)
 					if err != nil {
 						return err
@@ -111,6 +196,10 @@ func main() {
 						return err
 					}
 
+					if err = helper.ExportAPIVersion(lib.DBUSConn, "com.application.distrobox"); err != nil {
+						return err
+					}
+
 					if err = lib.DBUSConn.Export(
 						introspect.Introspectable(helper.UserIntrospectXML),
 						"/com/application/APM",
@@ -135,6 +224,8 @@ func main() {
 				Name:  "dbus-system",
 				Usage: lib.T_("Start system D-Bus service com.application.APM"),
 				Action: func(ctx context.Context, cmd *cli.Command) error {
+					lib.InitServiceLogger()
+
 					err := lib.InitDBus(true)
 					if err != nil {
 						return err
@@ -151,6 +242,10 @@ func main() {
 						return err
 					}
 
+					if err = helper.ExportAPIVersion(lib.DBUSConn, "com.application.system"); err != nil {
+						return err
+					}
+
 					if err = lib.DBUSConn.Export(
 						introspect.Introspectable(helper.SystemIntrospectXML),
 						"/com/application/APM",
@@ -161,11 +256,24 @@ func main() {
 
 					lib.Env.Format = "dbus"
 
+					if lib.Env.UpdateCheckEnabled {
+						go system.NewUpdateChecker(sysActions).Run(ctx)
+					}
+
+					if lib.IsAtomic() {
+						go system.NewConfigWatcher(sysActions).Run(ctx)
+					}
+
 					select {}
 				},
 			},
 			system.CommandList(),
 			distrobox.CommandList(),
+			system.LogsCommand(),
+			system.RecoverCommand(),
+			system.DoctorCommand(),
+			system.DBCommand(),
+			helper.CompletionCommand(),
 			{
 				Name:      "help",
 				Aliases:   []string{"h"},
@@ -186,7 +294,27 @@ func main() {
 			},
 			Error: true,
 		})
+
+		cleanup()
+		os.Exit(1)
+	}
+}
+
+// languageFromArgs возвращает значение флага --lang из аргументов командной строки до их разбора.
+func languageFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--lang="); ok {
+			return value
+		}
+		if arg == "--lang" && i+1 < len(args) {
+			return args[i+1]
+		}
 	}
+
+	return ""
 }
 
 func cleanup() {
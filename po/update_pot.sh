@@ -3,4 +3,4 @@
 
 sh po/update_potfiles.sh
 
-cat ./po/POTFILES | xargs xgettext --language=C --keyword=T_ --keyword=TN_:1,2 --keyword=TD_:2 --keyword=TC_:1c,2 -o po/apm.pot --from-code=UTF-8 --add-comments --package-name=apm
+cat ./po/POTFILES | xargs xgettext --language=C --keyword=T_ --keyword=N_ --keyword=TN_:1,2 --keyword=TD_:2 --keyword=TC_:1c,2 -o po/apm.pot --from-code=UTF-8 --add-comments --package-name=apm
@@ -3,7 +3,7 @@
 
 touch ./po/unsort-POTFILES
 
-find ./ -iname "*.go" -type f -exec grep -lrE 'T_\(|TN_\(|TD_\(|TC_\(' {} + | while read file; do echo "${file#./}" >> ./po/unsort-POTFILES; done
+find ./ -iname "*.go" -type f -exec grep -lrE 'T_\(|N_\(|TN_\(|TD_\(|TC_\(' {} + | while read file; do echo "${file#./}" >> ./po/unsort-POTFILES; done
 
 cat ./po/unsort-POTFILES | sort | uniq > ./po/POTFILES
 
@@ -0,0 +1,44 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// container_sizes_test.go
+package distrobox
+
+import (
+	"apm/cmd/distrobox/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseContainerSizes проверяет разбор размеров контейнеров из вывода podman system df -v.
+func TestParseContainerSizes(t *testing.T) {
+	output := `{
+  "Images": [{"Repository": "docker.io/library/archlinux", "Size": 450000000}],
+  "Containers": [
+    {"ContainerID": "1a2b", "Image": "archlinux", "Size": 1933049856, "RWSize": 120, "Names": "arch"},
+    {"ContainerID": "3c4d", "Image": "alt", "Size": 524288, "RWSize": 0, "Names": "alt"}
+  ],
+  "Volumes": []
+}`
+
+	sizes, err := service.ParseContainerSizes(output)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"arch": 1933049856, "alt": 524288}, sizes)
+
+	_, err = service.ParseContainerSizes("not json")
+	assert.Error(t, err)
+}
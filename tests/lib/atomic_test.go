@@ -0,0 +1,55 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// atomic_test.go
+package lib
+
+import (
+	"apm/lib"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestResolveAtomic проверяет, что параметр atomic конфигурации задаёт тип системы без проверок, а auto
+// оставляет результат проверок. Признаки для диагностики собираются только при запросе.
+func TestResolveAtomic(t *testing.T) {
+	detected := lib.DetectAtomic()
+	assert.Equal(t, "detected", detected.Source)
+	assert.NotNil(t, detected.Evidence)
+
+	auto := lib.ResolveAtomic(lib.AtomicAuto)
+	assert.Equal(t, detected.IsAtomic, auto.IsAtomic)
+	assert.Equal(t, "detected", auto.Source)
+
+	forcedOn := lib.ResolveAtomic(lib.AtomicForceOn)
+	assert.True(t, forcedOn.IsAtomic)
+	assert.Equal(t, "config", forcedOn.Source)
+	assert.Nil(t, forcedOn.Evidence)
+
+	forcedOff := lib.ResolveAtomic(lib.AtomicForceOff)
+	assert.False(t, forcedOff.IsAtomic)
+	assert.Equal(t, "config", forcedOff.Source)
+
+	previous := lib.GetAtomicDetection()
+	defer lib.SetAtomicDetection(previous)
+
+	lib.SetAtomicDetection(forcedOn)
+	assert.True(t, lib.IsAtomic())
+	assert.Equal(t, detected.Evidence, lib.GetAtomicDetection().Evidence)
+	lib.SetAtomicDetection(forcedOff)
+	assert.False(t, lib.IsAtomic())
+}
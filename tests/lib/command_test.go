@@ -0,0 +1,84 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// command_test.go
+package lib
+
+import (
+	"apm/lib"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// processAlive сообщает, что процесс существует и не стал зомби.
+func processAlive(pid int) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+
+	// Состояние процесса идёт сразу после имени команды в скобках
+	fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+// TestCommandRun_CancelKillsProcessGroup проверяет, что отмена контекста останавливает не только обёртку sh,
+// но и запущенный ею дочерний процесс, и что apm дожидается их завершения.
+func TestCommandRun_CancelKillsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", fmt.Sprintf("sleep 60 & echo $! > %s; wait", pidFile))
+	done := make(chan error, 1)
+	go func() {
+		done <- lib.CommandRun(cmd)
+	}()
+
+	var childPID int
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(pidFile)
+		if err != nil {
+			return false
+		}
+		childPID, err = strconv.Atoi(strings.TrimSpace(string(content)))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, processAlive(childPID))
+
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(lib.CommandWaitDelay + 5*time.Second):
+		t.Fatal("command did not stop after cancellation")
+	}
+
+	assert.True(t, lib.WaitCommands(time.Second))
+	assert.Eventually(t, func() bool {
+		return !processAlive(childPID)
+	}, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,93 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// logrotate_test.go
+package lib
+
+import (
+	"apm/lib"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRotatingFile_Rotate проверяет, что при превышении размера журнал переносится в сжатую копию,
+// а лишние копии удаляются.
+func TestRotatingFile_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apm.log")
+	file, err := lib.OpenRotatingFile(path, 100, 2, true)
+	assert.NoError(t, err)
+
+	line := strings.Repeat("x", 59) + "\n"
+	for i := 0; i < 5; i++ {
+		_, err = file.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, file.Close())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, line, string(content))
+
+	assert.FileExists(t, path+".1.gz")
+	assert.FileExists(t, path+".2.gz")
+	assert.NoFileExists(t, path+".1")
+	assert.NoFileExists(t, path+".3.gz")
+}
+
+// TestRotatingFile_ConcurrentWrites проверяет, что записи из нескольких горутин не теряются и не
+// перемешиваются при ротации.
+func TestRotatingFile_ConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apm.log")
+	file, err := lib.OpenRotatingFile(path, 1024, 100, false)
+	assert.NoError(t, err)
+
+	line := strings.Repeat("y", 31) + "\n"
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				_, _ = file.Write([]byte(line))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.NoError(t, file.Close())
+
+	paths, err := filepath.Glob(path + "*")
+	assert.NoError(t, err)
+
+	total := 0
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len(content), 1024)
+		for _, l := range strings.SplitAfter(string(content), "\n") {
+			if l == "" {
+				continue
+			}
+			assert.Equal(t, line, l)
+			total++
+		}
+	}
+	assert.Equal(t, 8*50, total)
+}
@@ -0,0 +1,95 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// storage_test.go
+package lib
+
+import (
+	"apm/lib"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestResolveStorage проверяет выбор баз: общие для root, $XDG_STATE_HOME/apm для пользователя и пути
+// из конфигурации, если они заданы.
+func TestResolveStorage(t *testing.T) {
+	previous := lib.Env
+	defer func() { lib.Env = previous }()
+
+	stateDir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateDir)
+	lib.Env.PathDBSQL = "/var/apm/apm.db"
+	lib.Env.PathDBKV = "/var/apm/pogreb"
+	lib.Env.PathUserDBSQL = ""
+	lib.Env.PathUserDBKV = ""
+
+	root := lib.ResolveStorage(0)
+	assert.Equal(t, lib.StorageScopeSystem, root.Scope)
+	assert.Equal(t, "/var/apm/apm.db", root.PathDBSQL)
+	assert.Equal(t, "/var/apm/pogreb", root.PathDBKV)
+
+	user := lib.ResolveStorage(1000)
+	assert.Equal(t, lib.StorageScopeUser, user.Scope)
+	assert.Equal(t, filepath.Join(stateDir, "apm", "apm.db"), user.PathDBSQL)
+	assert.Equal(t, filepath.Join(stateDir, "apm", "pogreb"), user.PathDBKV)
+	assert.Equal(t, "/var/apm/apm.db", user.SystemPathDBSQL)
+
+	lib.Env.PathUserDBSQL = "/srv/apm/user.db"
+	overridden := lib.ResolveStorage(1000)
+	assert.Equal(t, "/srv/apm/user.db", overridden.PathDBSQL)
+	assert.Equal(t, filepath.Join(stateDir, "apm", "pogreb"), overridden.PathDBKV)
+	assert.NotEqual(t, user.Reason, overridden.Reason)
+}
+
+// TestMigrateUserStorage проверяет, что принадлежащие пользователю базы из общего расположения переносятся
+// в новое при первом запуске и не трогаются, если база пользователя уже есть.
+func TestMigrateUserStorage(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+	info := lib.StorageInfo{
+		Scope:           lib.StorageScopeUser,
+		PathDBSQL:       filepath.Join(newDir, "apm", "apm.db"),
+		PathDBKV:        filepath.Join(newDir, "apm", "pogreb"),
+		SystemPathDBSQL: filepath.Join(oldDir, "apm.db"),
+		SystemPathDBKV:  filepath.Join(oldDir, "pogreb"),
+	}
+
+	assert.NoError(t, os.WriteFile(info.SystemPathDBSQL, []byte("sqlite"), 0o644))
+	assert.NoError(t, os.MkdirAll(info.SystemPathDBKV, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(info.SystemPathDBKV, "main.pix"), []byte("kv"), 0o644))
+
+	migratedFrom, err := lib.MigrateUserStorage(info)
+	assert.NoError(t, err)
+	assert.Equal(t, info.SystemPathDBSQL, migratedFrom)
+	assert.NoFileExists(t, info.SystemPathDBSQL)
+	assert.NoDirExists(t, info.SystemPathDBKV)
+
+	content, err := os.ReadFile(info.PathDBSQL)
+	assert.NoError(t, err)
+	assert.Equal(t, "sqlite", string(content))
+	assert.FileExists(t, filepath.Join(info.PathDBKV, "main.pix"))
+
+	assert.NoError(t, os.WriteFile(info.SystemPathDBSQL, []byte("other"), 0o644))
+	migratedFrom, err = lib.MigrateUserStorage(info)
+	assert.NoError(t, err)
+	assert.Empty(t, migratedFrom)
+	assert.FileExists(t, info.SystemPathDBSQL)
+}
@@ -0,0 +1,390 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// api_test.go
+package reply
+
+import (
+	"apm/cmd/common/reply"
+	"apm/cmd/distrobox"
+	distroService "apm/cmd/distrobox/service"
+	"apm/cmd/system"
+	"apm/cmd/system/apt"
+	"apm/cmd/system/service"
+	"apm/lib"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// diskSpaceDeltaMB изменение занятого места в примере ответа upgrade --dry-run.
+var diskSpaceDeltaMB = -0.42
+
+// apiContracts типизированные ответы основных команд: пример ответа для записи эталона и
+// конструктор пустого ответа, в который эталон читается без неизвестных полей.
+var apiContracts = []struct {
+	name   string
+	sample interface{}
+	target func() interface{}
+}{
+	{
+		name: "system_list",
+		sample: system.PackageListResponse{
+			Message: "1 record found",
+			Packages: []system.ShortPackageResponse{
+				{Name: "zip", Version: "3.0-alt3", Installed: true, Reason: "manual", Description: "Compression utility"},
+			},
+			TotalCount: 1,
+		},
+		target: func() interface{} { return &system.PackageListResponse{} },
+	},
+	{
+		name: "system_list_full",
+		sample: system.PackageListFullResponse{
+			Message: "1 record found",
+			Packages: []apt.Package{
+				{Name: "zip", Version: "3.0-alt3", Installed: true, InstallReason: "manual", Description: "Compression utility"},
+			},
+			TotalCount: 1,
+		},
+		target: func() interface{} { return &system.PackageListFullResponse{} },
+	},
+	{
+		name: "system_upgradable",
+		sample: system.UpgradableListResponse{
+			Message: "1 record found",
+			Packages: []system.UpgradablePackageResponse{
+				{Name: "zip", Version: "3.0-alt4", VersionInstalled: "3.0-alt3", Description: "Compression utility"},
+			},
+			TotalCount: 1,
+		},
+		target: func() interface{} { return &system.UpgradableListResponse{} },
+	},
+	{
+		name: "system_search",
+		sample: system.PackageSearchResponse{
+			Message: "1 record found",
+			Packages: []system.ShortPackageResponse{
+				{Name: "zsh", Version: "5.9-alt2", Description: "The Z shell", MatchedOn: "name"},
+			},
+		},
+		target: func() interface{} { return &system.PackageSearchResponse{} },
+	},
+	{
+		name: "system_search_full",
+		sample: system.PackageSearchFullResponse{
+			Message: "1 record found",
+			Packages: []system.SearchPackageResponse{
+				{Package: apt.Package{Name: "zsh", Version: "5.9-alt2", Description: "The Z shell"}, MatchedOn: "name"},
+			},
+		},
+		target: func() interface{} { return &system.PackageSearchFullResponse{} },
+	},
+	{
+		name: "system_info",
+		sample: system.PackageInfoResponse{
+			Message:     "Package found",
+			PackageInfo: system.ShortPackageResponse{Name: "zip", Version: "3.0-alt3", Installed: true, Reason: "manual"},
+		},
+		target: func() interface{} { return &system.PackageInfoResponse{} },
+	},
+	{
+		name: "system_info_full",
+		sample: system.PackageInfoFullResponse{
+			Message:     "Package found",
+			PackageInfo: apt.Package{Name: "zip", Version: "3.0-alt3", Installed: true, InstallReason: "manual"},
+		},
+		target: func() interface{} { return &system.PackageInfoFullResponse{} },
+	},
+	{
+		name: "image_status",
+		sample: system.ImageStatusResponse{
+			Message:         "Image status",
+			BootedImage:     system.ImageStatus{Status: "Changed"},
+			BaseSignature:   service.SignatureStatus{Image: "registry.example/os:latest", Status: "unsigned"},
+			PendingImage:    &service.ImageHistory{ID: 4, ImageName: "localhost/os:latest", Status: service.ImageStatusBuilt},
+			ScheduledReboot: &service.ScheduledReboot{Type: "reboot", At: "2025-03-25T18:00:00+03:00"},
+			Atomic:          lib.AtomicDetection{IsAtomic: true, Source: "detected", Evidence: []string{"The system is booted from an ostree deployment"}},
+		},
+		target: func() interface{} { return &system.ImageStatusResponse{} },
+	},
+	{
+		name: "image_history",
+		sample: system.ImageHistoryResponse{
+			Message:    "1 record found",
+			History:    []service.ImageHistory{{ID: 3, ImageName: "localhost/os:latest", Status: service.ImageStatusDeployed}},
+			TotalCount: 1,
+		},
+		target: func() interface{} { return &system.ImageHistoryResponse{} },
+	},
+	{
+		name: "system_install",
+		sample: system.PackageChangesResponse{
+			Message: "zip installed successfully",
+			Info:    apt.PackageChanges{NewInstalledPackages: []string{"zip"}, NewInstalledCount: 1},
+		},
+		target: func() interface{} { return &system.PackageChangesResponse{} },
+	},
+	{
+		name: "system_packages_count",
+		sample: system.QuickStatsResponse{
+			Message:      "120 packages in the database",
+			Total:        120,
+			Installed:    40,
+			Upgradable:   3,
+			LastSyncTime: "2025-03-25T18:00:00+03:00",
+		},
+		target: func() interface{} { return &system.QuickStatsResponse{} },
+	},
+	{
+		name: "system_upgrade_dry_run",
+		sample: system.PackageChangesResponse{
+			Message:          "1 package will be upgraded",
+			Info:             apt.PackageChanges{UpgradedPackages: []string{"zip"}, UpgradedCount: 1},
+			Packages:         []string{"zip"},
+			Simulated:        true,
+			DiskSpaceDeltaMB: &diskSpaceDeltaMB,
+		},
+		target: func() interface{} { return &system.PackageChangesResponse{} },
+	},
+	{
+		name: "image_apply",
+		sample: system.ImageApplyResponse{
+			Message:       "Changes applied successfully. A reboot is required",
+			BootedImage:   system.ImageStatus{Status: "Changed"},
+			BaseSignature: service.SignatureStatus{Image: "registry.example/os:latest", Status: "unsigned"},
+			ImageName:     "localhost/os:latest",
+			Arch:          "x86_64",
+			BuildResult: system.BuildResult{
+				BuildHooks: []service.BuildHookResult{{Hook: service.HookPostBuild, Path: "/etc/apm/hooks/post-build", ExitCode: 0}},
+			},
+		},
+		target: func() interface{} { return &system.ImageApplyResponse{} },
+	},
+	{
+		name: "image_apply_no_changes",
+		sample: system.ImageApplyResponse{
+			Message:     "No changes since the current image, configuration hash 3f2a matches. Use --force to rebuild",
+			BootedImage: system.ImageStatus{Status: "Changed"},
+			ImageName:   "localhost/os:latest",
+			NoChanges:   true,
+			ConfigHash:  "3f2a",
+		},
+		target: func() interface{} { return &system.ImageApplyResponse{} },
+	},
+	{
+		name: "image_update",
+		sample: system.ImageUpdateResponse{
+			Message:       "Image built successfully. Run the image switch command to deploy it",
+			Updated:       true,
+			BootedImage:   system.ImageStatus{Status: "Changed"},
+			BaseSignature: service.SignatureStatus{Image: "registry.example/os:latest", Status: "unsigned"},
+			PendingImage:  &service.ImageHistory{ID: 5, ImageName: "localhost/os:latest", Status: service.ImageStatusBuilt},
+		},
+		target: func() interface{} { return &system.ImageUpdateResponse{} },
+	},
+	{
+		name: "distrobox_list",
+		sample: distrobox.PackageListResponse{
+			Message:    "1 record found",
+			Packages:   []distroService.PackageInfo{{Name: "firefox", Version: "128.0", Container: "arch", Manager: "pacman"}},
+			TotalCount: 1,
+		},
+		target: func() interface{} { return &distrobox.PackageListResponse{} },
+	},
+	{
+		name: "distrobox_search",
+		sample: distrobox.PackageSearchResponse{
+			Message:  "1 record found",
+			Packages: []distroService.PackageInfo{{Name: "firefox", Version: "128.0", Container: "arch", Manager: "pacman"}},
+		},
+		target: func() interface{} { return &distrobox.PackageSearchResponse{} },
+	},
+	{
+		name: "distrobox_info",
+		sample: distrobox.PackageInfoResponse{
+			Message: "Package found",
+			PackageInfo: distroService.InfoPackageAnswer{
+				Package: distroService.PackageInfo{Name: "firefox", Container: "arch", Installed: true, Exporting: true},
+				Paths:   []string{"/usr/share/applications/firefox.desktop"},
+			},
+		},
+		target: func() interface{} { return &distrobox.PackageInfoResponse{} },
+	},
+	{
+		name: "containers",
+		sample: distrobox.ContainerListResponse{
+			Containers: []distrobox.ContainerListItem{
+				{ContainerInfo: distroService.ContainerInfo{OS: "Arch Linux", ContainerName: "arch", Active: true}, Running: true, SizeMB: 1843.5},
+			},
+		},
+		target: func() interface{} { return &distrobox.ContainerListResponse{} },
+	},
+}
+
+// renderJSON выводит ответ в формате json и возвращает напечатанный текст.
+func renderJSON(t *testing.T, data map[string]interface{}) []byte {
+	lib.Env.Format = "json"
+	defer func() { lib.Env.Format = "text" }()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	err = reply.CliResponse(context.Background(), reply.APIResponse{Data: data})
+	_ = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return out
+}
+
+// TestAPIContract_Golden читает эталоны ответов текущей версии контракта в типизированные ответы.
+// Переименованное или удалённое поле даёт неизвестное поле в эталоне. Эталоны лежат в каталоге
+// testdata/api/v<APIVersion>, поэтому повышение версии требует явно записать эталоны новой версии.
+func TestAPIContract_Golden(t *testing.T) {
+	dir := filepath.Join("testdata", "api", "v"+reply.APIVersion)
+
+	for _, contract := range apiContracts {
+		t.Run(contract.name, func(t *testing.T) {
+			path := filepath.Join(dir, contract.name+".json")
+			if *updateGolden {
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				// Эталоны хранятся с отступами, чтобы изменения контракта было видно в истории
+				reply.SetPrettyJSON(true)
+				defer reply.SetPrettyJSON(false)
+				if err := os.WriteFile(path, renderJSON(t, reply.StructData(contract.sample)), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			golden, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden file: %v", err)
+			}
+
+			resp := reply.APIResponse{Data: contract.target()}
+			decoder := json.NewDecoder(bytes.NewReader(golden))
+			decoder.DisallowUnknownFields()
+			if err = decoder.Decode(&resp); err != nil {
+				t.Fatalf("%s does not match the current response structs: %v", path, err)
+			}
+
+			if resp.APIVersion != reply.APIVersion {
+				t.Errorf("%s: apiVersion = %q, want %q", path, resp.APIVersion, reply.APIVersion)
+			}
+		})
+	}
+}
+
+// TestStructData проверяет, что поля ответа сохраняют типы и подчиняются правилу omitempty.
+func TestStructData(t *testing.T) {
+	packages := []distroService.PackageInfo{{Name: "firefox"}}
+	data := reply.StructData(distrobox.PackageListResponse{Message: "1 record found", Packages: packages, TotalCount: 1})
+
+	if _, ok := data["packages"].([]distroService.PackageInfo); !ok {
+		t.Errorf("packages lost its type: %T", data["packages"])
+	}
+	if data["totalCount"] != 1 {
+		t.Errorf("totalCount = %v, want 1", data["totalCount"])
+	}
+
+	data = reply.StructData(system.ImageStatusResponse{Message: "Image status"})
+	if _, ok := data["pendingImage"]; ok {
+		t.Error("empty pendingImage is not omitted")
+	}
+	if _, ok := data["bootedImage"]; !ok {
+		t.Error("bootedImage is omitted")
+	}
+}
+
+// TestResponseData проверяет, что типизированный ответ раскрывается в карту вместе со встроенными полями.
+func TestResponseData(t *testing.T) {
+	resp := reply.APIResponse{Data: &system.ImageUpdateResponse{
+		Message:     "The base image has not changed",
+		BuildResult: system.BuildResult{Warning: "unsigned base image"},
+	}}
+
+	data, ok := reply.ResponseData(&resp)
+	if !ok {
+		t.Fatal("typed response is not converted")
+	}
+	if data["warning"] != "unsigned base image" {
+		t.Errorf("warning = %v, want the embedded field", data["warning"])
+	}
+	if _, ok := data["updated"]; !ok {
+		t.Error("updated is omitted")
+	}
+	if _, ok := resp.Data.(map[string]interface{}); !ok {
+		t.Errorf("resp.Data = %T, want the converted map", resp.Data)
+	}
+
+	if _, ok := reply.ResponseData(&reply.APIResponse{Data: "text"}); ok {
+		t.Error("string data is reported as a response map")
+	}
+}
+
+// TestJSONOutput_Compact проверяет, что json выводится одной строкой, а ключи data идут в стабильном порядке.
+func TestJSONOutput_Compact(t *testing.T) {
+	data := map[string]interface{}{"message": "Package not found", "version": "1.0", "name": "zip"}
+	out := renderJSON(t, map[string]interface{}{"message": "ok", "version": "1.0", "name": "zip"})
+	if lines := strings.Count(strings.TrimSpace(string(out)), "\n"); lines != 0 {
+		t.Errorf("json output is not a single line:\n%s", out)
+	}
+
+	lib.Env.Format = "json"
+	defer func() { lib.Env.Format = "text" }()
+	reply.SetPrettyJSON(true)
+	defer reply.SetPrettyJSON(false)
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	err = reply.CliResponse(context.Background(), reply.APIResponse{Data: data, Error: true})
+	os.Stdout = stdout
+	_ = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pretty, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "\"data\": {\n    \"message\": \"Package not found\",\n    \"name\": \"zip\",\n    \"version\": \"1.0\"\n  }"
+	if !strings.Contains(string(pretty), want) {
+		t.Errorf("unexpected pretty json output:\n%s", pretty)
+	}
+}
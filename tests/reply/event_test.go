@@ -0,0 +1,85 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// event_test.go
+package reply
+
+import (
+	"apm/cmd/common/reply"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// eventNameRegexp находит имена событий в вызовах WithEventName. Для имён с суффиксом пакета или слоя
+// захватывается префикс: "system.downloadProgress-"+pkgName.
+var eventNameRegexp = regexp.MustCompile(`WithEventName\("([^"]+)"`)
+
+// TestEventNames_Documented перечисляет все имена событий, которые отправляет код, и сверяет их
+// с реестром событий: у каждого события есть описание, а в реестре нет событий, которые не отправляются.
+func TestEventNames_Documented(t *testing.T) {
+	root := filepath.Join("..", "..")
+	emitted := map[string]string{}
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && path != root && (entry.Name() == "tests" || strings.HasPrefix(entry.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, match := range eventNameRegexp.FindAllStringSubmatch(string(content), -1) {
+			emitted[match[1]] = path
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documented := map[string]bool{}
+	for _, name := range reply.EventNames() {
+		documented[name] = true
+	}
+
+	var emittedNames []string
+	for name, path := range emitted {
+		emittedNames = append(emittedNames, name)
+		if !documented[name] {
+			t.Errorf("event %s emitted in %s is missing from the event registry", name, path)
+		}
+	}
+	sort.Strings(emittedNames)
+	t.Logf("events emitted by apm: %s", strings.Join(emittedNames, ", "))
+
+	for name := range documented {
+		if _, ok := emitted[name]; !ok {
+			t.Errorf("event %s is in the event registry but never emitted", name)
+		}
+	}
+}
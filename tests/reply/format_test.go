@@ -0,0 +1,168 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// format_test.go
+package reply
+
+import (
+	"apm/cmd/common/reply"
+	"apm/cmd/distrobox"
+	distroService "apm/cmd/distrobox/service"
+	"apm/cmd/system"
+	"apm/cmd/system/service"
+	"apm/lib"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// renderText выводит ответ в текстовом формате без цветов и возвращает напечатанный текст.
+func renderText(t *testing.T, data map[string]interface{}) string {
+	lib.Env.Format = "text"
+	if err := reply.SetColorMode(reply.ColorNever); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	err = reply.CliResponse(context.Background(), reply.APIResponse{Data: data})
+	_ = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(out)
+}
+
+// assertGolden сравнивает вывод с эталоном testdata/<name>.golden, с флагом -update перезаписывает эталон.
+func assertGolden(t *testing.T, name string, got string) {
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s:\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
+
+func TestTextFormat_SystemPackages(t *testing.T) {
+	out := renderText(t, map[string]interface{}{
+		"message": "2 records found",
+		"packages": []system.ShortPackageResponse{
+			{
+				Name:        "zip",
+				Installed:   true,
+				Version:     "3.0-alt3",
+				Reason:      "manual",
+				Description: "A file compression and packaging utility compatible with PKZIP\nLong description.",
+			},
+			{
+				Name:        "zsh",
+				Version:     "5.9-alt2",
+				Description: "The Z shell, " + strings.Repeat("a very long description ", 10),
+			},
+		},
+		"totalCount": 2,
+	})
+
+	assertGolden(t, "system_packages", out)
+}
+
+func TestTextFormat_DistroboxPackages(t *testing.T) {
+	out := renderText(t, map[string]interface{}{
+		"message": "1 record found",
+		"packages": []distroService.PackageInfo{
+			{
+				Name:        "firefox",
+				Version:     "128.0",
+				Description: "Web browser",
+				Container:   "arch",
+				Installed:   true,
+				Exporting:   true,
+				Manager:     "pacman",
+			},
+		},
+	})
+
+	assertGolden(t, "distrobox_packages", out)
+}
+
+func TestTextFormat_Containers(t *testing.T) {
+	out := renderText(t, map[string]interface{}{
+		"message": "1 record found",
+		"containers": []distrobox.ContainerListItem{
+			{
+				ContainerInfo:  distroService.ContainerInfo{OS: "Arch Linux", ContainerName: "arch", Active: true},
+				Status:         "Up 2 hours",
+				Image:          "docker.io/library/archlinux:latest",
+				Running:        true,
+				PackageCount:   412,
+				InstalledCount: 187,
+				ExportedCount:  2,
+				Manager:        "pacman",
+				Network:        "host",
+				SizeMB:         1843.5,
+			},
+		},
+	})
+
+	assertGolden(t, "containers", out)
+}
+
+func TestTextFormat_ImageHistory(t *testing.T) {
+	out := renderText(t, map[string]interface{}{
+		"message": "1 record found",
+		"history": []service.ImageHistory{
+			{
+				ID:         3,
+				ImageName:  "localhost/os:latest",
+				ImageID:    "4f2a9c",
+				ConfigHash: "c0ffee",
+				Status:     "deployed",
+				ImageDate:  "2025-03-25T17:37:00+03:00",
+				PackageDiff: &service.PackageDiff{
+					Installed: []string{"zip"},
+				},
+			},
+		},
+	})
+
+	assertGolden(t, "image_history", out)
+}
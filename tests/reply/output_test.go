@@ -0,0 +1,84 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// output_test.go
+package reply
+
+import (
+	"apm/cmd/common/reply"
+	"apm/lib"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestOutputFile_JSON проверяет, что ответ записывается в файл целиком, а в терминал выводится текстом.
+func TestOutputFile_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "result.json")
+	reply.SetOutputFile(path)
+	defer reply.SetOutputFile("")
+
+	out := renderText(t, map[string]interface{}{"message": "Package found", "name": "zip"})
+	if !strings.Contains(out, "Package found") {
+		t.Errorf("terminal output is not text:\n%s", out)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resp reply.APIResponse
+	if err = json.Unmarshal(content, &resp); err != nil {
+		t.Fatalf("output file is not json: %v\n%s", err, content)
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	if data["name"] != "zip" || resp.APIVersion != reply.APIVersion {
+		t.Errorf("unexpected output file content:\n%s", content)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".*.tmp"))
+	if len(matches) > 0 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+}
+
+// TestOutputFile_WriteError проверяет, что ошибка записи файла возвращается вызывающему.
+func TestOutputFile_WriteError(t *testing.T) {
+	lib.Env.Format = "json"
+	defer func() { lib.Env.Format = "text" }()
+
+	reply.SetOutputFile(filepath.Join(t.TempDir(), "missing", "result.json"))
+	defer reply.SetOutputFile("")
+
+	stdout := os.Stdout
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = devNull
+	defer func() {
+		os.Stdout = stdout
+		_ = devNull.Close()
+	}()
+
+	if err = reply.CliResponse(context.Background(), reply.APIResponse{Data: map[string]interface{}{"message": "ok"}}); err == nil {
+		t.Error("write error is not returned")
+	}
+}
@@ -0,0 +1,64 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reply
+
+import (
+	"apm/cmd/common/reply"
+	"context"
+	"testing"
+	"time"
+)
+
+// TestTimingCollector проверяет суммирование замеров этапа и nil-коллектор вне операции.
+func TestTimingCollector(t *testing.T) {
+	ctx, timings := reply.WithTimings(context.Background())
+	if reply.TimingsFromContext(ctx) != timings {
+		t.Fatal("collector is not stored in the context")
+	}
+
+	for i := 0; i < 2; i++ {
+		stop := timings.Start(reply.TimingApt)
+		time.Sleep(5 * time.Millisecond)
+		stop()
+	}
+
+	result := timings.Milliseconds()
+	if result[reply.TimingApt] < 10 {
+		t.Errorf("apt = %d ms, want at least 10 ms", result[reply.TimingApt])
+	}
+
+	missing := reply.TimingsFromContext(context.Background())
+	missing.Start(reply.TimingApt)()
+	if missing.Milliseconds() != nil {
+		t.Error("nil collector must not report timings")
+	}
+}
+
+// TestTimingSummary проверяет порядок этапов в строке подробного вывода.
+func TestTimingSummary(t *testing.T) {
+	summary := reply.TimingSummary("", map[string]int64{
+		reply.TimingPackagesDB: 40,
+		reply.TimingApt:        1200,
+		reply.TimingAptCheck:   85,
+		"custom":               3,
+	})
+
+	expected := "Timings: apt check 85 ms, apt 1200 ms, database sync 40 ms, Custom 3 ms"
+	if summary != expected {
+		t.Errorf("TimingSummary() = %q, want %q", summary, expected)
+	}
+}
@@ -0,0 +1,150 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// translate_test.go
+package reply
+
+import (
+	"apm/cmd/common/reply"
+	"apm/cmd/distrobox"
+	distroService "apm/cmd/distrobox/service"
+	"apm/cmd/system"
+	"apm/cmd/system/apt"
+	"apm/cmd/system/service"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// collectFieldPaths обходит поля структуры и возвращает пути через точку по json-тегам,
+// как они выглядят в ответе. Встроенные структуры раскрываются на том же уровне.
+func collectFieldPaths(t reflect.Type, prefix string, seen map[reflect.Type]bool) []string {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var paths []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous {
+			paths = append(paths, collectFieldPaths(field.Type, prefix, seen)...)
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		paths = append(paths, path)
+		paths = append(paths, collectFieldPaths(field.Type, path, seen)...)
+	}
+
+	return paths
+}
+
+// TestFieldLabels_ResponseStructs проверяет, что у каждого поля ответов есть подпись.
+func TestFieldLabels_ResponseStructs(t *testing.T) {
+	responses := map[string]interface{}{
+		"ShortPackageResponse":       system.ShortPackageResponse{},
+		"UpgradablePackageResponse":  system.UpgradablePackageResponse{},
+		"Package":                    apt.Package{},
+		"ImageStatus":                system.ImageStatus{},
+		"service.ImageStatus":        service.ImageStatus{},
+		"ContainerInfo":              distroService.ContainerInfo{},
+		"ContainerListItem":          distrobox.ContainerListItem{},
+		"PackageInfo":                distroService.PackageInfo{},
+		"InfoPackageAnswer":          distroService.InfoPackageAnswer{},
+		"PackageQueryResult":         distroService.PackageQueryResult{},
+		"ImageStatusResponse":        system.ImageStatusResponse{},
+		"ImageHistoryResponse":       system.ImageHistoryResponse{},
+		"PackageChangesResponse":     system.PackageChangesResponse{},
+		"ImageApplyResponse":         system.ImageApplyResponse{},
+		"ImageUpdateResponse":        system.ImageUpdateResponse{},
+		"ImageBuildResponse":         system.ImageBuildResponse{},
+		"MaintainerListResponse":     system.MaintainerListResponse{},
+		"QuickStatsResponse":         system.QuickStatsResponse{},
+		"PackageInfoResponse":        distrobox.PackageInfoResponse{},
+		"PackageListResponse":        system.PackageListResponse{},
+		"PackageListFullResponse":    system.PackageListFullResponse{},
+		"UpgradableListResponse":     system.UpgradableListResponse{},
+		"PackageSearchResponse":      system.PackageSearchResponse{},
+		"PackageSearchFullResponse":  system.PackageSearchFullResponse{},
+		"system.PackageInfoResponse": system.PackageInfoResponse{},
+		"PackageInfoFullResponse":    system.PackageInfoFullResponse{},
+		"ContainerListResponse":      distrobox.ContainerListResponse{},
+		"InitContainerResponse":      distrobox.InitContainerResponse{},
+	}
+
+	for name, response := range responses {
+		for _, path := range collectFieldPaths(reflect.TypeOf(response), "", map[reflect.Type]bool{}) {
+			// Сообщение ответа выводится корнем дерева без подписи
+			if path == "message" {
+				continue
+			}
+			if !reply.HasFieldLabel(path) {
+				t.Errorf("%s: no label for field %q", name, path)
+			}
+		}
+	}
+}
+
+// TestTranslateField_Fallback проверяет подписи полей без записи в реестре.
+func TestTranslateField_Fallback(t *testing.T) {
+	cases := map[string]string{
+		"someNewField":       "Some new field",
+		"imageID":            "Image ID",
+		"packageInfo.rawKey": "Raw key",
+		"snake_case_key":     "Snake case key",
+	}
+
+	for path, expected := range cases {
+		if got := reply.TranslateField("", path); got != expected {
+			t.Errorf("TranslateField(%q) = %q, want %q", path, got, expected)
+		}
+	}
+}
+
+// TestTranslateField_Overrides проверяет приоритет подписей пути и команды.
+func TestTranslateField_Overrides(t *testing.T) {
+	reply.RegisterFieldLabels("apm test", map[string]string{"name": "Test name"})
+
+	if got := reply.TranslateField("apm test", "packages.name"); got != "Test name" {
+		t.Errorf("command override: got %q", got)
+	}
+	if got := reply.TranslateField("", "packages.name"); got != reply.TranslateKey("name") {
+		t.Errorf("override leaked to other commands: got %q", got)
+	}
+	if got := reply.TranslateField("", "config.image"); got == reply.TranslateKey("image") {
+		t.Errorf("dotted path label is not used: got %q", got)
+	}
+}
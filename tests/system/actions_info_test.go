@@ -98,12 +98,11 @@ func TestInfo_Success_sqlmock(t *testing.T) {
 	assert.NoError(t, err)
 	assert.False(t, resp.Error)
 
-	data, ok := resp.Data.(map[string]interface{})
+	data, ok := resp.Data.(system.PackageInfoFullResponse)
 	assert.True(t, ok)
-	assert.Equal(t, "Найден пакет", data["message"])
+	assert.Equal(t, "Найден пакет", data.Message)
 
-	pkgInfo, ok := data["packageInfo"].(apt.Package)
-	assert.True(t, ok)
+	pkgInfo := data.PackageInfo
 	assert.Equal(t, fakePkg.Name, pkgInfo.Name)
 	assert.Equal(t, fakePkg.Version, pkgInfo.Version)
 	assert.Equal(t, fakePkg.Installed, pkgInfo.Installed)
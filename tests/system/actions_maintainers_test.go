@@ -0,0 +1,67 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// actions_maintainers_test.go
+package system
+
+import (
+	"apm/cmd/system"
+	"apm/cmd/system/apt"
+	"apm/cmd/system/service"
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestListMaintainers_sqlmock проверяет, что сопровождающие возвращаются в порядке убывания числа пакетов.
+func TestListMaintainers_sqlmock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	packageDBSvc := apt.NewPackageDBService(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM host_image_packages")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT maintainer, COUNT(*) FROM host_image_packages GROUP BY maintainer")).
+		WillReturnRows(sqlmock.NewRows([]string{"maintainer", "count"}).
+			AddRow("Vendor <vendor@example.org>", 2).
+			AddRow(nil, 1))
+
+	actions := system.NewActionsWithDeps(
+		packageDBSvc,
+		apt.NewActions(packageDBSvc),
+		&service.HostImageService{},
+		&service.HostDBService{},
+		&service.HostConfigService{},
+	)
+
+	resp, err := actions.ListMaintainers(context.Background())
+	assert.NoError(t, err)
+
+	data, ok := resp.Data.(system.MaintainerListResponse)
+	assert.True(t, ok)
+	assert.Equal(t, 2, data.TotalCount)
+	assert.Equal(t, []apt.MaintainerCount{
+		{Maintainer: "Vendor <vendor@example.org>", Count: 2},
+		{Maintainer: "", Count: 1},
+	}, data.Maintainers)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
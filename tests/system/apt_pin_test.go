@@ -0,0 +1,40 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// apt_pin_test.go
+package system
+
+import (
+	"apm/cmd/system/apt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParsePin проверяет разбор файла закрепления, который записывает apt-pin set.
+func TestParsePin(t *testing.T) {
+	pin := apt.ParsePin("Package: vim\nPin: version 2:9.1.0-alt1\nPin-Priority: 1001\n")
+
+	assert.Equal(t, apt.AptPin{Package: "vim", Version: "2:9.1.0-alt1", Priority: 1001}, pin)
+}
+
+// TestValidatePin проверяет, что имя пакета не может выйти за пределы каталога preferences.d.
+func TestValidatePin(t *testing.T) {
+	assert.NoError(t, apt.ValidatePin("vim", "9.1*", apt.DefaultPinPriority))
+	assert.Error(t, apt.ValidatePin("../sources", "1.0", apt.DefaultPinPriority))
+	assert.Error(t, apt.ValidatePin("vim", "1.0\nPin-Priority: -1", apt.DefaultPinPriority))
+	assert.Error(t, apt.ValidatePin("vim", "1.0", 0))
+}
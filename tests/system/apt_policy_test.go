@@ -0,0 +1,84 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+// apt_policy_test.go
+package system
+
+import (
+	"apm/cmd/system/apt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseVersionTable проверяет разбор таблицы версий apt-cache policy для info --all-versions.
+func TestParseVersionTable(t *testing.T) {
+	releaseOutput := `Package Files:
+ 100 /var/lib/rpm/Packages
+     release a=now
+ 500 http://ftp.altlinux.org/pub/distributions/ALTLinux Sisyphus/noarch/classic pkglist
+     release o=ALT Linux Team,a=Sisyphus,l=Sisyphus,c=classic
+ 500 http://ftp.altlinux.org/pub/distributions/ALTLinux Sisyphus/x86_64/classic pkglist
+     release o=ALT Linux Team,a=Sisyphus,l=Sisyphus,c=classic
+ 990 http://mirror.example/p10 p10/x86_64/classic pkglist
+Pinned Packages:
+`
+
+	tests := []struct {
+		name   string
+		output string
+		want   []apt.VersionEntry
+	}{
+		{
+			name: "installed and newer version",
+			output: `vim-console:
+  Installed: 4:9.1.0-alt1
+  Candidate: 4:9.1.1-alt1
+  Version table:
+     4:9.1.1-alt1 0
+        500 http://ftp.altlinux.org/pub/distributions/ALTLinux Sisyphus/x86_64/classic pkglist
+ *** 4:9.1.0-alt1 0
+        990 http://mirror.example/p10 p10/x86_64/classic pkglist
+        100 RPM Database
+`,
+			want: []apt.VersionEntry{
+				{Version: "4:9.1.1-alt1", Priority: 500, Repository: "http://ftp.altlinux.org/pub/distributions/ALTLinux",
+					Archive: "Sisyphus", Component: "classic", Origin: "ALT Linux Team", Label: "Sisyphus"},
+				{Version: "4:9.1.0-alt1", Priority: 990, Repository: "http://mirror.example/p10",
+					Archive: "p10", Component: "classic", Installed: true},
+			},
+		},
+		{
+			name: "installed only from the package database",
+			output: `local-tool:
+  Installed: 1.0-alt1
+  Candidate: 1.0-alt1
+  Version table:
+ *** 1.0-alt1 0
+        100 RPM Database
+`,
+			want: []apt.VersionEntry{{Version: "1.0-alt1", Priority: 100, Installed: true}},
+		},
+		{
+			name:   "unknown package",
+			output: "W: Unable to locate package nothing\n",
+			want:   []apt.VersionEntry{},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, apt.ParseVersionTable(tt.output, releaseOutput), tt.name)
+	}
+}
@@ -0,0 +1,67 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// apt_sources_test.go
+
+package system
+
+import (
+	"apm/cmd/system/service"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAddRepository проверяет, что репозиторий добавляется в общий список источников apt, а его ключ
+// устанавливается для apt-rpm через vendors.list, а не через apt-key.
+func TestAddRepository(t *testing.T) {
+	svc := service.NewHostConfigService(filepath.Join(t.TempDir(), "image.yml"), nil)
+	svc.Config = &service.Config{Image: "registry.example/os:latest"}
+
+	source, err := svc.AddRepository("https://repo.example/pub/x86_64/", "classic", "https://repo.example/key.asc", "example")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "rpm [example] https://repo.example/pub x86_64 classic", source.SourceLine)
+	assert.Equal(t, "example", source.Vendor())
+	assert.Equal(t, []service.AptSourceConfig{source}, svc.Config.AptSources)
+
+	_, err = svc.AddAptSource("rpm [example] https://repo.example/pub x86_64 classic", "")
+	assert.Error(t, err, "the same source added with add-source")
+
+	_, err = svc.AddRepository("https://repo.example/pub/noarch", "classic", "", "example")
+	assert.NoError(t, err)
+	assert.Len(t, svc.AptSourceWarnings(), 1, "vendor without a key URL")
+
+	for _, args := range [][2]string{
+		{"https://repo.example", "classic"},
+		{"https://repo.example/pub/x86_64", "classic;rm"},
+		{"gopher://repo.example/pub/x86_64", "classic"},
+	} {
+		_, err = svc.AddRepository(args[0], args[1], "", "")
+		assert.Error(t, err, args[0]+" "+args[1])
+	}
+
+	dockerfile, err := svc.DockerfileContent("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, dockerfile, `RUN echo "rpm [example] https://repo.example/pub x86_64 classic" >> /etc/apt/sources.list.d/custom.list`)
+	assert.Contains(t, dockerfile, "gpg --homedir /usr/lib/alt-gpgkeys")
+	assert.Contains(t, dockerfile, "> /etc/apt/vendors.list.d/apm-example.list")
+	assert.NotContains(t, dockerfile, "apt-key")
+}
@@ -0,0 +1,48 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// build_cache_test.go
+package system
+
+import (
+	"apm/cmd/system/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseBuildCacheSize проверяет размер кеша сборки из вывода podman system df.
+func TestParseBuildCacheSize(t *testing.T) {
+	podmanOutput := `[
+  {"Type": "Images", "Total": 12, "Active": 2, "RawSize": 5368709120, "RawReclaimable": 1073741824, "TotalCount": 12},
+  {"Type": "Containers", "Total": 2, "Active": 1, "RawSize": 1048576, "RawReclaimable": 0, "TotalCount": 2},
+  {"Type": "Local Volumes", "Total": 0, "Active": 0, "RawSize": 0, "RawReclaimable": 0, "TotalCount": 0}
+]`
+	size, err := service.ParseBuildCacheSize([]byte(podmanOutput))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1073741824), size)
+
+	withBuildCache := `[
+  {"Type": "Images", "RawSize": 5368709120, "RawReclaimable": 1073741824},
+  {"Type": "Build Cache", "RawSize": 209715200, "RawReclaimable": 209715200}
+]`
+	size, err = service.ParseBuildCacheSize([]byte(withBuildCache))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(209715200), size)
+
+	_, err = service.ParseBuildCacheSize([]byte("not json"))
+	assert.Error(t, err)
+}
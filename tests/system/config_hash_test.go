@@ -0,0 +1,62 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// config_hash_test.go
+package system
+
+import (
+	"apm/cmd/system/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHashConfig проверяет, что закрепление пакета и базового образа меняет хеш конфигурации,
+// иначе image apply посчитает, что изменений нет, и не соберёт образ.
+func TestHashConfig(t *testing.T) {
+	var cfg service.Config
+	cfg.Image = "registry.example/os:latest"
+	cfg.Packages.Install = []string{"zip"}
+
+	base, err := service.HashConfig(cfg, "sha256:base")
+	assert.NoError(t, err)
+
+	same, err := service.HashConfig(cfg, "sha256:base")
+	assert.NoError(t, err)
+	assert.Equal(t, base, same)
+
+	held := cfg
+	held.HeldPackages = []service.HeldPackage{{Name: "zip"}}
+	heldHash, err := service.HashConfig(held, "sha256:base")
+	assert.NoError(t, err)
+	assert.NotEqual(t, base, heldHash)
+
+	heldVersion := cfg
+	heldVersion.HeldPackages = []service.HeldPackage{{Name: "zip", Version: "3.0-alt3"}}
+	heldVersionHash, err := service.HashConfig(heldVersion, "sha256:base")
+	assert.NoError(t, err)
+	assert.NotEqual(t, heldHash, heldVersionHash)
+
+	pinned := cfg
+	pinned.Pinned = true
+	pinnedHash, err := service.HashConfig(pinned, "sha256:base")
+	assert.NoError(t, err)
+	assert.NotEqual(t, base, pinnedHash)
+
+	otherBase, err := service.HashConfig(cfg, "sha256:other")
+	assert.NoError(t, err)
+	assert.NotEqual(t, base, otherBase)
+}
@@ -0,0 +1,57 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// config_reload_test.go
+package system
+
+import (
+	"apm/cmd/system/service"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReloadConfig_SkipsOwnWrites проверяет, что запись самого сервиса не считается изменением извне,
+// а правка файла другим процессом заменяет конфигурацию.
+func TestReloadConfig_SkipsOwnWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image.yml")
+	assert.NoError(t, os.WriteFile(path, []byte("image: registry.example/os:latest\n"), 0644))
+
+	svc := service.NewHostConfigService(path, nil)
+	if !assert.NoError(t, svc.LoadConfig()) {
+		return
+	}
+
+	svc.Config.Packages.Install = append(svc.Config.Packages.Install, "zip")
+	assert.NoError(t, svc.SaveConfig())
+	current := svc.Config
+
+	previous, err := svc.ReloadConfig()
+	assert.NoError(t, err)
+	assert.Same(t, current, previous)
+	assert.Same(t, current, svc.Config)
+	assert.Empty(t, service.SummarizeConfigChanges(previous, svc.Config))
+
+	assert.NoError(t, os.WriteFile(path, []byte("image: registry.example/os:latest\npackages:\n  install: [zip, vim]\n"), 0644))
+
+	previous, err = svc.ReloadConfig()
+	assert.NoError(t, err)
+	assert.Same(t, current, previous)
+	assert.Equal(t, []string{"zip", "vim"}, svc.Config.Packages.Install)
+	assert.NotEmpty(t, service.SummarizeConfigChanges(previous, svc.Config))
+}
@@ -0,0 +1,59 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// dockerfile_import_test.go
+package system
+
+import (
+	"apm/cmd/system/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseDockerfile проверяет перенос базового образа, пакетов, команд и переменных окружения.
+func TestParseDockerfile(t *testing.T) {
+	cfg, warnings := service.ParseDockerfile(`# сторонний Dockerfile
+FROM registry.altlinux.org/sisyphus/base:latest
+ENV LANG=ru_RU.UTF-8
+RUN apt-get update && \
+    apt-get -y install vim htop+ nano- && \
+    apt-get remove -y firefox
+RUN systemctl enable sshd
+COPY motd /etc/motd
+`)
+
+	assert.Equal(t, "registry.altlinux.org/sisyphus/base:latest", cfg.Image)
+	assert.Equal(t, []string{"vim", "htop"}, cfg.Packages.Install)
+	assert.Equal(t, []string{"nano", "firefox"}, cfg.Packages.Remove)
+	assert.Equal(t, []string{"systemctl enable sshd"}, cfg.Commands)
+	assert.Equal(t, []service.EnvVar{{Key: "LANG", Value: "ru_RU.UTF-8"}}, cfg.EnvVars)
+	assert.Len(t, warnings, 1)
+}
+
+// TestParseDockerfile_MultiStage проверяет, что из многоэтапной сборки переносится последняя стадия.
+func TestParseDockerfile_MultiStage(t *testing.T) {
+	cfg, warnings := service.ParseDockerfile(`FROM alt:sisyphus AS builder
+RUN make
+FROM alt:p11
+RUN apt-get install -y git
+`)
+
+	assert.Equal(t, "alt:p11", cfg.Image)
+	assert.Equal(t, []string{"git"}, cfg.Packages.Install)
+	assert.Empty(t, cfg.Commands)
+	assert.Len(t, warnings, 1)
+}
@@ -0,0 +1,111 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+// held_packages_test.go
+package system
+
+import (
+	"apm/cmd/system/service"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPinPackage проверяет, что в конфигурацию попадают только допустимые имя и версия пакета:
+// они подставляются в команды Dockerfile.
+func TestPinPackage(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     string
+		version string
+		wantErr bool
+	}{
+		{"name only", "vim-console", "", false},
+		{"epoch version", "zip", "1:3.0-alt3", false},
+		{"trimmed", " gcc-c++ ", " 13.2.1-alt2 ", false},
+		{"empty name", "", "", true},
+		{"option", "-y", "", true},
+		{"command", "vim; rm -rf /", "", true},
+		{"version with spaces", "zip", "3.0 && reboot", true},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "image.yml")
+		assert.NoError(t, os.WriteFile(path, []byte("image: registry.example/os:latest\n"), 0644))
+		svc := service.NewHostConfigService(path, nil)
+		if !assert.NoError(t, svc.LoadConfig()) {
+			return
+		}
+
+		held, err := svc.PinPackage(tt.pkg, tt.version)
+		if tt.wantErr {
+			assert.Error(t, err, tt.name)
+			assert.Empty(t, svc.Config.HeldPackages, tt.name)
+			continue
+		}
+
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, service.HeldPackage{Name: strings.TrimSpace(tt.pkg), Version: strings.TrimSpace(tt.version)}, held, tt.name)
+		assert.Equal(t, []service.HeldPackage{held}, svc.Config.HeldPackages, tt.name)
+	}
+}
+
+// TestDockerfileHoldLines проверяет строки Dockerfile для закреплённых пакетов: версия устанавливается
+// перед закреплением, а каждый пакет закрепляется через apt-mark hold.
+func TestDockerfileHoldLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		held    []service.HeldPackage
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "without version",
+			held:    []service.HeldPackage{{Name: "vim-console"}},
+			want:    []string{"RUN apt-mark hold vim-console"},
+			notWant: []string{"vim-console="},
+		},
+		{
+			name: "with version",
+			held: []service.HeldPackage{{Name: "zip", Version: "1:3.0-alt3"}, {Name: "vim-console"}},
+			want: []string{"apt-get -y install zip=1:3.0-alt3", "RUN apt-mark hold zip", "RUN apt-mark hold vim-console"},
+		},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "image.yml")
+		svc := service.NewHostConfigService(path, nil)
+		svc.Config = &service.Config{Image: "registry.example/os:latest", HeldPackages: tt.held}
+
+		dockerfile, err := svc.DockerfileContent("")
+		if !assert.NoError(t, err, tt.name) {
+			continue
+		}
+
+		for _, line := range tt.want {
+			assert.Contains(t, dockerfile, line, tt.name)
+		}
+		for _, line := range tt.notWant {
+			assert.NotContains(t, dockerfile, line, tt.name)
+		}
+		// Строки want перечислены в порядке, в котором они идут в Dockerfile
+		for i := 1; i < len(tt.want); i++ {
+			assert.Less(t, strings.Index(dockerfile, tt.want[i-1]), strings.Index(dockerfile, tt.want[i]), tt.name)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+// image_check_test.go
+package system
+
+import (
+	"apm/cmd/system/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCompareImageDigests проверяет сравнение дайджеста образа в реестре с дайджестом образа хоста.
+func TestCompareImageDigests(t *testing.T) {
+	inspect := []byte(`{"Name": "registry.example/os", "Digest": "sha256:remote", "Created": "2025-03-25T18:00:00Z", "Layers": []}`)
+
+	tests := []struct {
+		name        string
+		localDigest string
+		output      []byte
+		wantStatus  string
+		wantUpdate  bool
+		wantErr     bool
+	}{
+		{"up to date", "sha256:remote", inspect, service.ImageUpToDate, false, false},
+		{"newer in registry", "sha256:local", inspect, service.ImageUpdateAvailable, true, false},
+		{"no local digest", "", inspect, service.ImageUpdateAvailable, true, false},
+		{"invalid output", "sha256:remote", []byte("Error: manifest unknown"), "", false, true},
+	}
+
+	for _, tt := range tests {
+		check, err := service.CompareImageDigests(service.ImageUpdateCheck{Image: "registry.example/os:latest", LocalDigest: tt.localDigest}, tt.output)
+		if tt.wantErr {
+			assert.Error(t, err, tt.name)
+			assert.Nil(t, check.UpdateAvailable, tt.name)
+			continue
+		}
+
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.wantStatus, check.Status, tt.name)
+		if assert.NotNil(t, check.UpdateAvailable, tt.name) {
+			assert.Equal(t, tt.wantUpdate, *check.UpdateAvailable, tt.name)
+		}
+		assert.Equal(t, "sha256:remote", check.RemoteDigest, tt.name)
+		assert.Equal(t, "2025-03-25T18:00:00Z", check.RemoteCreated, tt.name)
+		assert.Equal(t, tt.localDigest, check.LocalDigest, tt.name)
+	}
+}
@@ -0,0 +1,121 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+// image_history_test.go
+package system
+
+import (
+	"apm/cmd/common/helper"
+	"apm/cmd/system/service"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseTimeBound проверяет границы периода image history --since и --until.
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2025, 3, 25, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"30d", now.AddDate(0, 0, -30), false},
+		{"2w", now.AddDate(0, 0, -14), false},
+		{"12h", now.Add(-12 * time.Hour), false},
+		{"45m", now.Add(-45 * time.Minute), false},
+		{"2025-03-01", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2025-03-01T10:00:00+03:00", time.Date(2025, 3, 1, 7, 0, 0, 0, time.UTC), false},
+		{"-5d", time.Time{}, true},
+		{"-1h", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"d", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := helper.ParseTimeBound(tt.value, now)
+		if tt.wantErr {
+			assert.Error(t, err, tt.value)
+			continue
+		}
+
+		assert.NoError(t, err, tt.value)
+		assert.True(t, tt.want.Equal(got), "%s: expected %s, got %s", tt.value, tt.want, got)
+	}
+}
+
+// TestGetImageHistoriesFiltered проверяет фильтры истории образов по названию, периоду и статусу.
+// Записи без статуса, созданные до его появления, считаются установленными.
+func TestGetImageHistoriesFiltered(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "apm.db"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	history := service.NewHostDBService(db)
+	records := []service.ImageHistory{
+		{ImageName: "os-old", Status: service.ImageStatusDeployed, ImageDate: "2025-01-10T12:00:00Z"},
+		{ImageName: "os-built", Status: service.ImageStatusBuilt, ImageDate: "2025-02-10T12:00:00Z"},
+		{ImageName: "os-new", Status: service.ImageStatusDeployed, ImageDate: "2025-03-10T12:00:00Z"},
+	}
+	for _, record := range records {
+		if !assert.NoError(t, history.SaveImageToDB(ctx, record)) {
+			return
+		}
+	}
+	_, err = db.Exec("UPDATE host_image_history SET status = NULL WHERE imagename = 'os-old'")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		filter service.ImageHistoryFilter
+		want   []string
+	}{
+		{"no filter", service.ImageHistoryFilter{}, []string{"os-new", "os-built", "os-old"}},
+		{"name", service.ImageHistoryFilter{ImageName: "new"}, []string{"os-new"}},
+		{"since", service.ImageHistoryFilter{Since: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}, []string{"os-new", "os-built"}},
+		{"until", service.ImageHistoryFilter{Until: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}, []string{"os-old"}},
+		{"period", service.ImageHistoryFilter{
+			Since: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+			Until: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		}, []string{"os-built"}},
+		{"deployed with legacy records", service.ImageHistoryFilter{Status: service.ImageStatusDeployed}, []string{"os-new", "os-old"}},
+		{"built", service.ImageHistoryFilter{Status: service.ImageStatusBuilt}, []string{"os-built"}},
+		{"name and status", service.ImageHistoryFilter{ImageName: "os", Status: service.ImageStatusBuilt}, []string{"os-built"}},
+	}
+
+	for _, tt := range tests {
+		histories, err := history.GetImageHistoriesFiltered(ctx, tt.filter, 10, 0)
+		assert.NoError(t, err, tt.name)
+
+		names := []string{}
+		for _, h := range histories {
+			names = append(names, h.ImageName)
+		}
+		assert.Equal(t, tt.want, names, tt.name)
+
+		count, err := history.CountImageHistoriesFiltered(ctx, tt.filter)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, len(tt.want), count, tt.name)
+	}
+}
@@ -0,0 +1,111 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// image_retention_test.go
+
+package system
+
+import (
+	"apm/cmd/system/service"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// retentionBootc выводит состояние хоста с закреплённым развёртыванием образа ccc.
+const retentionBootc = `#!/bin/sh
+cat <<'JSON'
+{"status": {
+  "booted": {"image": {"image": {"image": "registry.example/os", "transport": "registry"}, "imageDigest": "sha256:aaa"}},
+  "otherDeployments": [{"image": {"image": {"image": "registry.example/os", "transport": "registry"}, "imageDigest": "sha256:ccc"}, "pinned": true}]
+}}
+JSON
+`
+
+// retentionPodman выводит пять собранных apm образов от новых к старым. Удаление ddd завершается ошибкой,
+// все вызовы rmi записываются в журнал.
+const retentionPodman = `#!/bin/sh
+case "$1" in
+images)
+	cat <<'JSON'
+[
+  {"Id": "aaa", "Created": 5, "Size": 10},
+  {"Id": "bbb", "Created": 4, "Size": 20},
+  {"Id": "ccc", "Created": 3, "Size": 30},
+  {"Id": "ddd", "Created": 2, "Size": 40},
+  {"Id": "eee", "Created": 1, "Size": 50}
+]
+JSON
+	;;
+rmi)
+	echo "$2" >> "$RMI_LOG"
+	[ "$2" != "ddd" ]
+	;;
+esac
+`
+
+// TestCollectGarbage_FailedRemoval проверяет, что закреплённое развёртывание не удаляется, а отметка об удалении
+// в истории остаётся только у образов, удаление которых прошло успешно.
+func TestCollectGarbage_FailedRemoval(t *testing.T) {
+	dir := t.TempDir()
+	for name, script := range map[string]string{"bootc": retentionBootc, "podman": retentionPodman} {
+		if !assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755)) {
+			return
+		}
+	}
+	rmiLog := filepath.Join(dir, "rmi.log")
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("RMI_LOG", rmiLog)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "apm.db"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	history := service.NewHostDBService(db)
+	for _, imageID := range []string{"bbb", "ccc", "ddd", "eee"} {
+		record := service.ImageHistory{ImageName: "os-" + imageID, ImageID: imageID, ImageDate: "2025-03-10T12:00:00Z"}
+		if !assert.NoError(t, history.SaveImageToDB(ctx, record)) {
+			return
+		}
+	}
+
+	images := service.NewHostImageService(service.NewHostConfigService(filepath.Join(dir, "image-apm.yml"), history))
+	result, err := images.CollectGarbage(ctx, service.RetentionPolicy{KeepImages: 1})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"bbb"}, result.PrunedImages)
+	assert.Equal(t, int64(20), result.FreedBytes)
+
+	removed, err := os.ReadFile(rmiLog)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bbb", "ddd"}, strings.Fields(string(removed)))
+
+	histories, err := history.GetImageHistoriesFiltered(ctx, service.ImageHistoryFilter{}, 10, 0)
+	assert.NoError(t, err)
+
+	marked := map[string]bool{}
+	for _, h := range histories {
+		marked[h.ImageID] = h.ImageRemoved != ""
+	}
+	assert.Equal(t, map[string]bool{"bbb": true, "ccc": false, "ddd": false, "eee": false}, marked)
+}
@@ -0,0 +1,53 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// journal_test.go
+package system
+
+import (
+	"apm/cmd/system"
+	"apm/lib"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReadPendingOperation проверяет чтение журнала незавершённой операции из KV-хранилища.
+func TestReadPendingOperation(t *testing.T) {
+	lib.Env.PathDBKV = filepath.Join(t.TempDir(), "kv")
+	db := lib.GetDBKv()
+
+	pending, err := system.ReadPendingOperation()
+	assert.NoError(t, err)
+	assert.Nil(t, pending)
+
+	err = db.Put([]byte("system:pendingOperation"),
+		[]byte(`{"operation":"install","packages":["zip"],"apply":true,"steps":["apt"],"startedAt":"2025-01-01T00:00:00Z"}`))
+	assert.NoError(t, err)
+
+	pending, err = system.ReadPendingOperation()
+	assert.NoError(t, err)
+	if assert.NotNil(t, pending) {
+		assert.Equal(t, "install", pending.Operation)
+		assert.Equal(t, []string{"zip"}, pending.Packages)
+		assert.True(t, pending.Apply)
+		assert.True(t, pending.Done(system.OperationStepApt))
+		assert.False(t, pending.Done(system.OperationStepPackagesDB))
+	}
+
+	assert.NoError(t, db.Delete([]byte("system:pendingOperation")))
+}
@@ -0,0 +1,141 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// locale_test.go
+package system
+
+import (
+	"apm/cmd/system"
+	"apm/lib"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode"
+
+	"github.com/urfave/cli/v3"
+)
+
+// runSystemHelp выполняет "apm system --help" на языке lang и возвращает вывод.
+func runSystemHelp(t *testing.T, lang string) string {
+	if err := lib.SetLanguage(lang); err != nil {
+		t.Fatalf("SetLanguage(%s): %v", lang, err)
+	}
+
+	var out bytes.Buffer
+	root := &cli.Command{
+		Name:     "apm",
+		Writer:   &out,
+		Commands: []*cli.Command{system.CommandList()},
+	}
+	if err := root.Run(context.Background(), []string{"apm", "system", "--help"}); err != nil {
+		t.Fatalf("run apm system --help (%s): %v", lang, err)
+	}
+
+	return out.String()
+}
+
+// hasCyrillic проверяет, есть ли в тексте кириллица.
+func hasCyrillic(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// useRussianCatalog подключает каталог po/ru.po из репозитория на время теста.
+func useRussianCatalog(t *testing.T) {
+	catalog, err := os.ReadFile(filepath.Join("..", "..", "po", "ru.po"))
+	if err != nil {
+		t.Fatalf("read ru.po: %v", err)
+	}
+
+	localesDir := t.TempDir()
+	messagesDir := filepath.Join(localesDir, "ru", "LC_MESSAGES")
+	if err = os.MkdirAll(messagesDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(messagesDir, "apm.po"), catalog, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	previousPath := lib.Env.PathLocales
+	lib.Env.PathLocales = localesDir
+	t.Cleanup(func() {
+		lib.Env.PathLocales = previousPath
+		lib.InitLocales()
+	})
+}
+
+// TestLanguageOverride проверяет, что при --lang en в выводе нет русского текста, а при --lang ru перевод подключается.
+func TestLanguageOverride(t *testing.T) {
+	useRussianCatalog(t)
+
+	if out := runSystemHelp(t, "en"); hasCyrillic(out) {
+		t.Errorf("English output contains Cyrillic text:\n%s", out)
+	}
+
+	if out := runSystemHelp(t, "ru"); !hasCyrillic(out) {
+		t.Errorf("Russian output is not translated:\n%s", out)
+	}
+
+	if err := lib.SetLanguage("de"); err == nil {
+		t.Error("SetLanguage accepted an unsupported language")
+	}
+}
+
+// TestPluralForms проверяет формы множественного числа из каталога: три формы в русском и две в английском.
+func TestPluralForms(t *testing.T) {
+	useRussianCatalog(t)
+
+	cases := []struct {
+		lang     string
+		count    int
+		expected string
+	}{
+		{"ru", 1, "1 запись найдена"},
+		{"ru", 3, "3 записи найдены"},
+		{"ru", 5, "5 записей найдено"},
+		{"ru", 11, "11 записей найдено"},
+		{"ru", 21, "21 запись найдена"},
+		{"en", 1, "1 record found"},
+		{"en", 5, "5 records found"},
+	}
+
+	for _, c := range cases {
+		if err := lib.SetLanguage(c.lang); err != nil {
+			t.Fatalf("SetLanguage(%s): %v", c.lang, err)
+		}
+
+		got := fmt.Sprintf(lib.TN_("%d record found", "%d records found", c.count), c.count)
+		if got != c.expected {
+			t.Errorf("%s, %d: got %q, want %q", c.lang, c.count, got, c.expected)
+		}
+	}
+
+	if err := lib.SetLanguage("ru"); err != nil {
+		t.Fatal(err)
+	}
+	got := fmt.Sprintf(lib.TN_("%d package successfully installed", "%d packages successfully installed", 2), 2)
+	if got != "2 пакета успешно установлено" {
+		t.Errorf("install message: got %q", got)
+	}
+}
@@ -0,0 +1,135 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// quick_stats_test.go
+package system
+
+import (
+	"apm/cmd/system"
+	"apm/cmd/system/apt"
+	"apm/cmd/system/service"
+	"apm/lib"
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// newQuickStatsActions создаёт Actions только с базой пакетов.
+func newQuickStatsActions(db *sql.DB) *system.Actions {
+	packageDBSvc := apt.NewPackageDBService(db)
+
+	return system.NewActionsWithDeps(
+		packageDBSvc,
+		apt.NewActions(packageDBSvc),
+		&service.HostImageService{},
+		&service.HostDBService{},
+		&service.HostConfigService{},
+	)
+}
+
+// useTempKV открывает KV-хранилище во временном каталоге, если оно ещё не открыто другим тестом.
+func useTempKV(tb testing.TB) {
+	if lib.CheckDBKv() == nil {
+		lib.Env.PathDBKV = filepath.Join(tb.TempDir(), "kv")
+	}
+}
+
+// TestQuickStats_sqlmock проверяет, что QuickStats возвращает три подсчёта и время синхронизации.
+func TestQuickStats_sqlmock(t *testing.T) {
+	useTempKV(t)
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Подсчёты выполняются параллельно, поэтому порядок запросов не фиксирован
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(regexp.QuoteMeta("PRAGMA table_info(host_image_packages)")).
+		WillReturnRows(sqlmock.NewRows([]string{"cid", "name", "type", "notnull", "dflt_value", "pk"}))
+	mock.ExpectQuery(regexp.QuoteMeta("PRAGMA table_info(host_image_packages)")).
+		WillReturnRows(sqlmock.NewRows([]string{"cid", "name", "type", "notnull", "dflt_value", "pk"}))
+	mock.ExpectQuery(`^SELECT COUNT\(\*\) FROM host_image_packages$`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(120))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM host_image_packages WHERE installed = ?")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(40))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM host_image_packages WHERE installed = 1 AND versionInstalled != ''")).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	apt.RecordSyncTime()
+
+	resp, err := newQuickStatsActions(db).QuickStats(context.Background())
+	assert.NoError(t, err)
+
+	data, ok := resp.Data.(system.QuickStatsResponse)
+	if assert.True(t, ok) {
+		assert.Equal(t, int64(120), data.Total)
+		assert.Equal(t, int64(40), data.Installed)
+		assert.Equal(t, 3, data.Upgradable)
+		_, err = time.Parse(time.RFC3339, data.LastSyncTime)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// BenchmarkQuickStats замеряет QuickStats на базе из 50 000 пакетов: подсчёт должен укладываться в 100 мс.
+func BenchmarkQuickStats(b *testing.B) {
+	useTempKV(b)
+
+	db, err := sql.Open("sqlite3", filepath.Join(b.TempDir(), "apm.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	packages := make([]apt.Package, 50000)
+	for i := range packages {
+		packages[i] = apt.Package{
+			Name:      fmt.Sprintf("package-%d", i),
+			Version:   "1.0-alt1",
+			Installed: i%4 == 0,
+		}
+		if packages[i].Installed {
+			packages[i].VersionInstalled = "0.9-alt1"
+		}
+	}
+	if err = apt.NewPackageDBService(db).SavePackagesToDB(context.Background(), packages); err != nil {
+		b.Fatal(err)
+	}
+
+	actions := newQuickStatsActions(db)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	started := time.Now()
+	for i := 0; i < b.N; i++ {
+		if _, err = actions.QuickStats(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if perOp := time.Since(started) / time.Duration(b.N); perOp > 100*time.Millisecond {
+		b.Errorf("QuickStats took %v, want under 100ms", perOp)
+	}
+}
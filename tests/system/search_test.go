@@ -0,0 +1,72 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+// search_test.go
+package system
+
+import (
+	"apm/cmd/system/apt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSearchMatch проверяет, в каком поле пакет совпал с запросом.
+func TestSearchMatch(t *testing.T) {
+	tests := []struct {
+		pkg   apt.Package
+		query string
+		want  string
+	}{
+		{apt.Package{Name: "vim-console", Description: "Console editor"}, "vim", apt.MatchedOnName},
+		{apt.Package{Name: "neovim", Description: "Vim-fork focused on extensibility"}, "VIM", apt.MatchedOnBoth},
+		{apt.Package{Name: "nano", Description: "Small editor, a vim alternative"}, "vim", apt.MatchedOnDescription},
+		{apt.Package{Name: "emacs", Description: "Extensible editor"}, "vim", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, apt.SearchMatch(tt.pkg, tt.query), tt.pkg.Name)
+	}
+}
+
+// TestSortBySearchRelevance проверяет порядок выдачи: точное совпадение названия, начало названия,
+// вхождение в название, совпадение только в описании; при равной релевантности - по названию.
+func TestSortBySearchRelevance(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		input []string
+		want  []string
+	}{
+		{"relevance", "vim", []string{"nano", "neovim", "vim-console", "vim", "gvim"}, []string{"vim", "vim-console", "gvim", "neovim", "nano"}},
+		{"case insensitive", "VIM", []string{"vim-data", "Vim"}, []string{"Vim", "vim-data"}},
+		{"description only", "editor", []string{"vim", "emacs", "nano"}, []string{"emacs", "nano", "vim"}},
+	}
+
+	for _, tt := range tests {
+		packages := make([]apt.Package, 0, len(tt.input))
+		for _, name := range tt.input {
+			packages = append(packages, apt.Package{Name: name})
+		}
+
+		apt.SortBySearchRelevance(packages, tt.query)
+
+		names := make([]string, 0, len(packages))
+		for _, pkg := range packages {
+			names = append(names, pkg.Name)
+		}
+		assert.Equal(t, tt.want, names, tt.name)
+	}
+}
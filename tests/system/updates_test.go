@@ -0,0 +1,105 @@
+// Atomic Package Manager
+// Copyright (C) 2025 Дмитрий Удалов dmitry@udalov.online
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// updates_test.go
+package system
+
+import (
+	"apm/cmd/system"
+	"apm/lib"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUpdateCheckBackoff проверяет удвоение интервала после неудачных проверок и ограничение в сутки.
+func TestUpdateCheckBackoff(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{6 * time.Hour, 0, 6 * time.Hour},
+		{6 * time.Hour, 1, 12 * time.Hour},
+		{6 * time.Hour, 2, 24 * time.Hour},
+		{6 * time.Hour, 10, 24 * time.Hour},
+		{time.Hour, 3, 8 * time.Hour},
+		{36 * time.Hour, 0, 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, system.UpdateCheckBackoff(tt.interval, tt.failures), "interval %s, failures %d", tt.interval, tt.failures)
+	}
+}
+
+// TestGetAvailableUpdates_NotChecked проверяет, что до первой проверки возвращается пустой результат, а не ошибка.
+func TestGetAvailableUpdates_NotChecked(t *testing.T) {
+	lib.Env.PathDBKV = filepath.Join(t.TempDir(), "kv")
+	assert.NoError(t, lib.GetDBKv().Delete([]byte("system:availableUpdates")))
+
+	resp, err := system.NewActionsWithDeps(nil, nil, nil, nil, nil).GetAvailableUpdates(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	data := resp.Data.(map[string]interface{})
+	assert.Equal(t, system.AvailableUpdates{Packages: []string{}}, data["updates"])
+}
+
+// TestNetworkMetered проверяет, что проверка откладывается на лимитном и неполном соединении.
+func TestNetworkMetered(t *testing.T) {
+	tests := []struct {
+		name         string
+		metered      uint32
+		connectivity uint32
+		want         bool
+	}{
+		{"unknown", 0, 0, false},
+		{"full", 2, 4, false},
+		{"metered", 1, 4, true},
+		{"guessed metered", 3, 4, true},
+		{"guessed not metered", 4, 4, false},
+		{"portal", 2, 2, true},
+		{"limited", 0, 3, true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, system.NetworkMetered(tt.metered, tt.connectivity), tt.name)
+	}
+}
+
+// TestRepositoriesConfigured проверяет поиск подключённых репозиториев: закомментированные строки
+// и отсутствующие файлы не учитываются.
+func TestRepositoriesConfigured(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	commented := write("commented.list", "# rpm [alt] http://ftp.altlinux.org/pub/distributions/ALTLinux Sisyphus/x86_64 classic\n\n")
+	enabled := write("enabled.list", "  rpm [alt] http://ftp.altlinux.org/pub/distributions/ALTLinux Sisyphus/noarch classic\n")
+	missing := filepath.Join(dir, "missing.list")
+
+	assert.False(t, system.RepositoriesConfigured(nil))
+	assert.False(t, system.RepositoriesConfigured([]string{commented, missing}))
+	assert.True(t, system.RepositoriesConfigured([]string{missing, commented, enabled}))
+}